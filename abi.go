@@ -0,0 +1,211 @@
+package imt
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// abiEncode encodes a list of values as a Solidity ABI tuple, as done by
+// `abi.encode`. Only the elementary types are supported: address, bool,
+// uint<M>, int<M>, bytes<M>, bytes and string.
+func abiEncode(types []string, values []any) ([]byte, error) {
+	if len(types) != len(values) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(types), len(values))
+	}
+
+	head := make([]byte, 0, 32*len(types))
+	var tail []byte
+
+	for i, typ := range types {
+		word, dynamic, err := abiEncodeValue(typ, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("value %d: %w", i, err)
+		}
+		if dynamic {
+			head = append(head, abiWord(big.NewInt(int64(32*len(types)+len(tail))))...)
+			tail = append(tail, word...)
+		} else {
+			head = append(head, word...)
+		}
+	}
+
+	return append(head, tail...), nil
+}
+
+// abiEncodeValue encodes a single value. For static types the result is a
+// single 32 byte word, for dynamic types it is the length-prefixed tail.
+func abiEncodeValue(typ string, value any) ([]byte, bool, error) {
+	switch {
+	case typ == "address":
+		b, err := abiBytes(value)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(b) != 20 {
+			return nil, false, fmt.Errorf("address must be 20 bytes, got %d", len(b))
+		}
+		word := make([]byte, 32)
+		copy(word[12:], b)
+		return word, false, nil
+
+	case typ == "bool":
+		v, ok := value.(bool)
+		if !ok {
+			return nil, false, fmt.Errorf("bool value must be a bool, got %T", value)
+		}
+		word := make([]byte, 32)
+		if v {
+			word[31] = 1
+		}
+		return word, false, nil
+
+	case typ == "string" || typ == "bytes":
+		var b []byte
+		if typ == "string" {
+			s, ok := value.(string)
+			if !ok {
+				return nil, false, fmt.Errorf("string value must be a string, got %T", value)
+			}
+			b = []byte(s)
+		} else {
+			var err error
+			if b, err = abiBytes(value); err != nil {
+				return nil, false, err
+			}
+		}
+		out := abiWord(big.NewInt(int64(len(b))))
+		out = append(out, b...)
+		if rem := len(b) % 32; rem != 0 {
+			out = append(out, make([]byte, 32-rem)...)
+		}
+		return out, true, nil
+
+	case strings.HasPrefix(typ, "bytes"):
+		size, err := abiTypeSize(typ, "bytes", 1, 32, 1)
+		if err != nil {
+			return nil, false, err
+		}
+		b, err := abiBytes(value)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(b) != size {
+			return nil, false, fmt.Errorf("%s must be %d bytes, got %d", typ, size, len(b))
+		}
+		word := make([]byte, 32)
+		copy(word, b)
+		return word, false, nil
+
+	case strings.HasPrefix(typ, "uint"), strings.HasPrefix(typ, "int"):
+		signed := strings.HasPrefix(typ, "int")
+		prefix := "uint"
+		if signed {
+			prefix = "int"
+		}
+		size, err := abiTypeSize(typ, prefix, 8, 256, 8)
+		if err != nil {
+			return nil, false, err
+		}
+		n, err := abiInteger(value)
+		if err != nil {
+			return nil, false, err
+		}
+		if signed {
+			limit := new(big.Int).Lsh(big.NewInt(1), uint(size-1))
+			if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+				return nil, false, fmt.Errorf("value %s overflows %s", n, typ)
+			}
+			if n.Sign() < 0 {
+				n = new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+			}
+		} else if n.Sign() < 0 || n.BitLen() > size {
+			return nil, false, fmt.Errorf("value %s overflows %s", n, typ)
+		}
+		return abiWord(n), false, nil
+	}
+
+	return nil, false, fmt.Errorf("unsupported type %q", typ)
+}
+
+// abiTypeSize parses the size suffix of a sized type such as uint64 or
+// bytes32, returning the default of 256 for bare uint and int.
+func abiTypeSize(typ, prefix string, lowest, highest, step int) (int, error) {
+	suffix := strings.TrimPrefix(typ, prefix)
+	if suffix == "" && prefix != "bytes" {
+		return 256, nil
+	}
+	size, err := strconv.Atoi(suffix)
+	if err != nil || size < lowest || size > highest || size%step != 0 {
+		return 0, fmt.Errorf("unsupported type %q", typ)
+	}
+	return size, nil
+}
+
+// abiWord left-pads a non-negative integer to a 32 byte word.
+func abiWord(n *big.Int) []byte {
+	word := make([]byte, 32)
+	n.FillBytes(word)
+	return word
+}
+
+// abiBytes converts a hex string or byte value into raw bytes.
+func abiBytes(value any) ([]byte, error) {
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case [20]byte:
+		return v[:], nil
+	case [32]byte:
+		return v[:], nil
+	case string:
+		if !strings.HasPrefix(v, "0x") {
+			return nil, fmt.Errorf("hex value %q must start with 0x", v)
+		}
+		return hex.DecodeString(v[2:])
+	}
+	return nil, fmt.Errorf("cannot convert %T to bytes", value)
+}
+
+// abiInteger converts a decimal or hex string, a JSON number or a Go integer
+// into a big integer.
+func abiInteger(value any) (*big.Int, error) {
+	switch v := value.(type) {
+	case *big.Int:
+		return new(big.Int).Set(v), nil
+	case int:
+		return big.NewInt(int64(v)), nil
+	case int64:
+		return big.NewInt(v), nil
+	case uint64:
+		return new(big.Int).SetUint64(v), nil
+	case json.Number:
+		return abiInteger(string(v))
+	case float64:
+		if v != float64(int64(v)) {
+			return nil, fmt.Errorf("number %v is not an integer", v)
+		}
+		return big.NewInt(int64(v)), nil
+	case string:
+		base := 10
+		s := v
+		negative := strings.HasPrefix(s, "-")
+		s = strings.TrimPrefix(s, "-")
+		if strings.HasPrefix(s, "0x") {
+			base = 16
+			s = s[2:]
+		}
+		n, ok := new(big.Int).SetString(s, base)
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %q", v)
+		}
+		if negative {
+			n.Neg(n)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to an integer", value)
+}
@@ -0,0 +1,210 @@
+// Package accumulator implements a dynamic accumulator in the style of
+// Utreexo, for membership sets where storing the whole tree is too expensive:
+// the accumulator only holds the roots of a forest of perfect binary trees,
+// at most one per height, so its size is logarithmic in the number of
+// elements. Each element is proven by a witness, its path to the root of its
+// tree, which its holder keeps up to date with the updates returned by Add
+// and Delete.
+//
+// Elements are added at the next position, merging the trees of equal
+// heights like a binary counter. Deleting an element replaces it by the zero
+// value, and a node whose children are both the zero value is the zero value
+// itself, so the subtrees of deleted elements collapse.
+package accumulator
+
+import (
+	"errors"
+	"math/bits"
+
+	"github.com/noble-assets/imt"
+)
+
+// Accumulator holds the roots of the forest of an accumulator.
+type Accumulator[N comparable] struct {
+	hash imt.HashFunction[N]
+	zero N
+
+	// The root of the tree of each height, if the bit of that height of the
+	// number of leaves is set.
+	roots  []N
+	leaves uint64
+}
+
+// Witness proves that an element is in an accumulator. The siblings go from
+// the element up to the root of its tree.
+type Witness[N comparable] struct {
+	Position uint64 // The position of the element, in order of addition.
+	Leaf     N      // The element.
+	Siblings []N    // The siblings of the path of the element.
+}
+
+// Update describes a change of an accumulator, which the holders of the
+// witnesses of the other elements apply with Witness.Update.
+type Update[N comparable] struct {
+	Deleted  bool   // Whether an element was deleted rather than added.
+	Position uint64 // The position of the element added or deleted.
+	Leaves   uint64 // The number of positions before the change.
+
+	// For an addition, the roots that the new element merged with, from
+	// height 0, and the nodes of the path of the new element that they
+	// merged with.
+	Roots []N
+	Nodes []N
+
+	// For a deletion, the new nodes of the path of the deleted element, from
+	// the element up to the root of its tree.
+	Path []N
+}
+
+// New returns an empty accumulator hashing pairs of nodes with hash, whose
+// zero value marks the deleted elements and cannot be added.
+func New[N comparable](hash imt.HashFunction[N], zero N) *Accumulator[N] {
+	return &Accumulator[N]{hash: hash, zero: zero}
+}
+
+// Roots returns the roots of the trees of the forest, from the highest tree
+// to the lowest, which is the order of their positions.
+func (a *Accumulator[N]) Roots() []N {
+	roots := make([]N, 0, bits.OnesCount64(a.leaves))
+	for height := len(a.roots) - 1; height >= 0; height-- {
+		if a.leaves&(1<<height) != 0 {
+			roots = append(roots, a.roots[height])
+		}
+	}
+	return roots
+}
+
+// Leaves returns the number of positions of the accumulator, which counts
+// the deleted elements.
+func (a *Accumulator[N]) Leaves() uint64 {
+	return a.leaves
+}
+
+// Add adds an element and returns its witness, and the update to apply to
+// the witnesses of the other elements.
+func (a *Accumulator[N]) Add(leaf N) (*Witness[N], Update[N], error) {
+	if leaf == a.zero {
+		return nil, Update[N]{}, errors.New("the zero value cannot be added")
+	}
+	witness := &Witness[N]{Position: a.leaves, Leaf: leaf}
+	update := Update[N]{Position: a.leaves, Leaves: a.leaves}
+
+	node := leaf
+	height := 0
+	for ; a.leaves&(1<<height) != 0; height++ {
+		update.Roots = append(update.Roots, a.roots[height])
+		update.Nodes = append(update.Nodes, node)
+		witness.Siblings = append(witness.Siblings, a.roots[height])
+		node = a.parent(a.roots[height], node)
+	}
+	if height == len(a.roots) {
+		a.roots = append(a.roots, node)
+	} else {
+		a.roots[height] = node
+	}
+	a.leaves++
+	return witness, update, nil
+}
+
+// Verify reports whether a witness proves that its element is in the
+// accumulator.
+func (a *Accumulator[N]) Verify(witness *Witness[N]) bool {
+	height, _, ok := tree(a.leaves, witness.Position)
+	if !ok || len(witness.Siblings) != height || witness.Leaf == a.zero {
+		return false
+	}
+	return a.root(witness.Position, witness.Leaf, witness.Siblings) == a.roots[height]
+}
+
+// Delete deletes the element of a witness, and returns the update to apply
+// to the witnesses of the other elements.
+func (a *Accumulator[N]) Delete(witness *Witness[N]) (Update[N], error) {
+	if !a.Verify(witness) {
+		return Update[N]{}, errors.New("the witness is not valid")
+	}
+	height, _, _ := tree(a.leaves, witness.Position)
+	update := Update[N]{Deleted: true, Position: witness.Position, Leaves: a.leaves, Path: []N{a.zero}}
+
+	node := a.zero
+	for level, sibling := range witness.Siblings {
+		if witness.Position&(1<<level) == 0 {
+			node = a.parent(node, sibling)
+		} else {
+			node = a.parent(sibling, node)
+		}
+		update.Path = append(update.Path, node)
+	}
+	a.roots[height] = node
+	return update, nil
+}
+
+// Update updates the witness of an element after a change of the
+// accumulator. The updates must be applied in order. The witness of a
+// deleted element is no longer valid.
+func (w *Witness[N]) Update(update Update[N]) {
+	height, start, ok := tree(update.Leaves, w.Position)
+	if !ok {
+		return
+	}
+	if !update.Deleted {
+		// The tree of the witness merged with the new element.
+		if height < len(update.Nodes) {
+			w.Siblings = append(w.Siblings, update.Nodes[height])
+			w.Siblings = append(w.Siblings, update.Roots[height+1:]...)
+		}
+		return
+	}
+
+	// The paths of two elements of the same tree meet at the parent of the
+	// highest level where their positions differ, where the node of the
+	// path of the deleted element is the sibling of the witness.
+	if deletedHeight, deletedStart, _ := tree(update.Leaves, update.Position); deletedHeight != height || deletedStart != start {
+		return
+	}
+	if diff := w.Position ^ update.Position; diff != 0 {
+		level := bits.Len64(diff) - 1
+		w.Siblings[level] = update.Path[level]
+	}
+}
+
+// root returns the root of the tree of a position from its leaf and its
+// siblings.
+func (a *Accumulator[N]) root(position uint64, leaf N, siblings []N) N {
+	node := leaf
+	for level, sibling := range siblings {
+		if position&(1<<level) == 0 {
+			node = a.parent(node, sibling)
+		} else {
+			node = a.parent(sibling, node)
+		}
+	}
+	return node
+}
+
+// parent returns the parent of two nodes, which is the zero value if both
+// are.
+func (a *Accumulator[N]) parent(left, right N) N {
+	if left == a.zero && right == a.zero {
+		return a.zero
+	}
+	return a.hash([]N{left, right})
+}
+
+// tree returns the height and the first position of the tree holding a
+// position in a forest of the given number of positions, and whether the
+// position exists.
+func tree(leaves, position uint64) (height int, start uint64, ok bool) {
+	if position >= leaves {
+		return 0, 0, false
+	}
+	for height = bits.Len64(leaves) - 1; height >= 0; height-- {
+		if leaves&(1<<height) == 0 {
+			continue
+		}
+		if position < start+1<<height {
+			return height, start, true
+		}
+		start += 1 << height
+	}
+	return 0, 0, false
+}
@@ -0,0 +1,140 @@
+package accumulator_test
+
+import (
+	"math/bits"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/accumulator"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestRootsMatchTrees(t *testing.T) {
+	acc := accumulator.New(imttest.Uint64Hash, 0)
+	var leaves []uint64
+	for n := 1; n <= 40; n++ {
+		leaf := uint64(n)
+		if _, _, err := acc.Add(leaf); err != nil {
+			t.Fatal(err)
+		}
+		leaves = append(leaves, leaf)
+
+		// The trees of the forest hold the leaves in order, from the
+		// highest one.
+		roots := acc.Roots()
+		if len(roots) != bits.OnesCount(uint(n)) || acc.Leaves() != uint64(n) {
+			t.Fatalf("%d leaves: got %d roots", n, len(roots))
+		}
+		start := 0
+		for i, height := 0, bits.Len(uint(n))-1; height >= 0; height-- {
+			if n&(1<<height) == 0 {
+				continue
+			}
+			want := leaves[start]
+			if height > 0 {
+				tree, err := imt.New(imttest.Uint64Hash, height, 0, 2, leaves[start:start+1<<height])
+				if err != nil {
+					t.Fatal(err)
+				}
+				want = tree.Root()
+			}
+			if roots[i] != want {
+				t.Fatalf("%d leaves: the root of height %d differs from the one of a tree", n, height)
+			}
+			start += 1 << height
+			i++
+		}
+	}
+}
+
+func TestWitnessUpdates(t *testing.T) {
+	r := imttest.Rand(1)
+	acc := accumulator.New(imttest.Uint64Hash, 0)
+	live := make(map[uint64]*accumulator.Witness[uint64])
+	var deleted []*accumulator.Witness[uint64]
+
+	for step := range 300 {
+		// Delete a random element, or add a new one.
+		var added *accumulator.Witness[uint64]
+		var update accumulator.Update[uint64]
+		var err error
+		if len(live) > 0 && r.IntN(3) == 0 {
+			var victim *accumulator.Witness[uint64]
+			for _, witness := range live {
+				victim = witness
+				break
+			}
+			update, err = acc.Delete(victim)
+			delete(live, victim.Position)
+			deleted = append(deleted, victim)
+		} else {
+			added, update, err = acc.Add(uint64(step + 1))
+		}
+		if err != nil {
+			t.Fatalf("step %d: %v", step, err)
+		}
+		for _, witness := range live {
+			witness.Update(update)
+		}
+		if added != nil {
+			live[added.Position] = added
+		}
+
+		for position, witness := range live {
+			if !acc.Verify(witness) {
+				t.Fatalf("step %d: the witness of position %d was rejected", step, position)
+			}
+		}
+		for _, witness := range deleted {
+			if acc.Verify(witness) {
+				t.Fatalf("step %d: the witness of deleted position %d was accepted", step, witness.Position)
+			}
+		}
+	}
+}
+
+func TestVerifyRejectsForgeries(t *testing.T) {
+	acc := accumulator.New(imttest.Uint64Hash, 0)
+	var witnesses []*accumulator.Witness[uint64]
+	for leaf := uint64(1); leaf <= 6; leaf++ {
+		witness, update, err := acc.Add(leaf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, other := range witnesses {
+			other.Update(update)
+		}
+		witnesses = append(witnesses, witness)
+	}
+
+	tests := []struct {
+		name  string
+		forge func(w *accumulator.Witness[uint64])
+	}{
+		{"other leaf", func(w *accumulator.Witness[uint64]) { w.Leaf = 9 }},
+		{"zero leaf", func(w *accumulator.Witness[uint64]) { w.Leaf = 0 }},
+		{"other position", func(w *accumulator.Witness[uint64]) { w.Position ^= 1 }},
+		{"missing position", func(w *accumulator.Witness[uint64]) { w.Position = 6 }},
+		{"altered sibling", func(w *accumulator.Witness[uint64]) { w.Siblings[0]++ }},
+		{"extra sibling", func(w *accumulator.Witness[uint64]) { w.Siblings = append(w.Siblings, 1) }},
+		{"missing sibling", func(w *accumulator.Witness[uint64]) { w.Siblings = w.Siblings[1:] }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			original := witnesses[2]
+			forged := *original
+			forged.Siblings = append([]uint64(nil), original.Siblings...)
+			test.forge(&forged)
+			if acc.Verify(&forged) {
+				t.Fatal("forged witness accepted")
+			}
+			if _, err := acc.Delete(&forged); err == nil {
+				t.Fatal("deleted the element of a forged witness")
+			}
+		})
+	}
+
+	if _, _, err := acc.Add(0); err == nil {
+		t.Fatal("added the zero value")
+	}
+}
@@ -0,0 +1,109 @@
+package imt
+
+// AppendProof proves that appending a leaf at a given index transforms the
+// root of the tree from OldRoot into NewRoot. Light clients that trust
+// OldRoot can use it to follow each insertion without downloading the tree.
+type AppendProof[N comparable] struct {
+	OldRoot     N     `json:"oldRoot"`     // The root before the insertion.
+	NewRoot     N     `json:"newRoot"`     // The root after the insertion.
+	Leaf        N     `json:"leaf"`        // The appended leaf.
+	LeafIndex   int   `json:"leafIndex"`   // The index of the appended leaf.
+	Siblings    [][]N `json:"siblings"`    // Sibling nodes at each level.
+	PathIndices []int `json:"pathIndices"` // Position indices at each level.
+}
+
+// CreateAppendProof creates an AppendProof for the insertion of the leaf at
+// the given index. At the time of that insertion, the siblings on the left of
+// the path were complete subtrees and the siblings on the right were empty, so
+// the proof is built from the current left siblings and the zero values. For
+// this reason, the proof of an old insertion is only valid if none of the
+// leaves before it has been updated or deleted since.
+func (t *IMT[N]) CreateAppendProof(index int) (*AppendProof[N], error) {
+	if index < 0 || index >= t.nodes[0].Len() {
+		return nil, t.opError(OpCreateAppendProof, 0, index, ErrLeafNotFound)
+	}
+
+	proof := &AppendProof[N]{
+		Leaf:        t.readNode(0, index),
+		LeafIndex:   index,
+		Siblings:    make([][]N, t.depth),
+		PathIndices: make([]int, t.depth),
+	}
+
+	oldNode, newNode := t.zeroes[0], proof.Leaf
+	for level := 0; level < t.depth; level++ {
+		position := index % t.arity
+		start := index - position
+
+		siblings := make([]N, 0, t.arity-1)
+		for i := start; i < start+t.arity; i++ {
+			switch {
+			case i < index:
+				siblings = append(siblings, t.readNode(level, i))
+			case i > index:
+				siblings = append(siblings, t.zeroes[level])
+			}
+		}
+		proof.Siblings[level] = siblings
+		proof.PathIndices[level] = position
+
+		oldNode = t.hashChildren(insertChild(siblings, position, oldNode))
+		newNode = t.hashChildren(insertChild(siblings, position, newNode))
+		index = index / t.arity
+	}
+
+	proof.OldRoot = oldNode
+	proof.NewRoot = newNode
+
+	return proof, nil
+}
+
+// VerifyAppendProof verifies that appending the leaf of the proof transforms
+// OldRoot into NewRoot. It checks that the position of the leaf was empty
+// before the insertion and that every position after it is still empty, so
+// the leaf was indeed appended at the end of the tree. The zero value must be
+// the one the tree was created with, and the leaf index must be the position
+// of the path indices, so the proof cannot be presented at another position.
+func VerifyAppendProof[N comparable](proof *AppendProof[N], zeroValue N, hash HashFunction[N]) bool {
+	if proof == nil {
+		return false
+	}
+	path := &MerkleProof[N]{LeafIndex: proof.LeafIndex, Siblings: proof.Siblings, PathIndices: proof.PathIndices}
+	if _, err := ProofArity(path); err != nil {
+		return false
+	}
+
+	zero := zeroValue
+	oldNode, newNode := zeroValue, proof.Leaf
+	for level, siblings := range proof.Siblings {
+		position := proof.PathIndices[level]
+		if position < 0 || position > len(siblings) {
+			return false
+		}
+		for _, sibling := range siblings[position:] {
+			if sibling != zero {
+				return false
+			}
+		}
+
+		oldNode = hash(insertChild(siblings, position, oldNode))
+		newNode = hash(insertChild(siblings, position, newNode))
+
+		zeroes := make([]N, len(siblings)+1)
+		for i := range zeroes {
+			zeroes[i] = zero
+		}
+		zero = hash(zeroes)
+	}
+
+	return oldNode == proof.OldRoot && newNode == proof.NewRoot
+}
+
+// insertChild returns a new list of children made of the siblings with the
+// node inserted at the given position.
+func insertChild[N comparable](siblings []N, position int, node N) []N {
+	children := make([]N, 0, len(siblings)+1)
+	children = append(children, siblings[:position]...)
+	children = append(children, node)
+	return append(children, siblings[position:]...)
+}
@@ -0,0 +1,74 @@
+package imt_test
+
+import (
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestVerifyAppendProof(t *testing.T) {
+	for _, arity := range []int{2, 3} {
+		tree, err := imt.New(imttest.Uint64Hash, 4, 0, arity, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range 10 {
+			oldRoot := tree.Root()
+			if err := tree.Insert(uint64(i + 1)); err != nil {
+				t.Fatal(err)
+			}
+			proof, err := tree.CreateAppendProof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof.OldRoot != oldRoot || proof.NewRoot != tree.Root() {
+				t.Fatalf("arity %d, leaf %d: the proof does not go from the old root to the new root", arity, i)
+			}
+			if !imt.VerifyAppendProof(proof, 0, imttest.Uint64Hash) {
+				t.Fatalf("arity %d, leaf %d: valid proof rejected", arity, i)
+			}
+		}
+	}
+}
+
+func TestVerifyAppendProofRejectsForgeries(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3, 4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		forge  func(proof *imt.AppendProof[uint64])
+		accept bool
+	}{
+		{"valid", func(*imt.AppendProof[uint64]) {}, true},
+		{"leaf index rewritten", func(p *imt.AppendProof[uint64]) { p.LeafIndex = 2 }, false},
+		{"negative leaf index", func(p *imt.AppendProof[uint64]) { p.LeafIndex = -1 }, false},
+		{"path index out of range", func(p *imt.AppendProof[uint64]) { p.PathIndices[0] = 2 }, false},
+		{"leaf altered", func(p *imt.AppendProof[uint64]) { p.Leaf++ }, false},
+		{"sibling altered", func(p *imt.AppendProof[uint64]) { p.Siblings[1][0]++ }, false},
+		{"old root altered", func(p *imt.AppendProof[uint64]) { p.OldRoot++ }, false},
+		{"new root altered", func(p *imt.AppendProof[uint64]) { p.NewRoot++ }, false},
+		{"level missing", func(p *imt.AppendProof[uint64]) {
+			p.Siblings, p.PathIndices = p.Siblings[:3], p.PathIndices[:3]
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proof, err := tree.CreateAppendProof(5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.forge(proof)
+			if got := imt.VerifyAppendProof(proof, 0, imttest.Uint64Hash); got != test.accept {
+				t.Fatalf("VerifyAppendProof = %v, want %v", got, test.accept)
+			}
+		})
+	}
+
+	if imt.VerifyAppendProof[uint64](nil, 0, imttest.Uint64Hash) {
+		t.Fatal("nil proof accepted")
+	}
+}
@@ -0,0 +1,26 @@
+package imt
+
+// WithArena reduces the allocations of the construction of a tree from a list
+// of leaves, in New and the constructors built on it: the nodes of every level
+// are carved out of a single block allocated upfront instead of one slice per
+// level, and the slice of children passed to the hash function is reused
+// between calls instead of being allocated for every internal node. The hash
+// function must therefore not retain the children slice. Levels that outgrow
+// their part of the block during later insertions are reallocated
+// individually. The option has no effect when interning is enabled.
+func WithArena() Option {
+	return func(o *options) {
+		o.arena = true
+	}
+}
+
+// arenaSize returns the total number of nodes of a tree of the given size,
+// arity and depth, summed over all its levels.
+func arenaSize(size, arity, depth int) int {
+	total := size
+	for level := 0; level < depth; level++ {
+		size = (size + arity - 1) / arity
+		total += size
+	}
+	return total
+}
@@ -0,0 +1,109 @@
+package imt
+
+import "slices"
+
+// WithAutoGrow makes a full tree add a level above its root instead of
+// failing with ErrTreeFull when leaves are inserted. The old root becomes the
+// leftmost child of the new root, whose other children are zero subtrees, so
+// the tree is the one that New would have created with the same leaves and
+// the new depth. The tree grows up to maxDepth, if it is positive, and as
+// long as its capacity fits in an int, see ValidateConfig. Every growth is
+// recorded, see DepthIncreases, and CreateProofAtDepth creates proofs against
+// the roots of the smaller depths.
+func WithAutoGrow(maxDepth int) Option {
+	return func(o *options) {
+		o.autoGrow = true
+		o.maxDepth = maxDepth
+	}
+}
+
+// DepthIncrease records the growth of a tree by one level.
+type DepthIncrease[N comparable] struct {
+	Size  int // The number of leaves of the tree when it grew.
+	Depth int // The depth of the tree before the growth.
+	Root  N   // The root of the tree before the growth.
+}
+
+// DepthIncreases returns the growths of the tree since it was created, in
+// order. They are not part of snapshots.
+func (t *IMT[N]) DepthIncreases() []DepthIncrease[N] {
+	return slices.Clone(t.increases)
+}
+
+// CreateProofAtDepth creates a MerkleProof for a leaf of the tree as if the
+// tree had the given depth, which must hold the leaf and not be greater than
+// the depth of the tree. Its root is the ancestor of the leaf at that depth,
+// which is the root of the tree before it grew beyond that depth, if the
+// leaves before the growth were not updated since.
+func (t *IMT[N]) CreateProofAtDepth(index, depth int) (*MerkleProof[N], error) {
+	if index < 0 || index >= t.nodes[0].Len() {
+		return nil, t.opError(OpCreateProof, 0, index, ErrLeafNotFound)
+	}
+	if depth < 1 || depth > t.depth || uint64(index) >= capacity(t.arity, depth) {
+		return nil, t.opError(OpCreateProof, depth, 0, ErrNodeNotFound)
+	}
+
+	proof := t.createProof(0, index)
+	proof.Root = t.readNode(depth, 0)
+	proof.Siblings = proof.Siblings[:depth]
+	proof.PathIndices = proof.PathIndices[:depth]
+	proof.Depth = depth
+	return proof, nil
+}
+
+// neededDepth returns the depth the tree must have to hold size more leaves,
+// which is greater than its depth only if the tree grows automatically, or
+// ErrTreeFull if it cannot hold them.
+func (t *IMT[N]) neededDepth(op string, size int) (int, error) {
+	needed := uint64(t.nodes[0].Len()) + uint64(size)
+	depth := t.depth
+	for capacity(t.arity, depth) < needed {
+		if !t.options.autoGrow || capacity(t.arity, depth+1) > uint64(maxInt) || t.options.maxDepth > 0 && depth >= t.options.maxDepth {
+			return 0, t.opError(op, -1, -1, ErrTreeFull)
+		}
+		depth++
+	}
+	return depth, nil
+}
+
+// grow adds levels above the root of the tree until it has the given depth.
+func (t *IMT[N]) grow(depth int) {
+	if t.depth == depth {
+		return
+	}
+	for t.depth < depth {
+		root := t.Root()
+		t.increases = append(t.increases, DepthIncrease[N]{Size: t.nodes[0].Len(), Depth: t.depth, Root: root})
+
+		children := make([]N, t.arity)
+		for i := range children {
+			children[i] = t.zeroes[t.depth-1]
+		}
+		t.zeroes = append(t.zeroes, t.hash(children))
+		children[0] = root
+		t.nodes = append(t.nodes, t.newLevel(1))
+		t.nodes[t.depth+1].Append(t.hash(children))
+
+		t.depth++
+		t.dropLevel(t.depth - 1)
+	}
+	if t.proofs != nil {
+		t.proofs.clear()
+	}
+}
+
+// shrink removes the levels added by grow above the given depth, which
+// undoes the growth of a failed insertion.
+func (t *IMT[N]) shrink(depth int) {
+	if t.depth == depth {
+		return
+	}
+	t.increases = t.increases[:len(t.increases)-(t.depth-depth)]
+	t.zeroes = t.zeroes[:depth]
+	t.nodes = t.nodes[:depth+1]
+	if lazy, ok := t.nodes[depth].(*lazyLevel[N]); ok {
+		t.nodes[depth] = t.newLevel(1)
+		t.nodes[depth].Append(lazy.Get(0))
+	}
+	t.depth = depth
+}
@@ -0,0 +1,123 @@
+package imt_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestAutoGrow(t *testing.T) {
+	leaves := imttest.RandomLeaves(imttest.Rand(1), 27, imttest.Uint64Leaf)
+	tree, err := imt.New(imttest.Uint64Hash, 1, 0, 3, nil, imt.WithAutoGrow(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for size, leaf := range leaves {
+		if err := tree.Insert(leaf); err != nil {
+			t.Fatal(err)
+		}
+		// The tree is the one New creates with the same leaves and depth.
+		want, err := imt.New(imttest.Uint64Hash, tree.Depth(), 0, 3, leaves[:size+1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tree.Root() != want.Root() {
+			t.Fatalf("after %d leaves, the root at depth %d differs from the one of New", size+1, tree.Depth())
+		}
+	}
+
+	increases := tree.DepthIncreases()
+	if tree.Depth() != 3 || len(increases) != 2 {
+		t.Fatalf("the tree grew to depth %d with the increases %+v", tree.Depth(), increases)
+	}
+	for _, increase := range increases {
+		before, err := imt.New(imttest.Uint64Hash, increase.Depth, 0, 3, leaves[:increase.Size])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if increase.Size != pow(3, increase.Depth) || increase.Root != before.Root() {
+			t.Fatalf("unexpected increase %+v", increase)
+		}
+		// The proofs at the old depth verify against the old root.
+		for index := range increase.Size {
+			proof, err := tree.CreateProofAtDepth(index, increase.Depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof.Root != increase.Root || !imt.VerifyProof(proof, imttest.Uint64Hash) {
+				t.Fatalf("the proof of leaf %d at depth %d was rejected", index, increase.Depth)
+			}
+		}
+	}
+
+	// The tree doesn't grow beyond its maximum depth.
+	root := tree.Root()
+	if err := tree.Insert(1); !errors.Is(err, imt.ErrTreeFull) {
+		t.Fatalf("Insert into a tree of maximum depth returned %v", err)
+	}
+	if err := tree.InsertMany([]uint64{1, 2}); !errors.Is(err, imt.ErrTreeFull) {
+		t.Fatalf("InsertMany into a tree of maximum depth returned %v", err)
+	}
+	if tree.Depth() != 3 || tree.Root() != root || len(tree.DepthIncreases()) != 2 {
+		t.Fatal("a failed insertion changed the tree")
+	}
+
+	for _, test := range []struct{ index, depth int }{
+		{0, 0},
+		{0, 4},
+		{3, 1},
+		{27, 3},
+		{-1, 3},
+	} {
+		if _, err := tree.CreateProofAtDepth(test.index, test.depth); err == nil {
+			t.Fatalf("CreateProofAtDepth(%d, %d) succeeded", test.index, test.depth)
+		}
+	}
+}
+
+func TestAutoGrowInsertMany(t *testing.T) {
+	hasher := &failingHasher{}
+	tree, err := imt.New(imttest.Uint64Hash, 1, 0, 2, []uint64{1}, imt.WithAutoGrow(0), imt.WithBatchHasher[uint64](hasher, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := tree.Root()
+
+	// A failed insertion undoes the growth.
+	hasher.fail = true
+	if err := tree.InsertMany([]uint64{2, 3, 4, 5}); err == nil {
+		t.Fatal("InsertMany succeeded with a failing hasher")
+	}
+	if tree.Depth() != 1 || tree.Root() != root || tree.Size() != 1 || len(tree.DepthIncreases()) != 0 {
+		t.Fatal("a failed insertion grew the tree")
+	}
+
+	// A batch grows the tree by several levels at once.
+	hasher.fail = false
+	leaves := []uint64{1, 2, 3, 4, 5}
+	if err := tree.InsertMany(leaves[1:]); err != nil {
+		t.Fatal(err)
+	}
+	want, err := imt.New(imttest.Uint64Hash, 3, 0, 2, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Depth() != 3 || tree.Root() != want.Root() {
+		t.Fatalf("the tree grew to depth %d", tree.Depth())
+	}
+	increases := tree.DepthIncreases()
+	if len(increases) != 2 || increases[0] != (imt.DepthIncrease[uint64]{Size: 1, Depth: 1, Root: root}) || increases[1].Size != 1 || increases[1].Depth != 2 {
+		t.Fatalf("unexpected increases %+v", increases)
+	}
+}
+
+// pow returns base to the power of exp.
+func pow(base, exp int) int {
+	result := 1
+	for range exp {
+		result *= base
+	}
+	return result
+}
@@ -0,0 +1,273 @@
+package imt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrVersionNotCovered is returned by RestoreBackup when no backup covers the
+// requested version.
+var ErrVersionNotCovered = errors.New("no backup covers the requested version")
+
+// The prefixes and extension of the names of the files written by a Backup,
+// which are followed by the version of the tree they end at.
+const (
+	backupFullPrefix  = "full-"
+	backupDeltaPrefix = "delta-"
+	backupExtension   = ".imt"
+)
+
+// Backup writes backups of a tree to a directory: full snapshots, written by
+// Full, and incremental deltas holding the operations committed since the
+// previous backup, written by Delta, so that large trees don't need a full
+// snapshot at every interval. RestoreBackup restores the tree at the version
+// of any backup, and at the version after any operation of a delta.
+//
+// Operations are held in memory until the next backup, so the ones committed
+// after the last backup are lost if the process stops. Like replication, the
+// deltas don't carry leaf data, insertion records and expiry deadlines: a tree
+// restored after a delta keeps the ones of its full snapshot, except for the
+// leaves deleted since, and loses the ones set since.
+type Backup[N comparable] struct {
+	tree      *IMT[N]
+	codec     NodeCodec[N]
+	dir       string
+	chunkSize int
+	cancel    func()
+
+	// mu guards pending, which is appended to by the goroutine writing to the
+	// tree.
+	mu      sync.Mutex
+	pending []LogEntry[N]
+}
+
+// NewBackup returns a Backup of the tree writing to dir, encoding nodes with
+// the codec and snapshots in chunks of chunkSize leaves. It starts recording
+// the operations of the tree, so Full should be called first.
+func NewBackup[N comparable](tree *IMT[N], codec NodeCodec[N], dir string, chunkSize int) (*Backup[N], error) {
+	if chunkSize <= 0 {
+		return nil, errors.New("chunk size must be positive")
+	}
+	b := &Backup[N]{
+		tree:      tree,
+		codec:     codec,
+		dir:       dir,
+		chunkSize: chunkSize,
+	}
+	b.cancel = tree.OnLogEntry(func(entry LogEntry[N]) {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		b.pending = append(b.pending, entry)
+	})
+	return b, nil
+}
+
+// Full writes a full snapshot of the tree and returns its version. It must not
+// run concurrently with writes to the tree.
+func (b *Backup[N]) Full() (uint64, error) {
+	version := b.tree.Version()
+	err := b.writeFile(backupFullPrefix, version, func(w *bufio.Writer) error {
+		return b.tree.Snapshot(b.codec, b.chunkSize, func(chunk []byte) error {
+			return writeRecord(w, chunk)
+		})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = nil
+	return version, nil
+}
+
+// Delta writes the operations committed since the previous backup and returns
+// the version of the tree after the last one. It writes nothing and returns
+// the version of the tree if no operation was committed.
+func (b *Backup[N]) Delta() (uint64, error) {
+	b.mu.Lock()
+	pending := b.pending
+	b.mu.Unlock()
+	if len(pending) == 0 {
+		return b.tree.Version(), nil
+	}
+
+	version := pending[len(pending)-1].Version
+	err := b.writeFile(backupDeltaPrefix, version, func(w *bufio.Writer) error {
+		for _, entry := range pending {
+			if err := WriteLogEntry(w, b.codec, entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending = b.pending[len(pending):]
+	return version, nil
+}
+
+// Close stops recording the operations of the tree.
+func (b *Backup[N]) Close() {
+	b.cancel()
+}
+
+// writeFile writes a backup file atomically, by renaming a temporary file
+// once its content is synced.
+func (b *Backup[N]) writeFile(prefix string, version uint64, write func(w *bufio.Writer) error) error {
+	file, err := os.CreateTemp(b.dir, "imt-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	if err := write(w); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), filepath.Join(b.dir, backupFileName(prefix, version)))
+}
+
+// backupFileName returns the name of a backup file, whose zero-padded version
+// makes the names sort in the order of the versions.
+func backupFileName(prefix string, version uint64) string {
+	return fmt.Sprintf("%s%020d%s", prefix, version, backupExtension)
+}
+
+// RestoreBackup restores the tree at the given version from the backups
+// written to dir: the latest full snapshot at or before the version, followed
+// by the operations of the deltas up to the version, checking the root after
+// each of them. It returns ErrVersionNotCovered if no full snapshot precedes
+// the version, or if the deltas don't reach it exactly. The options are the
+// ones of the restored tree.
+func RestoreBackup[N comparable](dir string, version uint64, hash HashFunction[N], zeroValue N, codec NodeCodec[N], opts ...Option) (*IMT[N], error) {
+	fulls, err := backupVersions(dir, backupFullPrefix)
+	if err != nil {
+		return nil, err
+	}
+	i := len(fulls) - 1
+	for i >= 0 && fulls[i] > version {
+		i--
+	}
+	if i < 0 {
+		return nil, ErrVersionNotCovered
+	}
+
+	t, err := restoreFull(filepath.Join(dir, backupFileName(backupFullPrefix, fulls[i])), hash, zeroValue, codec, opts)
+	if err != nil {
+		return nil, err
+	}
+	if t.Version() == version {
+		return t, nil
+	}
+
+	deltas, err := backupVersions(dir, backupDeltaPrefix)
+	if err != nil {
+		return nil, err
+	}
+	follower := NewFollower(t)
+	for _, delta := range deltas {
+		if delta <= fulls[i] {
+			continue
+		}
+		file, err := os.Open(filepath.Join(dir, backupFileName(backupDeltaPrefix, delta)))
+		if err != nil {
+			return nil, err
+		}
+		for entry, err := range ReadLogEntries(file, codec) {
+			if err != nil {
+				file.Close()
+				return nil, err
+			}
+			if entry.Version <= t.Version() {
+				continue
+			}
+			if entry.Version > version {
+				break
+			}
+			if err := follower.Apply(entry); err != nil {
+				file.Close()
+				return nil, err
+			}
+		}
+		file.Close()
+		if t.Version() >= version {
+			break
+		}
+	}
+	if t.Version() != version {
+		return nil, ErrVersionNotCovered
+	}
+
+	return t, nil
+}
+
+// restoreFull restores a tree from a full snapshot file.
+func restoreFull[N comparable](path string, hash HashFunction[N], zeroValue N, codec NodeCodec[N], opts []Option) (*IMT[N], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	restorer := NewSnapshotRestorer(hash, zeroValue, codec, opts...)
+	r := bufio.NewReader(file)
+	for {
+		chunk, err := readRecord(r, maxLogEntrySize)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := restorer.Add(chunk); err != nil {
+			return nil, err
+		}
+	}
+	return restorer.Finish()
+}
+
+// backupVersions returns the sorted versions of the backup files of dir with
+// the given prefix.
+func backupVersions(dir, prefix string) ([]uint64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var versions []uint64
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, backupExtension) {
+			continue
+		}
+		version, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, prefix), backupExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	slices.Sort(versions)
+	return versions, nil
+}
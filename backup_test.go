@@ -0,0 +1,119 @@
+package imt_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestRestoreBackup(t *testing.T) {
+	rng := imttest.Rand(1)
+	tree, err := imt.New(imttest.Uint64Hash, 6, 0, 2, imttest.RandomLeaves(rng, 8, imttest.Uint64Leaf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range []int{2, 5} {
+		if err := tree.SetExpiry(index, time.Unix(int64(index), 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := t.TempDir()
+	backup, err := imt.NewBackup(tree, uint64Codec{}, dir, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backup.Close()
+
+	// The state of the tree at every version, restored below.
+	type state struct {
+		root      uint64
+		leaves    []uint64
+		deleted   []int
+		deadlines map[int]time.Time
+	}
+	states := make(map[uint64]state)
+	record := func() {
+		deadlines := make(map[int]time.Time)
+		for index := range tree.Size() {
+			if deadline, ok := tree.Expiry(index); ok {
+				deadlines[index] = deadline
+			}
+		}
+		states[tree.Version()] = state{tree.Root(), tree.Leaves(), tree.DeletedIndices(), deadlines}
+	}
+	steps := []func() error{
+		func() error { _, err := backup.Full(); return err },
+		func() error { return tree.Insert(rng.Uint64()) },
+		func() error { return tree.Update(1, rng.Uint64()) },
+		func() error { return tree.Delete(2) },
+		func() error { _, err := backup.Delta(); return err },
+		func() error { return tree.InsertMany(imttest.RandomLeaves(rng, 3, imttest.Uint64Leaf)) },
+		func() error { return tree.Delete(6) },
+		func() error { _, err := tree.Compact(); return err },
+		func() error { _, err := backup.Delta(); return err },
+		func() error { return tree.Insert(rng.Uint64()) },
+		func() error { _, err := backup.Full(); return err },
+		func() error { return tree.Update(0, rng.Uint64()) },
+		func() error { return tree.Delete(3) },
+		func() error { _, err := backup.Delta(); return err },
+	}
+	record()
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		record()
+	}
+
+	for version, want := range states {
+		restored, err := imt.RestoreBackup(dir, version, imttest.Uint64Hash, 0, uint64Codec{})
+		if err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		if restored.Version() != version || restored.Root() != want.root {
+			t.Fatalf("version %d: restored version %d with root %d, want root %d", version, restored.Version(), restored.Root(), want.root)
+		}
+		if !slices.Equal(restored.Leaves(), want.leaves) || !slices.Equal(restored.DeletedIndices(), want.deleted) {
+			t.Fatalf("version %d: the restored leaves differ", version)
+		}
+		for index := range restored.Size() {
+			deadline, ok := restored.Expiry(index)
+			if want, expires := want.deadlines[index]; ok != expires || !deadline.Equal(want) {
+				t.Fatalf("version %d: leaf %d has the deadline %v, want %v", version, index, deadline, want)
+			}
+		}
+	}
+
+	// Deltas don't carry deadlines, so the ones set after the last full
+	// snapshot are lost.
+	if err := tree.SetExpiry(0, time.Unix(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Insert(rng.Uint64()); err != nil {
+		t.Fatal(err)
+	}
+	version, err := backup.Delta()
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := imt.RestoreBackup(dir, version, imttest.Uint64Hash, 0, uint64Codec{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Root() != tree.Root() {
+		t.Fatal("the restored root differs from the one of the tree")
+	}
+	if _, ok := restored.Expiry(0); ok {
+		t.Fatal("a deadline set after the last full snapshot was restored")
+	}
+
+	for _, version := range []uint64{0, 7, tree.Version() + 1} {
+		if _, err := imt.RestoreBackup(dir, version, imttest.Uint64Hash, 0, uint64Codec{}); !errors.Is(err, imt.ErrVersionNotCovered) {
+			t.Fatalf("RestoreBackup at version %d returned %v", version, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package imt
+
+import "errors"
+
+// DefaultBatchSize is the number of lists of children submitted at once to a
+// BatchHasher when no batch size is configured.
+const DefaultBatchSize = 4096
+
+// BatchResult is the result of a batch submitted to a BatchHasher.
+type BatchResult[N comparable] struct {
+	Hashes []N   // The hashes of the lists of children, in order.
+	Err    error // The error that prevented the batch from being hashed.
+}
+
+// BatchHasher hashes many lists of children at once, so that the hashing of
+// the bulk paths of the tree can be offloaded to an accelerator such as a GPU
+// or an FPGA. Submit queues a batch and returns immediately with a channel
+// that receives its result, which lets the tree submit all the batches of a
+// level before waiting for any of them. The hashes must be the ones computed
+// by the hash function of the tree.
+type BatchHasher[N comparable] interface {
+	Submit(batch [][]N) <-chan BatchResult[N]
+}
+
+// WithBatchHasher sets a BatchHasher used, instead of the hash function, by
+// the construction of the tree from a list of leaves and by InsertMany, with
+// batches of batchSize lists of children, or DefaultBatchSize if batchSize is
+// not positive. The other operations hash a single path and keep using the
+// hash function. The type of the nodes of the hasher must match the tree.
+func WithBatchHasher[N comparable](hasher BatchHasher[N], batchSize int) Option {
+	return func(o *options) {
+		o.batchHasher = hasher
+		o.batchSize = batchSize
+	}
+}
+
+// batchHasher returns the BatchHasher of the options, if any.
+func batchHasher[N comparable](opts options) (BatchHasher[N], error) {
+	if opts.batchHasher == nil {
+		return nil, nil
+	}
+	hasher, ok := opts.batchHasher.(BatchHasher[N])
+	if !ok {
+		return nil, errors.New("the batch hasher does not hash the nodes of the tree")
+	}
+	return hasher, nil
+}
+
+// hashAll returns the parents of count lists of children of the given level,
+// where children(i) returns the i-th list. The parents of lists of zero
+// values are the zero value of the level above and are not hashed, and the
+// other lists are hashed by hashLists.
+func (t *IMT[N]) hashAll(level, count int, children func(i int) []N) ([]N, error) {
+	parents := make([]N, count)
+	lists := make([][]N, 0, count)
+	positions := make([]int, 0, count)
+	for i := range count {
+		list := children(i)
+		if parent, ok := t.zeroParent(level, list); ok {
+			parents[i] = parent
+			continue
+		}
+		lists = append(lists, list)
+		positions = append(positions, i)
+	}
+
+	hashes, err := t.hashLists(len(lists), func(i int) []N {
+		return lists[i]
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, hash := range hashes {
+		parents[positions[i]] = hash
+	}
+	return parents, nil
+}
+
+// zeroParent returns the zero value of the level above the given one if the
+// children are all the zero value of their level, in which case hashing them
+// is not needed. The root of the empty tree is not stored, so the children of
+// the root are always hashed.
+func (t *IMT[N]) zeroParent(level int, children []N) (N, bool) {
+	var zero N
+	if level+1 >= t.depth {
+		return zero, false
+	}
+	for _, child := range children {
+		if !t.equal(child, t.zeroes[level]) {
+			return zero, false
+		}
+	}
+	return t.zeroes[level+1], true
+}
+
+// hashLists returns the hashes of count lists of children, where children(i)
+// returns the i-th list. It uses the batch hasher of the tree if it has one,
+// submitting every batch before waiting for the first result, and otherwise
+// hashes the lists in parallel if the tree allows it, in which case children
+// must be safe for concurrent use.
+func (t *IMT[N]) hashLists(count int, children func(i int) []N) ([]N, error) {
+	if t.batchHasher == nil {
+		return t.hashParallel(count, children), nil
+	}
+	hashes := make([]N, 0, count)
+
+	size := t.options.batchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	var results []<-chan BatchResult[N]
+	for start := 0; start < count; start += size {
+		batch := make([][]N, min(size, count-start))
+		for i := range batch {
+			batch[i] = children(start + i)
+		}
+		results = append(results, t.batchHasher.Submit(batch))
+	}
+
+	var err error
+	for i, result := range results {
+		r := <-result
+		if err != nil {
+			continue
+		}
+		if r.Err != nil {
+			err = r.Err
+		} else if len(r.Hashes) != min(size, count-i*size) {
+			err = errors.New("the batch hasher returned the wrong number of hashes")
+		}
+		hashes = append(hashes, r.Hashes...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
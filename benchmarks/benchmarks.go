@@ -0,0 +1,291 @@
+// Package benchmarks measures the throughput of trees with 32-byte nodes
+// across hash functions, depths, arities and storage options, so that
+// configurations can be compared on the same machine with the same workload.
+//
+// The benchmarks are plain functions taking a *testing.B. To run them with
+// `go test -bench`, call Run from a benchmark of a test file:
+//
+//	func BenchmarkTrees(b *testing.B) {
+//		benchmarks.Run(b, benchmarks.Matrix([]string{"keccak256"}, []int{20, 32}, []int{2, 4}, benchmarks.StorageNames()))
+//	}
+//
+// Measure runs the same benchmarks programmatically with testing.Benchmark.
+package benchmarks
+
+import (
+	"encoding/binary"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/vectors"
+)
+
+// Node is the type of the nodes of the benchmarked trees.
+type Node = [32]byte
+
+// prefill is the number of leaves of the tree before the benchmarks of the
+// operations that need existing leaves.
+const prefill = 1 << 12
+
+// storages are the storage options of the trees, by name.
+var storages = map[string][]imt.Option{
+	"chunked":     nil,
+	"arena":       {imt.WithArena()},
+	"interned":    {imt.WithInterning()},
+	"leaves-only": {imt.WithLeavesOnly(1 << 10)},
+	"parallel":    {imt.WithMaxGoroutines(0)},
+}
+
+// StorageNames returns the names of the storage options that can be used in
+// a Config, sorted.
+func StorageNames() []string {
+	names := make([]string, 0, len(storages))
+	for name := range storages {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Config is a configuration of the benchmarked trees.
+type Config struct {
+	Hash    string // The name of the hash function, as accepted by vectors.Hash.
+	Depth   int    // The depth of the tree.
+	Arity   int    // The number of children per node.
+	Storage string // The name of the storage option, see StorageNames.
+}
+
+// String returns the name of the benchmarks of the configuration.
+func (c Config) String() string {
+	return fmt.Sprintf("%s/depth=%d/arity=%d/%s", c.Hash, c.Depth, c.Arity, c.Storage)
+}
+
+// Matrix returns every combination of the given parameters.
+func Matrix(hashes []string, depths, arities []int, storages []string) []Config {
+	var configs []Config
+	for _, hash := range hashes {
+		for _, depth := range depths {
+			for _, arity := range arities {
+				for _, storage := range storages {
+					configs = append(configs, Config{Hash: hash, Depth: depth, Arity: arity, Storage: storage})
+				}
+			}
+		}
+	}
+	return configs
+}
+
+// newTree returns a tree with the configuration and the given leaves.
+func (c Config) newTree(leaves []Node) (*imt.IMT[Node], error) {
+	hash, err := vectors.Hash(c.Hash)
+	if err != nil {
+		return nil, err
+	}
+	opts, ok := storages[c.Storage]
+	if !ok {
+		return nil, fmt.Errorf("unknown storage %q", c.Storage)
+	}
+	return imt.New(hash, c.Depth, Node{}, c.Arity, leaves, opts...)
+}
+
+// newFilledTree returns a tree with the configuration and up to prefill
+// leaves, bounded by its capacity.
+func (c Config) newFilledTree() (*imt.IMT[Node], error) {
+	t, err := c.newTree(nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.newTree(leaves(int(min(t.Capacity(), prefill))))
+}
+
+// leaves returns n deterministic leaves.
+func leaves(n int) []Node {
+	result := make([]Node, n)
+	for i := range result {
+		binary.BigEndian.PutUint64(result[i][24:], uint64(i)+1)
+	}
+	return result
+}
+
+// Benchmark is a benchmark of an operation of a tree.
+type Benchmark struct {
+	Name string                   // The name of the operation.
+	Unit string                   // The unit of the throughput, such as "inserts/s".
+	Run  func(*testing.B, Config) // The benchmark.
+}
+
+// Benchmarks returns the benchmarks run for each configuration.
+func Benchmarks() []Benchmark {
+	return []Benchmark{
+		{Name: "Insert", Unit: "inserts/s", Run: BenchmarkInsert},
+		{Name: "InsertMany", Unit: "inserts/s", Run: BenchmarkInsertMany},
+		{Name: "Update", Unit: "updates/s", Run: BenchmarkUpdate},
+		{Name: "CreateProof", Unit: "proofs/s", Run: BenchmarkCreateProof},
+		{Name: "VerifyProof", Unit: "verifications/s", Run: BenchmarkVerifyProof},
+	}
+}
+
+// Run runs every benchmark for every configuration as sub-benchmarks of b.
+func Run(b *testing.B, configs []Config) {
+	for _, config := range configs {
+		for _, benchmark := range Benchmarks() {
+			b.Run(config.String()+"/"+benchmark.Name, func(b *testing.B) {
+				benchmark.Run(b, config)
+			})
+		}
+	}
+}
+
+// Result is the result of a benchmark for a configuration.
+type Result struct {
+	Config      Config
+	Benchmark   string  // The name of the operation.
+	Unit        string  // The unit of the throughput.
+	Throughput  float64 // The number of operations per second.
+	AllocsPerOp int64   // The number of allocations per operation.
+	BytesPerOp  int64   // The number of bytes allocated per operation.
+}
+
+// Measure runs every benchmark for every configuration with
+// testing.Benchmark and returns the results.
+func Measure(configs []Config) []Result {
+	var results []Result
+	for _, config := range configs {
+		for _, benchmark := range Benchmarks() {
+			r := testing.Benchmark(func(b *testing.B) {
+				benchmark.Run(b, config)
+			})
+			result := Result{
+				Config:      config,
+				Benchmark:   benchmark.Name,
+				Unit:        benchmark.Unit,
+				AllocsPerOp: r.AllocsPerOp(),
+				BytesPerOp:  r.AllocedBytesPerOp(),
+			}
+			if r.T > 0 {
+				result.Throughput = float64(r.N) / r.T.Seconds()
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// BenchmarkInsert measures insertions into an empty tree.
+func BenchmarkInsert(b *testing.B, config Config) {
+	t, err := config.newTree(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	inserted := leaves(b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if uint64(t.Size()) == t.Capacity() {
+			b.StopTimer()
+			if t, err = config.newTree(nil); err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+		}
+		if err := t.Insert(inserted[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportThroughput(b, "inserts/s")
+}
+
+// BenchmarkInsertMany measures the insertion of all the leaves at once into
+// an empty tree.
+func BenchmarkInsertMany(b *testing.B, config Config) {
+	t, err := config.newTree(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	inserted := leaves(b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for len(inserted) > 0 {
+		if uint64(t.Size()) == t.Capacity() {
+			b.StopTimer()
+			if t, err = config.newTree(nil); err != nil {
+				b.Fatal(err)
+			}
+			b.StartTimer()
+		}
+		n := int(min(uint64(len(inserted)), t.Capacity()-uint64(t.Size())))
+		if err := t.InsertMany(inserted[:n]); err != nil {
+			b.Fatal(err)
+		}
+		inserted = inserted[n:]
+	}
+	reportThroughput(b, "inserts/s")
+}
+
+// BenchmarkUpdate measures updates of the leaves of a tree.
+func BenchmarkUpdate(b *testing.B, config Config) {
+	t, err := config.newFilledTree()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var leaf Node
+		binary.BigEndian.PutUint64(leaf[:8], uint64(i)+1)
+		if err := t.Update(i%t.Size(), leaf); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportThroughput(b, "updates/s")
+}
+
+// BenchmarkCreateProof measures the generation of proofs of the leaves of a
+// tree.
+func BenchmarkCreateProof(b *testing.B, config Config) {
+	t, err := config.newFilledTree()
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := t.CreateProof(i % t.Size()); err != nil {
+			b.Fatal(err)
+		}
+	}
+	reportThroughput(b, "proofs/s")
+}
+
+// BenchmarkVerifyProof measures the verification of proofs.
+func BenchmarkVerifyProof(b *testing.B, config Config) {
+	t, err := config.newFilledTree()
+	if err != nil {
+		b.Fatal(err)
+	}
+	proofs := make([]*imt.MerkleProof[Node], 0, 64)
+	for i := 0; i < cap(proofs); i++ {
+		proof, err := t.CreateProof(i * t.Size() / cap(proofs))
+		if err != nil {
+			b.Fatal(err)
+		}
+		proofs = append(proofs, proof)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if !t.VerifyProof(proofs[i%len(proofs)]) {
+			b.Fatal("the proof does not verify")
+		}
+	}
+	reportThroughput(b, "verifications/s")
+}
+
+// reportThroughput reports the number of operations per second.
+func reportThroughput(b *testing.B, unit string) {
+	if elapsed := b.Elapsed(); elapsed > 0 {
+		b.ReportMetric(float64(b.N)/elapsed.Seconds(), unit)
+	}
+}
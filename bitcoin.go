@@ -0,0 +1,95 @@
+package imt
+
+import (
+	"crypto/sha256"
+	"math/bits"
+)
+
+// BitcoinProof is a Merkle proof of a transaction of a Bitcoin block, as used
+// by SPV clients.
+type BitcoinProof struct {
+	Index    int        `json:"index"`    // The index of the transaction in the block.
+	Siblings [][32]byte `json:"siblings"` // Sibling hashes from the transaction up.
+}
+
+// BitcoinHash is the hash of Bitcoin Merkle trees: the double SHA-256 hash of
+// the concatenation of the children.
+func BitcoinHash(children [][32]byte) [32]byte {
+	h := sha256.New()
+	for _, child := range children {
+		h.Write(child[:])
+	}
+	return sha256.Sum256(h.Sum(nil))
+}
+
+// BitcoinMerkleRoot computes the Merkle root of a Bitcoin block from the IDs
+// of its transactions, in internal byte order. When a level has an odd number
+// of nodes, the last node is paired with a copy of itself. The root of a list
+// of one transaction is its ID, and the root of an empty list is zero.
+func BitcoinMerkleRoot(txids [][32]byte) [32]byte {
+	if len(txids) == 0 {
+		return [32]byte{}
+	}
+	level := append([][32]byte(nil), txids...)
+	for len(level) > 1 {
+		level = bitcoinLevel(level)
+	}
+	return level[0]
+}
+
+// CreateBitcoinProof creates the proof of the transaction at the given index.
+func CreateBitcoinProof(txids [][32]byte, index int) (*BitcoinProof, error) {
+	if index < 0 || index >= len(txids) {
+		return nil, ErrLeafNotFound
+	}
+	proof := &BitcoinProof{Index: index}
+	level := append([][32]byte(nil), txids...)
+	for position := index; len(level) > 1; position /= 2 {
+		sibling := position ^ 1
+		if sibling == len(level) {
+			sibling = position
+		}
+		proof.Siblings = append(proof.Siblings, level[sibling])
+		level = bitcoinLevel(level)
+	}
+	return proof, nil
+}
+
+// VerifyBitcoinProof verifies that a transaction is committed under the
+// Merkle root of a block of count transactions, which the caller must get
+// from a trusted source. The proof must have one sibling per level of the
+// tree of count transactions, so that no internal node passes for a
+// transaction, and a sibling may only equal its node where the node is the
+// last of a level with an odd number of nodes, where it must.
+func VerifyBitcoinProof(root, txid [32]byte, count int, proof *BitcoinProof) bool {
+	if proof == nil || count <= 0 || proof.Index < 0 || proof.Index >= count ||
+		len(proof.Siblings) != bits.Len(uint(count-1)) {
+		return false
+	}
+	node, position, width := txid, proof.Index, count
+	for _, sibling := range proof.Siblings {
+		if (sibling == node) != (position == width-1 && width%2 == 1) {
+			return false
+		}
+		if position%2 == 0 {
+			node = BitcoinHash([][32]byte{node, sibling})
+		} else {
+			node = BitcoinHash([][32]byte{sibling, node})
+		}
+		position, width = position/2, (width+1)/2
+	}
+	return node == root
+}
+
+// bitcoinLevel hashes the nodes of a level in pairs, pairing the last node
+// with itself if the number of nodes is odd.
+func bitcoinLevel(level [][32]byte) [][32]byte {
+	if len(level)%2 == 1 {
+		level = append(level, level[len(level)-1])
+	}
+	next := make([][32]byte, len(level)/2)
+	for i := range next {
+		next[i] = BitcoinHash(level[2*i : 2*i+2])
+	}
+	return next
+}
@@ -0,0 +1,125 @@
+package imt_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/noble-assets/imt"
+)
+
+// txid decodes a transaction ID or a Merkle root in the reversed byte order
+// displayed by Bitcoin clients.
+func txid(s string) [32]byte {
+	b := decodeHex(s)
+	slices.Reverse(b)
+	return [32]byte(b)
+}
+
+func TestBitcoinBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		txids []string
+		root  string
+	}{
+		{"block 0", []string{
+			"4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b",
+		}, "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"},
+		{"block 170", []string{
+			"b1fea52486ce0c62bb442b530a3f0132b826c74e473d1f2c220bfa78111c5082",
+			"f4184fc596403b9d638783cf57adfe4c75c605f6356fbc91338530e9831e9e16",
+		}, "7dac2c5666815c17a3b36427de37bb9d2e2c5ccec3f8633eb91a4205cb4c10ff"},
+		{"block 100000", []string{
+			"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+			"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+			"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+			"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+		}, "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			txids := make([][32]byte, len(test.txids))
+			for i, s := range test.txids {
+				txids[i] = txid(s)
+			}
+			root := txid(test.root)
+			if imt.BitcoinMerkleRoot(txids) != root {
+				t.Fatal("wrong Merkle root")
+			}
+			for i := range txids {
+				proof, err := imt.CreateBitcoinProof(txids, i)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !imt.VerifyBitcoinProof(root, txids[i], len(txids), proof) {
+					t.Fatalf("transaction %d: valid proof rejected", i)
+				}
+				if imt.VerifyBitcoinProof(root, txids[i], len(txids)+1, proof) {
+					t.Fatalf("transaction %d: proof accepted for another number of transactions", i)
+				}
+			}
+		})
+	}
+}
+
+func TestBitcoinProofRejectsForgeries(t *testing.T) {
+	txids := make([][32]byte, 5)
+	for i := range txids {
+		txids[i][0] = byte(i + 1)
+	}
+	root := imt.BitcoinMerkleRoot(txids)
+
+	// The last node of a level with an odd number of nodes is paired with
+	// itself.
+	proof, err := imt.CreateBitcoinProof(txids, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.Siblings[0] != txids[4] || !imt.VerifyBitcoinProof(root, txids[4], len(txids), proof) {
+		t.Fatal("the proof of the last transaction was rejected")
+	}
+
+	// The 64-byte preimage of an internal node may parse as a transaction,
+	// whose ID is the node.
+	internal := imt.BitcoinHash(txids[:2])
+	full, err := imt.CreateBitcoinProof(txids, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	short := &imt.BitcoinProof{Index: 0, Siblings: full.Siblings[1:]}
+	if imt.VerifyBitcoinProof(root, internal, len(txids), short) {
+		t.Fatal("internal node accepted as a transaction")
+	}
+
+	// A list whose last transactions are duplicated has the same root, but
+	// the duplicates are not the last nodes of their levels.
+	duplicated := append(slices.Clone(txids), txids[4])
+	if imt.BitcoinMerkleRoot(duplicated) != root {
+		t.Fatal("the duplicated list has another root")
+	}
+	for _, index := range []int{4, 5} {
+		proof, err := imt.CreateBitcoinProof(duplicated, index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if imt.VerifyBitcoinProof(root, duplicated[index], len(duplicated), proof) {
+			t.Fatalf("proof of duplicated transaction %d accepted", index)
+		}
+	}
+
+	invalid := []struct {
+		name  string
+		count int
+		proof *imt.BitcoinProof
+	}{
+		{"nil proof", len(txids), nil},
+		{"no transactions", 0, full},
+		{"index beyond the transactions", len(txids), &imt.BitcoinProof{Index: len(txids), Siblings: full.Siblings}},
+		{"negative index", len(txids), &imt.BitcoinProof{Index: -1, Siblings: full.Siblings}},
+		{"extra sibling", len(txids), &imt.BitcoinProof{Siblings: append(slices.Clone(full.Siblings), root)}},
+	}
+	for _, test := range invalid {
+		if imt.VerifyBitcoinProof(root, txids[0], test.count, test.proof) {
+			t.Fatalf("%s: proof accepted", test.name)
+		}
+	}
+}
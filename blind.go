@@ -0,0 +1,155 @@
+package imt
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"slices"
+
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+// BlindingSize is the size in bytes of the blinding factors drawn by
+// BlindedIMT.
+const BlindingSize = 32
+
+// CommitFunc computes the hiding commitment H(value, blinding) of a value.
+type CommitFunc[T any, N comparable] func(value T, blinding []byte) (N, error)
+
+// SHA256Commitment returns a CommitFunc of 32-byte leaves computing
+// sha256(sha256(domain || blinding || value)). The blinding factors must all
+// have the same size, such as BlindingSize, for the encoding to be
+// unambiguous. Hashing twice separates the commitments from the internal
+// nodes like SHA256LeafHasher.
+func SHA256Commitment(domain string) CommitFunc[[]byte, [32]byte] {
+	return func(value []byte, blinding []byte) ([32]byte, error) {
+		inner := sha256.New()
+		inner.Write([]byte(domain))
+		inner.Write(blinding)
+		inner.Write(value)
+		return sha256.Sum256(inner.Sum(nil)), nil
+	}
+}
+
+// Keccak256Commitment returns a CommitFunc of 32-byte leaves computing
+// keccak256(keccak256(domain || blinding || value)), like SHA256Commitment.
+func Keccak256Commitment(domain string) CommitFunc[[]byte, [32]byte] {
+	return func(value []byte, blinding []byte) ([32]byte, error) {
+		inner := keccak.Sum256([]byte(domain), blinding, value)
+		return keccak.Sum256(inner[:]), nil
+	}
+}
+
+// Opening is the opening of a commitment: the committed value and its
+// blinding factor.
+type Opening[T any] struct {
+	Value    T
+	Blinding []byte
+}
+
+// BlindedProof is the proof of a commitment, with its opening if it is
+// revealed. Without the opening, the proof only shows that the commitment is
+// in the tree and reveals nothing about the value.
+type BlindedProof[T any, N comparable] struct {
+	*MerkleProof[N]
+	Opening *Opening[T]
+}
+
+// BlindedIMT wraps a tree whose leaves are hiding commitments to values, so
+// that privacy-sensitive registries can publish their roots and proofs
+// without revealing the values. The blinding factors are either returned to
+// the caller only or also kept as the data of the leaves, see SetLeafData,
+// which then requires a LeafDataCodec handling *Opening[T] to snapshot the
+// tree. Anyone holding the opening of a leaf can link it to its value, so
+// the data of the leaves must be kept private.
+type BlindedIMT[T any, N comparable] struct {
+	tree   *IMT[N]
+	commit CommitFunc[T, N]
+	store  bool
+}
+
+// NewBlindedIMT returns a BlindedIMT storing the commitments in the given
+// tree, and keeping their openings as the data of the leaves if store is
+// true.
+func NewBlindedIMT[T any, N comparable](tree *IMT[N], commit CommitFunc[T, N], store bool) *BlindedIMT[T, N] {
+	return &BlindedIMT[T, N]{tree: tree, commit: commit, store: store}
+}
+
+// Tree returns the underlying tree.
+func (b *BlindedIMT[T, N]) Tree() *IMT[N] {
+	return b.tree
+}
+
+// Commit inserts a commitment to a value with a random blinding factor of
+// BlindingSize bytes, and returns its index and the blinding factor.
+func (b *BlindedIMT[T, N]) Commit(value T) (index int, blinding []byte, err error) {
+	blinding = make([]byte, BlindingSize)
+	if _, err := rand.Read(blinding); err != nil {
+		return 0, nil, err
+	}
+	index, err = b.CommitWithBlinding(value, blinding)
+	if err != nil {
+		return 0, nil, err
+	}
+	return index, blinding, nil
+}
+
+// CommitWithBlinding inserts a commitment to a value with the given blinding
+// factor, which must be secret and not reused, and returns its index.
+func (b *BlindedIMT[T, N]) CommitWithBlinding(value T, blinding []byte) (int, error) {
+	leaf, err := b.commit(value, blinding)
+	if err != nil {
+		return 0, err
+	}
+	index := b.tree.NextIndex()
+	if err := b.tree.Insert(leaf); err != nil {
+		return 0, err
+	}
+	if b.store {
+		if err := b.tree.SetLeafData(index, &Opening[T]{Value: value, Blinding: blinding}); err != nil {
+			return 0, err
+		}
+	}
+	return index, nil
+}
+
+// Opening returns the opening of a leaf, if it is stored.
+func (b *BlindedIMT[T, N]) Opening(index int) (*Opening[T], bool) {
+	data, ok := b.tree.LeafData(index)
+	if !ok {
+		return nil, false
+	}
+	opening, ok := data.(*Opening[T])
+	return opening, ok
+}
+
+// CreateProof creates the proof of a leaf, with its opening if reveal is
+// true, which requires the opening to be stored.
+func (b *BlindedIMT[T, N]) CreateProof(index int, reveal bool) (*BlindedProof[T, N], error) {
+	proof, err := b.tree.CreateProof(index)
+	if err != nil {
+		return nil, err
+	}
+	blinded := &BlindedProof[T, N]{MerkleProof: proof}
+	if reveal {
+		opening, ok := b.Opening(index)
+		if !ok {
+			return nil, errors.New("the opening of the leaf is not stored")
+		}
+		blinded.Opening = &Opening[T]{Value: opening.Value, Blinding: slices.Clone(opening.Blinding)}
+	}
+	return blinded, nil
+}
+
+// VerifyBlindedProof verifies the Merkle proof of a BlindedProof and, if it
+// reveals the opening, that its leaf is the commitment to the opened value.
+func VerifyBlindedProof[T any, N comparable](proof *BlindedProof[T, N], hash HashFunction[N], commit CommitFunc[T, N]) bool {
+	if proof == nil || !VerifyProof(proof.MerkleProof, hash) {
+		return false
+	}
+	if proof.Opening == nil {
+		return true
+	}
+	leaf, err := commit(proof.Opening.Value, proof.Opening.Blinding)
+	return err == nil && leaf == proof.Leaf
+}
@@ -0,0 +1,126 @@
+package imt_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/noble-assets/imt"
+)
+
+func TestCommitments(t *testing.T) {
+	blinding := bytes.Repeat([]byte{7}, imt.BlindingSize)
+	inner := sha256.Sum256(append(append([]byte("domain"), blinding...), "value"...))
+	if leaf, err := imt.SHA256Commitment("domain")([]byte("value"), blinding); err != nil || leaf != sha256.Sum256(inner[:]) {
+		t.Fatalf("SHA256Commitment = %x, %v", leaf, err)
+	}
+
+	commit := imt.Keccak256Commitment("domain")
+	leaf, err := commit([]byte("value"), blinding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, other := range []struct{ value, blinding []byte }{
+		{[]byte("other"), blinding},
+		{[]byte("value"), bytes.Repeat([]byte{8}, imt.BlindingSize)},
+	} {
+		if got, _ := commit(other.value, other.blinding); got == leaf {
+			t.Fatal("the commitments of different openings are equal")
+		}
+	}
+}
+
+func TestBlindedIMT(t *testing.T) {
+	commit := imt.SHA256Commitment("test")
+	tree, err := imt.NewSHA256Binary(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blinded := imt.NewBlindedIMT(tree, commit, true)
+
+	values := [][]byte{[]byte("a"), []byte("b"), []byte("a")}
+	blindings := make([][]byte, len(values))
+	for i, value := range values {
+		index, blinding, err := blinded.Commit(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if index != i || len(blinding) != imt.BlindingSize {
+			t.Fatalf("Commit = %d with a blinding of %d bytes", index, len(blinding))
+		}
+		blindings[i] = blinding
+	}
+	// The commitments of equal values differ.
+	leaves := tree.Leaves()
+	if leaves[0] == leaves[2] {
+		t.Fatal("the commitments of equal values are equal")
+	}
+	if opening, ok := blinded.Opening(1); !ok || !bytes.Equal(opening.Value, values[1]) || !bytes.Equal(opening.Blinding, blindings[1]) {
+		t.Fatal("the opening of leaf 1 was not stored")
+	}
+
+	hidden, err := blinded.CreateProof(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hidden.Opening != nil || !imt.VerifyBlindedProof(hidden, imt.SHA256Hash, commit) {
+		t.Fatal("the proof without opening was rejected")
+	}
+	proof, err := blinded.CreateProof(1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imt.VerifyBlindedProof(proof, imt.SHA256Hash, commit) {
+		t.Fatal("the proof with its opening was rejected")
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(proof *imt.BlindedProof[[]byte, [32]byte])
+	}{
+		{"wrong value", func(proof *imt.BlindedProof[[]byte, [32]byte]) {
+			proof.Opening.Value = values[0]
+		}},
+		{"wrong blinding", func(proof *imt.BlindedProof[[]byte, [32]byte]) {
+			proof.Opening.Blinding = blindings[0]
+		}},
+		{"opening of another leaf", func(proof *imt.BlindedProof[[]byte, [32]byte]) {
+			proof.Opening = &imt.Opening[[]byte]{Value: values[0], Blinding: blindings[0]}
+		}},
+		{"tampered root", func(proof *imt.BlindedProof[[]byte, [32]byte]) {
+			proof.Root[0] ^= 1
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tampered, err := blinded.CreateProof(1, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.tamper(tampered)
+			if imt.VerifyBlindedProof(tampered, imt.SHA256Hash, commit) {
+				t.Fatal("the tampered proof was accepted")
+			}
+		})
+	}
+	if imt.VerifyBlindedProof(nil, imt.SHA256Hash, commit) {
+		t.Fatal("a missing proof was accepted")
+	}
+	// A proof is only valid for the commitment function of the tree.
+	if imt.VerifyBlindedProof(proof, imt.SHA256Hash, imt.SHA256Commitment("other")) {
+		t.Fatal("the proof was accepted with another commitment function")
+	}
+
+	// Openings can only be revealed if they are stored.
+	unstored := imt.NewBlindedIMT(tree, commit, false)
+	index, err := unstored.CommitWithBlinding([]byte("c"), blindings[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := unstored.Opening(index); ok {
+		t.Fatal("the opening was stored")
+	}
+	if _, err := unstored.CreateProof(index, true); err == nil {
+		t.Fatal("revealed an opening that is not stored")
+	}
+}
@@ -0,0 +1,149 @@
+package imt
+
+import (
+	"fmt"
+	"io"
+	"iter"
+	"time"
+)
+
+// The phases of the construction of a tree by a Builder.
+const (
+	BuildPhaseAdd  = "add"
+	BuildPhaseHash = "hash"
+)
+
+// BuildProgress reports the progress of a Builder.
+type BuildProgress struct {
+	Phase  string // BuildPhaseAdd or BuildPhaseHash.
+	Leaves int    // The number of leaves accepted so far.
+	Levels int    // The number of levels hashed so far, in BuildPhaseHash.
+	Depth  int    // The depth of the tree.
+}
+
+// Builder accumulates the leaves of a tree from slices, iterators and
+// streams, checking each of them with an optional validator, and then builds
+// the tree in a single pass, as New does, optionally reporting its progress.
+type Builder[N comparable] struct {
+	hash      HashFunction[N]
+	depth     int
+	zeroValue N
+	arity     int
+	opts      []Option
+
+	leaves   []N
+	validate func(index int, leaf N) error
+	progress func(progress BuildProgress)
+	interval int
+}
+
+// NewBuilder returns a Builder of trees with the given parameters, which are
+// the same as the ones of New. The parameters are checked before any leaf is
+// added.
+func NewBuilder[N comparable](hash HashFunction[N], depth int, zeroValue N, arity int, opts ...Option) (*Builder[N], error) {
+	if err := ValidateConfig(depth, arity, hash); err != nil {
+		return nil, err
+	}
+	return &Builder[N]{
+		hash:      hash,
+		depth:     depth,
+		zeroValue: zeroValue,
+		arity:     arity,
+		opts:      opts,
+	}, nil
+}
+
+// Validate sets a function checking each added leaf, given its index in the
+// tree. A leaf it rejects is not added, and its error is returned by the
+// method that added it.
+func (b *Builder[N]) Validate(validate func(index int, leaf N) error) {
+	b.validate = validate
+}
+
+// OnProgress sets a function notified every interval accepted leaves, and
+// after each level hashed by Build.
+func (b *Builder[N]) OnProgress(interval int, progress func(progress BuildProgress)) {
+	b.interval, b.progress = interval, progress
+}
+
+// Len returns the number of leaves accepted so far.
+func (b *Builder[N]) Len() int {
+	return len(b.leaves)
+}
+
+// Add validates and accepts a leaf.
+func (b *Builder[N]) Add(leaf N) error {
+	if uint64(len(b.leaves)) >= capacity(b.arity, b.depth) {
+		return ErrTreeFull
+	}
+	if b.validate != nil {
+		if err := b.validate(len(b.leaves), leaf); err != nil {
+			return fmt.Errorf("leaf %d is invalid: %w", len(b.leaves), err)
+		}
+	}
+	b.leaves = append(b.leaves, leaf)
+	if b.progress != nil && b.interval > 0 && len(b.leaves)%b.interval == 0 {
+		b.progress(BuildProgress{Phase: BuildPhaseAdd, Leaves: len(b.leaves), Depth: b.depth})
+	}
+	return nil
+}
+
+// AddSlice adds the leaves of a slice in order, stopping at the first one
+// that is rejected.
+func (b *Builder[N]) AddSlice(leaves []N) error {
+	for _, leaf := range leaves {
+		if err := b.Add(leaf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddSeq adds the leaves of an iterator in order, stopping at the first one
+// that is rejected.
+func (b *Builder[N]) AddSeq(leaves iter.Seq[N]) error {
+	for leaf := range leaves {
+		if err := b.Add(leaf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddFrom adds the leaves read from r, as written by WriteLeaves, stopping at
+// the first one that is rejected.
+func (b *Builder[N]) AddFrom(r io.Reader, codec NodeCodec[N]) error {
+	for leaf, err := range ReadLeaves(r, codec) {
+		if err != nil {
+			return err
+		}
+		if err := b.Add(leaf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Build builds the tree from the accepted leaves. The Builder keeps its
+// leaves, so Build can be called again after adding more of them.
+func (b *Builder[N]) Build() (*IMT[N], error) {
+	opts := newOptions(b.opts)
+	timer := opts.levelTimer
+	if b.progress != nil {
+		opts.levelTimer = func(op string, level int, elapsed time.Duration) {
+			if timer != nil {
+				timer(op, level, elapsed)
+			}
+			if op == OpBuild {
+				b.progress(BuildProgress{Phase: BuildPhaseHash, Leaves: len(b.leaves), Levels: level + 1, Depth: b.depth})
+			}
+		}
+	}
+	t, err := newWithOptions(b.hash, b.depth, b.zeroValue, b.arity, b.leaves, opts)
+	if err != nil {
+		return nil, err
+	}
+	// The progress is only reported for this construction.
+	t.options.levelTimer = timer
+	return t, nil
+}
@@ -0,0 +1,91 @@
+package imt
+
+import "container/list"
+
+// nodeKey identifies a node of the tree.
+type nodeKey struct {
+	level int
+	index int
+}
+
+// nodeCache is a bounded cache of nodes with a least-recently-used eviction
+// policy. It counts its hits and misses.
+type nodeCache[N comparable] struct {
+	size    int
+	entries map[nodeKey]*list.Element
+	order   *list.List
+
+	hits   uint64
+	misses uint64
+}
+
+// cacheEntry is the value of the elements of the order of a nodeCache.
+type cacheEntry[N comparable] struct {
+	key  nodeKey
+	node N
+}
+
+func newNodeCache[N comparable](size int) *nodeCache[N] {
+	return &nodeCache[N]{
+		size:    size,
+		entries: make(map[nodeKey]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns the cached node at the given position, if any.
+func (c *nodeCache[N]) get(level, index int) (N, bool) {
+	element, ok := c.entries[nodeKey{level, index}]
+	if !ok {
+		c.misses++
+		var zero N
+		return zero, false
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*cacheEntry[N]).node, true
+}
+
+// put caches the node at the given position, evicting the least recently used
+// node if the cache is full.
+func (c *nodeCache[N]) put(level, index int, node N) {
+	if c.size <= 0 {
+		return
+	}
+	key := nodeKey{level, index}
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*cacheEntry[N]).node = node
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[N]).key)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry[N]{key: key, node: node})
+}
+
+// trim evicts the least recently used nodes until at most size remain, and
+// returns the number of evicted nodes.
+func (c *nodeCache[N]) trim(size int) int {
+	evicted := 0
+	for c.order.Len() > max(size, 0) {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry[N]).key)
+		evicted++
+	}
+	return evicted
+}
+
+// TrimCache evicts the least recently used nodes of the cache of a tree in
+// leaves-only mode until it holds at most size nodes, releasing their memory
+// after a burst of proofs, and returns the number of evicted nodes. It does
+// nothing for other trees.
+func (t *IMT[N]) TrimCache(size int) int {
+	if t.cache == nil {
+		return 0
+	}
+	return t.cache.trim(size)
+}
@@ -0,0 +1,115 @@
+package imt
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+)
+
+// CalldataProof is the layout of a proof expected by common on-chain
+// verifiers of trees with an arity greater than 2, such as quinary Poseidon
+// trees: the siblings form a fixed `[depth][arity-1]` array and the path
+// indices are packed into a single uint256.
+type CalldataProof[N comparable] struct {
+	Root        N        // The root hash of the tree.
+	Leaf        N        // The leaf value being proven.
+	Siblings    [][]N    // A [depth][arity-1] array of sibling nodes.
+	PathIndices *big.Int // The packed path indices.
+}
+
+// PathIndexBits returns the number of bits used by each path index when
+// packing the path indices of a tree with the given arity, which is the
+// number of bits needed to represent arity-1.
+func PathIndexBits(arity int) int {
+	return bits.Len(uint(arity - 1))
+}
+
+// EncodeCalldata converts a proof into its calldata layout. The path index of
+// level i is stored in bits [i*w, (i+1)*w) of the packed value, where w is
+// PathIndexBits(arity), so verifiers can extract it with a shift and a mask.
+// The proof must have exactly depth levels of arity-1 siblings each.
+func EncodeCalldata[N comparable](proof *MerkleProof[N], depth, arity int) (*CalldataProof[N], error) {
+	if proof == nil {
+		return nil, errors.New("proof is nil")
+	}
+	if arity < 2 {
+		return nil, errors.New("arity must be at least 2")
+	}
+	if len(proof.Siblings) != depth || len(proof.PathIndices) != depth {
+		return nil, fmt.Errorf("proof has %d levels, expected %d", len(proof.Siblings), depth)
+	}
+
+	width := uint(PathIndexBits(arity))
+	calldata := &CalldataProof[N]{
+		Root:        proof.Root,
+		Leaf:        proof.Leaf,
+		Siblings:    make([][]N, depth),
+		PathIndices: new(big.Int),
+	}
+	for level := 0; level < depth; level++ {
+		if len(proof.Siblings[level]) != arity-1 {
+			return nil, fmt.Errorf("proof has %d siblings at level %d, expected %d", len(proof.Siblings[level]), level, arity-1)
+		}
+		pathIndex := proof.PathIndices[level]
+		if pathIndex < 0 || pathIndex >= arity {
+			return nil, fmt.Errorf("path index %d of level %d is out of range", pathIndex, level)
+		}
+
+		calldata.Siblings[level] = make([]N, arity-1)
+		copy(calldata.Siblings[level], proof.Siblings[level])
+		calldata.PathIndices.Or(calldata.PathIndices, new(big.Int).Lsh(big.NewInt(int64(pathIndex)), uint(level)*width))
+	}
+
+	return calldata, nil
+}
+
+// DecodeCalldata converts a proof from its calldata layout back into a
+// MerkleProof, mirroring EncodeCalldata. The depth and arity are inferred from
+// the shape of the siblings.
+func DecodeCalldata[N comparable](calldata *CalldataProof[N]) (*MerkleProof[N], error) {
+	if calldata == nil || calldata.PathIndices == nil {
+		return nil, errors.New("calldata is incomplete")
+	}
+	depth := len(calldata.Siblings)
+	if depth == 0 {
+		return nil, errors.New("calldata has no levels")
+	}
+	arity := len(calldata.Siblings[0]) + 1
+	if arity < 2 {
+		return nil, errors.New("arity must be at least 2")
+	}
+
+	width := uint(PathIndexBits(arity))
+	if calldata.PathIndices.Sign() < 0 || uint(calldata.PathIndices.BitLen()) > uint(depth)*width {
+		return nil, errors.New("packed path indices overflow the depth")
+	}
+
+	proof := &MerkleProof[N]{
+		Root:        calldata.Root,
+		Leaf:        calldata.Leaf,
+		Siblings:    make([][]N, depth),
+		PathIndices: make([]int, depth),
+		Depth:       depth,
+		Arity:       arity,
+	}
+	mask := big.NewInt(int64(1)<<width - 1)
+	for level := 0; level < depth; level++ {
+		if len(calldata.Siblings[level]) != arity-1 {
+			return nil, fmt.Errorf("calldata has %d siblings at level %d, expected %d", len(calldata.Siblings[level]), level, arity-1)
+		}
+		pathIndex := int(new(big.Int).And(new(big.Int).Rsh(calldata.PathIndices, uint(level)*width), mask).Int64())
+		if pathIndex >= arity {
+			return nil, fmt.Errorf("path index %d of level %d is out of range", pathIndex, level)
+		}
+
+		proof.Siblings[level] = make([]N, arity-1)
+		copy(proof.Siblings[level], calldata.Siblings[level])
+		proof.PathIndices[level] = pathIndex
+	}
+	if err := proof.Canonicalize(); err != nil {
+		return nil, err
+	}
+
+	return proof, nil
+}
@@ -0,0 +1,210 @@
+package imt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// checkpointTag prefixes the digests of signed checkpoints, so that their
+// signatures cannot be replayed as signatures of other messages.
+const checkpointTag = "IMT-CHECKPOINT-V1"
+
+// SignedCheckpoint is an attestation of the state of a tree: its root and
+// number of leaves in a domain, such as a chain or an application.
+type SignedCheckpoint struct {
+	Domain    string   `json:"domain"`
+	Root      []byte   `json:"root"`    // The root, encoded with the NodeCodec of the tree.
+	Count     uint64   `json:"count"`   // The number of leaves.
+	Version   uint64   `json:"version"` // The version of the tree, which is not signed.
+	Digest    [32]byte `json:"digest"`
+	Signature []byte   `json:"signature"`
+}
+
+// SignedCheckpointDigest returns the canonical digest signed for a
+// checkpoint: the SHA-256 hash of a tag, the domain and the root, each
+// prefixed with its length as a 32-bit big-endian integer, and the number of
+// leaves as a 64-bit big-endian integer.
+func SignedCheckpointDigest(domain string, root []byte, count uint64) [32]byte {
+	h := sha256.New()
+	h.Write([]byte(checkpointTag))
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(domain))))
+	h.Write([]byte(domain))
+	h.Write(binary.BigEndian.AppendUint32(nil, uint32(len(root))))
+	h.Write(root)
+	h.Write(binary.BigEndian.AppendUint64(nil, count))
+	return [32]byte(h.Sum(nil))
+}
+
+// Verify checks that the digest of the checkpoint is the one of its domain,
+// root and number of leaves, and that it is signed by the given public key,
+// which must be an *ecdsa.PublicKey, whose signatures are ASN.1-encoded, an
+// ed25519.PublicKey, or an *rsa.PublicKey, whose signatures are PKCS #1 v1.5
+// signatures of a SHA-256 digest.
+func (c SignedCheckpoint) Verify(public crypto.PublicKey) error {
+	if SignedCheckpointDigest(c.Domain, c.Root, c.Count) != c.Digest {
+		return errors.New("the digest does not match the checkpoint")
+	}
+	valid := false
+	switch key := public.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, c.Digest[:], c.Signature)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, c.Digest[:], c.Signature)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, c.Digest[:], c.Signature) == nil
+	default:
+		return errors.New("unsupported public key type")
+	}
+	if !valid {
+		return errors.New("invalid signature")
+	}
+	return nil
+}
+
+// CheckpointSink receives the checkpoints signed by a Checkpointer, e.g. to
+// publish them to a gossip network or a data availability layer.
+type CheckpointSink interface {
+	WriteCheckpoint(checkpoint SignedCheckpoint) error
+}
+
+// CheckpointSinkFunc adapts a function to the CheckpointSink interface.
+type CheckpointSinkFunc func(checkpoint SignedCheckpoint) error
+
+// WriteCheckpoint calls f.
+func (f CheckpointSinkFunc) WriteCheckpoint(checkpoint SignedCheckpoint) error {
+	return f(checkpoint)
+}
+
+// CheckpointerConfig configures a Checkpointer.
+type CheckpointerConfig struct {
+	// The domain of the checkpoints, which binds them to a chain or an
+	// application.
+	Domain string
+	// The signer of the digests, and its options, which default to
+	// crypto.Hash(0) for Ed25519 keys, which sign the digest itself, and to
+	// crypto.SHA256 otherwise, which Go's ECDSA and RSA keys require.
+	Signer     crypto.Signer
+	SignerOpts crypto.SignerOpts
+	// The sink receiving the checkpoints.
+	Sink CheckpointSink
+	// The number of leaf writes between two checkpoints, or 0 to sign a
+	// checkpoint after every change of the root.
+	Every int
+}
+
+// Checkpointer signs checkpoints of a tree as it changes, like the sidecar of
+// a validator attesting the roots of a tree. Checkpoints are signed and
+// written to the sink in the goroutine writing to the tree, after the write,
+// which they delay. Failures to sign or write a checkpoint don't fail the
+// write and are reported by Err.
+type Checkpointer[N comparable] struct {
+	tree   *IMT[N]
+	codec  NodeCodec[N]
+	config CheckpointerConfig
+	stop   func()
+
+	mu      sync.Mutex
+	last    *SignedCheckpoint
+	err     error
+	version uint64
+}
+
+// NewCheckpointer returns a Checkpointer signing the checkpoints of a tree,
+// whose root is encoded with the codec.
+func NewCheckpointer[N comparable](tree *IMT[N], codec NodeCodec[N], config CheckpointerConfig) (*Checkpointer[N], error) {
+	if config.Signer == nil || config.Sink == nil {
+		return nil, errors.New("a signer and a sink are required")
+	}
+	if config.SignerOpts == nil {
+		config.SignerOpts = crypto.SHA256
+		if _, ok := config.Signer.Public().(ed25519.PublicKey); ok {
+			config.SignerOpts = crypto.Hash(0)
+		}
+	}
+	c := &Checkpointer[N]{tree: tree, codec: codec, config: config, version: tree.Version()}
+	c.stop = tree.OnLogEntry(c.onLogEntry)
+	return c, nil
+}
+
+// Close stops signing checkpoints.
+func (c *Checkpointer[N]) Close() {
+	c.stop()
+}
+
+// Checkpoint signs a checkpoint of the current state of the tree and writes
+// it to the sink, regardless of the number of writes since the last one. It
+// must not be called concurrently with writes to the tree.
+func (c *Checkpointer[N]) Checkpoint() (SignedCheckpoint, error) {
+	return c.sign(c.tree.Root(), c.tree.Version())
+}
+
+// Last returns the last checkpoint written to the sink, if any.
+func (c *Checkpointer[N]) Last() (SignedCheckpoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.last == nil {
+		return SignedCheckpoint{}, false
+	}
+	return *c.last, true
+}
+
+// Err returns the last error of a checkpoint signed after a write, if any.
+func (c *Checkpointer[N]) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// onLogEntry signs a checkpoint after a write if it is due.
+func (c *Checkpointer[N]) onLogEntry(entry LogEntry[N]) {
+	c.mu.Lock()
+	due := entry.Version-c.version >= uint64(max(c.config.Every, 1))
+	if c.config.Every <= 0 && c.last != nil {
+		// Only the changes of the root are attested.
+		root, err := c.codec.Encode(entry.Root)
+		due = err != nil || string(root) != string(c.last.Root) || uint64(c.tree.Size()) != c.last.Count
+	}
+	c.mu.Unlock()
+	if !due {
+		return
+	}
+	if _, err := c.sign(entry.Root, entry.Version); err != nil {
+		c.mu.Lock()
+		c.err = err
+		c.mu.Unlock()
+	}
+}
+
+// sign signs a checkpoint of the given root and version, with the current
+// number of leaves of the tree, and writes it to the sink.
+func (c *Checkpointer[N]) sign(root N, version uint64) (SignedCheckpoint, error) {
+	encoded, err := c.codec.Encode(root)
+	if err != nil {
+		return SignedCheckpoint{}, err
+	}
+	checkpoint := SignedCheckpoint{
+		Domain:  c.config.Domain,
+		Root:    encoded,
+		Count:   uint64(c.tree.Size()),
+		Version: version,
+	}
+	checkpoint.Digest = SignedCheckpointDigest(checkpoint.Domain, checkpoint.Root, checkpoint.Count)
+	if checkpoint.Signature, err = c.config.Signer.Sign(rand.Reader, checkpoint.Digest[:], c.config.SignerOpts); err != nil {
+		return SignedCheckpoint{}, err
+	}
+	if err := c.config.Sink.WriteCheckpoint(checkpoint); err != nil {
+		return SignedCheckpoint{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.last, c.version = &checkpoint, version
+	return checkpoint, nil
+}
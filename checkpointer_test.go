@@ -0,0 +1,185 @@
+package imt_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestSignedCheckpointDigest(t *testing.T) {
+	// The tag, the length-prefixed domain and root, and the count.
+	preimage := []byte("IMT-CHECKPOINT-V1")
+	preimage = append(preimage, 0, 0, 0, 5)
+	preimage = append(preimage, "chain"...)
+	preimage = append(preimage, 0, 0, 0, 2, 0xab, 0xcd)
+	preimage = binary.BigEndian.AppendUint64(preimage, 7)
+	if got := imt.SignedCheckpointDigest("chain", []byte{0xab, 0xcd}, 7); got != sha256.Sum256(preimage) {
+		t.Fatalf("SignedCheckpointDigest = %x", got)
+	}
+	// The lengths keep the domain and the root apart.
+	if imt.SignedCheckpointDigest("chain", []byte{0xab, 0xcd}, 7) == imt.SignedCheckpointDigest("chai", []byte{'n', 0xab, 0xcd}, 7) {
+		t.Fatal("the boundary between the domain and the root is not signed")
+	}
+}
+
+func TestCheckpointer(t *testing.T) {
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherECDSAKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherEd25519Key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherRSAKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		signer    crypto.Signer
+		untrusted crypto.Signer
+	}{
+		{"ecdsa", ecdsaKey, otherECDSAKey},
+		{"ed25519", ed25519Key, otherEd25519Key},
+		{"rsa", rsaKey, otherRSAKey},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var checkpoints []imt.SignedCheckpoint
+			checkpointer, err := imt.NewCheckpointer(tree, uint64Codec{}, imt.CheckpointerConfig{
+				Domain: "chain",
+				Signer: test.signer,
+				Sink: imt.CheckpointSinkFunc(func(checkpoint imt.SignedCheckpoint) error {
+					checkpoints = append(checkpoints, checkpoint)
+					return nil
+				}),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer checkpointer.Close()
+
+			if err := tree.InsertMany([]uint64{1, 2, 3}); err != nil {
+				t.Fatal(err)
+			}
+			if err := tree.Update(0, 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := tree.Insert(4); err != nil {
+				t.Fatal(err)
+			}
+			// Updating a leaf to its value does not change the root.
+			if len(checkpoints) != 2 || checkpointer.Err() != nil {
+				t.Fatalf("%d checkpoints signed, error %v", len(checkpoints), checkpointer.Err())
+			}
+			checkpoint, ok := checkpointer.Last()
+			if !ok || checkpoint.Count != 4 || checkpoint.Version != tree.Version() || checkpoint.Domain != "chain" {
+				t.Fatalf("last checkpoint %+v", checkpoint)
+			}
+			root, _ := uint64Codec{}.Encode(tree.Root())
+			if string(checkpoint.Root) != string(root) {
+				t.Fatal("the checkpoint does not hold the root of the tree")
+			}
+			if err := checkpoint.Verify(test.signer.Public()); err != nil {
+				t.Fatal(err)
+			}
+			if err := checkpoint.Verify(test.untrusted.Public()); err == nil {
+				t.Fatal("checkpoint accepted with an untrusted key")
+			}
+
+			tampered := []struct {
+				name   string
+				tamper func(c *imt.SignedCheckpoint)
+			}{
+				{"domain", func(c *imt.SignedCheckpoint) { c.Domain = "other" }},
+				{"root", func(c *imt.SignedCheckpoint) { c.Root = append([]byte(nil), c.Root...); c.Root[0] ^= 1 }},
+				{"count", func(c *imt.SignedCheckpoint) { c.Count++ }},
+				{"signature", func(c *imt.SignedCheckpoint) {
+					c.Signature = append([]byte(nil), c.Signature...)
+					c.Signature[len(c.Signature)-1] ^= 1
+				}},
+			}
+			for _, field := range tampered {
+				forged := checkpoint
+				field.tamper(&forged)
+				if err := forged.Verify(test.signer.Public()); err == nil {
+					t.Fatalf("checkpoint with a tampered %s accepted", field.name)
+				}
+				// Recomputing the digest doesn't make the signature valid.
+				forged.Digest = imt.SignedCheckpointDigest(forged.Domain, forged.Root, forged.Count)
+				if err := forged.Verify(test.signer.Public()); err == nil {
+					t.Fatalf("checkpoint with a tampered %s and its digest accepted", field.name)
+				}
+			}
+		})
+	}
+
+	var checkpoint imt.SignedCheckpoint
+	if err := checkpoint.Verify("not a key"); err == nil {
+		t.Fatal("checkpoint verified with an unsupported key")
+	}
+}
+
+func TestCheckpointerEvery(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var counts []uint64
+	checkpointer, err := imt.NewCheckpointer(tree, uint64Codec{}, imt.CheckpointerConfig{
+		Domain: "chain",
+		Signer: key,
+		Every:  3,
+		Sink: imt.CheckpointSinkFunc(func(checkpoint imt.SignedCheckpoint) error {
+			counts = append(counts, checkpoint.Count)
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer checkpointer.Close()
+	for i := range 7 {
+		if err := tree.Insert(uint64(i + 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(counts) != 2 || counts[0] != 3 || counts[1] != 6 {
+		t.Fatalf("checkpoints signed at counts %v, want [3 6]", counts)
+	}
+
+	if _, err := imt.NewCheckpointer(tree, uint64Codec{}, imt.CheckpointerConfig{Signer: key}); err == nil {
+		t.Fatal("Checkpointer created without a sink")
+	}
+}
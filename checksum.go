@@ -0,0 +1,76 @@
+package imt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// ErrChecksumMismatch is returned when decoding a stored level whose checksum
+// does not match its content.
+var ErrChecksumMismatch = errors.New("the checksum of the stored level does not match")
+
+// castagnoli is the CRC-32C table, which is computed in hardware on most CPUs.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// ChecksummedLevelValueCodec is a LevelValueCodec that appends a CRC-32C
+// checksum to each encoded level and verifies it when decoding, so that bit
+// rot or a partial write of a stored level is detected when the tree is loaded
+// rather than when one of its proofs fails to verify. Levels stored with
+// LevelValueCodec cannot be decoded with it, and vice versa.
+type ChecksummedLevelValueCodec[N comparable] struct {
+	Node NodeCodec[N]
+}
+
+// NewChecksummedLevelValueCodec returns a ChecksummedLevelValueCodec using
+// the given node codec.
+func NewChecksummedLevelValueCodec[N comparable](node NodeCodec[N]) ChecksummedLevelValueCodec[N] {
+	return ChecksummedLevelValueCodec[N]{Node: node}
+}
+
+// Encode encodes the level like LevelValueCodec, followed by the big-endian
+// CRC-32C checksum of that encoding.
+func (c ChecksummedLevelValueCodec[N]) Encode(value []N) ([]byte, error) {
+	b, err := appendNodes(nil, c.Node, value)
+	if err != nil {
+		return nil, err
+	}
+	return binary.BigEndian.AppendUint32(b, crc32.Checksum(b, castagnoli)), nil
+}
+
+// Decode verifies the checksum of a level encoded with Encode and decodes it,
+// returning an error wrapping ErrChecksumMismatch if the checksum is wrong.
+func (c ChecksummedLevelValueCodec[N]) Decode(b []byte) ([]N, error) {
+	if len(b) < crc32.Size {
+		return nil, errors.New("unexpected end of input")
+	}
+	body, sum := b[:len(b)-crc32.Size], binary.BigEndian.Uint32(b[len(b)-crc32.Size:])
+	if actual := crc32.Checksum(body, castagnoli); actual != sum {
+		return nil, fmt.Errorf("%w: expected %08x, got %08x", ErrChecksumMismatch, sum, actual)
+	}
+	return LevelValueCodec[N]{Node: c.Node}.Decode(body)
+}
+
+// EncodeJSON encodes the level as a JSON array, without checksum.
+func (c ChecksummedLevelValueCodec[N]) EncodeJSON(value []N) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// DecodeJSON decodes a level encoded with EncodeJSON.
+func (c ChecksummedLevelValueCodec[N]) DecodeJSON(b []byte) ([]N, error) {
+	var value []N
+	err := json.Unmarshal(b, &value)
+	return value, err
+}
+
+// Stringify returns a human readable representation of the level.
+func (c ChecksummedLevelValueCodec[N]) Stringify(value []N) string {
+	return fmt.Sprint(value)
+}
+
+// ValueType returns the identifier of the encoded type.
+func (c ChecksummedLevelValueCodec[N]) ValueType() string {
+	return "imt/ChecksummedLevel"
+}
@@ -0,0 +1,425 @@
+package imt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// NodeCodec encodes and decodes single tree nodes. Its method set is a subset
+// of the cosmossdk.io/collections `codec.ValueCodec` interface, so any value
+// codec of the collections framework can be used to encode the nodes.
+type NodeCodec[N comparable] interface {
+	Encode(value N) ([]byte, error)
+	Decode(b []byte) (N, error)
+}
+
+// Metadata contains the parameters of a tree that are needed, together with
+// its levels, to restore it.
+type Metadata struct {
+	Depth int `json:"depth"` // The depth of the tree.
+	Arity int `json:"arity"` // The number of children per node.
+	Size  int `json:"size"`  // The number of leaves in the tree.
+}
+
+// Metadata returns the parameters of the tree.
+func (t *IMT[N]) Metadata() Metadata {
+	return Metadata{
+		Depth: t.depth,
+		Arity: t.arity,
+		Size:  t.nodes[0].Len(),
+	}
+}
+
+// Level returns a copy of the nodes of a level of the tree, where level 0
+// contains the leaves and level Depth() contains the root.
+func (t *IMT[N]) Level(level int) ([]N, error) {
+	if level < 0 || level > t.depth {
+		return nil, t.opError(OpLevel, level, -1, ErrLevelNotFound)
+	}
+	return levelNodes(t.nodes[level], 0, t.nodes[level].Len()), nil
+}
+
+// NewFromLevels restores a tree from its levels, as returned by Level, without
+// recomputing any hash. Only the shape of the levels is validated, so the
+// levels must come from a trusted source such as the state of the application.
+func NewFromLevels[N comparable](hash HashFunction[N], zeroValue N, metadata Metadata, levels [][]N) (*IMT[N], error) {
+	t, err := New(hash, metadata.Depth, zeroValue, metadata.Arity, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(levels) != metadata.Depth+1 {
+		return nil, fmt.Errorf("expected %d levels, got %d", metadata.Depth+1, len(levels))
+	}
+	if len(levels[0]) != metadata.Size {
+		return nil, fmt.Errorf("expected %d leaves, got %d", metadata.Size, len(levels[0]))
+	}
+	if len(levels[0]) == 0 {
+		return t, nil
+	}
+
+	for level := 0; level <= metadata.Depth; level++ {
+		expected := len(levels[0])
+		for i := 0; i < level; i++ {
+			expected = (expected + metadata.Arity - 1) / metadata.Arity
+		}
+		if len(levels[level]) != expected {
+			return nil, fmt.Errorf("level %d must contain %d nodes, got %d", level, expected, len(levels[level]))
+		}
+		t.nodes[level] = t.newLevel(len(levels[level]))
+		for _, node := range levels[level] {
+			t.nodes[level].Append(node)
+		}
+	}
+
+	return t, nil
+}
+
+// MetadataValueCodec implements the collections `codec.ValueCodec` interface
+// for Metadata.
+type MetadataValueCodec struct{}
+
+// Encode encodes the metadata as a sequence of unsigned varints.
+func (MetadataValueCodec) Encode(value Metadata) ([]byte, error) {
+	if value.Depth < 0 || value.Arity < 0 || value.Size < 0 {
+		return nil, errors.New("metadata values cannot be negative")
+	}
+	b := binary.AppendUvarint(nil, uint64(value.Depth))
+	b = binary.AppendUvarint(b, uint64(value.Arity))
+	b = binary.AppendUvarint(b, uint64(value.Size))
+	return b, nil
+}
+
+// Decode decodes metadata encoded with Encode.
+func (MetadataValueCodec) Decode(b []byte) (Metadata, error) {
+	r := &byteReader{b: b}
+	value := Metadata{
+		Depth: r.int(),
+		Arity: r.int(),
+		Size:  r.int(),
+	}
+	if err := r.done(); err != nil {
+		return Metadata{}, err
+	}
+	return value, nil
+}
+
+// EncodeJSON encodes the metadata as JSON.
+func (MetadataValueCodec) EncodeJSON(value Metadata) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// DecodeJSON decodes metadata encoded with EncodeJSON.
+func (MetadataValueCodec) DecodeJSON(b []byte) (Metadata, error) {
+	var value Metadata
+	err := json.Unmarshal(b, &value)
+	return value, err
+}
+
+// Stringify returns a human readable representation of the metadata.
+func (MetadataValueCodec) Stringify(value Metadata) string {
+	return fmt.Sprintf("Metadata{Depth: %d, Arity: %d, Size: %d}", value.Depth, value.Arity, value.Size)
+}
+
+// ValueType returns the identifier of the encoded type.
+func (MetadataValueCodec) ValueType() string {
+	return "imt/Metadata"
+}
+
+// LevelValueCodec implements the collections `codec.ValueCodec` interface for
+// a level of the tree, encoding each node with the given NodeCodec.
+type LevelValueCodec[N comparable] struct {
+	Node NodeCodec[N]
+}
+
+// NewLevelValueCodec returns a LevelValueCodec using the given node codec.
+func NewLevelValueCodec[N comparable](node NodeCodec[N]) LevelValueCodec[N] {
+	return LevelValueCodec[N]{Node: node}
+}
+
+// Encode encodes the level as a node count followed by length-prefixed nodes.
+func (c LevelValueCodec[N]) Encode(value []N) ([]byte, error) {
+	return appendNodes(nil, c.Node, value)
+}
+
+// Decode decodes a level encoded with Encode.
+func (c LevelValueCodec[N]) Decode(b []byte) ([]N, error) {
+	r := &byteReader{b: b}
+	value := readNodes(r, c.Node)
+	if err := r.done(); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// EncodeJSON encodes the level as a JSON array.
+func (c LevelValueCodec[N]) EncodeJSON(value []N) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// DecodeJSON decodes a level encoded with EncodeJSON.
+func (c LevelValueCodec[N]) DecodeJSON(b []byte) ([]N, error) {
+	var value []N
+	err := json.Unmarshal(b, &value)
+	return value, err
+}
+
+// Stringify returns a human readable representation of the level.
+func (c LevelValueCodec[N]) Stringify(value []N) string {
+	return fmt.Sprint(value)
+}
+
+// ValueType returns the identifier of the encoded type.
+func (c LevelValueCodec[N]) ValueType() string {
+	return "imt/Level"
+}
+
+// ProofValueCodec implements the collections `codec.ValueCodec` interface for
+// MerkleProof, encoding each node with the given NodeCodec.
+type ProofValueCodec[N comparable] struct {
+	Node NodeCodec[N]
+}
+
+// NewProofValueCodec returns a ProofValueCodec using the given node codec.
+func NewProofValueCodec[N comparable](node NodeCodec[N]) ProofValueCodec[N] {
+	return ProofValueCodec[N]{Node: node}
+}
+
+// Encode encodes the proof as a version byte, which is always ProofVersion,
+// followed by the root, the leaf, the leaf index, the siblings and the path
+// index of each level, and finally the depth, the arity and the hash
+// identifier of the tree.
+func (c ProofValueCodec[N]) Encode(value MerkleProof[N]) ([]byte, error) {
+	if len(value.Siblings) != len(value.PathIndices) {
+		return nil, errors.New("the proof must contain a path index for each level")
+	}
+	if value.LeafIndex < 0 {
+		return nil, errors.New("the leaf index cannot be negative")
+	}
+
+	b, err := appendNode([]byte{ProofVersion}, c.Node, value.Root)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = appendNode(b, c.Node, value.Leaf); err != nil {
+		return nil, err
+	}
+	b = binary.AppendUvarint(b, uint64(value.LeafIndex))
+	b = binary.AppendUvarint(b, uint64(len(value.Siblings)))
+	for i, siblings := range value.Siblings {
+		if value.PathIndices[i] < 0 {
+			return nil, errors.New("path indices cannot be negative")
+		}
+		if b, err = appendNodes(b, c.Node, siblings); err != nil {
+			return nil, err
+		}
+		b = binary.AppendUvarint(b, uint64(value.PathIndices[i]))
+	}
+	if value.Depth < 0 || value.Arity < 0 {
+		return nil, errors.New("the depth and arity cannot be negative")
+	}
+	b = binary.AppendUvarint(b, uint64(value.Depth))
+	b = binary.AppendUvarint(b, uint64(value.Arity))
+	b = binary.AppendUvarint(b, uint64(len(value.HashID)))
+	b = append(b, value.HashID...)
+
+	return b, nil
+}
+
+// Decode decodes a proof encoded with Encode, dispatching on its version
+// byte so that proofs encoded by older versions of the package keep decoding.
+func (c ProofValueCodec[N]) Decode(b []byte) (MerkleProof[N], error) {
+	if len(b) == 0 {
+		return MerkleProof[N]{}, errors.New("unexpected end of input")
+	}
+	switch version := b[0]; version {
+	case 1, 2:
+		return c.decode(b[1:], version)
+	default:
+		return MerkleProof[N]{}, fmt.Errorf("unsupported proof version %d", b[0])
+	}
+}
+
+// decode decodes the body of a proof. Version 2 appended the parameters of
+// the tree to the layout of version 1.
+func (c ProofValueCodec[N]) decode(b []byte, version byte) (MerkleProof[N], error) {
+	r := &byteReader{b: b}
+	value := MerkleProof[N]{
+		Root:      readNode(r, c.Node),
+		Leaf:      readNode(r, c.Node),
+		LeafIndex: r.int(),
+	}
+	levels := r.length()
+	value.Siblings = make([][]N, levels)
+	value.PathIndices = make([]int, levels)
+	for i := 0; i < levels && r.err == nil; i++ {
+		value.Siblings[i] = readNodes(r, c.Node)
+		value.PathIndices[i] = r.int()
+	}
+	if version >= 2 {
+		value.Depth = r.int()
+		value.Arity = r.int()
+		value.HashID = string(r.bytes(r.length()))
+	}
+	if err := r.done(); err != nil {
+		return MerkleProof[N]{}, err
+	}
+	return value, nil
+}
+
+// EncodeJSON encodes the proof as JSON.
+func (c ProofValueCodec[N]) EncodeJSON(value MerkleProof[N]) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// DecodeJSON decodes a proof encoded with EncodeJSON.
+func (c ProofValueCodec[N]) DecodeJSON(b []byte) (MerkleProof[N], error) {
+	var value MerkleProof[N]
+	err := json.Unmarshal(b, &value)
+	return value, err
+}
+
+// Stringify returns a human readable representation of the proof.
+func (c ProofValueCodec[N]) Stringify(value MerkleProof[N]) string {
+	return fmt.Sprintf("%+v", value)
+}
+
+// ValueType returns the identifier of the encoded type.
+func (c ProofValueCodec[N]) ValueType() string {
+	return "imt/MerkleProof"
+}
+
+// appendNode appends a length-prefixed node.
+func appendNode[N comparable](b []byte, codec NodeCodec[N], node N) ([]byte, error) {
+	encoded, err := codec.Encode(node)
+	if err != nil {
+		return nil, err
+	}
+	b = binary.AppendUvarint(b, uint64(len(encoded)))
+	return append(b, encoded...), nil
+}
+
+// appendNodes appends a node count followed by length-prefixed nodes.
+func appendNodes[N comparable](b []byte, codec NodeCodec[N], nodes []N) ([]byte, error) {
+	b = binary.AppendUvarint(b, uint64(len(nodes)))
+	for _, node := range nodes {
+		var err error
+		if b, err = appendNode(b, codec, node); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// readNode reads a node written by appendNode.
+func readNode[N comparable](r *byteReader, codec NodeCodec[N]) N {
+	var node N
+	b := r.bytes(r.length())
+	if r.err != nil {
+		return node
+	}
+	node, r.err = codec.Decode(b)
+	return node
+}
+
+// readNodes reads a list of nodes written by appendNodes.
+func readNodes[N comparable](r *byteReader, codec NodeCodec[N]) []N {
+	count := r.length()
+	nodes := make([]N, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		nodes = append(nodes, readNode(r, codec))
+	}
+	return nodes
+}
+
+// byteReader reads varints and byte strings from a buffer, remembering the
+// first error so that callers only need to check it once.
+type byteReader struct {
+	b   []byte
+	err error
+}
+
+// uvarint reads an unsigned varint.
+func (r *byteReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.err = errors.New("invalid varint")
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+// varint reads a signed varint.
+func (r *byteReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.err = errors.New("invalid varint")
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+// int reads an unsigned varint that must fit in an int.
+func (r *byteReader) int() int {
+	v := r.uvarint()
+	if v > uint64(maxInt) {
+		r.fail(errors.New("value overflows int"))
+		return 0
+	}
+	return int(v)
+}
+
+// length reads an unsigned varint that is used as the length of what
+// follows, and therefore cannot exceed the remaining bytes.
+func (r *byteReader) length() int {
+	v := r.int()
+	if v > len(r.b) {
+		r.fail(errors.New("length exceeds the remaining bytes"))
+		return 0
+	}
+	return v
+}
+
+// bytes reads n raw bytes.
+func (r *byteReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n > len(r.b) {
+		r.err = errors.New("unexpected end of input")
+		return nil
+	}
+	b := r.b[:n]
+	r.b = r.b[n:]
+	return b
+}
+
+// fail records an error unless one was already recorded.
+func (r *byteReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// done returns the first error, or an error if there are unread bytes.
+func (r *byteReader) done() error {
+	if r.err != nil {
+		return r.err
+	}
+	if len(r.b) != 0 {
+		return errors.New("unexpected trailing bytes")
+	}
+	return nil
+}
+
+// maxInt is the largest value of an int.
+const maxInt = int(^uint(0) >> 1)
@@ -0,0 +1,179 @@
+package imt_test
+
+import (
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestMetadataValueCodec(t *testing.T) {
+	codec := imt.MetadataValueCodec{}
+	for _, metadata := range []imt.Metadata{{}, {Depth: 20, Arity: 2, Size: 5}, {Depth: 3, Arity: 300, Size: 1 << 20}} {
+		b, err := codec.Encode(metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := codec.Decode(b)
+		if err != nil || decoded != metadata {
+			t.Fatalf("Decode(Encode(%v)) = %v, %v", metadata, decoded, err)
+		}
+		b, err = codec.EncodeJSON(metadata)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded, err := codec.DecodeJSON(b); err != nil || decoded != metadata {
+			t.Fatalf("DecodeJSON(EncodeJSON(%v)) = %v, %v", metadata, decoded, err)
+		}
+	}
+
+	if _, err := codec.Encode(imt.Metadata{Depth: -1}); err == nil {
+		t.Fatal("encoded a negative depth")
+	}
+	invalid := map[string][]byte{
+		"empty":          nil,
+		"truncated":      {20, 2},
+		"trailing bytes": {20, 2, 5, 0},
+		"overflowing":    {0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01, 2, 5},
+	}
+	for name, b := range invalid {
+		if _, err := codec.Decode(b); err == nil {
+			t.Errorf("%s: invalid metadata decoded", name)
+		}
+	}
+}
+
+func TestLevelValueCodec(t *testing.T) {
+	codec := imt.NewLevelValueCodec[uint64](uint64Codec{})
+	for _, level := range [][]uint64{{}, {1}, {1, 2, 1 << 63}} {
+		b, err := codec.Encode(level)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := codec.Decode(b)
+		if err != nil || len(decoded) != len(level) {
+			t.Fatalf("Decode(Encode(%v)) = %v, %v", level, decoded, err)
+		}
+		for i := range level {
+			if decoded[i] != level[i] {
+				t.Fatalf("Decode(Encode(%v)) = %v", level, decoded)
+			}
+		}
+	}
+
+	invalid := map[string][]byte{
+		"empty":             nil,
+		"missing nodes":     {2, 8, 0, 0, 0, 0, 0, 0, 0, 1},
+		"short node":        {1, 7, 0, 0, 0, 0, 0, 0, 1},
+		"huge count":        {0xff, 0xff, 0xff, 0xff, 0x0f},
+		"trailing bytes":    {0, 0},
+		"invalid node size": {1, 2, 0, 1},
+	}
+	for name, b := range invalid {
+		if _, err := codec.Decode(b); err == nil {
+			t.Errorf("%s: invalid level decoded", name)
+		}
+	}
+}
+
+func TestProofValueCodec(t *testing.T) {
+	codec := imt.NewProofValueCodec[uint64](uint64Codec{})
+	for _, arity := range []int{2, 3, 5} {
+		leaves := imttest.RandomLeaves(imttest.Rand(uint64(arity)), 11, imttest.Uint64Leaf)
+		tree, err := imt.New(imttest.Uint64Hash, 4, 0, arity, leaves, imt.WithHashID("test"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for index := range leaves {
+			proof, err := tree.CreateProof(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := codec.Encode(*proof)
+			if err != nil {
+				t.Fatal(err)
+			}
+			decoded, err := codec.Decode(b)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !decoded.Equal(proof) || decoded.HashID != "test" || decoded.Depth != 4 || decoded.Arity != arity {
+				t.Fatalf("arity %d, leaf %d: the decoded proof differs", arity, index)
+			}
+			if !tree.VerifyProof(&decoded) {
+				t.Fatalf("arity %d, leaf %d: the decoded proof was rejected", arity, index)
+			}
+
+			for i := range b {
+				if _, err := codec.Decode(b[:i]); err == nil {
+					t.Fatalf("arity %d, leaf %d: a proof truncated to %d bytes was decoded", arity, index, i)
+				}
+			}
+			if _, err := codec.Decode(append(b, 0)); err == nil {
+				t.Fatalf("arity %d, leaf %d: a proof with trailing bytes was decoded", arity, index)
+			}
+		}
+	}
+
+	invalid := map[string]imt.MerkleProof[uint64]{
+		"negative leaf index": {LeafIndex: -1},
+		"missing path index":  {Siblings: [][]uint64{{1}}},
+		"negative path index": {Siblings: [][]uint64{{1}}, PathIndices: []int{-1}},
+		"negative depth":      {Depth: -1},
+		"negative arity":      {Arity: -1},
+	}
+	for name, proof := range invalid {
+		if _, err := codec.Encode(proof); err == nil {
+			t.Errorf("%s: invalid proof encoded", name)
+		}
+	}
+}
+
+func TestNewFromLevels(t *testing.T) {
+	leaves := imttest.RandomLeaves(imttest.Rand(1), 10, imttest.Uint64Leaf)
+	tree, err := imt.New(imttest.Uint64Hash, 3, 0, 3, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	levels := make([][]uint64, tree.Depth()+1)
+	for level := range levels {
+		if levels[level], err = tree.Level(level); err != nil {
+			t.Fatal(err)
+		}
+	}
+	restored, err := imt.NewFromLevels(imttest.Uint64Hash, 0, tree.Metadata(), levels)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Root() != tree.Root() || restored.Size() != tree.Size() {
+		t.Fatal("the restored tree differs")
+	}
+	if err := restored.Insert(5); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Insert(5); err != nil {
+		t.Fatal(err)
+	}
+	if restored.Root() != tree.Root() {
+		t.Fatal("the restored tree diverged after an insertion")
+	}
+
+	tests := []struct {
+		name     string
+		metadata imt.Metadata
+		levels   [][]uint64
+	}{
+		{"missing level", imt.Metadata{Depth: 3, Arity: 3, Size: 10}, levels[:3]},
+		{"wrong size", imt.Metadata{Depth: 3, Arity: 3, Size: 9}, levels},
+		{"wrong arity", imt.Metadata{Depth: 3, Arity: 2, Size: 10}, levels},
+		{"invalid depth", imt.Metadata{Depth: 0, Arity: 3, Size: 10}, levels},
+	}
+	for _, test := range tests {
+		if _, err := imt.NewFromLevels(imttest.Uint64Hash, 0, test.metadata, test.levels); err == nil {
+			t.Errorf("%s: the levels were restored", test.name)
+		}
+	}
+	if _, err := tree.Level(tree.Depth() + 1); err == nil {
+		t.Fatal("returned a level above the root")
+	}
+}
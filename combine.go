@@ -0,0 +1,74 @@
+package imt
+
+import "errors"
+
+// CombinedProof proves that a leaf belongs to one of two trees combined by
+// Combine: it extends the proof of the leaf in its tree with the root of the
+// other tree.
+type CombinedProof[N comparable] struct {
+	Root     N               // The combined root.
+	Proof    *MerkleProof[N] // The proof of the leaf in its tree.
+	Sibling  N               // The root of the other tree.
+	Position int             // 0 if the leaf belongs to the first tree, 1 otherwise.
+}
+
+// Combine returns the parent commitment of two trees, which is the hash of
+// their roots, in order.
+func Combine[N comparable](a, b *IMT[N], hash HashFunction[N]) N {
+	return hash([]N{a.Root(), b.Root()})
+}
+
+// ExtendProof extends the proof of a leaf of one of two combined trees to
+// their combined root, given the root of the other tree and the position of
+// the tree of the leaf, 0 for the first tree and 1 for the second one.
+func ExtendProof[N comparable](proof *MerkleProof[N], sibling N, position int, hash HashFunction[N]) (*CombinedProof[N], error) {
+	if proof == nil {
+		return nil, errors.New("proof is nil")
+	}
+	if position != 0 && position != 1 {
+		return nil, errors.New("position must be 0 or 1")
+	}
+	return &CombinedProof[N]{
+		Root:     combinedRoot(proof.Root, sibling, position, hash),
+		Proof:    proof,
+		Sibling:  sibling,
+		Position: position,
+	}, nil
+}
+
+// CreateCombinedProofs creates the proof of a leaf of the first tree and of a
+// leaf of the second one, both extended to the combined root of the trees.
+func CreateCombinedProofs[N comparable](a, b *IMT[N], indexA, indexB int, hash HashFunction[N]) (*CombinedProof[N], *CombinedProof[N], error) {
+	proofA, err := a.CreateProof(indexA)
+	if err != nil {
+		return nil, nil, err
+	}
+	proofB, err := b.CreateProof(indexB)
+	if err != nil {
+		return nil, nil, err
+	}
+	combinedA, _ := ExtendProof(proofA, b.Root(), 0, hash)
+	combinedB, _ := ExtendProof(proofB, a.Root(), 1, hash)
+	return combinedA, combinedB, nil
+}
+
+// VerifyCombinedProof verifies the proof of the leaf in its tree, with the
+// hash function of the trees, and that the root of the tree combined with the
+// sibling gives the combined root.
+func VerifyCombinedProof[N comparable](proof *CombinedProof[N], hash HashFunction[N]) bool {
+	if proof == nil || (proof.Position != 0 && proof.Position != 1) {
+		return false
+	}
+	if !VerifyProof(proof.Proof, hash) {
+		return false
+	}
+	return combinedRoot(proof.Proof.Root, proof.Sibling, proof.Position, hash) == proof.Root
+}
+
+// combinedRoot hashes a root with the root of the other tree.
+func combinedRoot[N comparable](root, sibling N, position int, hash HashFunction[N]) N {
+	if position == 0 {
+		return hash([]N{root, sibling})
+	}
+	return hash([]N{sibling, root})
+}
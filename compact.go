@@ -0,0 +1,129 @@
+package imt
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// remapCallbacks holds the functions notified when Compact moves leaves.
+type remapCallbacks struct {
+	mu        sync.Mutex
+	next      int
+	callbacks map[int]func(remap map[int]int)
+}
+
+// OnRemap registers a function called with the old→new mapping of the leaf
+// indices every time Compact moves leaves, so that systems holding indices or
+// proofs can update them. Deleted leaves are absent from the mapping. It
+// returns a function unregistering the callback.
+func (t *IMT[N]) OnRemap(callback func(remap map[int]int)) func() {
+	c := &t.remaps
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.callbacks == nil {
+		c.callbacks = make(map[int]func(map[int]int))
+	}
+	id := c.next
+	c.next++
+	c.callbacks[id] = callback
+
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		delete(c.callbacks, id)
+	}
+}
+
+// Compact rebuilds the tree without its deleted leaves, moving the following
+// leaves to close the gaps, and returns the old→new mapping of the leaf
+// indices, which is also passed to the callbacks registered with OnRemap.
+// The data, insertion records and deadlines of the leaves move with them.
+// Since it changes the positions of the leaves, it changes the root and
+// invalidates every previous proof.
+func (t *IMT[N]) Compact() (remap map[int]int, err error) {
+	if t.tombstones.count == 0 {
+		return map[int]int{}, nil
+	}
+	span := t.startSpan(OpCompact, t.nodes[0].Len())
+	defer func() {
+		span.End(err)
+	}()
+
+	oldRoot, removed, calls := t.Root(), t.tombstones.count, t.hashCalls.Load()
+	remap = make(map[int]int, t.LiveSize())
+	live := make([]N, 0, t.LiveSize())
+	for index := 0; index < t.nodes[0].Len(); index++ {
+		if !t.tombstones.has(index) {
+			remap[index] = len(live)
+			live = append(live, t.nodes[0].Get(index))
+		}
+	}
+
+	// The tree keeps its memo, so the compacted tree doesn't need one.
+	opts := t.options
+	opts.insertionRecords = false
+	opts.memoSize = 0
+	compacted, err := newWithOptions(t.hashFunc, t.depth, t.zeroes[0], t.arity, live, opts)
+	if err != nil {
+		return nil, err
+	}
+	t.nodes = compacted.nodes
+	t.interner = compacted.interner
+	t.cache = compacted.cache
+	t.proofs = compacted.proofs
+	t.positions = compacted.positions
+	t.hashCalls.Add(compacted.hashCalls.Load())
+	t.countHashes(OpCompact, calls)
+	t.tombstones = tombstones{}
+
+	if t.leafData != nil {
+		leafData := make(map[int]any, len(t.leafData))
+		for index, data := range t.leafData {
+			leafData[remap[index]] = data
+		}
+		t.leafData = leafData
+	}
+
+	if len(t.records) > 0 {
+		records := make([]InsertionRecord, 0, len(t.records))
+		for i, record := range t.records {
+			if _, ok := remap[t.recordsStart+i]; ok {
+				records = append(records, record)
+			}
+		}
+		// The first live leaf with a record is moved after the live leaves
+		// without one.
+		start := 0
+		for index := 0; index < t.recordsStart; index++ {
+			if _, ok := remap[index]; ok {
+				start++
+			}
+		}
+		t.records, t.recordsStart = records, start
+	}
+
+	t.expiries.remap(remap)
+
+	t.version++
+	t.notifyRoots(oldRoot, 1)
+	if t.logs.active() {
+		t.emitLog(LogEntry[N]{Op: OpCompact})
+	}
+	t.log(slog.LevelInfo, "imt: compacted tree",
+		slog.Int("removed", removed),
+		slog.Uint64("version", t.version),
+	)
+
+	t.remaps.mu.Lock()
+	callbacks := make([]func(map[int]int), 0, len(t.remaps.callbacks))
+	for _, callback := range t.remaps.callbacks {
+		callbacks = append(callbacks, callback)
+	}
+	t.remaps.mu.Unlock()
+	for _, callback := range callbacks {
+		callback(remap)
+	}
+
+	return remap, nil
+}
@@ -0,0 +1,65 @@
+package imt
+
+import (
+	"bytes"
+	"errors"
+	"math"
+)
+
+// The Certificate Transparency profile of RFC 6962 uses the same tree as the
+// CometBFT simple Merkle tree: SHA-256 with the 0x00 and 0x01 prefixes, and
+// an unbalanced tree split at the largest power of two smaller than the
+// number of entries. Audit paths are ordered from the leaf up, as in CometBFT.
+
+// CTLeafHash returns the Merkle Tree Hash of a log entry, SHA-256(0x00 ||
+// entry).
+func CTLeafHash(entry []byte) []byte {
+	return simpleLeafHash(entry)
+}
+
+// CTNodeHash returns the Merkle Tree Hash of an inner node, SHA-256(0x01 ||
+// left || right).
+func CTNodeHash(left, right []byte) []byte {
+	return simpleInnerHash(left, right)
+}
+
+// CTRootHash returns the Merkle Tree Hash of a list of log entries, which is
+// the root hash of a signed tree head of the log.
+func CTRootHash(entries [][]byte) []byte {
+	return SimpleHashFromByteSlices(entries)
+}
+
+// CTAuditPath returns the audit path of the entry at the given index, as
+// defined by RFC 6962, section 2.1.1: the sibling hashes from the leaf up to
+// the root.
+func CTAuditPath(entries [][]byte, index int) ([][]byte, error) {
+	if index < 0 || index >= len(entries) {
+		return nil, ErrLeafNotFound
+	}
+	if len(entries) == 1 {
+		return nil, nil
+	}
+	k := splitPoint(len(entries))
+	if index < k {
+		path, _ := CTAuditPath(entries[:k], index)
+		return append(path, SimpleHashFromByteSlices(entries[k:])), nil
+	}
+	path, _ := CTAuditPath(entries[k:], index-k)
+	return append(path, SimpleHashFromByteSlices(entries[:k])), nil
+}
+
+// VerifyCTAuditPath checks that the leaf hash at the given index is committed
+// under the root hash of a tree of treeSize entries, given its audit path.
+func VerifyCTAuditPath(root, leafHash []byte, index, treeSize uint64, path [][]byte) error {
+	if index >= treeSize || treeSize > math.MaxInt64 {
+		return errors.New("the index is outside the tree")
+	}
+	computed := computeHashFromAunts(int64(index), int64(treeSize), leafHash, path)
+	if computed == nil {
+		return errors.New("invalid proof layout")
+	}
+	if !bytes.Equal(computed, root) {
+		return errors.New("the audit path does not match the root")
+	}
+	return nil
+}
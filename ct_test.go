@@ -0,0 +1,138 @@
+package imt_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/noble-assets/imt"
+)
+
+// The test vectors of the reference implementation of RFC 6962, from the
+// Certificate Transparency project.
+var ctEntries = [][]byte{
+	decodeHex(""),
+	decodeHex("00"),
+	decodeHex("10"),
+	decodeHex("2021"),
+	decodeHex("3031"),
+	decodeHex("40414243"),
+	decodeHex("5051525354555657"),
+	decodeHex("606162636465666768696a6b6c6d6e6f"),
+}
+
+// ctRoots are the root hashes of the first 1 to 8 entries.
+var ctRoots = []string{
+	"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+	"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+	"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+	"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+	"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+	"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+	"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+	"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+}
+
+func decodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestCTHashes(t *testing.T) {
+	tests := []struct {
+		name string
+		hash []byte
+		want string
+	}{
+		{"empty tree", imt.CTRootHash(nil), "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"empty leaf", imt.CTLeafHash(nil), "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		{"leaf", imt.CTLeafHash([]byte("L123456")), "395aa064aa4c29f7010acfe3f25db9485bbd4b91897b6ad7ad547639252b4d56"},
+		{"node", imt.CTNodeHash([]byte("N123"), []byte("N456")), "aa217fe888e47007fa15edab33c2b492a722cb106c64667fc2b044444de66bbb"},
+	}
+	for _, test := range tests {
+		if got := hex.EncodeToString(test.hash); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+
+	for size, want := range ctRoots {
+		if got := hex.EncodeToString(imt.CTRootHash(ctEntries[:size+1])); got != want {
+			t.Errorf("root of %d entries: got %s, want %s", size+1, got, want)
+		}
+	}
+}
+
+func TestCTAuditPaths(t *testing.T) {
+	tests := []struct {
+		index int
+		size  int
+		path  []string
+	}{
+		{0, 1, nil},
+		{0, 8, []string{
+			"96a296d224f285c67bee93c30f8a309157f0daa35dc5b87e410b78630a09cfc7",
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"6b47aaf29ee3c2af9af889bc1fb9254dabd31177f16232dd6aab035ca39bf6e4",
+		}},
+		{5, 8, []string{
+			"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+			"ca854ea128ed050b41b35ffc1b87b8eb2bde461e9e3b5596ece6b9d5975a0ae0",
+			"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		}},
+		{2, 3, []string{
+			"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		}},
+		{1, 5, []string{
+			"6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d",
+			"5f083f0a1a33ca076a95279832580db3e0ef4584bdff1f54c8a360f50de3031e",
+			"bc1a0643b12e4d2d7c77918f44e0f4f79a838b6cf9ec5b5c283e1f4d88599e6b",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("leaf %d of %d", test.index, test.size), func(t *testing.T) {
+			entries := ctEntries[:test.size]
+			path, err := imt.CTAuditPath(entries, test.index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(path) != len(test.path) {
+				t.Fatalf("got a path of %d hashes, want %d", len(path), len(test.path))
+			}
+			for i, want := range test.path {
+				if got := hex.EncodeToString(path[i]); got != want {
+					t.Fatalf("hash %d: got %s, want %s", i, got, want)
+				}
+			}
+
+			root := decodeHex(ctRoots[test.size-1])
+			leaf := imt.CTLeafHash(entries[test.index])
+			index, size := uint64(test.index), uint64(test.size)
+			if err := imt.VerifyCTAuditPath(root, leaf, index, size, path); err != nil {
+				t.Fatal(err)
+			}
+			if err := imt.VerifyCTAuditPath(decodeHex(ctRoots[test.size%8]), leaf, index, size, path); err == nil {
+				t.Fatal("audit path accepted for another root")
+			}
+			if err := imt.VerifyCTAuditPath(root, leaf, size, size, path); err == nil {
+				t.Fatal("audit path accepted for an index outside the tree")
+			}
+			if err := imt.VerifyCTAuditPath(root, imt.CTLeafHash([]byte("other")), index, size, path); err == nil {
+				t.Fatal("audit path accepted for another entry")
+			}
+			if len(path) > 0 {
+				if err := imt.VerifyCTAuditPath(root, leaf, index, size, path[1:]); err == nil {
+					t.Fatal("truncated audit path accepted")
+				}
+			}
+		})
+	}
+
+	for _, index := range []int{-1, 8} {
+		if _, err := imt.CTAuditPath(ctEntries, index); err == nil {
+			t.Fatalf("audit path created for entry %d of 8", index)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package imt
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// WithDebugChecks makes every insertion, update and deletion check, once
+// done, that the levels of the tree have consistent lengths and that every
+// node on the paths it touched is the hash of its children. A failed check is
+// returned as the error of the operation, which is left applied so that the
+// tree can be inspected. The checks double the cost of the writes, so they
+// are meant for development, e.g. of new storage backends.
+func WithDebugChecks() Option {
+	return func(o *options) {
+		o.debugChecks = true
+	}
+}
+
+// debugCheck runs the debug checks, if enabled, after an operation that wrote
+// the leaves from first to last, inclusive, and their ancestors.
+func (t *IMT[N]) debugCheck(op string, first, last int) error {
+	if !t.options.debugChecks {
+		return nil
+	}
+	err := t.checkPaths(op, first, last)
+	if err != nil {
+		t.log(slog.LevelError, "imt: integrity check failed", slog.String("op", op), slog.Any("error", err))
+	}
+	return err
+}
+
+// checkPaths checks the levels of the tree and the paths of the leaves from
+// first to last, inclusive.
+func (t *IMT[N]) checkPaths(op string, first, last int) error {
+	if err := t.validateLevels(); err != nil {
+		return fmt.Errorf("debug check failed after %s of leaves %d to %d: %w", op, first, last, err)
+	}
+	start, end := first, last
+	for level := 0; level < t.depth; level++ {
+		start, end = start/t.arity, end/t.arity
+		for index := start; index <= end; index++ {
+			expected := t.hash(t.storedChildren(level, index))
+			if node := t.nodes[level+1].Get(index); !t.equal(node, expected) {
+				return fmt.Errorf("debug check failed after %s of leaves %d to %d: node %d of level %d is %v, expected %v", op, first, last, index, level+1, node, expected)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package imt
+
+// DeletePolicy selects what Delete does with the position of a deleted leaf.
+type DeletePolicy int
+
+const (
+	// DeleteZeroes sets deleted leaves to the zero value and keeps their
+	// positions, which are never reused. It is the default policy.
+	DeleteZeroes DeletePolicy = iota
+	// DeleteTruncateIfLast sets deleted leaves to the zero value like
+	// DeleteZeroes, but removes the last leaf of the tree when it is
+	// deleted, along with the deleted leaves before it, so that the size of
+	// the tree shrinks and the next insertions reuse their positions.
+	DeleteTruncateIfLast
+	// DeleteFreeList sets deleted leaves to the zero value like
+	// DeleteZeroes, and makes insertions fill the positions of the deleted
+	// leaves, lowest first, before appending leaves to the tree.
+	DeleteFreeList
+)
+
+// String returns the name of the policy.
+func (p DeletePolicy) String() string {
+	switch p {
+	case DeleteTruncateIfLast:
+		return "truncate-if-last"
+	case DeleteFreeList:
+		return "free-list"
+	default:
+		return "zeroes"
+	}
+}
+
+// WithDeletePolicy sets the policy applied by Delete. With a policy other
+// than DeleteZeroes, the index of an inserted leaf is not always the size of
+// the tree before the insertion and must be read from NextIndex. Followers
+// replicating a tree must use the same policy as the tree.
+func WithDeletePolicy(policy DeletePolicy) Option {
+	return func(o *options) {
+		o.deletePolicy = policy
+	}
+}
+
+// NextIndex returns the index at which Insert will put the next leaf: the
+// lowest deleted position if the tree reuses them, and the size of the tree
+// otherwise.
+func (t *IMT[N]) NextIndex() int {
+	if index, ok := t.freeIndex(); ok {
+		return index
+	}
+	return t.nodes[0].Len()
+}
+
+// freeIndex returns the lowest deleted position, if the tree reuses them and
+// there is one.
+func (t *IMT[N]) freeIndex() (int, bool) {
+	if t.options.deletePolicy != DeleteFreeList || t.tombstones.count == 0 {
+		return 0, false
+	}
+	return t.tombstones.first(), true
+}
+
+// freeIndices returns the count lowest deleted positions, in increasing
+// order, if the tree reuses them.
+func (t *IMT[N]) freeIndices(count int) []int {
+	if count == 0 {
+		return nil
+	}
+	return t.tombstones.indices()[:count]
+}
+
+// reuse inserts a leaf at a deleted position.
+func (t *IMT[N]) reuse(index int, leaf N) error {
+	if err := t.update(OpInsert, index, leaf); err != nil {
+		return err
+	}
+	t.tombstones.clear(index)
+	if t.options.insertionRecords && index >= t.recordsStart && index < t.recordsStart+len(t.records) {
+		t.records[index-t.recordsStart] = InsertionRecord{Sequence: t.version, Time: t.now()}
+	}
+	if t.logs.active() {
+		t.emitLog(LogEntry[N]{Op: OpInsert, Leaves: []N{leaf}})
+	}
+	return nil
+}
+
+// truncateDeleted removes the deleted leaves at the end of the tree, whose
+// nodes are already the ones of a tree without them since their value is the
+// zero value.
+func (t *IMT[N]) truncateDeleted() {
+	size := t.nodes[0].Len()
+	for size > 0 && t.tombstones.has(size-1) {
+		size--
+		t.tombstones.clear(size)
+	}
+	if size == t.nodes[0].Len() {
+		return
+	}
+
+	length := size
+	for level := 0; level < t.depth; level++ {
+		if length < t.nodes[level].Len() {
+			t.nodes[level].Truncate(length)
+		}
+		length = (length + t.arity - 1) / t.arity
+	}
+
+	if t.options.insertionRecords {
+		t.records = t.records[:max(0, min(len(t.records), size-t.recordsStart))]
+	}
+}
@@ -0,0 +1,180 @@
+package imt_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestDeletePolicies(t *testing.T) {
+	// Each test starts from the leaves 1 to 6, deletes some of them, and
+	// inserts 7, 8 and 9.
+	tests := []struct {
+		policy  imt.DeletePolicy
+		deleted []int
+		many    bool
+		leaves  []uint64 // The leaves after the deletions.
+		next    int
+		final   []uint64 // The leaves after the insertions.
+	}{
+		{imt.DeleteZeroes, []int{1, 5}, false, []uint64{1, 0, 3, 4, 5, 0}, 6, []uint64{1, 0, 3, 4, 5, 0, 7, 8, 9}},
+		{imt.DeleteTruncateIfLast, []int{1, 5}, false, []uint64{1, 0, 3, 4, 5}, 5, []uint64{1, 0, 3, 4, 5, 7, 8, 9}},
+		{imt.DeleteTruncateIfLast, []int{4, 2, 5}, false, []uint64{1, 2, 0, 4}, 4, []uint64{1, 2, 0, 4, 7, 8, 9}},
+		{imt.DeleteTruncateIfLast, []int{4, 2, 5}, true, []uint64{1, 2, 0, 4}, 4, []uint64{1, 2, 0, 4, 7, 8, 9}},
+		{imt.DeleteTruncateIfLast, []int{5, 4, 3, 2, 1, 0}, false, []uint64{}, 0, []uint64{7, 8, 9}},
+		{imt.DeleteFreeList, []int{4, 1}, false, []uint64{1, 0, 3, 4, 0, 6}, 1, []uint64{1, 7, 3, 4, 8, 6, 9}},
+		{imt.DeleteFreeList, []int{4, 1}, true, []uint64{1, 0, 3, 4, 0, 6}, 1, []uint64{1, 7, 3, 4, 8, 6, 9}},
+		{imt.DeleteFreeList, []int{5, 4, 3, 2}, true, []uint64{1, 2, 0, 0, 0, 0}, 2, []uint64{1, 2, 7, 8, 9, 0}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %v many %v", test.policy, test.deleted, test.many), func(t *testing.T) {
+			tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3, 4, 5, 6}, imt.WithDeletePolicy(test.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, index := range test.deleted {
+				if err := tree.Delete(index); err != nil {
+					t.Fatal(err)
+				}
+			}
+			checkLeaves(t, tree, test.leaves)
+			if tree.NextIndex() != test.next {
+				t.Fatalf("NextIndex = %d, want %d", tree.NextIndex(), test.next)
+			}
+
+			if test.many {
+				err = tree.InsertMany([]uint64{7, 8, 9})
+			} else {
+				for _, leaf := range []uint64{7, 8, 9} {
+					if err = tree.Insert(leaf); err != nil {
+						break
+					}
+				}
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkLeaves(t, tree, test.final)
+		})
+	}
+}
+
+// checkLeaves checks that a tree has the given leaves, and the root of a new
+// tree of these leaves.
+func checkLeaves(t *testing.T, tree *imt.IMT[uint64], leaves []uint64) {
+	t.Helper()
+	if !slices.Equal(tree.Leaves(), leaves) {
+		t.Fatalf("Leaves = %v, want %v", tree.Leaves(), leaves)
+	}
+	reference, err := imt.New(imttest.Uint64Hash, tree.Depth(), 0, tree.Arity(), leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.Root() != reference.Root() {
+		t.Fatal("the root differs from the one of a new tree of the same leaves")
+	}
+	for index := range leaves {
+		proof, err := tree.CreateProof(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tree.VerifyProof(proof) {
+			t.Fatalf("the proof of leaf %d was rejected", index)
+		}
+	}
+}
+
+func TestDeletePolicyFullTree(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 2, 0, 2, []uint64{1, 2, 3, 4}, imt.WithDeletePolicy(imt.DeleteFreeList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	// The deleted position is reused, but the tree cannot hold a second leaf.
+	if err := tree.InsertMany([]uint64{5, 6}); err == nil {
+		t.Fatal("inserted more leaves than the tree can hold")
+	}
+	checkLeaves(t, tree, []uint64{1, 0, 3, 4})
+	if err := tree.Insert(5); err != nil {
+		t.Fatalf("reusing the position of a full tree: %v", err)
+	}
+	checkLeaves(t, tree, []uint64{1, 5, 3, 4})
+}
+
+// failingHasher is a BatchHasher that fails while fail is set.
+type failingHasher struct {
+	fail bool
+}
+
+func (h *failingHasher) Submit(batch [][]uint64) <-chan imt.BatchResult[uint64] {
+	result := make(chan imt.BatchResult[uint64], 1)
+	if h.fail {
+		result <- imt.BatchResult[uint64]{Err: errors.New("hashing failed")}
+		return result
+	}
+	hashes := make([]uint64, len(batch))
+	for i, children := range batch {
+		hashes[i] = imttest.Uint64Hash(children)
+	}
+	result <- imt.BatchResult[uint64]{Hashes: hashes}
+	return result
+}
+
+func TestInsertManyFillingDeletedPositionsIsAtomic(t *testing.T) {
+	hasher := &failingHasher{}
+	opts := []imt.Option{imt.WithDeletePolicy(imt.DeleteFreeList), imt.WithBatchHasher[uint64](hasher, 0)}
+	var err error
+	trees := make([]*imt.IMT[uint64], 2)
+	for i := range trees {
+		trees[i], err = imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3, 4, 5, 6}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, index := range []int{4, 1} {
+			if err := trees[i].Delete(index); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	tree, follower := trees[0], trees[1]
+	var entries []imt.LogEntry[uint64]
+	defer tree.OnLogEntry(func(entry imt.LogEntry[uint64]) {
+		entries = append(entries, entry)
+	})()
+	updates, cancel := tree.SubscribeRoots(4)
+	defer cancel()
+
+	root, version := tree.Root(), tree.Version()
+	hasher.fail = true
+	if err := tree.InsertMany([]uint64{7, 8, 9}); err == nil {
+		t.Fatal("InsertMany succeeded although the batch hasher failed")
+	}
+	checkLeaves(t, tree, []uint64{1, 0, 3, 4, 0, 6})
+	if tree.Root() != root || tree.Version() != version || !slices.Equal(tree.DeletedIndices(), []int{1, 4}) {
+		t.Fatal("the failed insertion changed the tree")
+	}
+	if len(entries) != 0 || len(updates) != 0 {
+		t.Fatal("the failed insertion was logged or notified")
+	}
+
+	hasher.fail = false
+	if err := tree.InsertMany([]uint64{7, 8, 9}); err != nil {
+		t.Fatal(err)
+	}
+	checkLeaves(t, tree, []uint64{1, 7, 3, 4, 8, 6, 9})
+	if tree.Version() != version+3 || len(tree.DeletedIndices()) != 0 {
+		t.Fatalf("Version = %d and DeletedIndices = %v after the insertion", tree.Version(), tree.DeletedIndices())
+	}
+	if update := <-updates; update.OldRoot != root || update.NewRoot != tree.Root() || update.OpCount != 3 {
+		t.Fatalf("unexpected root update %+v", update)
+	}
+	if err := imt.NewFollower(follower).Apply(entries...); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,143 @@
+package imt
+
+import (
+	"encoding/hex"
+	"errors"
+	"math/big"
+
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+// EIP712Domain is the domain of EIP-712 typed data, which binds signatures to
+// an application, a chain and a verifying contract.
+type EIP712Domain struct {
+	Name              string
+	Version           string
+	ChainID           *big.Int
+	VerifyingContract [20]byte
+}
+
+// The EIP-712 types of the domain and of a proof. The siblings of a proof of
+// a tree with an arity greater than 2 are flattened level by level.
+const (
+	eip712DomainType = "EIP712Domain(string name,string version,uint256 chainId,address verifyingContract)"
+	eip712ProofType  = "MerkleProof(bytes32 root,bytes32 leaf,uint256 index,bytes32[] siblings)"
+)
+
+// RecoverFunc recovers the address of the signer of a digest from an
+// Ethereum signature, such as a wrapper around go-ethereum's
+// crypto.SigToPub.
+type RecoverFunc func(digest [32]byte, signature []byte) ([20]byte, error)
+
+// Separator returns the EIP-712 domain separator.
+func (d EIP712Domain) Separator() [32]byte {
+	chainID := d.ChainID
+	if chainID == nil {
+		chainID = new(big.Int)
+	}
+	name := keccak.Sum256([]byte(d.Name))
+	version := keccak.Sum256([]byte(d.Version))
+	typeHash := keccak.Sum256([]byte(eip712DomainType))
+	return keccak.Sum256(typeHash[:], name[:], version[:], abiWord(chainID), abiWord(new(big.Int).SetBytes(d.VerifyingContract[:])))
+}
+
+// EIP712ProofHash returns the EIP-712 struct hash of a proof.
+func EIP712ProofHash(proof *MerkleProof[[32]byte]) ([32]byte, error) {
+	if proof == nil {
+		return [32]byte{}, errors.New("proof is nil")
+	}
+	if proof.LeafIndex < 0 {
+		return [32]byte{}, errors.New("the leaf index cannot be negative")
+	}
+	var siblings [][]byte
+	for _, level := range proof.Siblings {
+		for _, sibling := range level {
+			siblings = append(siblings, sibling[:])
+		}
+	}
+	siblingsHash := keccak.Sum256(siblings...)
+	typeHash := keccak.Sum256([]byte(eip712ProofType))
+	return keccak.Sum256(typeHash[:], proof.Root[:], proof.Leaf[:], abiWord(big.NewInt(int64(proof.LeafIndex))), siblingsHash[:]), nil
+}
+
+// EIP712ProofDigest returns the digest signed by an EIP-712 signature of a
+// proof under a domain: keccak256(0x19 0x01 || domainSeparator || structHash).
+func EIP712ProofDigest(domain EIP712Domain, proof *MerkleProof[[32]byte]) ([32]byte, error) {
+	structHash, err := EIP712ProofHash(proof)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	separator := domain.Separator()
+	return keccak.Sum256([]byte{0x19, 0x01}, separator[:], structHash[:]), nil
+}
+
+// EIP712ProofTypedData returns the typed data of a proof under a domain, in
+// the JSON layout expected by the eth_signTypedData_v4 method of wallets.
+func EIP712ProofTypedData(domain EIP712Domain, proof *MerkleProof[[32]byte]) (map[string]any, error) {
+	if proof == nil {
+		return nil, errors.New("proof is nil")
+	}
+	siblings := []string{}
+	for _, level := range proof.Siblings {
+		for _, sibling := range level {
+			siblings = append(siblings, "0x"+hex.EncodeToString(sibling[:]))
+		}
+	}
+	chainID := domain.ChainID
+	if chainID == nil {
+		chainID = new(big.Int)
+	}
+	field := func(name, typ string) map[string]string {
+		return map[string]string{"name": name, "type": typ}
+	}
+	return map[string]any{
+		"types": map[string]any{
+			"EIP712Domain": []map[string]string{
+				field("name", "string"),
+				field("version", "string"),
+				field("chainId", "uint256"),
+				field("verifyingContract", "address"),
+			},
+			"MerkleProof": []map[string]string{
+				field("root", "bytes32"),
+				field("leaf", "bytes32"),
+				field("index", "uint256"),
+				field("siblings", "bytes32[]"),
+			},
+		},
+		"primaryType": "MerkleProof",
+		"domain": map[string]any{
+			"name":              domain.Name,
+			"version":           domain.Version,
+			"chainId":           chainID.String(),
+			"verifyingContract": "0x" + hex.EncodeToString(domain.VerifyingContract[:]),
+		},
+		"message": map[string]any{
+			"root":     "0x" + hex.EncodeToString(proof.Root[:]),
+			"leaf":     "0x" + hex.EncodeToString(proof.Leaf[:]),
+			"index":    big.NewInt(int64(proof.LeafIndex)).String(),
+			"siblings": siblings,
+		},
+	}, nil
+}
+
+// VerifySignedProof checks that a proof is valid under the given hash function
+// and that it was signed under a domain by the expected signer, whose address
+// is recovered from the signature with recover.
+func VerifySignedProof(domain EIP712Domain, proof *MerkleProof[[32]byte], hash HashFunction[[32]byte], signature []byte, signer [20]byte, recover RecoverFunc) error {
+	if !VerifyProof(proof, hash) {
+		return errors.New("invalid proof")
+	}
+	digest, err := EIP712ProofDigest(domain, proof)
+	if err != nil {
+		return err
+	}
+	recovered, err := recover(digest, signature)
+	if err != nil {
+		return err
+	}
+	if recovered != signer {
+		return errors.New("the proof was not signed by the expected signer")
+	}
+	return nil
+}
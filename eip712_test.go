@@ -0,0 +1,109 @@
+package imt_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+func TestEIP712DomainSeparator(t *testing.T) {
+	// The domain of the example of EIP-712.
+	domain := imt.EIP712Domain{
+		Name:              "Ether Mail",
+		Version:           "1",
+		ChainID:           big.NewInt(1),
+		VerifyingContract: [20]byte(decodeHex("cccccccccccccccccccccccccccccccccccccccc")),
+	}
+	want := decodeChunk(t, "f2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f")
+	if got := domain.Separator(); got != want {
+		t.Fatalf("Separator = %x, want %x", got, want)
+	}
+}
+
+func TestEIP712Proof(t *testing.T) {
+	tree, err := imt.New(imt.Keccak256Hash, 2, [32]byte{}, 3, [][32]byte{{1}, {2}, {3}, {4}, {5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := tree.CreateProof(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	domain := imt.EIP712Domain{Name: "Proofs", Version: "1", ChainID: big.NewInt(137)}
+
+	// The struct hash encodes the flattened siblings as the hash of their
+	// concatenation, as EIP-712 does for arrays.
+	var siblings [][]byte
+	for _, level := range proof.Siblings {
+		for _, sibling := range level {
+			siblings = append(siblings, sibling[:])
+		}
+	}
+	if len(siblings) != 4 {
+		t.Fatalf("got %d siblings, want 4", len(siblings))
+	}
+	typeHash := keccak.Sum256([]byte("MerkleProof(bytes32 root,bytes32 leaf,uint256 index,bytes32[] siblings)"))
+	siblingsHash := keccak.Sum256(siblings...)
+	var index [32]byte
+	binary.BigEndian.PutUint64(index[24:], 4)
+	structHash := keccak.Sum256(typeHash[:], proof.Root[:], proof.Leaf[:], index[:], siblingsHash[:])
+	if got, err := imt.EIP712ProofHash(proof); err != nil || got != structHash {
+		t.Fatalf("EIP712ProofHash = %x, %v, want %x", got, err, structHash)
+	}
+	separator := domain.Separator()
+	digest := keccak.Sum256([]byte{0x19, 0x01}, separator[:], structHash[:])
+	if got, err := imt.EIP712ProofDigest(domain, proof); err != nil || got != digest {
+		t.Fatalf("EIP712ProofDigest = %x, %v, want %x", got, err, digest)
+	}
+
+	typedData, err := imt.EIP712ProofTypedData(domain, proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := typedData["message"].(map[string]any)
+	if message["index"] != "4" || len(message["siblings"].([]string)) != 4 || typedData["domain"].(map[string]any)["chainId"] != "137" {
+		t.Fatalf("unexpected typed data %v", typedData)
+	}
+
+	signer := [20]byte{0xab}
+	// recover stands for the recovery of a signature, which is the digest it
+	// signs followed by the address of its signer.
+	recover := func(digest [32]byte, signature []byte) ([20]byte, error) {
+		if len(signature) != 52 || [32]byte(signature) != digest {
+			return [20]byte{}, errors.New("invalid signature")
+		}
+		return [20]byte(signature[32:]), nil
+	}
+	signature := append(digest[:], signer[:]...)
+
+	forged := *proof
+	forged.Leaf = [32]byte{9}
+	tests := []struct {
+		name      string
+		domain    imt.EIP712Domain
+		proof     *imt.MerkleProof[[32]byte]
+		signature []byte
+		signer    [20]byte
+		valid     bool
+	}{
+		{"valid", domain, proof, signature, signer, true},
+		{"other signer", domain, proof, signature, [20]byte{0xcd}, false},
+		{"other domain", imt.EIP712Domain{Name: "Proofs", Version: "2", ChainID: big.NewInt(137)}, proof, signature, signer, false},
+		{"invalid proof", domain, &forged, signature, signer, false},
+		{"invalid signature", domain, proof, signature[:40], signer, false},
+	}
+	for _, test := range tests {
+		err := imt.VerifySignedProof(test.domain, test.proof, imt.Keccak256Hash, test.signature, test.signer, recover)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: got error %v, want valid %v", test.name, err, test.valid)
+		}
+	}
+
+	if _, err := imt.EIP712ProofHash(nil); err == nil {
+		t.Fatal("hashed a nil proof")
+	}
+}
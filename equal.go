@@ -0,0 +1,38 @@
+package imt
+
+import "errors"
+
+// WithEqual sets the function deciding whether two nodes are equal, used
+// instead of == by the checks of the tree: the no-op check of Update, IndexOf,
+// the comparison of the computed root with the root of a proof by the
+// VerifyProof method, and the consistency checks of Validate, HealthCheck,
+// Repair, the debug checks, snapshot restoration and replication. It suits
+// node types with fields that don't affect their meaning, or with several
+// encodings of the same value, such as big integers. The type of the nodes of
+// the function must match the tree.
+func WithEqual[N comparable](equal func(a, b N) bool) Option {
+	return func(o *options) {
+		o.equal = equal
+	}
+}
+
+// equalFunc returns the equality function of the options, if any.
+func equalFunc[N comparable](opts options) (func(a, b N) bool, error) {
+	if opts.equal == nil {
+		return nil, nil
+	}
+	equal, ok := opts.equal.(func(a, b N) bool)
+	if !ok {
+		return nil, errors.New("the equality function does not compare the nodes of the tree")
+	}
+	return equal, nil
+}
+
+// equal reports whether two nodes are equal, with the equality function of
+// the tree if it has one.
+func (t *IMT[N]) equal(a, b N) bool {
+	if t.equalFunc != nil {
+		return t.equalFunc(a, b)
+	}
+	return a == b
+}
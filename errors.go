@@ -0,0 +1,76 @@
+package imt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The sentinel errors of the operations of the trees, which are wrapped in an
+// *Error by the methods of IMT.
+var (
+	ErrTreeFull      = errors.New("the tree is full")
+	ErrLeafNotFound  = errors.New("the leaf does not exist in this tree")
+	ErrLevelNotFound = errors.New("the level does not exist in this tree")
+	ErrNodeNotFound  = errors.New("the node does not exist in this tree")
+	ErrDuplicateLeaf = errors.New("the leaf already exists in this tree")
+)
+
+// The operations reported by an *Error, in addition to the ones of the
+// metrics and of the bulk paths.
+const (
+	OpCreateProof        = "create_proof"
+	OpCreateSubtreeProof = "create_subtree_proof"
+	OpCreateAppendProof  = "create_append_proof"
+	OpCreateSyncManifest = "create_sync_manifest"
+	OpSyncChunk          = "sync_chunk"
+	OpLevel              = "level"
+	OpSetLeafData        = "set_leaf_data"
+	OpSetExpiry          = "set_expiry"
+	OpRepair             = "repair"
+)
+
+// Error is an error of an operation of a tree, with the context needed to
+// report what failed and where without parsing its message. It wraps one of
+// the sentinel errors, such as ErrTreeFull, which errors.Is matches.
+type Error struct {
+	Op       string // The operation that failed, such as OpInsert.
+	Level    int    // The level of the node involved, 0 for a leaf, or -1 if none.
+	Index    int    // The index of the leaf or node involved, or -1 if none.
+	Size     int    // The number of leaves of the tree.
+	Capacity uint64 // The maximum number of leaves of the tree.
+	Err      error  // The sentinel error.
+}
+
+// Error returns the message of the sentinel error prefixed with the operation
+// and followed by the leaf or node involved and the size and capacity of the
+// tree.
+func (e *Error) Error() string {
+	var target string
+	switch {
+	case e.Level == 0:
+		target = fmt.Sprintf("leaf %d, ", e.Index)
+	case e.Level != -1 && e.Index != -1:
+		target = fmt.Sprintf("level %d, index %d, ", e.Level, e.Index)
+	case e.Level != -1:
+		target = fmt.Sprintf("level %d, ", e.Level)
+	}
+	return fmt.Sprintf("%s: %v (%ssize %d, capacity %d)", e.Op, e.Err, target, e.Size, e.Capacity)
+}
+
+// Unwrap returns the sentinel error.
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// opError returns an *Error of an operation of the tree on the node at the
+// given level and index, which are -1 if the operation involves no node.
+func (t *IMT[N]) opError(op string, level, index int, err error) error {
+	return &Error{
+		Op:       op,
+		Level:    level,
+		Index:    index,
+		Size:     t.nodes[0].Len(),
+		Capacity: t.Capacity(),
+		Err:      err,
+	}
+}
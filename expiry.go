@@ -0,0 +1,137 @@
+package imt
+
+import (
+	"container/heap"
+	"maps"
+	"slices"
+	"time"
+)
+
+// InsertWithExpiry inserts a leaf like Insert, and registers a deadline after
+// which SweepExpired deletes it.
+func (t *IMT[N]) InsertWithExpiry(leaf N, deadline time.Time) error {
+	index := t.NextIndex()
+	if err := t.Insert(leaf); err != nil {
+		return err
+	}
+	return t.SetExpiry(index, deadline)
+}
+
+// SetExpiry registers a deadline after which SweepExpired deletes a leaf,
+// replacing its previous deadline if any. A zero deadline removes it.
+func (t *IMT[N]) SetExpiry(index int, deadline time.Time) error {
+	if index < 0 || index >= t.nodes[0].Len() {
+		return t.opError(OpSetExpiry, 0, index, ErrLeafNotFound)
+	}
+	if deadline.IsZero() {
+		t.expiries.remove(index)
+		return nil
+	}
+	t.expiries.set(index, deadline)
+	return nil
+}
+
+// Expiry returns the deadline of a leaf, and whether it has one.
+func (t *IMT[N]) Expiry(index int) (time.Time, bool) {
+	i, ok := t.expiries.positions[index]
+	if !ok {
+		return time.Time{}, false
+	}
+	return t.expiries.entries[i].deadline, true
+}
+
+// SweepExpired deletes the leaves whose deadline is not after now, and
+// returns their indices in the order of their deadlines. Its cost is
+// proportional to the number of expired leaves, not to the size of the tree.
+func (t *IMT[N]) SweepExpired(now time.Time) ([]int, error) {
+	var cleared []int
+	for t.expiries.Len() > 0 && !t.expiries.entries[0].deadline.After(now) {
+		// Deleting the leaf removes its deadline.
+		index := t.expiries.entries[0].index
+		if err := t.Delete(index); err != nil {
+			return cleared, err
+		}
+		cleared = append(cleared, index)
+	}
+	return cleared, nil
+}
+
+// expiry is a deadline registered for a leaf.
+type expiry struct {
+	deadline time.Time
+	index    int
+}
+
+// expiries is a min-heap of the deadlines of the leaves of a tree, with the
+// position in the heap of the deadline of each leaf, so that replacing or
+// removing a deadline updates the heap in place.
+type expiries struct {
+	entries   []expiry
+	positions map[int]int
+}
+
+// set sets the deadline of a leaf.
+func (e *expiries) set(index int, deadline time.Time) {
+	if i, ok := e.positions[index]; ok {
+		e.entries[i].deadline = deadline
+		heap.Fix(e, i)
+		return
+	}
+	if e.positions == nil {
+		e.positions = make(map[int]int)
+	}
+	heap.Push(e, expiry{deadline: deadline, index: index})
+}
+
+// remove removes the deadline of a leaf, if it has one.
+func (e *expiries) remove(index int) {
+	if i, ok := e.positions[index]; ok {
+		heap.Remove(e, i)
+	}
+}
+
+// remap moves the deadlines of the leaves moved by Compact, and removes the
+// ones of the leaves it removed.
+func (e *expiries) remap(remap map[int]int) {
+	entries := e.entries[:0]
+	for _, entry := range e.entries {
+		if index, ok := remap[entry.index]; ok {
+			entries = append(entries, expiry{deadline: entry.deadline, index: index})
+		}
+	}
+	e.entries = entries
+	clear(e.positions)
+	for i, entry := range e.entries {
+		e.positions[entry.index] = i
+	}
+	heap.Init(e)
+}
+
+func (e *expiries) clone() expiries {
+	return expiries{entries: slices.Clone(e.entries), positions: maps.Clone(e.positions)}
+}
+
+func (e *expiries) Len() int { return len(e.entries) }
+
+func (e *expiries) Less(i, j int) bool {
+	return e.entries[i].deadline.Before(e.entries[j].deadline)
+}
+
+func (e *expiries) Swap(i, j int) {
+	e.entries[i], e.entries[j] = e.entries[j], e.entries[i]
+	e.positions[e.entries[i].index] = i
+	e.positions[e.entries[j].index] = j
+}
+
+func (e *expiries) Push(x any) {
+	entry := x.(expiry)
+	e.positions[entry.index] = len(e.entries)
+	e.entries = append(e.entries, entry)
+}
+
+func (e *expiries) Pop() any {
+	entry := e.entries[len(e.entries)-1]
+	e.entries = e.entries[:len(e.entries)-1]
+	delete(e.positions, entry.index)
+	return entry
+}
@@ -0,0 +1,87 @@
+package imt_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestSetExpiryReplacesDeadlines(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1000, 0)
+	for i := range 1000 {
+		if err := tree.SetExpiry(1, start.Add(time.Duration(i%7)*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		if i%3 == 1 {
+			if err := tree.SetExpiry(1, time.Time{}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if tree.ExpiryHeapLen() != 1 {
+		t.Fatalf("%d deadlines in the heap of a single leaf", tree.ExpiryHeapLen())
+	}
+	if deadline, ok := tree.Expiry(1); !ok || !deadline.Equal(start.Add(5*time.Second)) {
+		t.Fatalf("Expiry = %v, %v", deadline, ok)
+	}
+	if err := tree.SetExpiry(1, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tree.Expiry(1); ok || tree.ExpiryHeapLen() != 0 {
+		t.Fatal("the cleared deadline was kept")
+	}
+}
+
+func TestSweepExpired(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Unix(1000, 0)
+	deadlines := map[int]time.Duration{0: 5, 1: 1, 2: 3, 4: 2}
+	for index, after := range deadlines {
+		if err := tree.SetExpiry(index, start.Add(after*time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Moving a deadline earlier or later reorders the sweep.
+	if err := tree.SetExpiry(0, start); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.SetExpiry(1, start.Add(4*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	cleared, err := tree.SweepExpired(start.Add(3 * time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cleared, []int{0, 4, 2}) {
+		t.Fatalf("SweepExpired cleared %v, want [0 4 2]", cleared)
+	}
+	if !slices.Equal(tree.Leaves(), []uint64{0, 2, 0, 4, 0}) || tree.ExpiryHeapLen() != 1 {
+		t.Fatalf("Leaves = %v with %d deadlines", tree.Leaves(), tree.ExpiryHeapLen())
+	}
+
+	// Compact moves the remaining deadline with its leaf.
+	if _, err := tree.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if deadline, ok := tree.Expiry(0); !ok || !deadline.Equal(start.Add(4*time.Second)) {
+		t.Fatalf("Expiry of the moved leaf = %v, %v", deadline, ok)
+	}
+	cleared, err = tree.SweepExpired(start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cleared, []int{0}) || tree.ExpiryHeapLen() != 0 {
+		t.Fatalf("SweepExpired cleared %v, leaving %d deadlines", cleared, tree.ExpiryHeapLen())
+	}
+}
@@ -0,0 +1,12 @@
+package imt
+
+// ExpiryHeapLen returns the number of deadlines in the heap of the tree.
+func (t *IMT[N]) ExpiryHeapLen() int {
+	return t.expiries.Len()
+}
+
+// CorruptNode overwrites a stored node, bypassing the hashing of its
+// ancestors.
+func (t *IMT[N]) CorruptNode(level, index int, node N) {
+	t.nodes[level].Set(index, node)
+}
@@ -0,0 +1,294 @@
+package imt
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"io"
+	"iter"
+	"os"
+	"slices"
+)
+
+// maxLeafSize bounds the size of an encoded leaf read from a stream.
+const maxLeafSize = 1 << 20
+
+// RootBuilder computes the root of a tree from its leaves, added in order,
+// without building the tree: it only holds, for each level, the children of
+// the rightmost node that are already complete, which is at most
+// depth*(arity-1) nodes. Together with SortLeaves, it lets machines with
+// modest memory compute the root of trees whose leaves don't fit in memory.
+type RootBuilder[N comparable] struct {
+	hash   HashFunction[N]
+	depth  int
+	arity  int
+	zeroes []N
+
+	// The complete children of the rightmost node of each level, and the root
+	// once the tree is full.
+	frontier [][]N
+	size     uint64
+}
+
+// NewRootBuilder returns a RootBuilder for a tree with the given parameters,
+// which are the same as the ones of New.
+func NewRootBuilder[N comparable](hash HashFunction[N], depth int, zeroValue N, arity int) (*RootBuilder[N], error) {
+	t, err := New(hash, depth, zeroValue, arity, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &RootBuilder[N]{
+		hash:     hash,
+		depth:    depth,
+		arity:    arity,
+		zeroes:   append(t.Zeroes(), t.Root()),
+		frontier: make([][]N, depth+1),
+	}, nil
+}
+
+// Add appends a leaf, hashing every subtree that it completes.
+func (b *RootBuilder[N]) Add(leaf N) error {
+	if b.size >= capacity(b.arity, b.depth) {
+		return ErrTreeFull
+	}
+	b.size++
+
+	node := leaf
+	for level := 0; level < b.depth; level++ {
+		b.frontier[level] = append(b.frontier[level], node)
+		if len(b.frontier[level]) < b.arity {
+			return nil
+		}
+		node = b.hash(b.frontier[level])
+		b.frontier[level] = b.frontier[level][:0]
+	}
+	b.frontier[b.depth] = []N{node}
+
+	return nil
+}
+
+// AddFrom appends the leaves read from r, as written by WriteLeaves.
+func (b *RootBuilder[N]) AddFrom(r io.Reader, codec NodeCodec[N]) error {
+	for leaf, err := range ReadLeaves(r, codec) {
+		if err != nil {
+			return err
+		}
+		if err := b.Add(leaf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Size returns the number of leaves added so far.
+func (b *RootBuilder[N]) Size() uint64 {
+	return b.size
+}
+
+// Root returns the root of the tree made of the leaves added so far, which is
+// the root of a tree created by New with the same leaves.
+func (b *RootBuilder[N]) Root() N {
+	if len(b.frontier[b.depth]) == 1 {
+		return b.frontier[b.depth][0]
+	}
+
+	// Close the rightmost node of each level, padding it with zero values.
+	var node N
+	carry := false
+	for level := 0; level < b.depth; level++ {
+		if len(b.frontier[level]) == 0 && !carry {
+			continue
+		}
+		children := make([]N, 0, b.arity)
+		children = append(children, b.frontier[level]...)
+		if carry {
+			children = append(children, node)
+		}
+		for len(children) < b.arity {
+			children = append(children, b.zeroes[level])
+		}
+		node = b.hash(children)
+		carry = true
+	}
+	if !carry {
+		return b.zeroes[b.depth]
+	}
+	return node
+}
+
+// WriteLeaves writes leaves to w, each encoded with the codec and prefixed
+// with its length as an unsigned varint.
+func WriteLeaves[N comparable](w io.Writer, codec NodeCodec[N], leaves iter.Seq[N]) error {
+	bw := bufio.NewWriter(w)
+	for leaf := range leaves {
+		b, err := codec.Encode(leaf)
+		if err != nil {
+			return err
+		}
+		if err := writeRecord(bw, b); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ReadLeaves returns an iterator over the leaves written by WriteLeaves. The
+// iteration stops at the end of the stream or after yielding an error.
+func ReadLeaves[N comparable](r io.Reader, codec NodeCodec[N]) iter.Seq2[N, error] {
+	return func(yield func(N, error) bool) {
+		var zero N
+		br := bufio.NewReader(r)
+		for {
+			b, err := readRecord(br, maxLeafSize)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			leaf, err := codec.Decode(b)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(leaf, nil) {
+				return
+			}
+		}
+	}
+}
+
+// SortLeaves sorts the leaves read from r, as written by WriteLeaves, and
+// writes them to w in the same format, holding at most chunkSize leaves in
+// memory. It is an external merge sort: the leaves are sorted in chunks that
+// are written to temporary files in dir, or the default directory for
+// temporary files if dir is empty, and the chunks are then merged. If dedupe
+// is true, only the first of the leaves that compare equal is kept. The
+// temporary files are removed before returning.
+func SortLeaves[N comparable](r io.Reader, w io.Writer, codec NodeCodec[N], compare func(a, b N) int, chunkSize int, dedupe bool, dir string) error {
+	if chunkSize <= 0 {
+		return errors.New("chunk size must be positive")
+	}
+
+	var runs []*os.File
+	defer func() {
+		for _, run := range runs {
+			run.Close()
+			os.Remove(run.Name())
+		}
+	}()
+
+	chunk := make([]N, 0, chunkSize)
+	flush := func() error {
+		slices.SortStableFunc(chunk, compare)
+		run, err := os.CreateTemp(dir, "imt-leaves-*")
+		if err != nil {
+			return err
+		}
+		runs = append(runs, run)
+		if err := WriteLeaves(run, codec, slices.Values(chunk)); err != nil {
+			return err
+		}
+		if _, err := run.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		chunk = chunk[:0]
+		return nil
+	}
+
+	for leaf, err := range ReadLeaves(r, codec) {
+		if err != nil {
+			return err
+		}
+		chunk = append(chunk, leaf)
+		if len(chunk) == chunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(chunk) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	// Merge the runs, taking the smallest head of all the runs each time.
+	merge := &runHeap[N]{compare: compare}
+	for i, run := range runs {
+		next, stop := iter.Pull2(ReadLeaves(run, codec))
+		defer stop()
+		if err := merge.pull(i, next); err != nil {
+			return err
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	var last N
+	written := false
+	for merge.Len() > 0 {
+		head := merge.heads[0]
+		if !dedupe || !written || compare(head.leaf, last) != 0 {
+			b, err := codec.Encode(head.leaf)
+			if err != nil {
+				return err
+			}
+			if err := writeRecord(bw, b); err != nil {
+				return err
+			}
+			last, written = head.leaf, true
+		}
+		heap.Pop(merge)
+		if err := merge.pull(head.run, head.next); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// runHead is the smallest leaf of a sorted run that has not been merged yet.
+type runHead[N comparable] struct {
+	leaf N
+	run  int
+	next func() (N, error, bool)
+}
+
+// runHeap is a min-heap of the heads of the runs merged by SortLeaves. Heads
+// of equal leaves are ordered by run, which keeps the merge stable.
+type runHeap[N comparable] struct {
+	heads   []runHead[N]
+	compare func(a, b N) int
+}
+
+// pull pushes the next leaf of a run, if any.
+func (h *runHeap[N]) pull(run int, next func() (N, error, bool)) error {
+	leaf, err, ok := next()
+	if !ok {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	heap.Push(h, runHead[N]{leaf: leaf, run: run, next: next})
+	return nil
+}
+
+func (h *runHeap[N]) Len() int { return len(h.heads) }
+
+func (h *runHeap[N]) Less(i, j int) bool {
+	if c := h.compare(h.heads[i].leaf, h.heads[j].leaf); c != 0 {
+		return c < 0
+	}
+	return h.heads[i].run < h.heads[j].run
+}
+
+func (h *runHeap[N]) Swap(i, j int) { h.heads[i], h.heads[j] = h.heads[j], h.heads[i] }
+
+func (h *runHeap[N]) Push(x any) { h.heads = append(h.heads, x.(runHead[N])) }
+
+func (h *runHeap[N]) Pop() any {
+	head := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return head
+}
@@ -0,0 +1,88 @@
+package imt
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// LeafFormat is the format of a file of leaves read by LoadLeaves.
+type LeafFormat string
+
+// The formats supported by LoadLeaves.
+const (
+	// LeafFormatJSON is a JSON array whose elements are strings, which are
+	// decoded without their quotes, or numbers, which are decoded as written.
+	LeafFormatJSON LeafFormat = "json"
+	// LeafFormatCSV is a CSV file where the first field of every record is a
+	// leaf.
+	LeafFormatCSV LeafFormat = "csv"
+	// LeafFormatCSVHeader is LeafFormatCSV with a header record, which is
+	// skipped.
+	LeafFormatCSVHeader LeafFormat = "csv-header"
+)
+
+// LoadLeaves reads a list of leaves in the given format, and decodes each of
+// them with decode, e.g. hex decoding for byte array nodes.
+func LoadLeaves[N comparable](r io.Reader, format LeafFormat, decode func(string) (N, error)) ([]N, error) {
+	var values []string
+	switch format {
+	case LeafFormatJSON:
+		var elements []json.RawMessage
+		if err := json.NewDecoder(r).Decode(&elements); err != nil {
+			return nil, err
+		}
+		values = make([]string, len(elements))
+		for i, element := range elements {
+			if len(element) > 0 && element[0] == '"' {
+				if err := json.Unmarshal(element, &values[i]); err != nil {
+					return nil, fmt.Errorf("leaf %d: %w", i, err)
+				}
+			} else {
+				values[i] = string(bytes.TrimSpace(element))
+			}
+		}
+	case LeafFormatCSV, LeafFormatCSVHeader:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		if format == LeafFormatCSVHeader {
+			if len(records) == 0 {
+				return nil, errors.New("the header record is missing")
+			}
+			records = records[1:]
+		}
+		values = make([]string, len(records))
+		for i, record := range records {
+			values[i] = record[0]
+		}
+	default:
+		return nil, fmt.Errorf("unknown leaf format %q", format)
+	}
+
+	leaves := make([]N, len(values))
+	for i, value := range values {
+		leaf, err := decode(value)
+		if err != nil {
+			return nil, fmt.Errorf("leaf %d: %w", i, err)
+		}
+		leaves[i] = leaf
+	}
+	return leaves, nil
+}
+
+// NewFromLeafFile creates a tree, like New, with the leaves read by
+// LoadLeaves.
+func NewFromLeafFile[N comparable](hash HashFunction[N], depth int, zeroValue N, arity int, r io.Reader, format LeafFormat, decode func(string) (N, error), opts ...Option) (*IMT[N], error) {
+	leaves, err := LoadLeaves(r, format, decode)
+	if err != nil {
+		return nil, err
+	}
+	return New(hash, depth, zeroValue, arity, leaves, opts...)
+}
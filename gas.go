@@ -0,0 +1,27 @@
+package imt
+
+// GasMeter is notified of the work performed by the tree, so that a Cosmos SDK
+// module embedding it can charge gas proportional to the actual work. Every
+// call is deterministic: it only depends on the sequence of operations applied
+// to the tree, never on timing or memory layout. Implementations may panic to
+// abort an operation, as the SDK gas meter does when it runs out of gas.
+type GasMeter interface {
+	// ConsumeHash is called before each invocation of the hash function with
+	// the number of children being hashed.
+	ConsumeHash(children int)
+
+	// ConsumeNodeRead is called for each stored node that is read. Reading the
+	// zero value of a missing node is free, as it is never stored.
+	ConsumeNodeRead()
+
+	// ConsumeNodeWrite is called for each node that is written.
+	ConsumeNodeWrite()
+}
+
+// SetGasMeter sets the meter notified of the work performed by subsequent
+// operations. Since SDK gas meters are scoped to a transaction, the meter is
+// expected to be replaced before each transaction. A nil meter disables
+// metering.
+func (t *IMT[N]) SetGasMeter(meter GasMeter) {
+	t.gasMeter = meter
+}
@@ -0,0 +1,28 @@
+package imt
+
+// opHashCalls is the number of hash calls of an operation.
+type opHashCalls struct {
+	op    string
+	calls uint64
+}
+
+// hash computes the hash of a list of children with the hash function of the
+// tree, or its memo if the tree has one.
+func (t *IMT[N]) hash(children []N) N {
+	if t.memo != nil {
+		return t.memo.sum(children)
+	}
+	return t.callHash(children)
+}
+
+// callHash calls the hash function of the tree and counts the call.
+func (t *IMT[N]) callHash(children []N) N {
+	t.hashCalls.Add(1)
+	return t.hashFunc(children)
+}
+
+// countHashes records the number of hash calls of an operation, which
+// started when the tree had made the given number of calls.
+func (t *IMT[N]) countHashes(op string, calls uint64) {
+	t.lastOp.Store(&opHashCalls{op: op, calls: t.hashCalls.Load() - calls})
+}
@@ -0,0 +1,170 @@
+// Package hashers implements hash functions over field elements, for trees
+// whose roots are verified by programs of proof systems rather than by
+// contracts hashing bytes.
+//
+// Felt is an element of the field of StarkNet and Cairo, and PedersenHash is
+// the Pedersen hash of StarkNet over the STARK curve, the hash of the Merkle
+// trees of Cairo's common library, so binary trees of felts hashed with it
+// compute the roots that Cairo programs verify.
+package hashers
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/noble-assets/imt"
+)
+
+// Felt is an element of the field of StarkNet and Cairo, the integers modulo
+// the prime P = 2^251 + 17·2^192 + 1, as 32 big-endian bytes. Its value must
+// be less than P.
+type Felt [32]byte
+
+// feltPrime is the prime P of the field of felts.
+var feltPrime, _ = new(big.Int).SetString("800000000000011000000000000000000000000000000000000000000000001", 16)
+
+// NewFelt returns the felt of x, which must be at least 0 and less than P.
+func NewFelt(x *big.Int) (Felt, error) {
+	if x.Sign() < 0 || x.Cmp(feltPrime) >= 0 {
+		return Felt{}, errors.New("the value is not a field element")
+	}
+	var f Felt
+	x.FillBytes(f[:])
+	return f, nil
+}
+
+// Big returns the value of the felt.
+func (f Felt) Big() *big.Int {
+	return new(big.Int).SetBytes(f[:])
+}
+
+// String returns the value of the felt in hexadecimal, prefixed with 0x.
+func (f Felt) String() string {
+	return fmt.Sprintf("%#x", f.Big())
+}
+
+// The STARK curve y^2 = x^3 + x + beta over the field of felts, and the
+// points of the Pedersen hash of StarkNet: the shift point and the points
+// multiplied by the low 248 bits and the high 4 bits of each input.
+var (
+	curveBeta, _   = new(big.Int).SetString("6f21413efbe40de150e596d72f7a8c5609ad26c15c915c1f4cdfcb99cee9e89", 16)
+	pedersenPoints = [5][2]string{
+		{"49ee3eba8c1600700ee1b87eb599f16716b0b1022947733551fde4050ca6804", "3ca0cfe4b3bc6ddf346d49d06ea0ed34e621062c0e056c1d0405d266e10268a"},
+		{"234287dcbaffe7f969c748655fca9e58fa8120b6d56eb0c1080d17957ebe47b", "3b056f100f96fb21e889527d41f4e39940135dd7a6c94cc6ed0268ee89e5615"},
+		{"4fa56f376c83db33f9dab2656558f3399099ec1de5e3018b7a6932dba8aa378", "3fa0984c931c9e38113e0c0e47e4401562761f92a7a23b45168f4e80ff5b54d"},
+		{"4ba4cc166be8dec764910f75b45f74b40c690c74709e90f3aa372f0bd2d6997", "40301cf5c1751f4b971e46c4ede85fcac5c59a5ce5ae7c48151f27b24b219c"},
+		{"54302dcb0e6cc1c6e44cca8f61a63bb2ca65048d53fb325d36ff12c49a58202", "1b77b3e37d13504b348046268d8ae25ce98ad783c25561a879dcc77e99c2426"},
+	}
+)
+
+// lowBits is the number of low bits of an input of the Pedersen hash
+// multiplying its first point.
+const lowBits = 248
+
+// point is an affine point of the STARK curve. The Pedersen hash never
+// reaches the point at infinity.
+type point struct {
+	x, y *big.Int
+}
+
+// pedersenTables holds the doublings of the points of the Pedersen hash
+// multiplied by the inputs: for each input, 2^i times its first point for
+// the low bits, followed by 2^i times its second point for the high bits.
+var pedersenTables = sync.OnceValue(func() [2][]point {
+	var tables [2][]point
+	for input := range tables {
+		for _, k := range []int{1 + 2*input, 2 + 2*input} {
+			p := parsePoint(pedersenPoints[k])
+			bits := lowBits
+			if k%2 == 0 {
+				bits = 252 - lowBits
+			}
+			for range bits {
+				tables[input] = append(tables[input], p)
+				p = p.add(p)
+			}
+		}
+	}
+	return tables
+})
+
+// parsePoint parses the hexadecimal coordinates of a point.
+func parsePoint(coordinates [2]string) point {
+	x, _ := new(big.Int).SetString(coordinates[0], 16)
+	y, _ := new(big.Int).SetString(coordinates[1], 16)
+	return point{x, y}
+}
+
+// add returns the sum of two points, which must not be opposite.
+func (p point) add(q point) point {
+	slope := new(big.Int)
+	if p.x.Cmp(q.x) == 0 {
+		// The tangent, of slope (3x^2 + 1) / 2y.
+		slope.Mul(p.x, p.x).Mul(slope, big.NewInt(3)).Add(slope, big.NewInt(1))
+		denominator := new(big.Int).Lsh(p.y, 1)
+		slope.Mul(slope, denominator.ModInverse(denominator, feltPrime))
+	} else {
+		slope.Sub(q.y, p.y)
+		denominator := new(big.Int).Sub(q.x, p.x)
+		denominator.Mod(denominator, feltPrime)
+		slope.Mul(slope, denominator.ModInverse(denominator, feltPrime))
+	}
+	slope.Mod(slope, feltPrime)
+
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, p.x).Sub(x, q.x).Mod(x, feltPrime)
+	y := new(big.Int).Sub(p.x, x)
+	y.Mul(y, slope).Sub(y, p.y).Mod(y, feltPrime)
+	return point{x, y}
+}
+
+// Pedersen returns the Pedersen hash of StarkNet of two felts, the x
+// coordinate of the shift point plus a times the points of the first input
+// and b times the points of the second input, where the low 248 bits and the
+// high 4 bits of each input multiply distinct points. It panics if a or b is
+// not less than P.
+func Pedersen(a, b Felt) Felt {
+	tables := pedersenTables()
+	sum := parsePoint(pedersenPoints[0])
+	for input, f := range [2]Felt{a, b} {
+		x := f.Big()
+		if x.Cmp(feltPrime) >= 0 {
+			panic("hashers: the input of the Pedersen hash is not a field element")
+		}
+		for i := range x.BitLen() {
+			if x.Bit(i) == 1 {
+				sum = sum.add(tables[input][i])
+			}
+		}
+	}
+	var f Felt
+	sum.x.FillBytes(f[:])
+	return f
+}
+
+// PedersenHash is the hash function of trees of felts hashed with the
+// Pedersen hash. Two children are hashed with Pedersen, like the nodes of
+// the Merkle trees of Cairo, and other numbers of children are hashed like
+// arrays by StarkNet: the Pedersen hashes of the children are chained from
+// 0, and the result is hashed with the number of children.
+func PedersenHash(children []Felt) Felt {
+	if len(children) == 2 {
+		return Pedersen(children[0], children[1])
+	}
+	var hash Felt
+	for _, child := range children {
+		hash = Pedersen(hash, child)
+	}
+	var count Felt
+	new(big.Int).SetInt64(int64(len(children))).FillBytes(count[:])
+	return Pedersen(hash, count)
+}
+
+// NewPedersenBinary returns an empty binary tree of felts hashed with
+// PedersenHash, whose zero value is 0 and whose hash identifier is
+// "pedersen", which computes the roots of the Merkle trees of Cairo.
+func NewPedersenBinary(depth int, opts ...imt.Option) (*imt.IMT[Felt], error) {
+	return imt.New(PedersenHash, depth, Felt{}, 2, nil, append([]imt.Option{imt.WithHashID("pedersen")}, opts...)...)
+}
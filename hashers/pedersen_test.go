@@ -0,0 +1,107 @@
+package hashers_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/noble-assets/imt/hashers"
+)
+
+func felt(t *testing.T, s string) hashers.Felt {
+	t.Helper()
+	x, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		t.Fatalf("invalid number %s", s)
+	}
+	f, err := hashers.NewFelt(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestPedersen(t *testing.T) {
+	// The vectors of the tests of the Pedersen hash of cairo-lang and
+	// starknet-crypto.
+	tests := []struct {
+		a, b, want string
+	}{
+		{"0", "0", "0x49ee3eba8c1600700ee1b87eb599f16716b0b1022947733551fde4050ca6804"},
+		{
+			"0x3d937c035c878245caf64531a5756109c53068da139362728feb561405371cb",
+			"0x208a0a10250e382e1e4bbe2880906c2791bf6275695e02fbbc6aeff9cd8b31a",
+			"0x30e480bed5fe53fa909cc0f8c4d99b8f9f2c016be4c41e13a4848797979c662",
+		},
+		{
+			"0x58f580910a6ca59b28927c08fe6c43e2e303ca384badc365795fc645d479d45",
+			"0x78734f65a067be9bdb39de18434d71e79f7b6466a4b66bbd979ab9e7515fe0b",
+			"0x68cc0b76cddd1dd4ed2301ada9b7c872b23875d5ff837b3a87993e0d9996b87",
+		},
+	}
+	for _, test := range tests {
+		if got := hashers.Pedersen(felt(t, test.a), felt(t, test.b)); got != felt(t, test.want) {
+			t.Errorf("Pedersen(%s, %s) = %s, want %s", test.a, test.b, got, test.want)
+		}
+	}
+
+	// StarkNet hashes arrays by chaining the hashes of their elements.
+	a, b, c := felt(t, "1"), felt(t, "2"), felt(t, "3")
+	want := hashers.Pedersen(hashers.Pedersen(hashers.Pedersen(hashers.Pedersen(hashers.Felt{}, a), b), c), c)
+	if got := hashers.PedersenHash([]hashers.Felt{a, b, c}); got != want {
+		t.Fatalf("PedersenHash of 3 children = %s, want %s", got, want)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Pedersen hashed a value that is not a field element")
+			}
+		}()
+		var huge hashers.Felt
+		for i := range huge {
+			huge[i] = 0xff
+		}
+		hashers.Pedersen(huge, a)
+	}()
+}
+
+func TestNewFelt(t *testing.T) {
+	prime, _ := new(big.Int).SetString("800000000000011000000000000000000000000000000000000000000000001", 16)
+	for _, x := range []*big.Int{big.NewInt(-1), prime} {
+		if _, err := hashers.NewFelt(x); err == nil {
+			t.Fatalf("NewFelt(%v) accepted a value outside the field", x)
+		}
+	}
+	last := new(big.Int).Sub(prime, big.NewInt(1))
+	f, err := hashers.NewFelt(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Big().Cmp(last) != 0 || f.String() != "0x800000000000011000000000000000000000000000000000000000000000000" {
+		t.Fatalf("the felt of P-1 is %s", f)
+	}
+}
+
+func TestPedersenBinary(t *testing.T) {
+	leaves := []hashers.Felt{felt(t, "1"), felt(t, "2"), felt(t, "3")}
+	tree, err := hashers.NewPedersenBinary(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.InsertMany(leaves); err != nil {
+		t.Fatal(err)
+	}
+	want := hashers.Pedersen(hashers.Pedersen(leaves[0], leaves[1]), hashers.Pedersen(leaves[2], hashers.Felt{}))
+	if tree.Root() != want {
+		t.Fatalf("Root = %s, want %s", tree.Root(), want)
+	}
+	for index := range leaves {
+		proof, err := tree.CreateProof(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tree.VerifyProof(proof) {
+			t.Fatalf("the proof of leaf %d was rejected", index)
+		}
+	}
+}
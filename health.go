@@ -0,0 +1,54 @@
+package imt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+)
+
+// healthCheckInterval is the number of nodes checked by HealthCheck between
+// two checks of its context.
+const healthCheckInterval = 1024
+
+// HealthCheck checks that a random sample of the internal nodes of the tree
+// are the hash of their stored children, which re-derives them from the
+// leaves when the nodes below them are sound. Each internal node is checked
+// with probability sampleRate, except the root, which is always checked, so a
+// rate of 1 checks the whole tree against its leaves. It also checks that the
+// levels have the lengths implied by the number of leaves. It is meant for the
+// readiness probes of services serving proofs, and returns the error of the
+// context if it is done before the check completes. Like Validate, it does not
+// notify the gas meter, and it must not run concurrently with writes.
+func (t *IMT[N]) HealthCheck(ctx context.Context, sampleRate float64) error {
+	if !(sampleRate > 0 && sampleRate <= 1) {
+		return errors.New("the sample rate must be in (0, 1]")
+	}
+	if err := t.validateLevels(); err != nil {
+		t.log(slog.LevelError, "imt: integrity check failed", slog.String("op", "health_check"), slog.Any("error", err))
+		return err
+	}
+
+	checked := 0
+	for level := 0; level < t.depth; level++ {
+		for index := range t.nodes[level+1].Len() {
+			if level+1 < t.depth && sampleRate < 1 && rand.Float64() >= sampleRate {
+				continue
+			}
+			if checked%healthCheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			checked++
+			if !t.equal(t.nodes[level+1].Get(index), t.hash(t.storedChildren(level, index))) {
+				err := fmt.Errorf("node %d of level %d is not the hash of its children", index, level+1)
+				t.log(slog.LevelError, "imt: integrity check failed", slog.String("op", "health_check"), slog.Any("error", err))
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,43 @@
+package imt
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+// Checkpoint returns the checkpoint of the tree with the semantics of
+// Hyperlane's MerkleTreeHook: its root and its number of inserted leaves, as
+// returned by `root()` and `count()`. `latestCheckpoint()` returns the index
+// of the last inserted leaf, count-1, instead of the count, and reverts on an
+// empty tree; that index is the one signed by validators and taken by
+// CheckpointDigest. It fails if the tree holds more leaves than a uint32
+// counts, which MerkleTreeHook never does.
+func (t *IMT[N]) Checkpoint() (root N, count uint32, err error) {
+	size := t.nodes[0].Len()
+	if uint64(size) > math.MaxUint32 {
+		return root, 0, errors.New("the tree contains more than 2^32-1 leaves")
+	}
+	return t.Root(), uint32(size), nil
+}
+
+// CheckpointDomainHash returns the domain hash of Hyperlane checkpoints, as
+// computed by `CheckpointLib.domainHash`.
+func CheckpointDomainHash(origin uint32, merkleTreeHook [32]byte) [32]byte {
+	return keccak.Sum256(binary.BigEndian.AppendUint32(nil, origin), merkleTreeHook[:], []byte("HYPERLANE"))
+}
+
+// CheckpointDigest returns the digest signed by Hyperlane validators for a
+// checkpoint, as computed by `CheckpointLib.digest`: the Ethereum signed
+// message hash of the domain hash, the root, the index and the message ID.
+// Like `CheckpointLib.digest`, it takes the checkpoint along with the origin
+// domain and the hook, since they are all signed. The merkleTreeHook is the
+// address of the origin MerkleTreeHook (formerly the Mailbox), left-padded to
+// 32 bytes, and the index is the count of the checkpoint minus one.
+func CheckpointDigest(origin uint32, merkleTreeHook [32]byte, root [32]byte, index uint32, messageID [32]byte) [32]byte {
+	domainHash := CheckpointDomainHash(origin, merkleTreeHook)
+	hash := keccak.Sum256(domainHash[:], root[:], binary.BigEndian.AppendUint32(nil, index), messageID[:])
+	return keccak.Sum256([]byte("\x19Ethereum Signed Message:\n32"), hash[:])
+}
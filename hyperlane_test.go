@@ -0,0 +1,88 @@
+package imt_test
+
+import (
+	"encoding/binary"
+	"math/bits"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+func TestHyperlaneCheckpoint(t *testing.T) {
+	// MerkleTreeHook trees have a depth of 32, whose capacity only fits in
+	// 64-bit ints.
+	depth := 32
+	if bits.UintSize != 64 {
+		depth = 16
+	}
+	tree, err := imt.NewKeccak256Binary(depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, count, err := tree.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 || root != tree.Root() {
+		t.Fatalf("checkpoint of an empty tree: %x, %d", root, count)
+	}
+	if depth == 32 && root != decodeChunk(t, "27ae5ba08d7291c96c8cbddcc148bf48a6d68c7974b94356f53754ef6171d757") {
+		t.Fatalf("the empty root %x is not Z_32 of Hyperlane's MerkleLib", root)
+	}
+
+	for i := range 3 {
+		if err := tree.Insert(keccak.Sum256([]byte{byte(i)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if root, count, err = tree.Checkpoint(); err != nil || count != 3 || root != tree.Root() {
+		t.Fatalf("checkpoint of 3 leaves: %x, %d, %v", root, count, err)
+	}
+}
+
+func TestCheckpointDigest(t *testing.T) {
+	var hook, root, messageID [32]byte
+	copy(hook[12:], decodeHex("48e6c30b97748d1e2e03bf3e9fbe3890ca5f8cca"))
+	root = decodeChunk(t, "27ae5ba08d7291c96c8cbddcc148bf48a6d68c7974b94356f53754ef6171d757")
+	messageID[31] = 7
+	origin, index := uint32(1), uint32(41)
+
+	// CheckpointLib hashes abi.encodePacked(origin, merkleTreeHook,
+	// "HYPERLANE") into the domain hash, and signs the Ethereum signed
+	// message hash of abi.encodePacked(domainHash, root, index, messageID),
+	// where the origin and the index take 4 bytes.
+	domainPreimage := decodeHex("00000001" +
+		"00000000000000000000000048e6c30b97748d1e2e03bf3e9fbe3890ca5f8cca" +
+		"48595045524c414e45")
+	domainHash := keccak.Sum256(domainPreimage)
+	if got := imt.CheckpointDomainHash(origin, hook); got != domainHash {
+		t.Fatalf("CheckpointDomainHash = %x, want %x", got, domainHash)
+	}
+	preimage := append(domainHash[:], root[:]...)
+	preimage = binary.BigEndian.AppendUint32(preimage, index)
+	preimage = append(preimage, messageID[:]...)
+	if len(preimage) != 100 {
+		t.Fatalf("the checkpoint preimage has %d bytes", len(preimage))
+	}
+	hash := keccak.Sum256(preimage)
+	want := keccak.Sum256([]byte("\x19Ethereum Signed Message:\n32"), hash[:])
+	if got := imt.CheckpointDigest(origin, hook, root, index, messageID); got != want {
+		t.Fatalf("CheckpointDigest = %x, want %x", got, want)
+	}
+
+	// Every signed field changes the digest.
+	digests := map[[32]byte]bool{want: true}
+	for _, digest := range [][32]byte{
+		imt.CheckpointDigest(origin+1, hook, root, index, messageID),
+		imt.CheckpointDigest(origin, root, root, index, messageID),
+		imt.CheckpointDigest(origin, hook, hook, index, messageID),
+		imt.CheckpointDigest(origin, hook, root, index+1, messageID),
+		imt.CheckpointDigest(origin, hook, root, index, root),
+	} {
+		if digests[digest] {
+			t.Fatal("a signed field does not change the digest")
+		}
+		digests[digest] = true
+	}
+}
@@ -0,0 +1,339 @@
+package imt
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// DefaultImportSubtreeSize is the maximum number of leaves of the subtrees
+// hashed by the workers of Import when no size is configured.
+const DefaultImportSubtreeSize = 1 << 16
+
+// NodeWriter persists the nodes of a tree computed by Import, e.g. into a
+// key-value store. It is called from a single goroutine, and every node is
+// written once.
+type NodeWriter[N comparable] interface {
+	WriteNode(level, index int, node N) error
+}
+
+// NodeWriterFunc adapts a function to the NodeWriter interface.
+type NodeWriterFunc[N comparable] func(level, index int, node N) error
+
+// WriteNode calls f.
+func (f NodeWriterFunc[N]) WriteNode(level, index int, node N) error {
+	return f(level, index, node)
+}
+
+// ImportConfig configures Import.
+type ImportConfig struct {
+	// The number of goroutines hashing subtrees, or runtime.GOMAXPROCS(0) if
+	// not positive.
+	Workers int
+	// The maximum number of leaves of the subtrees hashed by each worker, or
+	// DefaultImportSubtreeSize if not positive. The subtrees are complete
+	// subtrees of the tree, so their size is a power of the arity.
+	SubtreeSize int
+	// The expected number of leaves, used to estimate the remaining time, if
+	// known.
+	ExpectedLeaves int
+	// OnProgress, if set, is called every ProgressInterval, or every second
+	// if it is not positive, and once the import is complete.
+	OnProgress       func(ImportProgress)
+	ProgressInterval time.Duration
+}
+
+// ImportProgress reports the progress of Import.
+type ImportProgress struct {
+	Leaves          int           // The number of leaves imported so far.
+	Elapsed         time.Duration // The time since the start of the import.
+	LeavesPerSecond float64       // The average throughput.
+	// The estimated remaining time, if the expected number of leaves is
+	// known.
+	Remaining time.Duration
+}
+
+// importSegment is a subtree of leaves hashed by a worker of Import, and its
+// levels once hashed.
+type importSegment[N comparable] struct {
+	index  int
+	levels [][]N
+}
+
+// Import builds a tree from the leaves read from r, as written by WriteLeaves,
+// without holding them in memory: the leaves are read through a large buffer
+// and split into complete subtrees that are hashed by parallel workers, and
+// the nodes are passed to w as soon as they are final, so they are written
+// directly into a persistent backend. Only the subtrees being hashed and the
+// right edge of the tree are held in memory. It returns the root and the
+// number of leaves of the tree, which are the ones of a tree created by New
+// with the same leaves. The hash function must be safe for concurrent use.
+func Import[N comparable](ctx context.Context, r io.Reader, codec NodeCodec[N], hash HashFunction[N], depth int, zeroValue N, arity int, w NodeWriter[N], config ImportConfig) (root N, size int, err error) {
+	t, err := New(hash, depth, zeroValue, arity, nil)
+	if err != nil {
+		return root, 0, err
+	}
+
+	// The height and number of leaves of the subtrees of the workers.
+	subtreeSize := config.SubtreeSize
+	if subtreeSize <= 0 {
+		subtreeSize = DefaultImportSubtreeSize
+	}
+	height, span := 0, 1
+	for height < depth && span*arity <= subtreeSize {
+		height++
+		span *= arity
+	}
+	workers := config.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	segments := make(chan importSegment[N])
+	results := make(chan importSegment[N], workers)
+	// Tokens bound the number of segments read but not written yet, which
+	// may complete out of order.
+	tokens := make(chan struct{}, 2*workers)
+
+	var readErr error
+	go func() {
+		defer close(segments)
+		leaves := make([]N, 0, span)
+		send := func(index int) bool {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return false
+			}
+			select {
+			case segments <- importSegment[N]{index: index, levels: [][]N{leaves}}:
+			case <-ctx.Done():
+				return false
+			}
+			leaves = make([]N, 0, span)
+			return true
+		}
+
+		index := 0
+		for leaf, err := range ReadLeaves(bufio.NewReaderSize(r, 1<<20), codec) {
+			if err != nil {
+				readErr = err
+				return
+			}
+			leaves = append(leaves, leaf)
+			if len(leaves) == span {
+				if !send(index) {
+					return
+				}
+				index++
+			}
+		}
+		if len(leaves) > 0 {
+			send(index)
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for segment := range segments {
+				segment.levels = t.hashSubtree(segment.levels[0], height)
+				results <- segment
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	defer func() {
+		cancel()
+		for range results {
+		}
+	}()
+
+	edge := &importEdge[N]{tree: t, w: w, frontier: make([][]N, depth+1), written: make([]int, depth+1)}
+	pending := make(map[int]importSegment[N])
+	next := 0
+	start, reported := time.Now(), time.Now()
+	interval := config.ProgressInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	progress := func() {
+		if config.OnProgress == nil {
+			return
+		}
+		p := ImportProgress{Leaves: size, Elapsed: time.Since(start)}
+		if p.Elapsed > 0 {
+			p.LeavesPerSecond = float64(size) / p.Elapsed.Seconds()
+		}
+		if config.ExpectedLeaves > size && p.LeavesPerSecond > 0 {
+			p.Remaining = time.Duration(float64(config.ExpectedLeaves-size) / p.LeavesPerSecond * float64(time.Second))
+		}
+		config.OnProgress(p)
+	}
+
+	for segment := range results {
+		pending[segment.index] = segment
+		for {
+			segment, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			<-tokens
+
+			if uint64(size)+uint64(len(segment.levels[0])) > t.Capacity() {
+				return root, 0, ErrTreeFull
+			}
+			for level, nodes := range segment.levels[:height] {
+				first := segment.index * (span / pow(arity, level))
+				for i, node := range nodes {
+					if err := w.WriteNode(level, first+i, node); err != nil {
+						return root, 0, err
+					}
+				}
+			}
+			if err := edge.add(height, segment.levels[height][0]); err != nil {
+				return root, 0, err
+			}
+			size += len(segment.levels[0])
+			next++
+
+			if time.Since(reported) >= interval {
+				progress()
+				reported = time.Now()
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return root, 0, err
+		}
+	}
+	if readErr != nil {
+		return root, 0, readErr
+	}
+	if err := ctx.Err(); err != nil {
+		return root, 0, err
+	}
+
+	root, err = edge.finish(height, size)
+	if err != nil {
+		return root, 0, err
+	}
+	progress()
+	return root, size, nil
+}
+
+// hashSubtree returns the levels of the subtree of the given height whose
+// leaves are the given ones, padded with zero values, up to its root.
+func (t *IMT[N]) hashSubtree(leaves []N, height int) [][]N {
+	levels := make([][]N, height+1)
+	levels[0] = leaves
+	for level := 0; level < height; level++ {
+		below := levels[level]
+		parents := make([]N, (len(below)+t.arity-1)/t.arity)
+		for index := range parents {
+			children := make([]N, t.arity)
+			for i := range children {
+				if position := index*t.arity + i; position < len(below) {
+					children[i] = below[position]
+				} else {
+					children[i] = t.zeroes[level]
+				}
+			}
+			parent, ok := t.zeroParent(level, children)
+			if !ok {
+				parent = t.hash(children)
+			}
+			parents[index] = parent
+		}
+		levels[level+1] = parents
+	}
+	return levels
+}
+
+// importEdge computes and writes the nodes of Import above the subtrees of
+// the workers, from their roots, like a RootBuilder.
+type importEdge[N comparable] struct {
+	tree *IMT[N]
+	w    NodeWriter[N]
+	root N
+
+	// The complete children of the rightmost node of each level, and the
+	// number of nodes written at each level.
+	frontier [][]N
+	written  []int
+}
+
+// add writes a final node at the next index of a level, and the parents that
+// it completes.
+func (e *importEdge[N]) add(level int, node N) error {
+	if err := e.write(level, node); err != nil {
+		return err
+	}
+	if level == e.tree.depth {
+		e.root = node
+		return nil
+	}
+	e.frontier[level] = append(e.frontier[level], node)
+	if len(e.frontier[level]) < e.tree.arity {
+		return nil
+	}
+	parent := e.tree.hash(e.frontier[level])
+	e.frontier[level] = e.frontier[level][:0]
+	return e.add(level+1, parent)
+}
+
+// finish writes the rightmost nodes of the levels above the given one, which
+// are not complete, and returns the root of the tree.
+func (e *importEdge[N]) finish(level, size int) (N, error) {
+	if size == 0 {
+		return e.tree.Root(), nil
+	}
+	var node N
+	carry := false
+	for ; level < e.tree.depth; level++ {
+		if len(e.frontier[level]) == 0 && !carry {
+			continue
+		}
+		children := make([]N, 0, e.tree.arity)
+		children = append(children, e.frontier[level]...)
+		if carry {
+			children = append(children, node)
+		}
+		for len(children) < e.tree.arity {
+			children = append(children, e.tree.zeroes[level])
+		}
+		node = e.tree.hash(children)
+		if err := e.write(level+1, node); err != nil {
+			return node, err
+		}
+		carry = true
+	}
+	if !carry {
+		return e.root, nil
+	}
+	return node, nil
+}
+
+// write writes a node at the next index of a level.
+func (e *importEdge[N]) write(level int, node N) error {
+	e.written[level]++
+	return e.w.WriteNode(level, e.written[level]-1, node)
+}
+
+// pow returns base^exponent.
+func pow(base, exponent int) int {
+	result := 1
+	for range exponent {
+		result *= base
+	}
+	return result
+}
@@ -10,12 +10,23 @@
 // list of zeroes (one for each level) is used to compute the hash of a node
 // when not all of its children are defined. The number of children for each
 // node can also be specified with the arity parameter.
+//
+// Leaf indices and sizes are ints, and the capacity of a tree must fit in an
+// int, see ValidateConfig. A tree therefore holds at most 2^31-1 leaves on
+// 32-bit platforms, and larger trees require a 64-bit platform. Within that
+// bound, the arithmetic on indices and capacities cannot overflow.
 package imt
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"maps"
 	"math"
+	"math/bits"
 	"slices"
+	"sync/atomic"
+	"time"
 )
 
 // HashFunction is the hash function used to compute the tree nodes.
@@ -32,14 +43,20 @@ type MerkleProof[N comparable] struct {
 	LeafIndex   int   `json:"leafIndex"`   // The index of the leaf in the tree.
 	Siblings    [][]N `json:"siblings"`    // Sibling nodes at each level.
 	PathIndices []int `json:"pathIndices"` // Position indices at each level.
+
+	// The parameters of the tree that generated the proof. They are optional
+	// and only used to detect proofs generated under a different configuration.
+	Depth  int    `json:"depth,omitempty"`  // The depth of the tree.
+	Arity  int    `json:"arity,omitempty"`  // The number of children per node.
+	HashID string `json:"hashId,omitempty"` // The identifier of the hash function.
 }
 
 // IMT represents an Incremental Merkle Tree.
 type IMT[N comparable] struct {
-	// The matrix where all the tree nodes are stored. The first index indicates
-	// the level of the tree, while the second index represents the node's
-	// position within that specific level.
-	nodes [][]N
+	// The levels where all the tree nodes are stored. The first index indicates
+	// the level of the tree, while the index within a level represents the
+	// node's position within that specific level.
+	nodes []levelStore[N]
 
 	// A list of zero values calculated during the initialization of the tree.
 	// The list contains one value for each level of the tree, and the value for
@@ -49,8 +66,9 @@ type IMT[N comparable] struct {
 	// children are missing.
 	zeroes []N
 
-	// The hash function used to compute the tree nodes.
-	hash HashFunction[N]
+	// The hash function used to compute the tree nodes, called through the
+	// hash method, which counts the calls and uses the memo if any.
+	hashFunc HashFunction[N]
 
 	// The depth of the tree, which is the number of edges from the node to the
 	// tree's root node.
@@ -58,90 +76,327 @@ type IMT[N comparable] struct {
 
 	// The number of children per node.
 	arity int
+
+	// An optional meter notified of every hash computation and node access.
+	gasMeter GasMeter
+
+	// The parent context of the spans of the tree.
+	traceContext context.Context
+
+	// The optional configuration of the tree.
+	options options
+
+	// The number of hash computations performed since the tree was created,
+	// and the number of the last operation, which may be concurrent.
+	hashCalls atomic.Uint64
+	lastOp    atomic.Pointer[opHashCalls]
+
+	// The interner shared by the levels, if interning is enabled.
+	interner *interner[N]
+
+	// The optional hasher used by the bulk paths.
+	batchHasher BatchHasher[N]
+
+	// An optional function comparing nodes instead of ==.
+	equalFunc func(a, b N) bool
+
+	// The cache of the nodes computed on demand, in leaves-only mode.
+	cache *nodeCache[N]
+
+	// The cache of the proofs of the leaves, if enabled.
+	proofs *proofCache[N]
+
+	// The memo wrapping the hash function, if memoization is enabled.
+	memo *hashMemo[N]
+
+	// The unused part of the block the levels are carved from during the
+	// construction of the tree, if the arena is enabled.
+	arena []N
+
+	// The data associated with the leaves, by index.
+	leafData map[int]any
+
+	// The number of leaf writes since the tree was created.
+	version uint64
+
+	// The insertion records of the leaves from recordsStart, if insertions
+	// are recorded.
+	records      []InsertionRecord
+	recordsStart int
+
+	// The subscribers notified of every change of the root.
+	subscribers rootSubscribers[N]
+
+	// The deadlines after which leaves are deleted by SweepExpired.
+	expiries expiries
+
+	// The deleted leaves.
+	tombstones tombstones
+
+	// The growths of the depth of the tree, if it grows automatically.
+	increases []DepthIncrease[N]
+
+	// The index of the leaves other than the zero value, if they are unique.
+	positions map[N]int
+
+	// The callbacks notified when Compact moves leaves.
+	remaps remapCallbacks
+
+	// The callbacks notified of every committed operation.
+	logs logCallbacks[N]
 }
 
 // New initializes the tree with a hash function, the depth, the zero value to
 // use for zeroes, and the arity (i.e. the number of children for each node).
-// It also takes an optional parameter to initialize the tree with a list of leaves.
-func New[N comparable](hash HashFunction[N], depth int, zeroValue N, arity int, leaves []N) (*IMT[N], error) {
+// It also takes an optional parameter to initialize the tree with a list of leaves,
+// and options configuring optional behaviour.
+func New[N comparable](hash HashFunction[N], depth int, zeroValue N, arity int, leaves []N, opts ...Option) (*IMT[N], error) {
+	return newWithOptions(hash, depth, zeroValue, arity, leaves, newOptions(opts))
+}
+
+// MustNew is like New but panics if the tree cannot be created, which makes
+// it suitable for the initialization of package-level variables with
+// parameters known to be valid.
+func MustNew[N comparable](hash HashFunction[N], depth int, zeroValue N, arity int, leaves []N, opts ...Option) *IMT[N] {
+	t, err := New(hash, depth, zeroValue, arity, leaves, opts...)
+	if err != nil {
+		panic("imt: " + err.Error())
+	}
+	return t
+}
+
+// NewWithCapacity creates an empty tree like New, with the smallest depth
+// whose capacity is at least minLeaves, multiplied by the headroom factor set
+// with WithHeadroom, if any, and returns the chosen depth.
+func NewWithCapacity[N comparable](hash HashFunction[N], zeroValue N, arity, minLeaves int, opts ...Option) (*IMT[N], int, error) {
+	o := newOptions(opts)
+	if err := ValidateConfig(1, arity, hash); err != nil {
+		return nil, 0, err
+	}
+	depth, err := depthFor(arity, minLeaves, o.headroom)
+	if err != nil {
+		return nil, 0, err
+	}
+	t, err := newWithOptions(hash, depth, zeroValue, arity, nil, o)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, depth, nil
+}
+
+// WithHeadroom makes NewWithCapacity choose a depth whose capacity is at least
+// factor times the requested number of leaves, leaving room for growth.
+// Factors below 1 are ignored.
+func WithHeadroom(factor float64) Option {
+	return func(o *options) {
+		o.headroom = factor
+	}
+}
+
+// depthFor returns the smallest depth of a tree of the given arity holding at
+// least minLeaves leaves multiplied by the headroom factor.
+func depthFor(arity, minLeaves int, headroom float64) (int, error) {
+	needed := float64(max(minLeaves, 1)) * max(headroom, 1)
+	if needed >= math.MaxUint64 {
+		return 0, errors.New("no depth can hold that many leaves")
+	}
+	target := uint64(math.Ceil(needed))
+	if arity == 1 && target > 1 {
+		return 0, errors.New("a tree of arity 1 holds a single leaf")
+	}
+	depth := 1
+	for capacity(arity, depth) < target {
+		depth++
+	}
+	return depth, nil
+}
+
+// ValidateConfig checks the parameters of a tree without creating it, and
+// returns the error New would return for them: the hash function is required,
+// the depth and arity must be positive, and the capacity of the tree,
+// arity^depth leaves, must fit in an int, so that every leaf index and size
+// does. A binary tree has a depth of at most 62 on 64-bit platforms, and of
+// at most 30 on 32-bit platforms. It lets configurations be validated when
+// they are loaded, before any tree is built.
+func ValidateConfig[N comparable](depth, arity int, hash HashFunction[N]) error {
+	return validateConfig(depth, arity, hash, 0)
+}
+
+// validateConfig is ValidateConfig for a tree created with the given number
+// of leaves, which must not exceed its capacity.
+func validateConfig[N comparable](depth, arity int, hash HashFunction[N], leaves int) error {
 	if hash == nil {
-		return nil, errors.New("hash function is required")
+		return errors.New("hash function is required")
 	}
 	if depth <= 0 {
-		return nil, errors.New("depth must be positive")
+		return errors.New("depth must be positive")
 	}
 	if arity <= 0 {
-		return nil, errors.New("arity must be positive")
+		return errors.New("arity must be positive")
 	}
+	if capacity(arity, depth) > uint64(maxInt) {
+		return fmt.Errorf("the capacity of a tree of arity %d and depth %d overflows int", arity, depth)
+	}
+	if uint64(leaves) > capacity(arity, depth) {
+		return errors.New("the tree cannot contain more than arity^depth leaves")
+	}
+	return nil
+}
 
-	maxLeaves := int(math.Pow(float64(arity), float64(depth)))
-	if len(leaves) > maxLeaves {
-		return nil, errors.New("the tree cannot contain more than arity^depth leaves")
+// newWithOptions implements New with options that have already been applied,
+// so that trees derived from another tree can share its configuration.
+func newWithOptions[N comparable](hash HashFunction[N], depth int, zeroValue N, arity int, leaves []N, opts options) (*IMT[N], error) {
+	if err := validateConfig(depth, arity, hash, len(leaves)); err != nil {
+		return nil, err
 	}
 
 	// Initialize the attributes.
+	var err error
 	imt := &IMT[N]{
-		hash:   hash,
-		depth:  depth,
-		arity:  arity,
-		zeroes: make([]N, depth),
-		nodes:  make([][]N, depth+1),
-	}
-
-	for level := 0; level < depth; level++ {
-		imt.zeroes[level] = zeroValue
-		imt.nodes[level] = make([]N, 0)
-		// There must be a zero value for each tree level (except the root).
-		children := make([]N, arity)
-		for i := range children {
-			children[i] = zeroValue
-		}
-		zeroValue = hash(children)
+		hashFunc: hash,
+		depth:    depth,
+		arity:    arity,
+		zeroes:   make([]N, depth),
+		nodes:    make([]levelStore[N], depth+1),
+		options:  opts,
+	}
+	if imt.batchHasher, err = batchHasher[N](opts); err != nil {
+		return nil, err
+	}
+	if imt.equalFunc, err = equalFunc[N](opts); err != nil {
+		return nil, err
+	}
+	if opts.leavesOnly {
+		imt.cache = newNodeCache[N](opts.cacheSize)
+	}
+	if opts.proofCacheSize > 0 {
+		imt.proofs = newProofCache[N](opts.proofCacheSize)
+	}
+	if opts.memoSize > 0 {
+		imt.memo = newHashMemo(imt.callHash, opts.memoSize)
+	}
+	if opts.interning {
+		imt.interner = newInterner[N]()
+	} else if opts.arena && !opts.leavesOnly && len(leaves) > 0 {
+		imt.arena = make([]N, 0, arenaSize(len(leaves), arity, depth))
 	}
 
-	imt.nodes[depth] = make([]N, 0)
+	if opts.zeroes != nil {
+		if zeroValue, err = imt.presetZeroes(zeroValue, opts); err != nil {
+			return nil, err
+		}
+	} else {
+		for level := 0; level < depth; level++ {
+			imt.zeroes[level] = zeroValue
+			// There must be a zero value for each tree level (except the root).
+			children := make([]N, arity)
+			for i := range children {
+				children[i] = zeroValue
+			}
+			zeroValue = imt.hash(children)
+		}
+	}
 
 	// Initialize the tree with a list of leaves if there are any.
+	imt.nodes[0] = imt.newLevel(len(leaves))
+	for _, leaf := range leaves {
+		imt.nodes[0].Append(leaf)
+	}
+	if err := imt.indexLeaves(OpBuild); err != nil {
+		return nil, err
+	}
+	imt.version = uint64(len(leaves))
+	imt.recordInsertions(len(leaves))
 	if len(leaves) > 0 {
-		imt.nodes[0] = make([]N, len(leaves))
-		copy(imt.nodes[0], leaves)
-
-		for level := 0; level < depth; level++ {
-			numParents := int(math.Ceil(float64(len(imt.nodes[level])) / float64(arity)))
-			imt.nodes[level+1] = make([]N, numParents)
-
-			for index := 0; index < numParents; index++ {
-				position := index * arity
-				children := make([]N, arity)
-
-				for i := 0; i < arity; i++ {
-					childIdx := position + i
-					if childIdx < len(imt.nodes[level]) {
-						children[i] = imt.nodes[level][childIdx]
-					} else {
-						children[i] = imt.zeroes[level]
-					}
+		span := imt.startSpan(OpBuild, len(leaves))
+		if imt.pipelined() {
+			imt.buildPipelined()
+		} else {
+			for level := 0; level < depth; level++ {
+				err := imt.profileLevel(OpBuild, level, func() error {
+					return imt.buildLevel(level)
+				})
+				if err != nil {
+					span.End(err)
+					return nil, err
 				}
-
-				imt.nodes[level+1][index] = hash(children)
 			}
 		}
+		span.End(nil)
 	} else {
+		for level := 1; level < depth; level++ {
+			imt.nodes[level] = imt.newLevel(0)
+			imt.dropLevel(level)
+		}
 		// If there are no leaves, the default root is the last zero value.
-		imt.nodes[depth] = []N{zeroValue}
+		imt.nodes[depth] = imt.newLevel(1)
+		imt.nodes[depth].Append(zeroValue)
 	}
+	imt.arena = nil
+	imt.countHashes(OpBuild, 0)
 
 	return imt, nil
 }
 
+// buildLevel computes the nodes of the level above the given one, during the
+// construction of the tree from a list of leaves.
+func (t *IMT[N]) buildLevel(level int) error {
+	numParents := (t.nodes[level].Len() + t.arity - 1) / t.arity
+	t.nodes[level+1] = t.newLevel(numParents)
+
+	if t.batchHasher != nil || t.goroutines() > 1 {
+		nodes, err := t.hashAll(level, numParents, func(index int) []N {
+			return t.storedChildren(level, index)
+		})
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			t.nodes[level+1].Append(node)
+		}
+		t.dropLevel(level)
+		return nil
+	}
+
+	var buffer []N
+	if t.options.arena {
+		buffer = make([]N, t.arity)
+	}
+	for index := 0; index < numParents; index++ {
+		position := index * t.arity
+		children := buffer
+		if children == nil {
+			children = make([]N, t.arity)
+		}
+
+		for i := 0; i < t.arity; i++ {
+			childIdx := position + i
+			if childIdx < t.nodes[level].Len() {
+				children[i] = t.nodes[level].Get(childIdx)
+			} else {
+				children[i] = t.zeroes[level]
+			}
+		}
+
+		if parent, ok := t.zeroParent(level, children); ok {
+			t.nodes[level+1].Append(parent)
+		} else {
+			t.nodes[level+1].Append(t.hash(children))
+		}
+	}
+	t.dropLevel(level)
+
+	return nil
+}
+
 // Root returns the root of the tree. This value doesn't need to be stored as
 // it is always the first and unique element of the last level of the tree.
 func (t *IMT[N]) Root() N {
-	if len(t.nodes[t.depth]) == 0 {
+	if t.nodes[t.depth].Len() == 0 {
 		var zero N
 		return zero
 	}
-	return t.nodes[t.depth][0]
+	return t.nodes[t.depth].Get(0)
 }
 
 // Depth returns the depth of the tree, which equals the number of levels - 1.
@@ -153,9 +408,7 @@ func (t *IMT[N]) Depth() int {
 // level of the tree. The returned value is a copy of the slice and not the
 // original object.
 func (t *IMT[N]) Leaves() []N {
-	result := make([]N, len(t.nodes[0]))
-	copy(result, t.nodes[0])
-	return result
+	return levelNodes(t.nodes[0], 0, t.nodes[0].Len())
 }
 
 // Zeroes returns the list of zero values calculated during the initialization
@@ -169,15 +422,34 @@ func (t *IMT[N]) Arity() int {
 	return t.arity
 }
 
-// Size returns the number of leaves in the tree.
+// Size returns the number of leaves in the tree. It is an int like the leaf
+// indices, see ValidateConfig.
 func (t *IMT[N]) Size() int {
-	return len(t.nodes[0])
+	return t.nodes[0].Len()
+}
+
+// Capacity returns the maximum number of leaves of the tree, arity^depth. It
+// is computed with 64-bit integers, and always fits in an int since
+// ValidateConfig rejects larger trees.
+func (t *IMT[N]) Capacity() uint64 {
+	return capacity(t.arity, t.depth)
 }
 
 // IndexOf returns the index of the first occurrence of a leaf in the tree.
 // If the leaf does not exist it returns -1.
 func (t *IMT[N]) IndexOf(leaf N) int {
-	return slices.Index(t.nodes[0], leaf)
+	if t.positions != nil && leaf != t.zeroes[0] {
+		if index, ok := t.positions[leaf]; ok {
+			return index
+		}
+		return -1
+	}
+	for index := 0; index < t.nodes[0].Len(); index++ {
+		if t.equal(t.nodes[0].Get(index), leaf) {
+			return index
+		}
+	}
+	return -1
 }
 
 // Insert adds a new leaf to the tree. The leaves are inserted incrementally.
@@ -188,143 +460,449 @@ func (t *IMT[N]) IndexOf(leaf N) int {
 // value is the hash of that node and the zero value of that level. Otherwise,
 // the hash of the children is calculated.
 func (t *IMT[N]) Insert(leaf N) error {
-	maxLeaves := int(math.Pow(float64(t.arity), float64(t.depth)))
-	if len(t.nodes[0]) >= maxLeaves {
-		return errors.New("the tree is full")
+	return t.insert(leaf, nil)
+}
+
+// InsertWithProof inserts a leaf like Insert, and returns its index and its
+// proof against the new root. The siblings of the proof are collected while
+// the path of the leaf is hashed, instead of being read again afterwards.
+func (t *IMT[N]) InsertWithProof(leaf N) (index int, proof *MerkleProof[N], err error) {
+	index = t.NextIndex()
+	proof = &MerkleProof[N]{
+		Leaf:        leaf,
+		LeafIndex:   index,
+		Siblings:    make([][]N, 0, t.depth),
+		PathIndices: make([]int, 0, t.depth),
+		Arity:       t.arity,
+		HashID:      t.options.hashID,
+	}
+	if err := t.insert(leaf, proof); err != nil {
+		return 0, nil, err
 	}
+	// The tree may have grown.
+	proof.Root = t.Root()
+	proof.Depth = t.depth
+	return index, proof, nil
+}
 
+// insert implements Insert and InsertWithProof, adding the siblings of the
+// path of the leaf to proof if it is not nil.
+func (t *IMT[N]) insert(leaf N, proof *MerkleProof[N]) error {
+	if err := t.checkUnique(OpInsert, -1, leaf); err != nil {
+		return err
+	}
+	if index, ok := t.freeIndex(); ok {
+		if err := t.reuse(index, leaf); err != nil {
+			return err
+		}
+		// Updating a leaf doesn't collect its siblings.
+		if proof != nil {
+			*proof = *t.createProof(0, index)
+		}
+		return nil
+	}
+	depth, err := t.neededDepth(OpInsert, 1)
+	if err != nil {
+		return err
+	}
+
+	calls := t.hashCalls.Load()
+	oldRoot := t.Root()
+	t.grow(depth)
 	node := leaf
-	index := len(t.nodes[0])
+	index := t.nodes[0].Len()
 
 	for level := 0; level < t.depth; level++ {
-		position := index % t.arity
-		levelStartIndex := index - position
-		levelEndIndex := levelStartIndex + t.arity
+		t.putNode(level, index, node)
 
-		// Expand the slice if needed.
-		for len(t.nodes[level]) <= index {
-			var zero N
-			t.nodes[level] = append(t.nodes[level], zero)
+		children := t.children(level, index)
+		if proof != nil {
+			position := index % t.arity
+			siblings := make([]N, 0, t.arity-1)
+			siblings = append(siblings, children[:position]...)
+			siblings = append(siblings, children[position+1:]...)
+			proof.Siblings = append(proof.Siblings, siblings)
+			proof.PathIndices = append(proof.PathIndices, position)
 		}
-		t.nodes[level][index] = node
+		node = t.hashChildren(children)
+		index = index / t.arity
+	}
 
-		children := make([]N, t.arity)
-		for i := levelStartIndex; i < levelEndIndex; i++ {
-			childIdx := i - levelStartIndex
-			if i < len(t.nodes[level]) {
-				children[childIdx] = t.nodes[level][i]
-			} else {
-				children[childIdx] = t.zeroes[level]
+	t.writeNode(t.depth, 0, node)
+	t.indexLeaf(t.nodes[0].Len()-1, leaf)
+	t.version++
+	t.recordInsertions(1)
+	t.notifyRoots(oldRoot, 1)
+	t.logCommit(OpInsert, 1)
+	if t.logs.active() {
+		t.emitLog(LogEntry[N]{Op: OpInsert, Leaves: []N{leaf}})
+	}
+
+	t.countHashes(OpInsert, calls)
+	if t.options.metrics != nil {
+		t.options.metrics.IncInserts()
+		t.options.metrics.ObserveHashCalls(OpInsert, int(t.hashCalls.Load()-calls))
+	}
+
+	return t.debugCheck(OpInsert, t.nodes[0].Len()-1, t.nodes[0].Len()-1)
+}
+
+// InsertMany adds a list of leaves to the tree, as if they were inserted one
+// by one with Insert, but hashes each affected node only once. The nodes are
+// hashed level by level, with the BatchHasher of the tree if it has one,
+// including the ones of the deleted positions that the leaves fill. The
+// insertion is atomic: if it fails, the tree is left untouched, and it is
+// notified and logged as a single operation.
+func (t *IMT[N]) InsertMany(leaves []N) (err error) {
+	if len(leaves) == 0 {
+		return nil
+	}
+	span := t.startSpan(OpInsertMany, len(leaves))
+	defer func() {
+		span.End(err)
+	}()
+
+	free := 0
+	if t.options.deletePolicy == DeleteFreeList {
+		free = min(t.tombstones.count, len(leaves))
+	}
+	depth, err := t.neededDepth(OpInsertMany, len(leaves)-free)
+	if err != nil {
+		return err
+	}
+	if err := t.checkUniqueMany(OpInsertMany, leaves); err != nil {
+		return err
+	}
+
+	calls := t.hashCalls.Load()
+	oldRoot := t.Root()
+	if depth > t.depth {
+		// Undo the growth if the insertion fails.
+		defer func(depth int) {
+			if err != nil {
+				t.shrink(depth)
+			}
+		}(t.depth)
+		t.grow(depth)
+	}
+
+	// The leaves fill the deleted positions first, if the tree reuses them,
+	// and are appended after the last leaf.
+	size := t.nodes[0].Len()
+	indices := make([][]int, t.depth+1)
+	pending := make([][]N, t.depth+1)
+	indices[0] = t.freeIndices(free)
+	for i := range len(leaves) - free {
+		indices[0] = append(indices[0], size+i)
+	}
+	pending[0] = leaves
+
+	// Compute the new nodes of each level, from the parents of the nodes
+	// that change below, before writing any of them.
+	for level := 0; level < t.depth; level++ {
+		below, changed := pending[level], indices[level]
+		var parents []int
+		for _, index := range changed {
+			if parent := index / t.arity; len(parents) == 0 || parents[len(parents)-1] != parent {
+				parents = append(parents, parent)
 			}
 		}
 
-		node = t.hash(children)
-		index = index / t.arity
+		var nodes []N
+		err := t.profileLevel(OpInsertMany, level, func() (err error) {
+			// Read the children first, since hashing may be concurrent.
+			lists := make([][]N, len(parents))
+			next := 0
+			for i, parent := range parents {
+				children := make([]N, t.arity)
+				for j := range children {
+					position := parent*t.arity + j
+					if next < len(changed) && changed[next] == position {
+						children[j] = below[next]
+						next++
+					} else {
+						children[j] = t.readNode(level, position)
+					}
+				}
+				// Gas is charged for the parents of zero values too,
+				// although hashAll does not hash them.
+				if t.gasMeter != nil {
+					t.gasMeter.ConsumeHash(len(children))
+				}
+				lists[i] = children
+			}
+			nodes, err = t.hashAll(level, len(parents), func(i int) []N {
+				return lists[i]
+			})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		indices[level+1], pending[level+1] = parents, nodes
 	}
 
-	t.nodes[t.depth][0] = node
+	oldLeaves := make([]N, free)
+	for i, index := range indices[0][:free] {
+		oldLeaves[i] = t.readNode(0, index)
+	}
+	for level, nodes := range pending {
+		changed := indices[level]
+		if grow := changed[len(changed)-1] + 1 - t.nodes[level].Len(); grow > 0 {
+			t.nodes[level].Grow(grow)
+		}
+		for i, node := range nodes {
+			t.putNode(level, changed[i], node)
+		}
+	}
+	at := t.now()
+	for i, index := range indices[0][:free] {
+		t.tombstones.clear(index)
+		t.unindexLeaf(index, oldLeaves[i])
+		t.indexLeaf(index, leaves[i])
+		if t.options.insertionRecords && index >= t.recordsStart && index < t.recordsStart+len(t.records) {
+			t.records[index-t.recordsStart] = InsertionRecord{Sequence: t.version + uint64(i) + 1, Time: at}
+		}
+	}
+	for i, leaf := range leaves[free:] {
+		t.indexLeaf(size+i, leaf)
+	}
+	t.version += uint64(len(leaves))
+	t.recordInsertions(len(leaves) - free)
+	t.notifyRoots(oldRoot, len(leaves))
+	t.logCommit(OpInsertMany, len(leaves))
+	if t.logs.active() {
+		t.emitLog(LogEntry[N]{Op: OpInsertMany, Leaves: slices.Clone(leaves)})
+	}
 
-	return nil
+	t.countHashes(OpInsertMany, calls)
+	if t.options.metrics != nil {
+		for range leaves {
+			t.options.metrics.IncInserts()
+		}
+		t.options.metrics.ObserveHashCalls(OpInsertMany, int(t.hashCalls.Load()-calls))
+	}
+
+	return t.debugCheck(OpInsertMany, indices[0][0], t.nodes[0].Len()-1)
 }
 
 // Delete removes a leaf from the tree. It does not remove the leaf from the
 // data structure, but rather it sets the leaf to be deleted to the zero value.
+// The DeletePolicy of the tree can make it remove the last leaves, or reuse
+// the position of the leaf.
 func (t *IMT[N]) Delete(index int) error {
-	return t.Update(index, t.zeroes[0])
+	if err := t.update(OpDelete, index, t.zeroes[0]); err != nil {
+		return err
+	}
+	delete(t.leafData, index)
+	t.expiries.remove(index)
+	t.tombstones.set(index)
+	if t.options.deletePolicy == DeleteTruncateIfLast && index == t.nodes[0].Len()-1 {
+		t.truncateDeleted()
+	}
+	if t.logs.active() {
+		t.emitLog(LogEntry[N]{Op: OpDelete, Index: index})
+	}
+	return nil
 }
 
 // Update updates a leaf in the tree. It's very similar to the Insert function.
 func (t *IMT[N]) Update(index int, newLeaf N) error {
-	if index < 0 || index >= len(t.nodes[0]) {
-		return errors.New("the leaf does not exist in this tree")
+	if err := t.update(OpUpdate, index, newLeaf); err != nil {
+		return err
+	}
+	t.tombstones.clear(index)
+	if t.logs.active() {
+		t.emitLog(LogEntry[N]{Op: OpUpdate, Index: index, Leaves: []N{newLeaf}})
+	}
+	return nil
+}
+
+// update implements Update and Delete, which only differ in how they are
+// reported to the metrics.
+func (t *IMT[N]) update(op string, index int, newLeaf N) error {
+	if index < 0 || index >= t.nodes[0].Len() {
+		return t.opError(op, 0, index, ErrLeafNotFound)
+	}
+
+	calls := t.hashCalls.Load()
+	defer func() {
+		t.countHashes(op, calls)
+	}()
+	if t.options.metrics != nil {
+		defer func() {
+			t.options.metrics.IncUpdates()
+			t.options.metrics.ObserveHashCalls(op, int(t.hashCalls.Load()-calls))
+		}()
 	}
 
-	if t.nodes[0][index] == newLeaf {
+	oldLeaf := t.readNode(0, index)
+	if t.equal(oldLeaf, newLeaf) {
 		return nil
 	}
+	if err := t.checkUnique(op, index, newLeaf); err != nil {
+		return err
+	}
 
+	oldRoot := t.Root()
 	node := newLeaf
+	leafIndex := index
 
 	for level := 0; level < t.depth; level++ {
-		position := index % t.arity
-		levelStartIndex := index - position
-		levelEndIndex := levelStartIndex + t.arity
+		t.writeNode(level, index, node)
 
-		t.nodes[level][index] = node
-
-		children := make([]N, t.arity)
-		for i := levelStartIndex; i < levelEndIndex; i++ {
-			childIdx := i - levelStartIndex
-			if i < len(t.nodes[level]) {
-				children[childIdx] = t.nodes[level][i]
-			} else {
-				children[childIdx] = t.zeroes[level]
-			}
-		}
-
-		node = t.hash(children)
+		node = t.hashChildren(t.children(level, index))
 		index = index / t.arity
 	}
 
-	t.nodes[t.depth][0] = node
+	t.writeNode(t.depth, 0, node)
+	t.unindexLeaf(leafIndex, oldLeaf)
+	t.indexLeaf(leafIndex, newLeaf)
+	t.version++
+	t.notifyRoots(oldRoot, 1)
+	t.logCommit(op, 1)
 
-	return nil
+	return t.debugCheck(op, leafIndex, leafIndex)
+}
+
+// Migrate returns a new tree with the same depth, arity, zero value and
+// leaves, in the same order, but whose internal nodes are computed with a
+// different hash function. The original tree is left untouched, so it can keep
+// serving proofs until the migration is complete. The leaf data, insertion
+// records, deleted leaves and deadlines are carried over, but not the depth
+// increases, whose roots were computed with the previous hash function.
+func (t *IMT[N]) Migrate(newHash HashFunction[N]) (*IMT[N], error) {
+	// The zero values of the other hash function no longer apply.
+	opts := t.options
+	opts.zeroes = nil
+	migrated, err := newWithOptions(newHash, t.depth, t.zeroes[0], t.arity, t.Leaves(), opts)
+	if err != nil {
+		return nil, err
+	}
+	migrated.leafData = maps.Clone(t.leafData)
+	migrated.version = t.version
+	migrated.records = slices.Clone(t.records)
+	migrated.recordsStart = t.recordsStart
+	migrated.tombstones = t.tombstones.clone()
+	migrated.expiries = t.expiries.clone()
+	return migrated, nil
 }
 
 // CreateProof creates a MerkleProof for a leaf of the tree. That proof can be
 // verified by this tree using the same hash function.
 func (t *IMT[N]) CreateProof(index int) (*MerkleProof[N], error) {
-	if index < 0 || index >= len(t.nodes[0]) {
-		return nil, errors.New("the leaf does not exist in this tree")
+	if index < 0 || index >= t.nodes[0].Len() {
+		return nil, t.opError(OpCreateProof, 0, index, ErrLeafNotFound)
 	}
 
-	siblings := make([][]N, t.depth)
-	pathIndices := make([]int, t.depth)
+	calls := t.hashCalls.Load()
+	defer func() {
+		t.countHashes(OpCreateProof, calls)
+	}()
+	if t.options.metrics != nil {
+		start := time.Now()
+		defer func() {
+			t.options.metrics.ObserveProofLatency(time.Since(start))
+		}()
+	}
+
+	if t.proofs != nil {
+		if proof, ok := t.proofs.get(index, t.version); ok {
+			return proof, nil
+		}
+		proof := t.createProof(0, index)
+		t.proofs.put(index, t.version, proof)
+		return proof, nil
+	}
+	return t.createProof(0, index), nil
+}
+
+// CreateProofForEmpty creates a MerkleProof showing that the position at the
+// given index, which may be beyond the size of the tree, holds the zero value.
+// Missing subtrees are proven with the zero values of their levels. It returns
+// an error if the position is outside the capacity of the tree or holds a leaf
+// other than the zero value.
+func (t *IMT[N]) CreateProofForEmpty(index int) (*MerkleProof[N], error) {
+	if index < 0 || uint64(index) >= t.Capacity() {
+		return nil, errors.New("the position is outside the capacity of the tree")
+	}
+	if index < t.nodes[0].Len() && t.readNode(0, index) != t.zeroes[0] {
+		return nil, errors.New("the position is not empty")
+	}
+
+	return t.createProof(0, index), nil
+}
+
+// createProof creates a MerkleProof for the node at the given level and
+// index, whose siblings go from that level up to the root.
+func (t *IMT[N]) createProof(level, index int) *MerkleProof[N] {
+	siblings := make([][]N, 0, t.depth-level)
+	pathIndices := make([]int, 0, t.depth-level)
+	leaf := t.readNode(level, index)
 	leafIndex := index
 
-	for level := 0; level < t.depth; level++ {
+	for ; level < t.depth; level++ {
 		position := index % t.arity
 		levelStartIndex := index - position
 		levelEndIndex := levelStartIndex + t.arity
 
-		pathIndices[level] = position
-		siblings[level] = make([]N, 0, t.arity-1)
-
+		levelSiblings := make([]N, 0, t.arity-1)
 		for i := levelStartIndex; i < levelEndIndex; i++ {
 			if i != index {
-				if i < len(t.nodes[level]) {
-					siblings[level] = append(siblings[level], t.nodes[level][i])
-				} else {
-					siblings[level] = append(siblings[level], t.zeroes[level])
-				}
+				levelSiblings = append(levelSiblings, t.readNode(level, i))
 			}
 		}
+		siblings = append(siblings, levelSiblings)
+		pathIndices = append(pathIndices, position)
 
 		index = index / t.arity
 	}
 
 	return &MerkleProof[N]{
 		Root:        t.Root(),
-		Leaf:        t.nodes[0][leafIndex],
+		Leaf:        leaf,
 		LeafIndex:   leafIndex,
 		Siblings:    siblings,
 		PathIndices: pathIndices,
-	}, nil
+		Depth:       t.depth,
+		Arity:       t.arity,
+		HashID:      t.options.hashID,
+	}
 }
 
 // VerifyProof verifies a MerkleProof to confirm that a leaf indeed belongs to
 // a tree. Does not verify that the node belongs to this tree in particular.
 // Equivalent to calling the package-level VerifyProof function with this
-// tree's hash function.
+// tree's hash function, except that the roots are compared with the equality
+// function of the tree, if any.
 func (t *IMT[N]) VerifyProof(proof *MerkleProof[N]) bool {
-	return VerifyProof(proof, t.hash)
+	root, ok := proofRoot(proof, t.hash)
+	return ok && t.equal(proof.Root, root)
 }
 
 // VerifyProof verifies a MerkleProof to confirm that a leaf indeed belongs to
-// a tree.
+// a tree. Malformed proofs, as defined by ProofArity, are rejected.
 func VerifyProof[N comparable](proof *MerkleProof[N], hash HashFunction[N]) bool {
-	if proof == nil {
-		return false
+	root, ok := proofRoot(proof, hash)
+	return ok && proof.Root == root
+}
+
+// VerifyProofAgainstRoot verifies that a MerkleProof proves its leaf against
+// the given root, ignoring the root embedded in the proof. Verifiers that got
+// the trusted root from another channel, such as the state of a chain or a
+// signed checkpoint, should use it rather than VerifyProof, which trusts the
+// root supplied with the proof. Malformed proofs are rejected.
+func VerifyProofAgainstRoot[N comparable](proof *MerkleProof[N], expectedRoot N, hash HashFunction[N]) bool {
+	root, ok := proofRoot(proof, hash)
+	return ok && root == expectedRoot
+}
+
+// proofRoot computes the root of a proof from its leaf and siblings, and
+// reports whether the proof is well-formed.
+func proofRoot[N comparable](proof *MerkleProof[N], hash HashFunction[N]) (N, bool) {
+	if _, err := ProofArity(proof); err != nil {
+		var zero N
+		return zero, false
 	}
 
 	node := proof.Leaf
@@ -338,5 +916,158 @@ func VerifyProof[N comparable](proof *MerkleProof[N], hash HashFunction[N]) bool
 		node = hash(children)
 	}
 
-	return proof.Root == node
+	return node, true
+}
+
+// VerifyProofs verifies a batch of MerkleProofs and returns true if all of
+// them are valid. Proofs of the same root that share ancestors are only hashed
+// up to the first ancestor already verified by a previous proof, above which
+// their siblings are compared instead of hashed, so verifying many proofs of
+// the same tree is much cheaper than verifying them one by one. It accepts a
+// batch if and only if VerifyProof accepts each of its proofs.
+func VerifyProofs[N comparable](proofs []*MerkleProof[N], hash HashFunction[N]) (valid bool) {
+	profile(OpVerifyProofs, func() {
+		valid = verifyProofs(proofs, hash)
+	})
+	return valid
+}
+
+// verifyProofs implements VerifyProofs.
+func verifyProofs[N comparable](proofs []*MerkleProof[N], hash HashFunction[N]) bool {
+	// Nodes are only shared by proofs of trees of the same shape, since a
+	// node of a deeper tree is not the root of a shallower one.
+	type position struct {
+		root  N
+		depth int
+		arity int
+		level int
+		index int
+	}
+	type verifiedNode struct {
+		node     N
+		siblings []N
+	}
+
+	verified := make(map[position]verifiedNode)
+
+	for _, proof := range proofs {
+		arity, err := ProofArity(proof)
+		if err != nil {
+			return false
+		}
+
+		depth := len(proof.Siblings)
+		indices := make([]int, depth+1)
+		for level := depth - 1; level >= 0; level-- {
+			indices[level] = indices[level+1]*arity + proof.PathIndices[level]
+		}
+
+		path := make([]N, 0, depth+1)
+		node := proof.Leaf
+		level := 0
+		for ; level <= depth; level++ {
+			if _, ok := verified[position{root: proof.Root, depth: depth, arity: arity, level: level, index: indices[level]}]; ok {
+				break
+			}
+			path = append(path, node)
+			if level < depth {
+				node = hash(insertChild(proof.Siblings[level], proof.PathIndices[level], node))
+			}
+		}
+
+		if level > depth {
+			// No ancestor was verified before, so the root must match.
+			if node != proof.Root {
+				return false
+			}
+		} else {
+			// The rest of the path was verified by a previous proof, so the
+			// proof is valid if it goes through the same nodes.
+			for l := level; l <= depth; l++ {
+				known := verified[position{root: proof.Root, depth: depth, arity: arity, level: l, index: indices[l]}]
+				if l == level && known.node != node {
+					return false
+				}
+				if l < depth && !slices.Equal(known.siblings, proof.Siblings[l]) {
+					return false
+				}
+			}
+		}
+
+		for l, node := range path {
+			var siblings []N
+			if l < depth {
+				siblings = proof.Siblings[l]
+			}
+			verified[position{root: proof.Root, depth: depth, arity: arity, level: l, index: indices[l]}] = verifiedNode{node: node, siblings: siblings}
+		}
+	}
+
+	return true
+}
+
+// capacity returns arity^depth, saturating at math.MaxUint64.
+func capacity(arity, depth int) uint64 {
+	result := uint64(1)
+	for i := 0; i < depth; i++ {
+		hi, lo := bits.Mul64(result, uint64(arity))
+		if hi != 0 {
+			return math.MaxUint64
+		}
+		result = lo
+	}
+	return result
+}
+
+// children returns the children of the node of the next level that is the
+// parent of the given node, using the zero value of the level for the
+// children that have not been inserted yet.
+func (t *IMT[N]) children(level, index int) []N {
+	start := index - index%t.arity
+	children := make([]N, t.arity)
+	for i := range children {
+		children[i] = t.readNode(level, start+i)
+	}
+	return children
+}
+
+// readNode returns the node at the given position, or the zero value of the
+// level if it has not been inserted yet.
+func (t *IMT[N]) readNode(level, index int) N {
+	if index >= t.nodes[level].Len() {
+		return t.zeroes[level]
+	}
+	if t.gasMeter != nil {
+		t.gasMeter.ConsumeNodeRead()
+	}
+	return t.nodes[level].Get(index)
+}
+
+// writeNode stores a node at a position that must already exist.
+func (t *IMT[N]) writeNode(level, index int, node N) {
+	if t.gasMeter != nil {
+		t.gasMeter.ConsumeNodeWrite()
+	}
+	t.nodes[level].Set(index, node)
+}
+
+// putNode stores a node at a position that already exists or immediately
+// follows the last node of the level.
+func (t *IMT[N]) putNode(level, index int, node N) {
+	if index < t.nodes[level].Len() {
+		t.writeNode(level, index, node)
+		return
+	}
+	if t.gasMeter != nil {
+		t.gasMeter.ConsumeNodeWrite()
+	}
+	t.nodes[level].Append(node)
+}
+
+// hashChildren computes the hash of a list of children.
+func (t *IMT[N]) hashChildren(children []N) N {
+	if t.gasMeter != nil {
+		t.gasMeter.ConsumeHash(len(children))
+	}
+	return t.hash(children)
 }
@@ -0,0 +1,166 @@
+package imt_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+// uint64Codec encodes uint64 nodes as 8 big-endian bytes.
+type uint64Codec struct{}
+
+func (uint64Codec) Encode(value uint64) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, value), nil
+}
+
+func (uint64Codec) Decode(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, errors.New("invalid node")
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func TestValidateConfig(t *testing.T) {
+	// The largest depth of a binary tree whose capacity fits in an int.
+	maxBinaryDepth := bits.UintSize - 2
+
+	tests := []struct {
+		name  string
+		depth int
+		arity int
+		hash  imt.HashFunction[uint64]
+		valid bool
+	}{
+		{"binary", 20, 2, imttest.Uint64Hash, true},
+		{"largest binary", maxBinaryDepth, 2, imttest.Uint64Hash, true},
+		{"binary overflowing int", maxBinaryDepth + 1, 2, imttest.Uint64Hash, false},
+		{"quinary overflowing int", 40, 5, imttest.Uint64Hash, false},
+		{"unary", 100, 1, imttest.Uint64Hash, true},
+		{"no hash", 4, 2, nil, false},
+		{"zero depth", 0, 2, imttest.Uint64Hash, false},
+		{"negative depth", -1, 2, imttest.Uint64Hash, false},
+		{"zero arity", 4, 0, imttest.Uint64Hash, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := imt.ValidateConfig(test.depth, test.arity, test.hash)
+			if (err == nil) != test.valid {
+				t.Fatalf("ValidateConfig(%d, %d) = %v, want valid %v", test.depth, test.arity, err, test.valid)
+			}
+			if !test.valid {
+				return
+			}
+			// New must agree with ValidateConfig.
+			if _, err := imt.New(test.hash, test.depth, 0, test.arity, nil); err != nil {
+				t.Fatalf("New rejected a valid configuration: %v", err)
+			}
+		})
+	}
+}
+
+func TestNewChecksCapacity(t *testing.T) {
+	if _, err := imt.New(imttest.Uint64Hash, bits.UintSize-1, 0, 2, nil); err == nil {
+		t.Fatal("New accepted a capacity overflowing int")
+	}
+	if _, err := imt.New(imttest.Uint64Hash, 2, 0, 2, []uint64{1, 2, 3, 4, 5}); err == nil {
+		t.Fatal("New accepted more leaves than its capacity")
+	}
+	if _, err := imt.New(imttest.Uint64Hash, 2, 0, 2, []uint64{1, 2, 3, 4}); err != nil {
+		t.Fatalf("New rejected a full tree: %v", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("MustNew did not panic")
+			}
+		}()
+		imt.MustNew(imttest.Uint64Hash, bits.UintSize-1, 0, 2, nil)
+	}()
+}
+
+func TestNewWithCapacity(t *testing.T) {
+	tests := []struct {
+		arity     int
+		minLeaves int
+		headroom  float64
+		depth     int
+	}{
+		{2, 0, 0, 1},
+		{2, 1000, 0, 10},
+		{2, 1024, 0, 10},
+		{2, 1025, 0, 11},
+		{2, 1000, 2, 11},
+		{3, 10, 0, 3},
+		{1, 1, 0, 1},
+	}
+	for _, test := range tests {
+		tree, depth, err := imt.NewWithCapacity(imttest.Uint64Hash, 0, test.arity, test.minLeaves, imt.WithHeadroom(test.headroom))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if depth != test.depth || tree.Depth() != depth {
+			t.Fatalf("NewWithCapacity(%d, %d, %v) chose depth %d, want %d", test.arity, test.minLeaves, test.headroom, depth, test.depth)
+		}
+	}
+
+	impossible := []struct {
+		arity     int
+		minLeaves int
+	}{
+		{2, int(^uint(0) >> 1)},
+		{1, 2},
+	}
+	for _, test := range impossible {
+		if _, _, err := imt.NewWithCapacity(imttest.Uint64Hash, 0, test.arity, test.minLeaves); err == nil {
+			t.Fatalf("NewWithCapacity(%d, %d) accepted an impossible capacity", test.arity, test.minLeaves)
+		}
+	}
+}
+
+func TestAutoGrowStopsAtMaxDepth(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 1, 0, 2, nil, imt.WithAutoGrow(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 8 {
+		if err := tree.Insert(uint64(i + 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if tree.Depth() != 3 {
+		t.Fatalf("Depth = %d, want 3", tree.Depth())
+	}
+	if err := tree.Insert(9); !errors.Is(err, imt.ErrTreeFull) {
+		t.Fatalf("inserting beyond the maximum depth: got error %v", err)
+	}
+}
+
+func TestLargestIndex(t *testing.T) {
+	// The last position of the largest binary tree has the largest index.
+	tree, err := imt.New(imttest.Uint64Hash, bits.UintSize-2, 0, 2, []uint64{1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := int(tree.Capacity() - 1)
+	if last != 1<<(bits.UintSize-2)-1 {
+		t.Fatalf("Capacity = %d", tree.Capacity())
+	}
+	proof, err := tree.CreateProofForEmpty(last)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proof.LeafIndex != last || !tree.VerifyProof(proof) {
+		t.Fatal("the proof of the last position was rejected")
+	}
+	if err := proof.Canonicalize(); err != nil || proof.LeafIndex != last {
+		t.Fatalf("Canonicalize: leaf index %d, error %v", proof.LeafIndex, err)
+	}
+	if _, err := tree.CreateProofForEmpty(last + 1); err == nil {
+		t.Fatal("created a proof beyond the capacity")
+	}
+}
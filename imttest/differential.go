@@ -0,0 +1,87 @@
+package imttest
+
+import (
+	"fmt"
+	"math/rand/v2"
+
+	"github.com/noble-assets/imt"
+)
+
+// Mismatch describes a divergence between a tree and the reference tree.
+type Mismatch[N comparable] struct {
+	Step int   // The index of the operation after which they diverged.
+	Op   Op[N] // The operation after which they diverged.
+	Err  error // The description of the divergence.
+}
+
+func (m *Mismatch[N]) Error() string {
+	return fmt.Sprintf("step %d (%v): %v", m.Step, m.Op, m.Err)
+}
+
+func (m *Mismatch[N]) Unwrap() error {
+	return m.Err
+}
+
+// Differential applies operations to a tree and to a reference tree with the
+// same leaves, and compares them after each operation: both must accept or
+// reject the operation, have the same root, and produce the same proof for the
+// leaf targeted by the operation and for a leaf drawn from r. It returns a
+// *Mismatch for the first divergence.
+func Differential[N comparable](r *rand.Rand, t *imt.IMT[N], reference *Reference[N], ops []Op[N]) error {
+	for step, op := range ops {
+		mismatch := func(format string, args ...any) error {
+			return &Mismatch[N]{Step: step, Op: op, Err: fmt.Errorf(format, args...)}
+		}
+
+		treeErr, referenceErr := Apply(t, op), reference.Apply(op)
+		if (treeErr == nil) != (referenceErr == nil) {
+			return mismatch("the tree returned %v, the reference returned %v", treeErr, referenceErr)
+		}
+		if t.Root() != reference.Root() {
+			return mismatch("the root is %v, expected %v", t.Root(), reference.Root())
+		}
+		if t.Size() == 0 {
+			continue
+		}
+
+		indices := []int{r.IntN(t.Size())}
+		if op.Kind == Insert {
+			indices = append(indices, t.Size()-1)
+		} else if op.Index < t.Size() {
+			indices = append(indices, op.Index)
+		}
+		for _, index := range indices {
+			proof, err := t.CreateProof(index)
+			if err != nil {
+				return mismatch("the tree cannot prove leaf %d: %v", index, err)
+			}
+			expected, _ := reference.Proof(index)
+			expected.Depth, expected.Arity, expected.HashID = proof.Depth, proof.Arity, proof.HashID
+			if !proof.Equal(expected) {
+				return mismatch("the proof of leaf %d is %+v, expected %+v", index, proof, expected)
+			}
+			if !t.VerifyProof(proof) {
+				return mismatch("the proof of leaf %d does not verify", index)
+			}
+		}
+	}
+	return nil
+}
+
+// Fuzz runs Differential on a random tree with the parameters of the
+// configuration and the options, and n random operations, all derived from
+// the seed, so that a failure is reproduced by running Fuzz with the same
+// seed. The leaves are drawn from gen.
+func Fuzz[N comparable](seed uint64, config Config[N], n int, gen Generator[N], opts ...imt.Option) error {
+	r := Rand(seed)
+	size := r.IntN(int(min(config.Capacity(), 64)) + 1)
+	t, err := RandomTree(r, config, size, gen, opts...)
+	if err != nil {
+		return err
+	}
+	reference := NewReference(config, t.Leaves())
+	if err := Differential(r, t, reference, RandomOps(r, config, size, n, gen)); err != nil {
+		return fmt.Errorf("seed %d: %w", seed, err)
+	}
+	return nil
+}
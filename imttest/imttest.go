@@ -0,0 +1,207 @@
+// Package imttest provides helpers to write property tests against code that
+// uses incremental Merkle trees: seeded generators of random trees and
+// operation sequences, and a reference computation of the root that doesn't
+// rely on the incremental updates of the imt package.
+package imttest
+
+import (
+	"fmt"
+	"math/bits"
+	"math/rand/v2"
+
+	"github.com/noble-assets/imt"
+)
+
+// Config contains the parameters of a tree, as passed to imt.New.
+type Config[N comparable] struct {
+	Hash      imt.HashFunction[N]
+	Depth     int
+	ZeroValue N
+	Arity     int
+}
+
+// New creates a tree with the parameters of the configuration.
+func (c Config[N]) New(leaves []N, opts ...imt.Option) (*imt.IMT[N], error) {
+	return imt.New(c.Hash, c.Depth, c.ZeroValue, c.Arity, leaves, opts...)
+}
+
+// Capacity returns the maximum number of leaves of a tree with the parameters
+// of the configuration, saturating at math.MaxUint64.
+func (c Config[N]) Capacity() uint64 {
+	result := uint64(1)
+	for i := 0; i < c.Depth; i++ {
+		hi, lo := bits.Mul64(result, uint64(c.Arity))
+		if hi != 0 {
+			return ^uint64(0)
+		}
+		result = lo
+	}
+	return result
+}
+
+// Generator returns a random node.
+type Generator[N comparable] func(r *rand.Rand) N
+
+// Rand returns a deterministic source of randomness for the given seed, so
+// that a failing property test can be reproduced from its seed.
+func Rand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed))
+}
+
+// Uint64Hash is a deterministic, non-cryptographic hash function of uint64
+// nodes. It is much cheaper than a cryptographic hash function and sensitive
+// to the order of the children, which makes it suitable for property tests.
+func Uint64Hash(children []uint64) uint64 {
+	h := uint64(14695981039346656037)
+	for _, child := range children {
+		h ^= child
+		h *= 1099511628211
+		h ^= h >> 29
+	}
+	return h
+}
+
+// Uint64Leaf is a Generator of uint64 leaves, which are never 0 so that they
+// differ from the usual zero value.
+func Uint64Leaf(r *rand.Rand) uint64 {
+	return r.Uint64() | 1
+}
+
+// RandomLeaves returns n random leaves.
+func RandomLeaves[N comparable](r *rand.Rand, n int, gen Generator[N]) []N {
+	leaves := make([]N, n)
+	for i := range leaves {
+		leaves[i] = gen(r)
+	}
+	return leaves
+}
+
+// RandomTree returns a tree with the parameters of the configuration and size
+// random leaves.
+func RandomTree[N comparable](r *rand.Rand, config Config[N], size int, gen Generator[N], opts ...imt.Option) (*imt.IMT[N], error) {
+	return config.New(RandomLeaves(r, size, gen), opts...)
+}
+
+// OpKind is the kind of an operation on a tree.
+type OpKind int
+
+// The kinds of operations on a tree.
+const (
+	Insert OpKind = iota
+	Update
+	Delete
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case Insert:
+		return "insert"
+	case Update:
+		return "update"
+	case Delete:
+		return "delete"
+	default:
+		return fmt.Sprintf("OpKind(%d)", int(k))
+	}
+}
+
+// Op is an operation on a tree. Index is unused by insertions and Leaf is
+// unused by deletions.
+type Op[N comparable] struct {
+	Kind  OpKind
+	Index int
+	Leaf  N
+}
+
+func (op Op[N]) String() string {
+	switch op.Kind {
+	case Insert:
+		return fmt.Sprintf("insert(%v)", op.Leaf)
+	case Update:
+		return fmt.Sprintf("update(%d, %v)", op.Index, op.Leaf)
+	default:
+		return fmt.Sprintf("%v(%d)", op.Kind, op.Index)
+	}
+}
+
+// RandomOps returns n random operations that are valid when applied in order
+// to a tree of the given size: updates and deletions target existing leaves
+// and insertions never exceed the capacity of the tree.
+func RandomOps[N comparable](r *rand.Rand, config Config[N], size, n int, gen Generator[N]) []Op[N] {
+	capacity := config.Capacity()
+	ops := make([]Op[N], 0, n)
+	for len(ops) < n {
+		kind := OpKind(r.IntN(3))
+		switch {
+		case capacity == 0:
+			return ops
+		case size == 0:
+			kind = Insert
+		case uint64(size) >= capacity && kind == Insert:
+			kind = Update
+		}
+
+		op := Op[N]{Kind: kind}
+		switch kind {
+		case Insert:
+			op.Leaf = gen(r)
+			size++
+		case Update:
+			op.Index = r.IntN(size)
+			op.Leaf = gen(r)
+		case Delete:
+			op.Index = r.IntN(size)
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Apply applies an operation to a tree.
+func Apply[N comparable](t *imt.IMT[N], op Op[N]) error {
+	switch op.Kind {
+	case Insert:
+		return t.Insert(op.Leaf)
+	case Update:
+		return t.Update(op.Index, op.Leaf)
+	case Delete:
+		return t.Delete(op.Index)
+	default:
+		return fmt.Errorf("unknown operation %v", op.Kind)
+	}
+}
+
+// ApplyAll applies operations to a tree in order, stopping at the first
+// error, which is wrapped with the index of the operation.
+func ApplyAll[N comparable](t *imt.IMT[N], ops []Op[N]) error {
+	for i, op := range ops {
+		if err := Apply(t, op); err != nil {
+			return fmt.Errorf("operation %d (%v): %w", i, op, err)
+		}
+	}
+	return nil
+}
+
+// ApplyToLeaves returns the leaves of a tree after an operation, given its
+// leaves before the operation, without using a tree.
+func ApplyToLeaves[N comparable](config Config[N], leaves []N, op Op[N]) ([]N, error) {
+	switch op.Kind {
+	case Insert:
+		if uint64(len(leaves)) >= config.Capacity() {
+			return nil, imt.ErrTreeFull
+		}
+		return append(leaves, op.Leaf), nil
+	case Update, Delete:
+		if op.Index < 0 || op.Index >= len(leaves) {
+			return nil, imt.ErrLeafNotFound
+		}
+		if op.Kind == Update {
+			leaves[op.Index] = op.Leaf
+		} else {
+			leaves[op.Index] = config.ZeroValue
+		}
+		return leaves, nil
+	default:
+		return nil, fmt.Errorf("unknown operation %v", op.Kind)
+	}
+}
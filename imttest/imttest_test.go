@@ -0,0 +1,96 @@
+package imttest_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestGeneratorsAreDeterministic(t *testing.T) {
+	config := imttest.Config[uint64]{Hash: imttest.Uint64Hash, Depth: 4, Arity: 3}
+	first := imttest.RandomLeaves(imttest.Rand(7), 20, imttest.Uint64Leaf)
+	if again := imttest.RandomLeaves(imttest.Rand(7), 20, imttest.Uint64Leaf); !slices.Equal(first, again) {
+		t.Fatal("the leaves of the same seed differ")
+	}
+	if other := imttest.RandomLeaves(imttest.Rand(8), 20, imttest.Uint64Leaf); slices.Equal(first, other) {
+		t.Fatal("the leaves of different seeds are equal")
+	}
+	if slices.Contains(first, 0) {
+		t.Fatal("a generated leaf is the zero value")
+	}
+
+	ops := imttest.RandomOps(imttest.Rand(7), config, 5, 50, imttest.Uint64Leaf)
+	if again := imttest.RandomOps(imttest.Rand(7), config, 5, 50, imttest.Uint64Leaf); !slices.Equal(ops, again) {
+		t.Fatal("the operations of the same seed differ")
+	}
+}
+
+func TestCapacity(t *testing.T) {
+	tests := []struct {
+		depth, arity int
+		want         uint64
+	}{
+		{1, 2, 2},
+		{10, 2, 1024},
+		{4, 3, 81},
+		{100, 1, 1},
+		{64, 2, ^uint64(0)},
+		{40, 5, ^uint64(0)},
+	}
+	for _, test := range tests {
+		config := imttest.Config[uint64]{Depth: test.depth, Arity: test.arity}
+		if got := config.Capacity(); got != test.want {
+			t.Errorf("Capacity of depth %d and arity %d = %d, want %d", test.depth, test.arity, got, test.want)
+		}
+	}
+}
+
+func TestRandomOpsAreValid(t *testing.T) {
+	tests := []struct {
+		depth, arity, size int
+	}{
+		{1, 2, 0},
+		{2, 2, 4},
+		{3, 3, 10},
+		{4, 2, 0},
+		{2, 5, 25},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("depth %d arity %d size %d", test.depth, test.arity, test.size), func(t *testing.T) {
+			config := imttest.Config[uint64]{Hash: imttest.Uint64Hash, Depth: test.depth, Arity: test.arity}
+			r := imttest.Rand(uint64(test.size))
+			tree, err := imttest.RandomTree(r, config, test.size, imttest.Uint64Leaf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			leaves := tree.Leaves()
+			ops := imttest.RandomOps(r, config, test.size, 100, imttest.Uint64Leaf)
+			if len(ops) != 100 {
+				t.Fatalf("got %d operations, want 100", len(ops))
+			}
+			for i, op := range ops {
+				if err := imttest.Apply(tree, op); err != nil {
+					t.Fatalf("operation %d (%v): %v", i, op, err)
+				}
+				if leaves, err = imttest.ApplyToLeaves(config, leaves, op); err != nil {
+					t.Fatalf("operation %d (%v): %v", i, op, err)
+				}
+				if !slices.Equal(tree.Leaves(), leaves) || tree.Root() != imttest.ReferenceRoot(config, leaves) {
+					t.Fatalf("operation %d (%v): the tree differs from its leaves", i, op)
+				}
+			}
+		})
+	}
+
+	config := imttest.Config[uint64]{Hash: imttest.Uint64Hash, Depth: 1, Arity: 2}
+	if _, err := imttest.ApplyToLeaves(config, []uint64{1, 2}, imttest.Op[uint64]{Kind: imttest.Insert, Leaf: 3}); !errors.Is(err, imt.ErrTreeFull) {
+		t.Fatalf("inserting in a full tree: got error %v", err)
+	}
+	if _, err := imttest.ApplyToLeaves(config, []uint64{1}, imttest.Op[uint64]{Kind: imttest.Delete, Index: 1}); !errors.Is(err, imt.ErrLeafNotFound) {
+		t.Fatalf("deleting a missing leaf: got error %v", err)
+	}
+}
@@ -0,0 +1,112 @@
+package imttest
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/noble-assets/imt"
+)
+
+// Mutation is a corrupted copy of a valid proof, which every verifier must
+// reject.
+type Mutation[N comparable] struct {
+	Name  string              // The corruption applied, e.g. "sibling/level=2/index=0".
+	Proof *imt.MerkleProof[N] // The corrupted proof.
+}
+
+// Mutations returns the corruptions of a valid proof, covering every class of
+// corruption that a verifier must detect:
+//
+//   - "sibling": one sibling is altered, for every sibling of the proof.
+//   - "path-index": the path index of one level is changed to the next
+//     position, for every level of a tree with an arity greater than 1.
+//   - "truncate": the last level is removed.
+//   - "extend": a level is added above the root.
+//   - "leaf": the leaf is altered.
+//   - "swap-leaf": the leaf is swapped with its first sibling, unless they are
+//     equal.
+//   - "root": the root is altered.
+//
+// alter must return a node different from the one it is given, e.g. by
+// flipping a bit. The leaf index of the corrupted proofs is kept consistent
+// with their path indices, so that they are not trivially rejected by a
+// layout check.
+func Mutations[N comparable](proof *imt.MerkleProof[N], alter func(N) N) []Mutation[N] {
+	var mutations []Mutation[N]
+	add := func(name string, mutate func(p *imt.MerkleProof[N])) {
+		p := cloneProof(proof)
+		mutate(p)
+		_ = p.Canonicalize()
+		mutations = append(mutations, Mutation[N]{Name: name, Proof: p})
+	}
+
+	for level, siblings := range proof.Siblings {
+		for i := range siblings {
+			add(fmt.Sprintf("sibling/level=%d/index=%d", level, i), func(p *imt.MerkleProof[N]) {
+				p.Siblings[level][i] = alter(p.Siblings[level][i])
+			})
+		}
+		if arity := len(siblings) + 1; arity > 1 {
+			add(fmt.Sprintf("path-index/level=%d", level), func(p *imt.MerkleProof[N]) {
+				p.PathIndices[level] = (p.PathIndices[level] + 1) % arity
+			})
+		}
+	}
+	if len(proof.Siblings) > 0 {
+		add("truncate", func(p *imt.MerkleProof[N]) {
+			p.Siblings = p.Siblings[:len(p.Siblings)-1]
+			p.PathIndices = p.PathIndices[:len(p.PathIndices)-1]
+		})
+		add("extend", func(p *imt.MerkleProof[N]) {
+			siblings := make([]N, len(p.Siblings[0]))
+			for i := range siblings {
+				siblings[i] = alter(p.Root)
+			}
+			p.Siblings = append(p.Siblings, siblings)
+			p.PathIndices = append(p.PathIndices, 0)
+		})
+	}
+	add("leaf", func(p *imt.MerkleProof[N]) {
+		p.Leaf = alter(p.Leaf)
+	})
+	if len(proof.Siblings) > 0 && len(proof.Siblings[0]) > 0 && proof.Siblings[0][0] != proof.Leaf {
+		add("swap-leaf", func(p *imt.MerkleProof[N]) {
+			p.Leaf, p.Siblings[0][0] = p.Siblings[0][0], p.Leaf
+		})
+	}
+	add("root", func(p *imt.MerkleProof[N]) {
+		p.Root = alter(p.Root)
+	})
+
+	return mutations
+}
+
+// CheckRejects checks that verify accepts a valid proof and rejects every
+// mutation of it, returning an error listing the accepted mutations.
+func CheckRejects[N comparable](proof *imt.MerkleProof[N], alter func(N) N, verify func(*imt.MerkleProof[N]) bool) error {
+	if !verify(proof) {
+		return errors.New("the valid proof is rejected")
+	}
+	var accepted []string
+	for _, mutation := range Mutations(proof, alter) {
+		if verify(mutation.Proof) {
+			accepted = append(accepted, mutation.Name)
+		}
+	}
+	if len(accepted) > 0 {
+		return fmt.Errorf("corrupted proofs are accepted: %v", accepted)
+	}
+	return nil
+}
+
+// cloneProof returns a deep copy of a proof.
+func cloneProof[N comparable](proof *imt.MerkleProof[N]) *imt.MerkleProof[N] {
+	clone := *proof
+	clone.Siblings = make([][]N, len(proof.Siblings))
+	for level, siblings := range proof.Siblings {
+		clone.Siblings[level] = slices.Clone(siblings)
+	}
+	clone.PathIndices = slices.Clone(proof.PathIndices)
+	return &clone
+}
@@ -0,0 +1,116 @@
+package imttest
+
+import (
+	"math/bits"
+	"slices"
+
+	"github.com/noble-assets/imt"
+)
+
+// Reference is a reference Merkle tree that stores only its leaves and
+// recomputes every node from them, recursively, whenever it is needed. It
+// doesn't rely on any of the incremental techniques of the imt package, so
+// comparing the two detects bugs in the incremental updates.
+type Reference[N comparable] struct {
+	config Config[N]
+	leaves []N
+	zeroes []N
+}
+
+// NewReference returns a reference tree with the given leaves.
+func NewReference[N comparable](config Config[N], leaves []N) *Reference[N] {
+	zeroes := make([]N, config.Depth+1)
+	zeroes[0] = config.ZeroValue
+	for level := 1; level <= config.Depth; level++ {
+		children := make([]N, config.Arity)
+		for i := range children {
+			children[i] = zeroes[level-1]
+		}
+		zeroes[level] = config.Hash(children)
+	}
+	return &Reference[N]{config: config, leaves: slices.Clone(leaves), zeroes: zeroes}
+}
+
+// ReferenceRoot computes the root of a tree from its leaves with a reference
+// tree.
+func ReferenceRoot[N comparable](config Config[N], leaves []N) N {
+	return NewReference(config, leaves).Root()
+}
+
+// Leaves returns a copy of the leaves of the tree.
+func (r *Reference[N]) Leaves() []N {
+	return slices.Clone(r.leaves)
+}
+
+// Apply applies an operation to the tree.
+func (r *Reference[N]) Apply(op Op[N]) error {
+	leaves, err := ApplyToLeaves(r.config, r.leaves, op)
+	if err != nil {
+		return err
+	}
+	r.leaves = leaves
+	return nil
+}
+
+// Root returns the root of the tree.
+func (r *Reference[N]) Root() N {
+	return r.node(r.config.Depth, 0)
+}
+
+// Proof returns the proof of a leaf, with the siblings recomputed from the
+// leaves.
+func (r *Reference[N]) Proof(index int) (*imt.MerkleProof[N], error) {
+	if index < 0 || index >= len(r.leaves) {
+		return nil, imt.ErrLeafNotFound
+	}
+	proof := &imt.MerkleProof[N]{
+		Root:      r.Root(),
+		Leaf:      r.leaves[index],
+		LeafIndex: index,
+	}
+	for level := 0; level < r.config.Depth; level++ {
+		position := index % r.config.Arity
+		var siblings []N
+		for i := 0; i < r.config.Arity; i++ {
+			if i != position {
+				siblings = append(siblings, r.node(level, index-position+i))
+			}
+		}
+		proof.Siblings = append(proof.Siblings, siblings)
+		proof.PathIndices = append(proof.PathIndices, position)
+		index /= r.config.Arity
+	}
+	return proof, nil
+}
+
+// node computes the node at the given position from the leaves, only taking
+// the shortcut of using the zero value of a level for subtrees without any
+// leaf.
+func (r *Reference[N]) node(level, index int) N {
+	if level == 0 {
+		if index < len(r.leaves) {
+			return r.leaves[index]
+		}
+		return r.config.ZeroValue
+	}
+
+	// The first leaf below the node, which overflows only for subtrees that
+	// are beyond the last leaf.
+	first := uint64(index)
+	for i := 0; i < level; i++ {
+		hi, lo := bits.Mul64(first, uint64(r.config.Arity))
+		if hi != 0 {
+			return r.zeroes[level]
+		}
+		first = lo
+	}
+	if first >= uint64(len(r.leaves)) {
+		return r.zeroes[level]
+	}
+
+	children := make([]N, r.config.Arity)
+	for i := range children {
+		children[i] = r.node(level-1, index*r.config.Arity+i)
+	}
+	return r.config.Hash(children)
+}
@@ -0,0 +1,183 @@
+package imttest
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/noble-assets/imt"
+)
+
+// Failure is a failing scenario: a tree created with some leaves, to which a
+// sequence of operations is applied.
+type Failure[N comparable] struct {
+	Leaves []N     // The leaves of the tree before the operations.
+	Ops    []Op[N] // The operations applied to the tree.
+	Err    error   // The error reported by the check of the scenario.
+}
+
+// Check runs a scenario and returns an error if it fails.
+type Check[N comparable] func(leaves []N, ops []Op[N]) error
+
+// ShrinkUint64 returns candidates smaller than v to simplify a uint64 value
+// during shrinking.
+func ShrinkUint64(v uint64) []uint64 {
+	var candidates []uint64
+	for _, c := range []uint64{0, 1, v / 2, v - 1} {
+		if c < v && !slices.Contains(candidates, c) {
+			candidates = append(candidates, c)
+		}
+	}
+	return candidates
+}
+
+// Shrink reduces a failing scenario to a minimal one that still fails the
+// check: it removes as many operations and initial leaves as possible, then
+// replaces the leaves with the simpler candidates returned by simplify, which
+// may be nil, and the indices with smaller ones, until no change keeps the
+// scenario failing. The check must be deterministic.
+func Shrink[N comparable](failure Failure[N], check Check[N], simplify func(N) []N) Failure[N] {
+	current := failure
+	try := func(leaves []N, ops []Op[N]) bool {
+		if err := check(leaves, ops); err != nil {
+			current = Failure[N]{Leaves: leaves, Ops: ops, Err: err}
+			return true
+		}
+		return false
+	}
+
+	for progress := true; progress; {
+		progress = false
+
+		// Remove chunks of operations, then of initial leaves, from the
+		// largest to single elements.
+		for size := len(current.Ops); size > 0; size /= 2 {
+			for start := 0; start+size <= len(current.Ops); {
+				if try(current.Leaves, slices.Delete(slices.Clone(current.Ops), start, start+size)) {
+					progress = true
+				} else {
+					start += size
+				}
+			}
+		}
+		for size := len(current.Leaves); size > 0; size /= 2 {
+			for start := 0; start+size <= len(current.Leaves); {
+				if try(slices.Delete(slices.Clone(current.Leaves), start, start+size), current.Ops) {
+					progress = true
+				} else {
+					start += size
+				}
+			}
+		}
+
+		// Simplify the values and the indices.
+		if simplify != nil {
+			for i := range current.Leaves {
+				for _, candidate := range simplify(current.Leaves[i]) {
+					leaves := slices.Clone(current.Leaves)
+					leaves[i] = candidate
+					if try(leaves, current.Ops) {
+						progress = true
+						break
+					}
+				}
+			}
+			for i := range current.Ops {
+				if current.Ops[i].Kind == Delete {
+					continue
+				}
+				for _, candidate := range simplify(current.Ops[i].Leaf) {
+					ops := slices.Clone(current.Ops)
+					ops[i].Leaf = candidate
+					if try(current.Leaves, ops) {
+						progress = true
+						break
+					}
+				}
+			}
+		}
+		for i := range current.Ops {
+			if current.Ops[i].Kind == Insert {
+				continue
+			}
+			for _, candidate := range []int{0, current.Ops[i].Index / 2} {
+				if candidate >= current.Ops[i].Index {
+					continue
+				}
+				ops := slices.Clone(current.Ops)
+				ops[i].Index = candidate
+				if try(current.Leaves, ops) {
+					progress = true
+					break
+				}
+			}
+		}
+	}
+
+	return current
+}
+
+// DifferentialCheck returns a Check that runs Differential on a tree with the
+// parameters of the configuration and the options, with the randomness of the
+// proofs it compares derived from the seed.
+func DifferentialCheck[N comparable](seed uint64, config Config[N], opts ...imt.Option) Check[N] {
+	return func(leaves []N, ops []Op[N]) error {
+		t, err := config.New(leaves, opts...)
+		if err != nil {
+			return err
+		}
+		return Differential(Rand(seed), t, NewReference(config, leaves), ops)
+	}
+}
+
+// FuzzAndShrink runs the scenario of Fuzz for the seed and, if it fails,
+// returns its shrunk version, or nil if it succeeds.
+func FuzzAndShrink[N comparable](seed uint64, config Config[N], n int, gen Generator[N], simplify func(N) []N, opts ...imt.Option) *Failure[N] {
+	r := Rand(seed)
+	size := r.IntN(int(min(config.Capacity(), 64)) + 1)
+	leaves := RandomLeaves(r, size, gen)
+	ops := RandomOps(r, config, size, n, gen)
+
+	check := DifferentialCheck(seed, config, opts...)
+	err := check(leaves, ops)
+	if err == nil {
+		return nil
+	}
+	shrunk := Shrink(Failure[N]{Leaves: leaves, Ops: ops, Err: err}, check, simplify)
+	return &shrunk
+}
+
+// Snippet returns Go code declaring the leaves and the operations of the
+// failure, to be pasted into a test that replays it. format returns the Go
+// expression of a node, e.g. strconv.FormatUint for uint64 nodes, or %#v if
+// it is nil.
+func (f Failure[N]) Snippet(format func(N) string) string {
+	if format == nil {
+		format = func(node N) string { return fmt.Sprintf("%#v", node) }
+	}
+	typ := fmt.Sprintf("%T", *new(N))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %v\n", f.Err)
+	fmt.Fprintf(&b, "leaves := []%s{", typ)
+	for i, leaf := range f.Leaves {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(format(leaf))
+	}
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "ops := []imttest.Op[%s]{\n", typ)
+	for _, op := range f.Ops {
+		switch op.Kind {
+		case Insert:
+			fmt.Fprintf(&b, "\t{Kind: imttest.Insert, Leaf: %s},\n", format(op.Leaf))
+		case Update:
+			fmt.Fprintf(&b, "\t{Kind: imttest.Update, Index: %d, Leaf: %s},\n", op.Index, format(op.Leaf))
+		default:
+			fmt.Fprintf(&b, "\t{Kind: imttest.Delete, Index: %d},\n", op.Index)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
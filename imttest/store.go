@@ -0,0 +1,164 @@
+package imttest
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the error returned by the operations of a FaultyStore that
+// fail on command.
+var ErrInjected = errors.New("injected fault")
+
+// FaultyStore is an in-memory key-value store that fails, slows down or
+// stops writing on command, to test deterministically how an application
+// persisting trees, e.g. their levels encoded with the codecs of the imt
+// package, recovers from storage faults. Its methods have the signatures of
+// the methods of the same name of the cosmossdk.io/core/store KVStore. It is
+// safe for concurrent use.
+type FaultyStore struct {
+	mu      sync.Mutex
+	data    map[string][]byte
+	latency time.Duration
+
+	failReads     int // The number of upcoming reads that fail.
+	failWrites    int // The number of upcoming writes that fail.
+	writesLeft    int // The number of writes before all writes fail, or -1.
+	reads, writes int // The number of successful reads and writes.
+}
+
+// NewFaultyStore returns an empty store without any fault.
+func NewFaultyStore() *FaultyStore {
+	return &FaultyStore{data: make(map[string][]byte), writesLeft: -1}
+}
+
+// FailReads makes the next n reads fail with ErrInjected.
+func (s *FaultyStore) FailReads(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failReads = n
+}
+
+// FailWrites makes the next n writes fail with ErrInjected.
+func (s *FaultyStore) FailWrites(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failWrites = n
+}
+
+// CrashAfter lets the next n writes succeed and makes every following write
+// fail with ErrInjected, simulating a crash in the middle of a batch of
+// writes that leaves it partially applied. A negative n disables the crash.
+func (s *FaultyStore) CrashAfter(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.writesLeft = n
+}
+
+// SetLatency makes every operation sleep for the given duration.
+func (s *FaultyStore) SetLatency(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = latency
+}
+
+// Reset removes every fault and the latency, keeping the data.
+func (s *FaultyStore) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failReads, s.failWrites, s.writesLeft, s.latency = 0, 0, -1, 0
+}
+
+// Counts returns the number of successful reads and writes so far.
+func (s *FaultyStore) Counts() (reads, writes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.reads, s.writes
+}
+
+// Get returns the value of a key, or nil if the key does not exist.
+func (s *FaultyStore) Get(key []byte) ([]byte, error) {
+	if err := s.read(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.data[string(key)]), nil
+}
+
+// Has returns whether a key exists.
+func (s *FaultyStore) Has(key []byte) (bool, error) {
+	if err := s.read(); err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.data[string(key)]
+	return ok, nil
+}
+
+// Set sets the value of a key.
+func (s *FaultyStore) Set(key, value []byte) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[string(key)] = slices.Clone(value)
+	return nil
+}
+
+// Delete removes a key.
+func (s *FaultyStore) Delete(key []byte) error {
+	if err := s.write(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, string(key))
+	return nil
+}
+
+// read applies the latency and the faults of a read.
+func (s *FaultyStore) read() error {
+	s.mu.Lock()
+	latency := s.latency
+	fail := s.failReads > 0
+	if fail {
+		s.failReads--
+	} else {
+		s.reads++
+	}
+	s.mu.Unlock()
+
+	time.Sleep(latency)
+	if fail {
+		return ErrInjected
+	}
+	return nil
+}
+
+// write applies the latency and the faults of a write.
+func (s *FaultyStore) write() error {
+	s.mu.Lock()
+	latency := s.latency
+	fail := s.failWrites > 0 || s.writesLeft == 0
+	switch {
+	case s.failWrites > 0:
+		s.failWrites--
+	case s.writesLeft == 0:
+	default:
+		if s.writesLeft > 0 {
+			s.writesLeft--
+		}
+		s.writes++
+	}
+	s.mu.Unlock()
+
+	time.Sleep(latency)
+	if fail {
+		return ErrInjected
+	}
+	return nil
+}
@@ -0,0 +1,193 @@
+package imttest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+
+	"github.com/noble-assets/imt"
+)
+
+// ConcurrentTree is the method set of a tree that is safe for concurrent
+// use, such as a wrapper around an imt.IMT, exercised by Stress.
+type ConcurrentTree[N comparable] interface {
+	Insert(leaf N) error
+	Update(index int, leaf N) error
+	Delete(index int) error
+	CreateProof(index int) (*imt.MerkleProof[N], error)
+	Root() N
+	Size() int
+	Leaves() []N
+}
+
+// Locked is the simplest ConcurrentTree: an imt.IMT guarded by a mutex. It
+// serves as a baseline for Stress.
+type Locked[N comparable] struct {
+	mu   sync.RWMutex
+	tree *imt.IMT[N]
+}
+
+// NewLocked returns a Locked tree wrapping t, which must not be used directly
+// anymore.
+func NewLocked[N comparable](t *imt.IMT[N]) *Locked[N] {
+	return &Locked[N]{tree: t}
+}
+
+var _ imt.MerkleTree[int] = (*Locked[int])(nil)
+
+func (l *Locked[N]) Insert(leaf N) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tree.Insert(leaf)
+}
+
+func (l *Locked[N]) Update(index int, leaf N) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tree.Update(index, leaf)
+}
+
+func (l *Locked[N]) Delete(index int) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tree.Delete(index)
+}
+
+func (l *Locked[N]) CreateProof(index int) (*imt.MerkleProof[N], error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tree.CreateProof(index)
+}
+
+func (l *Locked[N]) VerifyProof(proof *imt.MerkleProof[N]) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.tree.VerifyProof(proof)
+}
+
+func (l *Locked[N]) Root() N {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.tree.Root()
+}
+
+func (l *Locked[N]) Size() int {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.tree.Size()
+}
+
+func (l *Locked[N]) Leaves() []N {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.tree.Leaves()
+}
+
+// StressConfig configures Stress. The ratios are the relative frequencies of
+// the operations; for example, inserts 1 and proofs 3 make a quarter of the
+// operations insertions and the rest proofs.
+type StressConfig struct {
+	Goroutines int    // The number of concurrent goroutines.
+	Ops        int    // The number of operations of each goroutine.
+	Seed       uint64 // The seed from which the randomness of each goroutine is derived.
+
+	Inserts int
+	Updates int
+	Deletes int
+	Proofs  int
+	Roots   int
+}
+
+// StressReport counts the operations performed by Stress.
+type StressReport struct {
+	Inserts, Updates, Deletes, Proofs, Roots int
+}
+
+// Stress hammers a tree with concurrent insertions, updates, deletions, proof
+// generations and root reads, with the given ratios, and is meant to run under
+// the race detector. The tree must be empty or contain leaves, and must be a
+// tree with the parameters of the configuration. It checks that every proof
+// verifies and that, once all the goroutines are done, the root is the
+// reference root of the final leaves. Insertions into a full tree are
+// counted but not reported as errors.
+func Stress[N comparable](t ConcurrentTree[N], config Config[N], stress StressConfig, gen Generator[N]) (StressReport, error) {
+	total := stress.Inserts + stress.Updates + stress.Deletes + stress.Proofs + stress.Roots
+	if stress.Goroutines <= 0 || total <= 0 {
+		return StressReport{}, errors.New("the number of goroutines and the sum of the ratios must be positive")
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		report StressReport
+		errs   []error
+	)
+	for g := 0; g < stress.Goroutines; g++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			var local StressReport
+			var err error
+			for i := 0; i < stress.Ops && err == nil; i++ {
+				n := r.IntN(total)
+				switch {
+				case n < stress.Inserts:
+					local.Inserts++
+					if insertErr := t.Insert(gen(r)); insertErr != nil && uint64(t.Size()) < config.Capacity() {
+						err = fmt.Errorf("insert: %w", insertErr)
+					}
+				case n < stress.Inserts+stress.Updates:
+					local.Updates++
+					if size := t.Size(); size > 0 {
+						if updateErr := t.Update(r.IntN(size), gen(r)); updateErr != nil {
+							err = fmt.Errorf("update: %w", updateErr)
+						}
+					}
+				case n < stress.Inserts+stress.Updates+stress.Deletes:
+					local.Deletes++
+					if size := t.Size(); size > 0 {
+						if deleteErr := t.Delete(r.IntN(size)); deleteErr != nil {
+							err = fmt.Errorf("delete: %w", deleteErr)
+						}
+					}
+				case n < total-stress.Roots:
+					local.Proofs++
+					if size := t.Size(); size > 0 {
+						index := r.IntN(size)
+						proof, proofErr := t.CreateProof(index)
+						switch {
+						case proofErr != nil:
+							err = fmt.Errorf("proof of leaf %d: %w", index, proofErr)
+						case !imt.VerifyProof(proof, config.Hash):
+							err = fmt.Errorf("the proof of leaf %d does not verify", index)
+						}
+					}
+				default:
+					local.Roots++
+					t.Root()
+				}
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Inserts += local.Inserts
+			report.Updates += local.Updates
+			report.Deletes += local.Deletes
+			report.Proofs += local.Proofs
+			report.Roots += local.Roots
+			if err != nil {
+				errs = append(errs, err)
+			}
+		}(Rand(stress.Seed + uint64(g)))
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return report, errors.Join(errs...)
+	}
+	if root, expected := t.Root(), ReferenceRoot(config, t.Leaves()); root != expected {
+		return report, fmt.Errorf("the final root is %v, expected %v", root, expected)
+	}
+	return report, nil
+}
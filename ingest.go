@@ -0,0 +1,212 @@
+package imt
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrBusy is returned by TrySubmit when the ingestion queue is full.
+var ErrBusy = errors.New("the ingestion queue is full")
+
+// ErrClosed is returned by the methods of an Ingester that was closed, or
+// whose context is done.
+var ErrClosed = errors.New("the ingester is closed")
+
+// Ingester inserts leaves submitted from any goroutine into a tree, in the
+// order they are submitted, in batches applied with InsertMany. At most a
+// fixed number of leaves can wait to be inserted: beyond it, Submit blocks and
+// TrySubmit returns ErrBusy, so a producer outpacing the hashing is slowed
+// down instead of buffering without bound.
+//
+// While the Ingester runs, the tree must only be accessed through View. Once
+// Close returns, the tree can be used directly again.
+type Ingester[N comparable] struct {
+	tree      *IMT[N]
+	queue     chan N
+	batchSize int
+
+	// flushes receives the channels closed once the queue is drained, stop is
+	// closed by Close and done is closed when the ingestion goroutine returns.
+	flushes chan chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	// lifecycle is held for reading while leaves are submitted, so that no
+	// leaf is queued after Close drained the queue.
+	lifecycle sync.RWMutex
+	closed    bool
+
+	// mu guards the tree and err.
+	mu  sync.Mutex
+	err error
+}
+
+// NewIngester returns an Ingester inserting at most batchSize leaves at once
+// into the tree, with room for limit leaves waiting to be inserted. It stops
+// when ctx is done, leaving the leaves still queued uninserted.
+func NewIngester[N comparable](ctx context.Context, tree *IMT[N], limit, batchSize int) (*Ingester[N], error) {
+	if limit <= 0 || batchSize <= 0 {
+		return nil, errors.New("limit and batch size must be positive")
+	}
+	in := &Ingester[N]{
+		tree:      tree,
+		queue:     make(chan N, limit),
+		batchSize: batchSize,
+		flushes:   make(chan chan struct{}),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go in.run(ctx)
+	return in, nil
+}
+
+// Submit queues a leaf, blocking while the queue is full, until ctx is done.
+// It returns the error of a previous batch if one failed, since the leaves
+// that follow a failed batch are not inserted, and ErrClosed once the
+// Ingester is closed.
+func (in *Ingester[N]) Submit(ctx context.Context, leaf N) error {
+	in.lifecycle.RLock()
+	defer in.lifecycle.RUnlock()
+	if err := in.check(); err != nil {
+		return err
+	}
+	select {
+	case in.queue <- leaf:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-in.done:
+		return ErrClosed
+	}
+}
+
+// TrySubmit queues a leaf, or returns ErrBusy if the queue is full.
+func (in *Ingester[N]) TrySubmit(leaf N) error {
+	in.lifecycle.RLock()
+	defer in.lifecycle.RUnlock()
+	if err := in.check(); err != nil {
+		return err
+	}
+	select {
+	case in.queue <- leaf:
+		return nil
+	default:
+		return ErrBusy
+	}
+}
+
+// check returns the error preventing leaves from being submitted, if any.
+func (in *Ingester[N]) check() error {
+	if in.closed {
+		return ErrClosed
+	}
+	select {
+	case <-in.done:
+		return ErrClosed
+	default:
+	}
+	return in.Err()
+}
+
+// Flush blocks until every leaf submitted before the call is inserted, and
+// returns the error of the first batch that failed, if any. It returns
+// ErrClosed if the context of the Ingester is done before the leaves are
+// inserted.
+func (in *Ingester[N]) Flush() error {
+	flushed := make(chan struct{})
+	select {
+	case in.flushes <- flushed:
+	case <-in.done:
+		return ErrClosed
+	}
+	select {
+	case <-flushed:
+		return in.Err()
+	case <-in.done:
+		return ErrClosed
+	}
+}
+
+// Close stops accepting leaves, inserts the leaves already queued and stops
+// the ingestion goroutine. Once it returns, every leaf whose submission
+// succeeded is in the tree, unless a batch failed, in which case Close returns
+// its error and the leaves after it are discarded. The tree is only held in
+// memory: persisting it, e.g. with Snapshot, is left to the caller, who can
+// access the tree directly after Close. Closing an Ingester again, or one
+// whose context is done, returns ErrClosed.
+func (in *Ingester[N]) Close() error {
+	in.lifecycle.Lock()
+	if in.closed {
+		in.lifecycle.Unlock()
+		return ErrClosed
+	}
+	in.closed = true
+	in.lifecycle.Unlock()
+
+	err := in.Flush()
+	close(in.stop)
+	<-in.done
+	return err
+}
+
+// Pending returns the number of leaves waiting to be inserted.
+func (in *Ingester[N]) Pending() int {
+	return len(in.queue)
+}
+
+// Err returns the error of the first batch that failed, if any.
+func (in *Ingester[N]) Err() error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return in.err
+}
+
+// View calls f with the tree between two batches, so that the tree can be
+// read, e.g. to compute proofs, while the Ingester runs.
+func (in *Ingester[N]) View(f func(tree *IMT[N])) {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	f(in.tree)
+}
+
+// run inserts the queued leaves until ctx is done or the Ingester is closed.
+// After a batch fails, the following leaves are discarded.
+func (in *Ingester[N]) run(ctx context.Context) {
+	defer close(in.done)
+	batch := make([]N, 0, in.batchSize)
+	for {
+		select {
+		case leaf := <-in.queue:
+			in.insert(append(batch[:0], leaf))
+		case flushed := <-in.flushes:
+			for len(in.queue) > 0 {
+				in.insert(batch[:0])
+			}
+			close(flushed)
+		case <-in.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// insert completes a batch with the queued leaves and inserts it.
+func (in *Ingester[N]) insert(batch []N) {
+drain:
+	for len(batch) < in.batchSize {
+		select {
+		case leaf := <-in.queue:
+			batch = append(batch, leaf)
+		default:
+			break drain
+		}
+	}
+
+	in.mu.Lock()
+	if in.err == nil {
+		in.err = in.tree.InsertMany(batch)
+	}
+	in.mu.Unlock()
+}
@@ -0,0 +1,36 @@
+package imt
+
+// RootReader is the read-only view of a tree needed by code that only
+// follows its root, such as a relayer publishing it.
+type RootReader[N comparable] interface {
+	// Root returns the root of the tree.
+	Root() N
+	// Size returns the number of leaves of the tree.
+	Size() int
+}
+
+// Prover is the read-only view of a tree needed by code that serves and
+// checks its proofs. Code that accepts a Prover instead of an *IMT can be
+// tested with a fake, and used with wrappers of the tree such as the
+// Locked tree of the imttest package.
+type Prover[N comparable] interface {
+	RootReader[N]
+	// CreateProof returns the proof of the leaf at the given index.
+	CreateProof(index int) (*MerkleProof[N], error)
+	// VerifyProof reports whether the proof is valid for the hash function
+	// of the tree.
+	VerifyProof(proof *MerkleProof[N]) bool
+}
+
+// MerkleTree is the method set shared by the mutable trees, so that an
+// application can choose the structure it uses, for example behind a flag,
+// without changing its call sites.
+type MerkleTree[N comparable] interface {
+	Prover[N]
+	// Insert appends a leaf to the tree.
+	Insert(leaf N) error
+	// Update replaces the leaf at the given index.
+	Update(index int, leaf N) error
+}
+
+var _ MerkleTree[int] = (*IMT[int])(nil)
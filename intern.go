@@ -0,0 +1,62 @@
+package imt
+
+import (
+	"math"
+	"slices"
+)
+
+// WithInterning stores each distinct node value of the tree once and makes
+// the levels reference the values by a 4-byte handle. It greatly reduces the
+// memory used by trees whose leaves, and hence subtrees, repeat a small set of
+// values, at the cost of a map lookup for every write. Values are never
+// released, even when no node references them anymore, and a tree can hold at
+// most 2^32 distinct values.
+func WithInterning() Option {
+	return func(o *options) {
+		o.interning = true
+	}
+}
+
+// interner assigns a handle to each distinct node value of a tree.
+type interner[N comparable] struct {
+	values  []N
+	handles map[N]uint32
+}
+
+func newInterner[N comparable]() *interner[N] {
+	return &interner[N]{handles: make(map[N]uint32)}
+}
+
+// intern returns the handle of a value, assigning a new one if the value has
+// not been seen before.
+func (i *interner[N]) intern(value N) uint32 {
+	if handle, ok := i.handles[value]; ok {
+		return handle
+	}
+	if uint64(len(i.values)) > math.MaxUint32 {
+		panic("imt: too many distinct node values to intern")
+	}
+	handle := uint32(len(i.values))
+	i.values = append(i.values, value)
+	i.handles[value] = handle
+	return handle
+}
+
+// distinct returns the number of distinct values interned so far.
+func (i *interner[N]) distinct() int {
+	return len(i.values)
+}
+
+// internedLevel is a levelStore that keeps the handles of its nodes, which
+// are shared with the other levels of the tree.
+type internedLevel[N comparable] struct {
+	interner *interner[N]
+	handles  []uint32
+}
+
+func (l *internedLevel[N]) Len() int              { return len(l.handles) }
+func (l *internedLevel[N]) Get(index int) N       { return l.interner.values[l.handles[index]] }
+func (l *internedLevel[N]) Set(index int, node N) { l.handles[index] = l.interner.intern(node) }
+func (l *internedLevel[N]) Append(node N)         { l.handles = append(l.handles, l.interner.intern(node)) }
+func (l *internedLevel[N]) Grow(n int)            { l.handles = slices.Grow(l.handles, n) }
+func (l *internedLevel[N]) Truncate(length int)   { l.handles = l.handles[:length] }
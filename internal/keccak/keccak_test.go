@@ -0,0 +1,41 @@
+package keccak
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestSum256(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"", "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{"abc", "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{"The quick brown fox jumps over the lazy dog", "4d741b6f1eb29cb2a9b9911c82f56fa8d73b04959d3d9d222895df6c0b28aa15"},
+		{"Transfer(address,address,uint256)", "ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+	}
+	for _, test := range tests {
+		got := Sum256([]byte(test.input))
+		if hex.EncodeToString(got[:]) != test.want {
+			t.Errorf("Sum256(%q) = %x, want %s", test.input, got, test.want)
+		}
+	}
+}
+
+func TestSum256Concatenates(t *testing.T) {
+	// The inputs are split around the rate, where blocks are absorbed.
+	data := bytes.Repeat([]byte("0123456789abcdef"), 40)
+	for _, size := range []int{rate - 1, rate, rate + 1, 2 * rate, len(data)} {
+		want := Sum256(data[:size])
+		for split := range size + 1 {
+			if got := Sum256(data[:split], nil, data[split:size]); got != want {
+				t.Fatalf("Sum256 of %d bytes split at %d differs", size, split)
+			}
+		}
+	}
+	if Sum256(data[:rate-1]) == Sum256(data[:rate]) {
+		t.Fatal("inputs of different lengths have the same digest")
+	}
+}
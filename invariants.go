@@ -0,0 +1,114 @@
+package imt
+
+import "fmt"
+
+// Invariant is a named consistency check of a tree. Check returns a message
+// describing the problem and whether the invariant is broken, which is the
+// signature of a Cosmos SDK invariant without its context.
+type Invariant struct {
+	Route string
+	Check func() (string, bool)
+}
+
+// Validate checks the internal consistency of the tree: every level contains
+// the number of nodes implied by the number of leaves, and every internal
+// node, including the root, is the hash of its children. Validation does not
+// notify the gas meter.
+func (t *IMT[N]) Validate() error {
+	if err := t.validateLevels(); err != nil {
+		return err
+	}
+
+	for level := 0; level < t.depth; level++ {
+		for index := range t.nodes[level+1].Len() {
+			if !t.equal(t.nodes[level+1].Get(index), t.hash(t.storedChildren(level, index))) {
+				return fmt.Errorf("node %d of level %d is not the hash of its children", index, level+1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Invariants returns the invariants of the tree:
+//
+//   - "levels": every level contains the number of nodes implied by the
+//     number of leaves, which means that the size matches the stored leaves.
+//   - "root": the root matches the root recomputed from the leaves.
+func (t *IMT[N]) Invariants() []Invariant {
+	return []Invariant{
+		{
+			Route: "levels",
+			Check: func() (string, bool) {
+				if err := t.validateLevels(); err != nil {
+					return err.Error(), true
+				}
+				return "", false
+			},
+		},
+		{
+			Route: "root",
+			Check: func() (string, bool) {
+				recomputed, err := New(t.hashFunc, t.depth, t.zeroes[0], t.arity, t.Leaves())
+				if err != nil {
+					return err.Error(), true
+				}
+				if !t.equal(recomputed.Root(), t.Root()) {
+					return fmt.Sprintf("stored root %v does not match recomputed root %v", t.Root(), recomputed.Root()), true
+				}
+				return "", false
+			},
+		},
+	}
+}
+
+// RegisterInvariants registers the invariants of a tree with register, which
+// is typically a closure around the RegisterRoute method of the Cosmos SDK
+// invariant registry. Since the tree of a module is loaded from the state of
+// each context, load is called every time an invariant is checked.
+func RegisterInvariants[N comparable](register func(route string, check func() (string, bool)), load func() (*IMT[N], error)) {
+	routes := []string{"levels", "root"}
+	for i, route := range routes {
+		register(route, func() (string, bool) {
+			t, err := load()
+			if err != nil {
+				return fmt.Sprintf("failed to load the tree: %v", err), true
+			}
+			return t.Invariants()[i].Check()
+		})
+	}
+}
+
+// validateLevels checks that the length of every level matches the number of
+// leaves.
+func (t *IMT[N]) validateLevels() error {
+	if t.nodes[t.depth].Len() != 1 {
+		return fmt.Errorf("the root level must contain 1 node, got %d", t.nodes[t.depth].Len())
+	}
+
+	expected := t.nodes[0].Len()
+	for level := 1; level < t.depth; level++ {
+		expected = (expected + t.arity - 1) / t.arity
+		if t.nodes[level].Len() != expected {
+			return fmt.Errorf("level %d must contain %d nodes, got %d", level, expected, t.nodes[level].Len())
+		}
+	}
+
+	return nil
+}
+
+// storedChildren returns the children of a node of the next level without
+// notifying the gas meter. For an empty tree, the children of the root are the
+// zero values of the level below it.
+func (t *IMT[N]) storedChildren(level, index int) []N {
+	children := make([]N, t.arity)
+	for i := range children {
+		position := index*t.arity + i
+		if position < t.nodes[level].Len() {
+			children[i] = t.nodes[level].Get(position)
+		} else {
+			children[i] = t.zeroes[level]
+		}
+	}
+	return children
+}
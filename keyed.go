@@ -0,0 +1,104 @@
+package imt
+
+import "errors"
+
+// KeyedIMT wraps a tree to address its leaves by key rather than by index.
+// Each new key is assigned the next leaf of the tree, and keeps it until it is
+// removed. The leaf of a removed key is deleted, and only reused by another
+// key if the delete policy of the tree reuses positions: setting the key
+// again assigns it a new leaf.
+type KeyedIMT[K comparable, N comparable] struct {
+	tree    *IMT[N]
+	indices map[K]int
+	cancel  func()
+}
+
+// NewKeyedIMT returns a KeyedIMT storing its leaves in the given tree, which
+// must not be modified directly anymore, except to be compacted: the keys
+// follow their leaves when Compact moves them, and the keys whose leaf was
+// deleted anyway are forgotten. The leaves already in the tree are kept but
+// have no key. Close must be called once the KeyedIMT is no longer used.
+func NewKeyedIMT[K comparable, N comparable](tree *IMT[N]) *KeyedIMT[K, N] {
+	k := &KeyedIMT[K, N]{
+		tree:    tree,
+		indices: make(map[K]int),
+	}
+	k.cancel = tree.OnRemap(func(remap map[int]int) {
+		for key, index := range k.indices {
+			if moved, ok := remap[index]; ok {
+				k.indices[key] = moved
+			} else {
+				delete(k.indices, key)
+			}
+		}
+	})
+	return k
+}
+
+// Close stops following the compactions of the tree, which then no longer
+// references the KeyedIMT. The KeyedIMT must not be used afterwards.
+func (k *KeyedIMT[K, N]) Close() {
+	k.cancel()
+}
+
+// Tree returns the underlying tree, for read-only access.
+func (k *KeyedIMT[K, N]) Tree() *IMT[N] {
+	return k.tree
+}
+
+// Len returns the number of keys.
+func (k *KeyedIMT[K, N]) Len() int {
+	return len(k.indices)
+}
+
+// Index returns the index of the leaf of a key, and whether the key exists.
+func (k *KeyedIMT[K, N]) Index(key K) (int, bool) {
+	index, ok := k.indices[key]
+	return index, ok
+}
+
+// Get returns the leaf of a key, and whether the key exists.
+func (k *KeyedIMT[K, N]) Get(key K) (N, bool) {
+	index, ok := k.indices[key]
+	if !ok {
+		var zero N
+		return zero, false
+	}
+	return k.tree.nodes[0].Get(index), true
+}
+
+// Set sets the leaf of a key, inserting a new leaf if the key doesn't exist
+// and updating its leaf otherwise. It returns the index of the leaf.
+func (k *KeyedIMT[K, N]) Set(key K, leaf N) (int, error) {
+	if index, ok := k.indices[key]; ok {
+		return index, k.tree.Update(index, leaf)
+	}
+	index := k.tree.NextIndex()
+	if err := k.tree.Insert(leaf); err != nil {
+		return 0, err
+	}
+	k.indices[key] = index
+	return index, nil
+}
+
+// ProofFor creates a proof for the leaf of a key.
+func (k *KeyedIMT[K, N]) ProofFor(key K) (*MerkleProof[N], error) {
+	index, ok := k.indices[key]
+	if !ok {
+		return nil, errors.New("the key does not exist in this tree")
+	}
+	return k.tree.CreateProof(index)
+}
+
+// Remove deletes the leaf of a key and forgets the key.
+func (k *KeyedIMT[K, N]) Remove(key K) error {
+	index, ok := k.indices[key]
+	if !ok {
+		return errors.New("the key does not exist in this tree")
+	}
+	if err := k.tree.Delete(index); err != nil {
+		return err
+	}
+	delete(k.indices, key)
+	return nil
+}
@@ -0,0 +1,85 @@
+package imt_test
+
+import (
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestKeyedIMTFollowsCompaction(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyed := imt.NewKeyedIMT[string](tree)
+	defer keyed.Close()
+	for i, key := range []string{"a", "b", "c", "d"} {
+		if index, err := keyed.Set(key, uint64(i+1)); err != nil || index != i+1 {
+			t.Fatalf("Set(%q) = %d, %v", key, index, err)
+		}
+	}
+	if err := keyed.Remove("b"); err != nil {
+		t.Fatal(err)
+	}
+	// The leaf of c is deleted behind the back of the keyed tree.
+	if err := tree.Delete(3); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Compact(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]struct {
+		index int
+		leaf  uint64
+	}{"a": {1, 1}, "d": {2, 4}}
+	if keyed.Len() != len(want) {
+		t.Fatalf("Len = %d, want %d", keyed.Len(), len(want))
+	}
+	for key, want := range want {
+		index, ok := keyed.Index(key)
+		leaf, _ := keyed.Get(key)
+		if !ok || index != want.index || leaf != want.leaf {
+			t.Fatalf("key %q: index %d, leaf %d", key, index, leaf)
+		}
+		proof, err := keyed.ProofFor(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proof.Leaf != want.leaf || !tree.VerifyProof(proof) {
+			t.Fatalf("the proof of key %q was rejected", key)
+		}
+	}
+	for _, key := range []string{"b", "c"} {
+		if _, ok := keyed.Index(key); ok {
+			t.Fatalf("the key %q of a deleted leaf is kept", key)
+		}
+	}
+}
+
+func TestKeyedIMTClose(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyed := imt.NewKeyedIMT[string](tree)
+	for _, key := range []string{"a", "b"} {
+		if _, err := keyed.Set(key, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := keyed.Remove("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	// A closed keyed tree no longer follows the compactions.
+	keyed.Close()
+	keyed.Close()
+	if _, err := tree.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if index, ok := keyed.Index("b"); !ok || index != 1 {
+		t.Fatalf("Index(b) = %d, %v after Close", index, ok)
+	}
+}
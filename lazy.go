@@ -0,0 +1,83 @@
+package imt
+
+// WithLeavesOnly stores only the leaves and the root of the tree and computes
+// the other nodes on demand from the leaves, keeping up to cacheSize of them
+// in a cache with a least-recently-used eviction policy. It reduces the memory
+// used by the tree by about half for binary trees, and more for larger
+// arities, at the cost of CPU: computing a node that is not cached requires
+// hashing the whole subtree below it, down to the cached nodes or the leaves.
+// It suits trees that are mostly written and whose proofs are rarely read.
+// Insertions stay cheap as long as the cache can hold the siblings of a path,
+// which is depth*arity nodes.
+//
+// Nodes computed on demand do not notify the gas meter, so gas accounting is
+// only deterministic if the cache is disabled or identical on every node. The
+// option takes precedence over WithArena.
+func WithLeavesOnly(cacheSize int) Option {
+	return func(o *options) {
+		o.leavesOnly = true
+		o.cacheSize = cacheSize
+	}
+}
+
+// lazyLevel is a levelStore for the internal levels of a tree in leaves-only
+// mode, which only stores the number of nodes of the level and computes them
+// from the level below.
+type lazyLevel[N comparable] struct {
+	tree   *IMT[N]
+	level  int
+	length int
+}
+
+func (l *lazyLevel[N]) Len() int { return l.length }
+
+func (l *lazyLevel[N]) Get(index int) N {
+	if node, ok := l.tree.cache.get(l.level, index); ok {
+		return node
+	}
+
+	below := l.tree.nodes[l.level-1]
+	children := make([]N, l.tree.arity)
+	for i := range children {
+		position := index*l.tree.arity + i
+		if position < below.Len() {
+			children[i] = below.Get(position)
+		} else {
+			children[i] = l.tree.zeroes[l.level-1]
+		}
+	}
+
+	node, ok := l.tree.zeroParent(l.level-1, children)
+	if !ok {
+		node = l.tree.hash(children)
+	}
+	l.tree.cache.put(l.level, index, node)
+	return node
+}
+
+// Set caches the node, which the tree only writes after updating the nodes
+// below it, so that a cached node is never stale.
+func (l *lazyLevel[N]) Set(index int, node N) {
+	l.tree.cache.put(l.level, index, node)
+}
+
+func (l *lazyLevel[N]) Append(node N) {
+	l.length++
+	l.tree.cache.put(l.level, l.length-1, node)
+}
+
+// Truncate only shortens the level: the cached nodes that it removes are
+// replaced when nodes are appended at their positions again.
+func (l *lazyLevel[N]) Truncate(length int) { l.length = length }
+
+// Grow does nothing, since the nodes are not stored.
+func (l *lazyLevel[N]) Grow(int) {}
+
+// dropLevel replaces an internal level by a lazyLevel of the same length if
+// the tree is in leaves-only mode.
+func (t *IMT[N]) dropLevel(level int) {
+	if !t.options.leavesOnly || level == 0 || level == t.depth {
+		return
+	}
+	t.nodes[level] = &lazyLevel[N]{tree: t, level: level, length: t.nodes[level].Len()}
+}
@@ -0,0 +1,54 @@
+package imt
+
+import "slices"
+
+// LeafDataCodec encodes and decodes the data associated with the leaves of a
+// tree, so that it can be included in snapshots.
+type LeafDataCodec interface {
+	Encode(data any) ([]byte, error)
+	Decode(b []byte) (any, error)
+}
+
+// WithLeafDataCodec sets the codec of the data associated with the leaves,
+// which is required to snapshot a tree carrying leaf data and to restore it.
+func WithLeafDataCodec(codec LeafDataCodec) Option {
+	return func(o *options) {
+		o.leafDataCodec = codec
+	}
+}
+
+// SetLeafData associates arbitrary data with a leaf, replacing the previous
+// data of the leaf if any. Setting nil data removes it. The data is kept
+// along with the leaf: it survives updates, is removed when the leaf is
+// deleted and is included in snapshots.
+func (t *IMT[N]) SetLeafData(index int, data any) error {
+	if index < 0 || index >= t.nodes[0].Len() {
+		return t.opError(OpSetLeafData, 0, index, ErrLeafNotFound)
+	}
+	if data == nil {
+		delete(t.leafData, index)
+		return nil
+	}
+	if t.leafData == nil {
+		t.leafData = make(map[int]any)
+	}
+	t.leafData[index] = data
+	return nil
+}
+
+// LeafData returns the data associated with a leaf, and whether there is any.
+func (t *IMT[N]) LeafData(index int) (any, bool) {
+	data, ok := t.leafData[index]
+	return data, ok
+}
+
+// leafDataIndices returns the indices of the leaves carrying data, in
+// increasing order.
+func (t *IMT[N]) leafDataIndices() []int {
+	indices := make([]int, 0, len(t.leafData))
+	for index := range t.leafData {
+		indices = append(indices, index)
+	}
+	slices.Sort(indices)
+	return indices
+}
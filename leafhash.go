@@ -0,0 +1,125 @@
+package imt
+
+import (
+	"crypto/sha256"
+
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+// LeafHasher hashes application data into a leaf node, so that the leaves of
+// a tree are derived from raw values with a single convention instead of
+// every caller pre-hashing them with its own.
+type LeafHasher[T any, N comparable] interface {
+	HashLeaf(data T) (N, error)
+}
+
+// LeafHasherFunc adapts a function to the LeafHasher interface.
+type LeafHasherFunc[T any, N comparable] func(data T) (N, error)
+
+// HashLeaf calls f.
+func (f LeafHasherFunc[T, N]) HashLeaf(data T) (N, error) {
+	return f(data)
+}
+
+// SHA256LeafHasher returns a LeafHasher of 32-byte leaves computing
+// sha256(sha256(domain || data)). The domain separates the leaves of
+// different applications, and hashing twice separates the leaves from the
+// internal nodes hashed with SHA256Hash, whose preimages are at least 64
+// bytes long while the preimage of the outer hash is 32 bytes long, so that
+// no data can be presented as an internal node or the other way around.
+func SHA256LeafHasher(domain string) LeafHasher[[]byte, [32]byte] {
+	return LeafHasherFunc[[]byte, [32]byte](func(data []byte) ([32]byte, error) {
+		inner := sha256.New()
+		inner.Write([]byte(domain))
+		inner.Write(data)
+		return sha256.Sum256(inner.Sum(nil)), nil
+	})
+}
+
+// Keccak256LeafHasher returns a LeafHasher of 32-byte leaves computing
+// keccak256(keccak256(domain || data)), which separates the leaves from the
+// internal nodes hashed with Keccak256Hash like SHA256LeafHasher. With an
+// empty domain and the ABI encoding of the values as data, the leaves are
+// the ones of OpenZeppelin's StandardMerkleTree.
+func Keccak256LeafHasher(domain string) LeafHasher[[]byte, [32]byte] {
+	return LeafHasherFunc[[]byte, [32]byte](func(data []byte) ([32]byte, error) {
+		inner := keccak.Sum256([]byte(domain), data)
+		return keccak.Sum256(inner[:]), nil
+	})
+}
+
+// DataIMT wraps a tree to insert application data, which is hashed into
+// leaves by a LeafHasher, rather than leaves.
+type DataIMT[T any, N comparable] struct {
+	tree   *IMT[N]
+	hasher LeafHasher[T, N]
+}
+
+// NewDataIMT returns a DataIMT storing the leaves hashed by the hasher in the
+// given tree.
+func NewDataIMT[T any, N comparable](tree *IMT[N], hasher LeafHasher[T, N]) *DataIMT[T, N] {
+	return &DataIMT[T, N]{tree: tree, hasher: hasher}
+}
+
+// Tree returns the underlying tree.
+func (d *DataIMT[T, N]) Tree() *IMT[N] {
+	return d.tree
+}
+
+// HashLeaf returns the leaf of some data.
+func (d *DataIMT[T, N]) HashLeaf(data T) (N, error) {
+	return d.hasher.HashLeaf(data)
+}
+
+// InsertData inserts the leaf of some data and returns its index.
+func (d *DataIMT[T, N]) InsertData(data T) (int, error) {
+	leaf, err := d.hasher.HashLeaf(data)
+	if err != nil {
+		return 0, err
+	}
+	index := d.tree.NextIndex()
+	if err := d.tree.Insert(leaf); err != nil {
+		return 0, err
+	}
+	return index, nil
+}
+
+// InsertManyData inserts the leaves of a list of data with InsertMany. No
+// leaf is inserted if the data of one of them cannot be hashed.
+func (d *DataIMT[T, N]) InsertManyData(data []T) error {
+	leaves := make([]N, len(data))
+	for i := range data {
+		leaf, err := d.hasher.HashLeaf(data[i])
+		if err != nil {
+			return err
+		}
+		leaves[i] = leaf
+	}
+	return d.tree.InsertMany(leaves)
+}
+
+// UpdateData replaces the leaf at the given index by the leaf of some data.
+func (d *DataIMT[T, N]) UpdateData(index int, data T) error {
+	leaf, err := d.hasher.HashLeaf(data)
+	if err != nil {
+		return err
+	}
+	return d.tree.Update(index, leaf)
+}
+
+// IndexOfData returns the index of the first leaf of some data, or -1 if
+// there is none.
+func (d *DataIMT[T, N]) IndexOfData(data T) (int, error) {
+	leaf, err := d.hasher.HashLeaf(data)
+	if err != nil {
+		return -1, err
+	}
+	return d.tree.IndexOf(leaf), nil
+}
+
+// VerifyData verifies a proof of the tree and that its leaf is the leaf of
+// the given data.
+func (d *DataIMT[T, N]) VerifyData(proof *MerkleProof[N], data T) bool {
+	leaf, err := d.hasher.HashLeaf(data)
+	return err == nil && proof != nil && d.tree.equal(proof.Leaf, leaf) && d.tree.VerifyProof(proof)
+}
@@ -0,0 +1,112 @@
+package imt_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/noble-assets/imt"
+)
+
+func TestLeafHashers(t *testing.T) {
+	inner := sha256.Sum256([]byte("domaindata"))
+	if leaf, err := imt.SHA256LeafHasher("domain").HashLeaf([]byte("data")); err != nil || leaf != sha256.Sum256(inner[:]) {
+		t.Fatalf("SHA256LeafHasher = %x, %v", leaf, err)
+	}
+	if a, _ := imt.SHA256LeafHasher("a").HashLeaf([]byte("data")); a == sha256.Sum256(inner[:]) {
+		t.Fatal("the leaves of different domains are equal")
+	}
+
+	// With an empty domain, the leaves of ABI encoded values are the ones of
+	// OpenZeppelin's StandardMerkleTree.
+	tree, err := imt.NewStandardMerkleTree(standardValues, []string{"address", "uint256"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := tree.Dump()
+	for _, value := range dump.Values {
+		var encoded []byte
+		address, _ := hex.DecodeString(strings.TrimPrefix(value.Value[0].(string), "0x"))
+		encoded = append(encoded, make([]byte, 12)...)
+		encoded = append(encoded, address...)
+		amount, _ := new(big.Int).SetString(value.Value[1].(string), 10)
+		encoded = append(encoded, amount.FillBytes(make([]byte, 32))...)
+
+		leaf, err := imt.Keccak256LeafHasher("").HashLeaf(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := dump.Tree[value.TreeIndex]; "0x"+hex.EncodeToString(leaf[:]) != want {
+			t.Fatalf("Keccak256LeafHasher = %x, want %s", leaf, want)
+		}
+	}
+}
+
+// errUnhashable is returned by stringHasher for the empty string.
+var errUnhashable = errors.New("cannot hash the empty string")
+
+// stringHasher hashes non-empty strings with SHA256LeafHasher.
+var stringHasher = imt.LeafHasherFunc[string, [32]byte](func(data string) ([32]byte, error) {
+	if data == "" {
+		return [32]byte{}, errUnhashable
+	}
+	return imt.SHA256LeafHasher("test").HashLeaf([]byte(data))
+})
+
+func TestDataIMT(t *testing.T) {
+	tree, err := imt.NewSHA256Binary(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := imt.NewDataIMT(tree, stringHasher)
+
+	if index, err := data.InsertData("a"); err != nil || index != 0 {
+		t.Fatalf("InsertData = %d, %v", index, err)
+	}
+	if err := data.InsertManyData([]string{"b", "c"}); err != nil {
+		t.Fatal(err)
+	}
+	// No leaf is inserted if one of them cannot be hashed.
+	if err := data.InsertManyData([]string{"d", ""}); !errors.Is(err, errUnhashable) || tree.Size() != 3 {
+		t.Fatalf("InsertManyData of unhashable data: %v, %d leaves", err, tree.Size())
+	}
+	if err := data.UpdateData(1, "e"); err != nil {
+		t.Fatal(err)
+	}
+	for s, want := range map[string]int{"a": 0, "e": 1, "c": 2, "b": -1} {
+		if index, err := data.IndexOfData(s); err != nil || index != want {
+			t.Fatalf("IndexOfData(%q) = %d, %v, want %d", s, index, err, want)
+		}
+	}
+
+	proof, err := tree.CreateProof(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.VerifyData(proof, "e") {
+		t.Fatal("the proof of the data was rejected")
+	}
+	tampered := *proof
+	tampered.Root[0] ^= 1
+	tests := []struct {
+		name  string
+		proof *imt.MerkleProof[[32]byte]
+		data  string
+	}{
+		{"other data", proof, "b"},
+		{"replaced data", proof, "a"},
+		{"unhashable data", proof, ""},
+		{"tampered proof", &tampered, "e"},
+		{"missing proof", nil, "e"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if data.VerifyData(test.proof, test.data) {
+				t.Fatal("the proof was accepted")
+			}
+		})
+	}
+}
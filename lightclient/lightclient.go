@@ -0,0 +1,136 @@
+// Package lightclient follows the root of an incremental Merkle tree without
+// holding the tree. Starting from a trusted genesis root, a Client applies
+// append proofs, each of which proves that appending a leaf transforms the
+// current root into the next one, and checks the signed checkpoints of the
+// tree against the roots it computed. Inclusion proofs are then verified
+// against the roots of the checkpoints.
+//
+// The package only exposes what verifiers need, so downstream code does not
+// have to depend on the surface of the trees themselves.
+package lightclient
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/noble-assets/imt"
+)
+
+// Config configures a Client. The hash function, zero value and codec must be
+// the ones of the followed tree and of its Checkpointer.
+type Config[N comparable] struct {
+	Hash      imt.HashFunction[N]
+	ZeroValue N
+	Codec     imt.NodeCodec[N]
+	// The domain of the checkpoints, and the public keys of the signers
+	// trusted to sign them.
+	Domain  string
+	Signers []crypto.PublicKey
+}
+
+// Client follows the root of a tree from its append proofs and checkpoints.
+// It is not safe for concurrent use.
+type Client[N comparable] struct {
+	config Config[N]
+	root   N
+	size   int
+
+	latest    imt.SignedCheckpoint
+	hasLatest bool
+}
+
+// New returns a Client following a tree from a trusted root and number of
+// leaves, such as the ones of the empty tree.
+func New[N comparable](config Config[N], genesisRoot N, genesisSize int) (*Client[N], error) {
+	if config.Hash == nil || config.Codec == nil {
+		return nil, errors.New("a hash function and a codec are required")
+	}
+	if len(config.Signers) == 0 {
+		return nil, errors.New("at least one signer is required")
+	}
+	return &Client[N]{config: config, root: genesisRoot, size: genesisSize}, nil
+}
+
+// Root returns the current root of the tree.
+func (c *Client[N]) Root() N {
+	return c.root
+}
+
+// Size returns the current number of leaves of the tree.
+func (c *Client[N]) Size() int {
+	return c.size
+}
+
+// Latest returns the last checkpoint accepted by Checkpoint, if any.
+func (c *Client[N]) Latest() (imt.SignedCheckpoint, bool) {
+	return c.latest, c.hasLatest
+}
+
+// Append applies the proof of the next insertion, which must append a leaf
+// at the current size of the tree to the current root. The position of the
+// leaf is the one of the path of the proof, which imt.VerifyAppendProof
+// checks against its leaf index.
+func (c *Client[N]) Append(proof *imt.AppendProof[N]) error {
+	if proof == nil {
+		return errors.New("proof is nil")
+	}
+	if proof.LeafIndex != c.size {
+		return fmt.Errorf("the proof appends leaf %d, expected leaf %d", proof.LeafIndex, c.size)
+	}
+	if proof.OldRoot != c.root {
+		return errors.New("the proof does not start from the current root")
+	}
+	if !imt.VerifyAppendProof(proof, c.config.ZeroValue, c.config.Hash) {
+		return errors.New("invalid append proof")
+	}
+	c.root = proof.NewRoot
+	c.size++
+	return nil
+}
+
+// Checkpoint verifies a checkpoint, which must be signed by a trusted signer
+// and attest the current root and number of leaves, and makes it the latest
+// checkpoint.
+func (c *Client[N]) Checkpoint(checkpoint imt.SignedCheckpoint) error {
+	root, err := c.verifyCheckpoint(checkpoint)
+	if err != nil {
+		return err
+	}
+	if checkpoint.Count != uint64(c.size) || root != c.root {
+		return fmt.Errorf("the checkpoint of %d leaves does not match the root of %d leaves", checkpoint.Count, c.size)
+	}
+	c.latest, c.hasLatest = checkpoint, true
+	return nil
+}
+
+// VerifyInclusionAtCheckpoint reports whether a proof proves that its leaf is
+// one of the leaves attested by a checkpoint signed by a trusted signer. The
+// root embedded in the proof is ignored.
+func (c *Client[N]) VerifyInclusionAtCheckpoint(proof *imt.MerkleProof[N], checkpoint imt.SignedCheckpoint) bool {
+	root, err := c.verifyCheckpoint(checkpoint)
+	if err != nil || proof == nil || proof.LeafIndex < 0 || uint64(proof.LeafIndex) >= checkpoint.Count {
+		return false
+	}
+	return imt.VerifyProofAgainstRoot(proof, root, c.config.Hash)
+}
+
+// verifyCheckpoint checks the domain and the signature of a checkpoint, and
+// returns its root.
+func (c *Client[N]) verifyCheckpoint(checkpoint imt.SignedCheckpoint) (N, error) {
+	var root N
+	if checkpoint.Domain != c.config.Domain {
+		return root, fmt.Errorf("the checkpoint is for domain %q, expected %q", checkpoint.Domain, c.config.Domain)
+	}
+	signed := false
+	for _, signer := range c.config.Signers {
+		if checkpoint.Verify(signer) == nil {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return root, errors.New("the checkpoint is not signed by a trusted signer")
+	}
+	return c.config.Codec.Decode(checkpoint.Root)
+}
@@ -0,0 +1,221 @@
+package lightclient_test
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+	"github.com/noble-assets/imt/lightclient"
+)
+
+// uint64Codec encodes uint64 nodes as 8 big-endian bytes.
+type uint64Codec struct{}
+
+func (uint64Codec) Encode(value uint64) ([]byte, error) {
+	return binary.BigEndian.AppendUint64(nil, value), nil
+}
+
+func (uint64Codec) Decode(b []byte) (uint64, error) {
+	if len(b) != 8 {
+		return 0, errors.New("invalid node")
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+// setup returns a tree signing a checkpoint every 3 writes, the checkpoints
+// it signed, and a client following it from its empty root.
+func setup(t *testing.T) (*imt.IMT[uint64], *imt.Checkpointer[uint64], *[]imt.SignedCheckpoint, *lightclient.Client[uint64]) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkpoints := new([]imt.SignedCheckpoint)
+	checkpointer, err := imt.NewCheckpointer(tree, uint64Codec{}, imt.CheckpointerConfig{
+		Domain: "test",
+		Signer: private,
+		Every:  3,
+		Sink: imt.CheckpointSinkFunc(func(checkpoint imt.SignedCheckpoint) error {
+			*checkpoints = append(*checkpoints, checkpoint)
+			return nil
+		}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(checkpointer.Close)
+	client, err := lightclient.New(lightclient.Config[uint64]{
+		Hash:    imttest.Uint64Hash,
+		Codec:   uint64Codec{},
+		Domain:  "test",
+		Signers: []crypto.PublicKey{public},
+	}, tree.Root(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree, checkpointer, checkpoints, client
+}
+
+func TestClientFollowsTree(t *testing.T) {
+	tree, checkpointer, checkpoints, client := setup(t)
+	for i := range 10 {
+		signed := len(*checkpoints)
+		if err := tree.Insert(uint64(i + 1)); err != nil {
+			t.Fatal(err)
+		}
+		proof, err := tree.CreateAppendProof(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := client.Append(proof); err != nil {
+			t.Fatalf("leaf %d: %v", i, err)
+		}
+		if len(*checkpoints) > signed {
+			if err := client.Checkpoint((*checkpoints)[len(*checkpoints)-1]); err != nil {
+				t.Fatalf("leaf %d: %v", i, err)
+			}
+		}
+	}
+	if client.Root() != tree.Root() || client.Size() != tree.Size() {
+		t.Fatal("the client diverged from the tree")
+	}
+
+	checkpoint, err := checkpointer.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Checkpoint(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	if latest, ok := client.Latest(); !ok || latest.Count != 10 {
+		t.Fatalf("Latest = %v, %v", latest, ok)
+	}
+
+	proof, err := tree.CreateProof(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !client.VerifyInclusionAtCheckpoint(proof, checkpoint) {
+		t.Fatal("valid inclusion proof rejected")
+	}
+	if client.VerifyInclusionAtCheckpoint(proof, (*checkpoints)[0]) {
+		t.Fatal("inclusion proof accepted against an older checkpoint")
+	}
+	if err := client.Checkpoint((*checkpoints)[0]); err == nil {
+		t.Fatal("stale checkpoint accepted")
+	}
+}
+
+func TestClientRejectsForgedAppend(t *testing.T) {
+	tree, _, _, client := setup(t)
+	for i, leaf := range []uint64{1, 2, 0, 0, 0, 7} {
+		if err := tree.Insert(leaf); err != nil {
+			t.Fatal(err)
+		}
+		if i < 2 {
+			proof, err := tree.CreateAppendProof(i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := client.Append(proof); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// The proof of leaf 5 starts from the root of the first two leaves, since
+	// the leaves between them are zero values, but appends at another
+	// position.
+	tests := []struct {
+		name  string
+		forge func(proof *imt.AppendProof[uint64])
+	}{
+		{"leaf index rewritten", func(p *imt.AppendProof[uint64]) { p.LeafIndex = 2 }},
+		{"path indices rewritten", func(p *imt.AppendProof[uint64]) {
+			p.LeafIndex = 2
+			p.PathIndices = []int{0, 1, 0, 0}
+		}},
+		{"new root altered", func(p *imt.AppendProof[uint64]) {
+			p.LeafIndex, p.PathIndices, p.NewRoot = 2, []int{0, 1, 0, 0}, p.NewRoot+1
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proof, err := tree.CreateAppendProof(5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if proof.OldRoot != client.Root() {
+				t.Fatal("the forgery does not start from the root of the client")
+			}
+			test.forge(proof)
+			root := client.Root()
+			if err := client.Append(proof); err == nil {
+				t.Fatal("forged append proof accepted")
+			}
+			if client.Root() != root || client.Size() != 2 {
+				t.Fatal("a rejected proof changed the client")
+			}
+		})
+	}
+}
+
+func TestClientRejectsUntrustedCheckpoints(t *testing.T) {
+	tree, checkpointer, _, client := setup(t)
+	if err := tree.Insert(1); err != nil {
+		t.Fatal(err)
+	}
+	proof, err := tree.CreateAppendProof(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Append(proof); err != nil {
+		t.Fatal(err)
+	}
+	checkpoint, err := checkpointer.Checkpoint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, other, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		forge func(checkpoint *imt.SignedCheckpoint)
+	}{
+		{"other domain", func(c *imt.SignedCheckpoint) { c.Domain = "other" }},
+		{"other count", func(c *imt.SignedCheckpoint) { c.Count++ }},
+		{"other signer", func(c *imt.SignedCheckpoint) { c.Signature = ed25519.Sign(other, c.Digest[:]) }},
+		{"altered signature", func(c *imt.SignedCheckpoint) { c.Signature[0] ^= 1 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			forged := checkpoint
+			forged.Signature = append([]byte(nil), checkpoint.Signature...)
+			test.forge(&forged)
+			if err := client.Checkpoint(forged); err == nil {
+				t.Fatal("forged checkpoint accepted")
+			}
+			inclusion, err := tree.CreateProof(0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if client.VerifyInclusionAtCheckpoint(inclusion, forged) {
+				t.Fatal("inclusion proof accepted against a forged checkpoint")
+			}
+		})
+	}
+	if err := client.Checkpoint(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+}
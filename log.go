@@ -0,0 +1,49 @@
+package imt
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLogger sets a logger the tree reports its significant events to:
+// commits of insertions, updates and deletions at the debug level,
+// compactions and restorations from snapshots at the info level, and
+// integrity failures, such as failed debug checks or snapshots whose root
+// does not match, at the error level. Without it, the tree logs nothing.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.logger = logger
+	}
+}
+
+// log logs an event of the tree with its depth, arity and size, if the tree
+// has a logger.
+func (t *IMT[N]) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	logger := t.options.logger
+	if logger == nil {
+		return
+	}
+	ctx := t.traceContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	attrs = append(attrs,
+		slog.Int("depth", t.depth),
+		slog.Int("arity", t.arity),
+		slog.Int("size", t.nodes[0].Len()),
+	)
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logCommit logs the commit of an operation that wrote count leaves.
+func (t *IMT[N]) logCommit(op string, count int) {
+	t.log(slog.LevelDebug, "imt: committed operation",
+		slog.String("op", op),
+		slog.Int("count", count),
+		slog.Uint64("version", t.version),
+		slog.Any("root", t.Root()),
+	)
+}
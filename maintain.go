@@ -0,0 +1,173 @@
+package imt
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// The maintenance tasks run by a Maintainer.
+const (
+	TaskCompact     = "compact"
+	TaskTrimCache   = "trim_cache"
+	TaskSweep       = "sweep_expired"
+	TaskHealthCheck = "health_check"
+)
+
+// MaintenanceConfig configures the tasks of a Maintainer. A task runs every
+// interval, and is disabled if its interval is zero.
+type MaintenanceConfig struct {
+	// Compaction, with Compact, once at least CompactThreshold of the leaves,
+	// as a fraction, are deleted.
+	CompactInterval  time.Duration
+	CompactThreshold float64
+
+	// Trimming of the cache of trees in leaves-only mode to at most
+	// TrimCacheSize nodes, with TrimCache.
+	TrimCacheInterval time.Duration
+	TrimCacheSize     int
+
+	// Deletion of the expired leaves, with SweepExpired.
+	SweepInterval time.Duration
+
+	// Sampling of the internal nodes, with HealthCheck.
+	HealthCheckInterval   time.Duration
+	HealthCheckSampleRate float64
+
+	// OnRun, if set, is called after each run of a task, outside of the lock
+	// of the tree.
+	OnRun func(run MaintenanceRun)
+}
+
+// MaintenanceRun describes a run of a maintenance task. Affected is the
+// number of leaves removed by a compaction, of nodes evicted from the cache
+// or of expired leaves deleted.
+type MaintenanceRun struct {
+	Task     string
+	Start    time.Time
+	Duration time.Duration
+	Affected int
+	Err      error
+}
+
+// Maintainer runs the maintenance tasks of a tree in a goroutine, so that
+// long-running services don't need to schedule them.
+type Maintainer[N comparable] struct {
+	view   func(f func(tree *IMT[N]))
+	config MaintenanceConfig
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewMaintainer starts a Maintainer running the tasks of config until ctx is
+// done or Stop is called. Each task runs in a call to view, which passes the
+// tree to its argument while holding whatever guards the tree against the
+// other goroutines using it, such as the View method of an Ingester.
+func NewMaintainer[N comparable](ctx context.Context, view func(f func(tree *IMT[N])), config MaintenanceConfig) (*Maintainer[N], error) {
+	if config.CompactInterval < 0 || config.TrimCacheInterval < 0 || config.SweepInterval < 0 || config.HealthCheckInterval < 0 {
+		return nil, errors.New("maintenance intervals must not be negative")
+	}
+	if config.CompactInterval > 0 && !(config.CompactThreshold >= 0 && config.CompactThreshold <= 1) {
+		return nil, errors.New("the compaction threshold must be in [0, 1]")
+	}
+	if config.HealthCheckInterval > 0 && !(config.HealthCheckSampleRate > 0 && config.HealthCheckSampleRate <= 1) {
+		return nil, errors.New("the sample rate must be in (0, 1]")
+	}
+	m := &Maintainer[N]{
+		view:   view,
+		config: config,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go m.run(ctx)
+	return m, nil
+}
+
+// Stop stops the Maintainer, waiting for the task running, if any, to
+// complete.
+func (m *Maintainer[N]) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+	})
+	<-m.done
+}
+
+// run runs the tasks when their tickers fire.
+func (m *Maintainer[N]) run(ctx context.Context) {
+	defer close(m.done)
+
+	tasks := []struct {
+		name     string
+		interval time.Duration
+	}{
+		{TaskCompact, m.config.CompactInterval},
+		{TaskTrimCache, m.config.TrimCacheInterval},
+		{TaskSweep, m.config.SweepInterval},
+		{TaskHealthCheck, m.config.HealthCheckInterval},
+	}
+	// A nil channel never fires, which disables the task.
+	ticks := make([]<-chan time.Time, len(tasks))
+	for i, task := range tasks {
+		if task.interval > 0 {
+			ticker := time.NewTicker(task.interval)
+			defer ticker.Stop()
+			ticks[i] = ticker.C
+		}
+	}
+
+	for {
+		select {
+		case <-ticks[0]:
+			m.runTask(ctx, TaskCompact)
+		case <-ticks[1]:
+			m.runTask(ctx, TaskTrimCache)
+		case <-ticks[2]:
+			m.runTask(ctx, TaskSweep)
+		case <-ticks[3]:
+			m.runTask(ctx, TaskHealthCheck)
+		case <-m.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runTask runs a task in a call to the view of the tree and reports it.
+func (m *Maintainer[N]) runTask(ctx context.Context, task string) {
+	run := MaintenanceRun{Task: task}
+	m.view(func(t *IMT[N]) {
+		run.Start = time.Now()
+		m.runOn(ctx, t, &run)
+		run.Duration = time.Since(run.Start)
+		if run.Err != nil && !errors.Is(run.Err, context.Canceled) {
+			t.log(slog.LevelWarn, "imt: maintenance task failed", slog.String("task", task), slog.Any("error", run.Err))
+		}
+	})
+	if m.config.OnRun != nil {
+		m.config.OnRun(run)
+	}
+}
+
+// runOn runs a task on the tree.
+func (m *Maintainer[N]) runOn(ctx context.Context, t *IMT[N], run *MaintenanceRun) {
+	switch run.Task {
+	case TaskCompact:
+		size := t.nodes[0].Len()
+		if t.tombstones.count > 0 && float64(t.tombstones.count) >= m.config.CompactThreshold*float64(size) {
+			remap, err := t.Compact()
+			run.Affected, run.Err = size-len(remap), err
+		}
+	case TaskTrimCache:
+		run.Affected = t.TrimCache(m.config.TrimCacheSize)
+	case TaskSweep:
+		swept, err := t.SweepExpired(run.Start)
+		run.Affected, run.Err = len(swept), err
+	case TaskHealthCheck:
+		run.Err = t.HealthCheck(ctx, m.config.HealthCheckSampleRate)
+	}
+}
@@ -0,0 +1,102 @@
+package imt
+
+import (
+	"container/list"
+	"hash/maphash"
+	"slices"
+	"sync"
+)
+
+// WithMemoization makes the tree remember the hashes of up to size lists of
+// children, with a least-recently-used eviction policy, and reuse them instead
+// of calling the hash function again. It pays off for very expensive hash
+// functions, such as Poseidon or MiMC, in trees with large regions of repeated
+// values, whose subtrees hash the same children. The memo is safe for
+// concurrent use, so it can be combined with parallel hashing, and Stats
+// reports its hits and misses.
+func WithMemoization(size int) Option {
+	return func(o *options) {
+		o.memoSize = size
+	}
+}
+
+// hashMemo is a bounded memo of a hash function, keyed by the children. The
+// children of each list are hashed together with maphash to find its entry,
+// and compared with the ones of the entry to detect collisions.
+type hashMemo[N comparable] struct {
+	hash HashFunction[N]
+	seed maphash.Seed
+	size int
+
+	mu      sync.Mutex
+	entries map[uint64]*list.Element
+	order   *list.List
+	hits    uint64
+	misses  uint64
+}
+
+// memoEntry is the value of the elements of the order of a hashMemo.
+type memoEntry[N comparable] struct {
+	key      uint64
+	children []N
+	parent   N
+}
+
+func newHashMemo[N comparable](hash HashFunction[N], size int) *hashMemo[N] {
+	return &hashMemo[N]{
+		hash:    hash,
+		seed:    maphash.MakeSeed(),
+		size:    size,
+		entries: make(map[uint64]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// sum returns the hash of the children, from the memo if it holds them. It
+// is the hash function of trees with memoization.
+func (m *hashMemo[N]) sum(children []N) N {
+	var h maphash.Hash
+	h.SetSeed(m.seed)
+	for _, child := range children {
+		maphash.WriteComparable(&h, child)
+	}
+	key := h.Sum64()
+
+	m.mu.Lock()
+	if element, ok := m.entries[key]; ok {
+		if entry := element.Value.(*memoEntry[N]); slices.Equal(entry.children, children) {
+			m.hits++
+			m.order.MoveToFront(element)
+			m.mu.Unlock()
+			return entry.parent
+		}
+	}
+	m.misses++
+	m.mu.Unlock()
+
+	// The children are copied since the tree may reuse their slice.
+	parent := m.hash(children)
+	entry := &memoEntry[N]{key: key, children: slices.Clone(children), parent: parent}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if element, ok := m.entries[key]; ok {
+		element.Value = entry
+		m.order.MoveToFront(element)
+		return parent
+	}
+	if m.order.Len() >= m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoEntry[N]).key)
+	}
+	m.entries[key] = m.order.PushFront(entry)
+	return parent
+}
+
+// stats returns the number of hashes in the memo, and its hits and misses.
+func (m *hashMemo[N]) stats() (size int, hits, misses uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len(), m.hits, m.misses
+}
@@ -0,0 +1,291 @@
+package imt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/bits"
+	"slices"
+)
+
+// MerkleMap is an authenticated map: it commits to a set of key-value pairs
+// with a single root, and proves that a key maps to a value or that it is
+// absent. Each key is hashed into a position of a sparse binary tree of
+// 32-byte nodes, whose other positions hold the zero value. Only the non-zero
+// nodes are stored, so the tree can be much deeper than the number of keys.
+//
+// The position of a key is made of the first depth bits of the SHA-256
+// digest of its encoding, so several keys may share a position. The leaf of a
+// position commits to the entries of all of its keys, the digests of the keys
+// and their values, in increasing order of the digests of the keys: it is the
+// SHA-256 digest of the number of entries, as 8 big-endian bytes, followed by
+// the digests of each entry. Since the number of entries is committed and the
+// leaves are not hashed like the nodes, the leaf of some entries is never the
+// leaf of other entries, nor a node. A proof carries the entries of the
+// position of its key, so it proves that a key is absent even if another key
+// holds its position. With a depth of 63, positions are shared by two keys
+// with a probability below 2^-63 per pair of keys, so proofs almost always
+// carry a single entry. Proofs are verified by a MerkleMap with the same
+// configuration, which may be empty.
+type MerkleMap[K comparable, V any] struct {
+	hash        HashFunction[[32]byte]
+	depth       int
+	encodeKey   func(K) []byte
+	encodeValue func(V) ([]byte, error)
+
+	// The values, the entries of the keys of each non-empty position, and
+	// the non-zero nodes of the sparse tree, by level and index.
+	values  map[K]V
+	buckets map[uint64][]MerkleMapEntry
+	nodes   []map[uint64][32]byte
+	zeroes  [][32]byte
+}
+
+// MerkleMapEntry is the commitment of a key-value pair of a MerkleMap: the
+// SHA-256 digests of the encodings of the key and the value.
+type MerkleMapEntry struct {
+	Key   [32]byte `json:"key"`
+	Value [32]byte `json:"value"`
+}
+
+// MerkleMapProof proves that a key of a MerkleMap maps to a value, or that it
+// is absent: it is the proof of the leaf at the position of the key, and the
+// entries of the keys at that position, from which the leaf is computed.
+type MerkleMapProof struct {
+	*MerkleProof[[32]byte]
+	Entries []MerkleMapEntry `json:"entries"`
+}
+
+// NewMerkleMap returns an empty MerkleMap hashing its nodes with hash, such as
+// Keccak256Hash, whose sparse tree has the given depth, which must be less
+// than the size of an int in bits, i.e. at most 63 on 64-bit platforms. The
+// keys and values are encoded into bytes with encodeKey and encodeValue,
+// which must be deterministic, and distinct keys must have distinct
+// encodings.
+func NewMerkleMap[K comparable, V any](hash HashFunction[[32]byte], depth int, encodeKey func(K) []byte, encodeValue func(V) ([]byte, error)) (*MerkleMap[K, V], error) {
+	// The sparse tree only stores its non-zero nodes, so its capacity may
+	// exceed the one allowed by ValidateConfig.
+	if hash == nil {
+		return nil, errors.New("hash function is required")
+	}
+	if depth <= 0 || depth >= bits.UintSize {
+		return nil, fmt.Errorf("the depth must be between 1 and %d", bits.UintSize-1)
+	}
+	m := &MerkleMap[K, V]{
+		hash:        hash,
+		depth:       depth,
+		encodeKey:   encodeKey,
+		encodeValue: encodeValue,
+		values:      make(map[K]V),
+		buckets:     make(map[uint64][]MerkleMapEntry),
+		nodes:       make([]map[uint64][32]byte, depth+1),
+		zeroes:      make([][32]byte, depth+1),
+	}
+	for level := range m.nodes {
+		m.nodes[level] = make(map[uint64][32]byte)
+		if level > 0 {
+			m.zeroes[level] = hash([][32]byte{m.zeroes[level-1], m.zeroes[level-1]})
+		}
+	}
+	return m, nil
+}
+
+// Root returns the root of the map.
+func (m *MerkleMap[K, V]) Root() [32]byte {
+	return m.node(m.depth, 0)
+}
+
+// Len returns the number of keys of the map.
+func (m *MerkleMap[K, V]) Len() int {
+	return len(m.values)
+}
+
+// Get returns the value of a key, and whether the key exists.
+func (m *MerkleMap[K, V]) Get(key K) (V, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+// Set sets the value of a key.
+func (m *MerkleMap[K, V]) Set(key K, value V) error {
+	entry, err := m.entry(key, value)
+	if err != nil {
+		return err
+	}
+	position := m.position(entry.Key)
+	bucket := m.buckets[position]
+	i, found := slices.BinarySearchFunc(bucket, entry.Key, compareEntryKey)
+	if found {
+		bucket[i] = entry
+	} else {
+		bucket = slices.Insert(bucket, i, entry)
+	}
+	m.values[key] = value
+	m.buckets[position] = bucket
+	m.write(position, m.bucketLeaf(bucket))
+	return nil
+}
+
+// Delete removes a key, if it exists.
+func (m *MerkleMap[K, V]) Delete(key K) {
+	if _, ok := m.values[key]; !ok {
+		return
+	}
+	digest := sha256.Sum256(m.encodeKey(key))
+	position := m.position(digest)
+	bucket := m.buckets[position]
+	if i, found := slices.BinarySearchFunc(bucket, digest, compareEntryKey); found {
+		bucket = slices.Delete(bucket, i, i+1)
+	}
+	delete(m.values, key)
+	if len(bucket) == 0 {
+		delete(m.buckets, position)
+	} else {
+		m.buckets[position] = bucket
+	}
+	m.write(position, m.bucketLeaf(bucket))
+}
+
+// ProveInclusion returns a proof that a key maps to its value.
+func (m *MerkleMap[K, V]) ProveInclusion(key K) (*MerkleMapProof, error) {
+	if _, ok := m.values[key]; !ok {
+		return nil, ErrLeafNotFound
+	}
+	return m.proof(m.position(sha256.Sum256(m.encodeKey(key)))), nil
+}
+
+// ProveExclusion returns a proof that a key is absent from the map, which
+// holds the entries of the other keys at its position, if any.
+func (m *MerkleMap[K, V]) ProveExclusion(key K) (*MerkleMapProof, error) {
+	if _, ok := m.values[key]; ok {
+		return nil, errors.New("the key exists in the map")
+	}
+	return m.proof(m.position(sha256.Sum256(m.encodeKey(key)))), nil
+}
+
+// VerifyInclusion reports whether a proof created by ProveInclusion proves
+// that a key maps to a value under the root of the proof.
+func (m *MerkleMap[K, V]) VerifyInclusion(proof *MerkleMapProof, key K, value V) bool {
+	entry, err := m.entry(key, value)
+	if err != nil || !m.verify(proof, entry.Key) {
+		return false
+	}
+	i, found := slices.BinarySearchFunc(proof.Entries, entry.Key, compareEntryKey)
+	return found && proof.Entries[i] == entry
+}
+
+// VerifyExclusion reports whether a proof created by ProveExclusion proves
+// that a key is absent under the root of the proof.
+func (m *MerkleMap[K, V]) VerifyExclusion(proof *MerkleMapProof, key K) bool {
+	digest := sha256.Sum256(m.encodeKey(key))
+	if !m.verify(proof, digest) {
+		return false
+	}
+	_, found := slices.BinarySearchFunc(proof.Entries, digest, compareEntryKey)
+	return !found
+}
+
+// verify reports whether a proof proves the leaf of its entries at the
+// position of the digest of a key, and whether its entries are the ones of
+// that position, in increasing order.
+func (m *MerkleMap[K, V]) verify(proof *MerkleMapProof, digest [32]byte) bool {
+	if proof == nil || proof.MerkleProof == nil || len(proof.Siblings) != m.depth {
+		return false
+	}
+	position := m.position(digest)
+	for i, entry := range proof.Entries {
+		if m.position(entry.Key) != position || i > 0 && compareEntryKey(proof.Entries[i-1], entry.Key) >= 0 {
+			return false
+		}
+	}
+	return uint64(proof.LeafIndex) == position && proof.Leaf == m.bucketLeaf(proof.Entries) &&
+		VerifyProof(proof.MerkleProof, m.hash)
+}
+
+// position returns the position in the tree of the key with the given
+// digest.
+func (m *MerkleMap[K, V]) position(digest [32]byte) uint64 {
+	return binary.BigEndian.Uint64(digest[:8]) >> (64 - m.depth)
+}
+
+// entry returns the entry of a key and a value.
+func (m *MerkleMap[K, V]) entry(key K, value V) (MerkleMapEntry, error) {
+	encoded, err := m.encodeValue(value)
+	if err != nil {
+		return MerkleMapEntry{}, err
+	}
+	return MerkleMapEntry{Key: sha256.Sum256(m.encodeKey(key)), Value: sha256.Sum256(encoded)}, nil
+}
+
+// bucketLeaf returns the leaf of the entries of a position, which is the zero
+// value if there are none.
+func (m *MerkleMap[K, V]) bucketLeaf(entries []MerkleMapEntry) [32]byte {
+	if len(entries) == 0 {
+		return m.zeroes[0]
+	}
+	h := sha256.New()
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(len(entries))))
+	for _, entry := range entries {
+		h.Write(entry.Key[:])
+		h.Write(entry.Value[:])
+	}
+	return [32]byte(h.Sum(nil))
+}
+
+// compareEntryKey orders the entries of a position by the digests of their
+// keys.
+func compareEntryKey(entry MerkleMapEntry, digest [32]byte) int {
+	return bytes.Compare(entry.Key[:], digest[:])
+}
+
+// node returns the node at the given level and index.
+func (m *MerkleMap[K, V]) node(level int, index uint64) [32]byte {
+	if node, ok := m.nodes[level][index]; ok {
+		return node
+	}
+	return m.zeroes[level]
+}
+
+// write sets the leaf at a position and updates its ancestors, only storing
+// the nodes that are not zero values.
+func (m *MerkleMap[K, V]) write(position uint64, leaf [32]byte) {
+	node, index := leaf, position
+	for level := 0; ; level++ {
+		if node == m.zeroes[level] {
+			delete(m.nodes[level], index)
+		} else {
+			m.nodes[level][index] = node
+		}
+		if level == m.depth {
+			return
+		}
+		if index%2 == 0 {
+			node = m.hash([][32]byte{node, m.node(level, index+1)})
+		} else {
+			node = m.hash([][32]byte{m.node(level, index-1), node})
+		}
+		index /= 2
+	}
+}
+
+// proof returns the proof of the leaf at a position and its entries.
+func (m *MerkleMap[K, V]) proof(position uint64) *MerkleMapProof {
+	proof := &MerkleProof[[32]byte]{
+		Root:        m.Root(),
+		Leaf:        m.node(0, position),
+		LeafIndex:   int(position),
+		Siblings:    make([][][32]byte, m.depth),
+		PathIndices: make([]int, m.depth),
+		Depth:       m.depth,
+		Arity:       2,
+	}
+	index := position
+	for level := range m.depth {
+		proof.Siblings[level] = [][32]byte{m.node(level, index^1)}
+		proof.PathIndices[level] = int(index % 2)
+		index /= 2
+	}
+	return &MerkleMapProof{MerkleProof: proof, Entries: slices.Clone(m.buckets[position])}
+}
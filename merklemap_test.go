@@ -0,0 +1,181 @@
+package imt_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"testing"
+
+	"github.com/noble-assets/imt"
+)
+
+func newStringMap(t *testing.T, depth int) *imt.MerkleMap[string, uint64] {
+	t.Helper()
+	m, err := imt.NewMerkleMap(imt.Keccak256Hash, depth,
+		func(key string) []byte { return []byte(key) },
+		func(value uint64) ([]byte, error) { return binary.BigEndian.AppendUint64(nil, value), nil },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestMerkleMap(t *testing.T) {
+	// With a depth of 2, the 20 keys share the 4 positions.
+	for _, depth := range []int{2, 16, bits.UintSize - 1} {
+		t.Run(fmt.Sprint("depth ", depth), func(t *testing.T) {
+			m, verifier := newStringMap(t, depth), newStringMap(t, depth)
+			for i := range 20 {
+				if err := m.Set(fmt.Sprint("key", i), uint64(i)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := m.Set("key3", 33); err != nil {
+				t.Fatal(err)
+			}
+			m.Delete("key4")
+			if m.Len() != 19 {
+				t.Fatalf("Len = %d, want 19", m.Len())
+			}
+
+			for i := range 20 {
+				key, value := fmt.Sprint("key", i), uint64(i)
+				if i == 3 {
+					value = 33
+				}
+				if i == 4 {
+					if _, err := m.ProveInclusion(key); err == nil {
+						t.Fatal("proved the inclusion of a deleted key")
+					}
+					continue
+				}
+				proof, err := m.ProveInclusion(key)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if proof.Root != m.Root() || !verifier.VerifyInclusion(proof, key, value) {
+					t.Fatalf("%s: valid inclusion proof rejected", key)
+				}
+				if verifier.VerifyInclusion(proof, key, value+1) || verifier.VerifyExclusion(proof, key) {
+					t.Fatalf("%s: inclusion proof accepted for another statement", key)
+				}
+			}
+
+			for i := 4; i < 40; i += 2 {
+				key := fmt.Sprint("key", i)
+				if i < 20 && i != 4 {
+					if _, err := m.ProveExclusion(key); err == nil {
+						t.Fatalf("%s: proved the exclusion of an existing key", key)
+					}
+					continue
+				}
+				proof, err := m.ProveExclusion(key)
+				if err != nil {
+					t.Fatalf("%s: %v", key, err)
+				}
+				if !verifier.VerifyExclusion(proof, key) {
+					t.Fatalf("%s: valid exclusion proof rejected", key)
+				}
+				if verifier.VerifyInclusion(proof, key, 0) {
+					t.Fatalf("%s: exclusion proof accepted as an inclusion proof", key)
+				}
+			}
+		})
+	}
+}
+
+func TestMerkleMapSharedPositions(t *testing.T) {
+	m := newStringMap(t, 1)
+	for i := range 8 {
+		if err := m.Set(fmt.Sprint("key", i), uint64(i)); err != nil {
+			t.Fatalf("keys sharing a position must be accepted: %v", err)
+		}
+	}
+	proof, err := m.ProveInclusion("key0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(proof.Entries) < 2 {
+		t.Fatal("expected several entries at the position of the key")
+	}
+	entry := imt.MerkleMapEntry{Key: sha256.Sum256([]byte("key0")), Value: sha256.Sum256(binary.BigEndian.AppendUint64(nil, 0))}
+
+	tests := []struct {
+		name  string
+		forge func(proof *imt.MerkleMapProof)
+	}{
+		{"entry removed", func(p *imt.MerkleMapProof) {
+			for i, e := range p.Entries {
+				if e == entry {
+					p.Entries = append(p.Entries[:i:i], p.Entries[i+1:]...)
+					return
+				}
+			}
+		}},
+		{"entries swapped", func(p *imt.MerkleMapProof) {
+			p.Entries[0], p.Entries[1] = p.Entries[1], p.Entries[0]
+		}},
+		{"entry duplicated", func(p *imt.MerkleMapProof) {
+			p.Entries = append(p.Entries, p.Entries[len(p.Entries)-1])
+		}},
+		{"value altered", func(p *imt.MerkleMapProof) {
+			for i := range p.Entries {
+				p.Entries[i].Value[0] ^= 1
+			}
+		}},
+		{"leaf index altered", func(p *imt.MerkleMapProof) { p.LeafIndex ^= 1 }},
+		{"sibling altered", func(p *imt.MerkleMapProof) { p.Siblings[0][0][0] ^= 1 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			proof, err := m.ProveInclusion("key0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.forge(proof)
+			if m.VerifyInclusion(proof, "key0", 0) {
+				t.Fatal("forged inclusion proof accepted")
+			}
+			if m.VerifyExclusion(proof, "key0") {
+				t.Fatal("forged exclusion proof accepted")
+			}
+		})
+	}
+}
+
+func TestMerkleMapRejectsEntriesPassedOffAsOne(t *testing.T) {
+	// The entries of a position must not be passed off as a single entry
+	// made of the hashes of the entries, which would prove that the keys
+	// of the position are absent.
+	m := newStringMap(t, 1)
+	for i := range 64 {
+		key := fmt.Sprint("key", i)
+		if err := m.Set(key, uint64(i)); err != nil {
+			t.Fatal(err)
+		}
+		proof, err := m.ProveInclusion(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(proof.Entries) < 2 {
+			continue
+		}
+		fold := imt.Keccak256Hash([][32]byte{proof.Entries[0].Key, proof.Entries[0].Value})
+		for _, entry := range proof.Entries[1 : len(proof.Entries)-1] {
+			fold = imt.Keccak256Hash([][32]byte{fold, imt.Keccak256Hash([][32]byte{entry.Key, entry.Value})})
+		}
+		last := proof.Entries[len(proof.Entries)-1]
+		forged := imt.MerkleMapEntry{Key: fold, Value: imt.Keccak256Hash([][32]byte{last.Key, last.Value})}
+		if int(forged.Key[0]>>7) != proof.LeafIndex {
+			continue
+		}
+		proof.Entries = []imt.MerkleMapEntry{forged}
+		if m.VerifyExclusion(proof, key) {
+			t.Fatal("entries passed off as one entry proved the exclusion of an existing key")
+		}
+		return
+	}
+	t.Fatal("no forgery could be built")
+}
@@ -0,0 +1,36 @@
+package imt
+
+import "time"
+
+// The operations reported to Metrics.ObserveHashCalls, in addition to
+// OpInsertMany.
+const (
+	OpInsert = "insert"
+	OpUpdate = "update"
+	OpDelete = "delete"
+)
+
+// Metrics is notified of the operations performed by a tree, giving
+// visibility into its hot paths. Implementations are typically adapters to a
+// metrics library, such as the Prometheus client.
+type Metrics interface {
+	// IncInserts is called after each successful insertion.
+	IncInserts()
+
+	// IncUpdates is called after each successful update or deletion.
+	IncUpdates()
+
+	// ObserveHashCalls is called after each successful write with the name of
+	// the operation and the number of hash computations it performed.
+	ObserveHashCalls(op string, calls int)
+
+	// ObserveProofLatency is called after each proof generation.
+	ObserveProofLatency(latency time.Duration)
+}
+
+// WithMetrics sets the metrics notified of the operations of the tree.
+func WithMetrics(metrics Metrics) Option {
+	return func(o *options) {
+		o.metrics = metrics
+	}
+}
@@ -0,0 +1,235 @@
+// Package metrics exports the metrics of trees in the Prometheus text
+// exposition format. A Collector implements imt.Metrics for one tree and
+// counts its insertions, updates, hash computations and proof latencies;
+// Handler serves the metrics of several collectors to a Prometheus scraper.
+//
+// The package doesn't depend on the Prometheus client library. Applications
+// using it can instead forward imt.Metrics to their own collectors.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/noble-assets/imt"
+)
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the buckets of
+// the proof latency histogram.
+var DefaultLatencyBuckets = []float64{0.00001, 0.00005, 0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1}
+
+// Collector collects the metrics of a tree, labeled with the name of the
+// tree. It is safe for concurrent use.
+type Collector struct {
+	name    string
+	buckets []float64
+
+	mu        sync.Mutex
+	inserts   uint64
+	updates   uint64
+	ops       map[string]uint64
+	hashCalls map[string]uint64
+	proofs    uint64
+	latencies []uint64 // The cumulative counts of the latency buckets.
+	latency   float64  // The sum of the latencies, in seconds.
+	size      func() (size int, capacity uint64)
+}
+
+var _ imt.Metrics = (*Collector)(nil)
+
+// New returns a Collector for the tree with the given name, with the given
+// latency buckets or DefaultLatencyBuckets if there are none. It is passed
+// to the tree with imt.WithMetrics.
+func New(name string, buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultLatencyBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Collector{
+		name:      name,
+		buckets:   buckets,
+		ops:       make(map[string]uint64),
+		hashCalls: make(map[string]uint64),
+		latencies: make([]uint64, len(buckets)),
+	}
+}
+
+// SetSizeFunc sets the function reporting the size and capacity of the tree
+// when the metrics are scraped. Since scrapes happen concurrently with the
+// operations of the tree, the function must synchronize with them, for
+// example by reading values the application updates after each block.
+func (c *Collector) SetSizeFunc(size func() (size int, capacity uint64)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.size = size
+}
+
+// IncInserts implements imt.Metrics.
+func (c *Collector) IncInserts() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inserts++
+}
+
+// IncUpdates implements imt.Metrics.
+func (c *Collector) IncUpdates() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.updates++
+}
+
+// ObserveHashCalls implements imt.Metrics.
+func (c *Collector) ObserveHashCalls(op string, calls int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ops[op]++
+	c.hashCalls[op] += uint64(calls)
+}
+
+// ObserveProofLatency implements imt.Metrics.
+func (c *Collector) ObserveProofLatency(latency time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	seconds := latency.Seconds()
+	c.proofs++
+	c.latency += seconds
+	for i, bound := range c.buckets {
+		if seconds <= bound {
+			c.latencies[i]++
+		}
+	}
+}
+
+// WriteTo writes the metrics of the collector in the Prometheus text
+// exposition format.
+func (c *Collector) WriteTo(w io.Writer) (int64, error) {
+	return write(w, []*Collector{c})
+}
+
+// Handler returns an HTTP handler serving the metrics of the collectors in
+// the Prometheus text exposition format.
+func Handler(collectors ...*Collector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		write(w, collectors)
+	})
+}
+
+// sample is a sample of a metric family.
+type sample struct {
+	suffix string
+	labels [][2]string
+	value  float64
+}
+
+// family is a metric family of the exposition format.
+type family struct {
+	name, typ, help string
+	samples         []sample
+}
+
+// write writes the metric families of the collectors, each family once.
+func write(w io.Writer, collectors []*Collector) (int64, error) {
+	families := []*family{
+		{name: "imt_tree_size", typ: "gauge", help: "The number of leaves of the tree."},
+		{name: "imt_tree_capacity", typ: "gauge", help: "The maximum number of leaves of the tree."},
+		{name: "imt_inserts_total", typ: "counter", help: "The number of leaves inserted."},
+		{name: "imt_updates_total", typ: "counter", help: "The number of leaves updated or deleted."},
+		{name: "imt_operations_total", typ: "counter", help: "The number of write operations, by operation."},
+		{name: "imt_hash_calls_total", typ: "counter", help: "The number of hash computations of write operations, by operation."},
+		{name: "imt_proof_latency_seconds", typ: "histogram", help: "The latency of proof generation."},
+	}
+	for _, c := range collectors {
+		c.collect(families)
+	}
+
+	bw := bufio.NewWriter(w)
+	counter := &countingWriter{w: bw}
+	for _, f := range families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		fmt.Fprintf(counter, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+		for _, s := range f.samples {
+			fmt.Fprintf(counter, "%s%s%s %s\n", f.name, s.suffix, formatLabels(s.labels), strconv.FormatFloat(s.value, 'g', -1, 64))
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		return counter.n, err
+	}
+	return counter.n, nil
+}
+
+// collect appends the samples of the collector to the families, in the order
+// of write.
+func (c *Collector) collect(families []*family) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	tree := [2]string{"tree", c.name}
+	add := func(f *family, suffix string, value float64, labels ...[2]string) {
+		f.samples = append(f.samples, sample{suffix: suffix, labels: append([][2]string{tree}, labels...), value: value})
+	}
+
+	if c.size != nil {
+		size, capacity := c.size()
+		add(families[0], "", float64(size))
+		add(families[1], "", float64(capacity))
+	}
+	add(families[2], "", float64(c.inserts))
+	add(families[3], "", float64(c.updates))
+	for _, op := range sortedKeys(c.ops) {
+		add(families[4], "", float64(c.ops[op]), [2]string{"op", op})
+		add(families[5], "", float64(c.hashCalls[op]), [2]string{"op", op})
+	}
+	for i, bound := range c.buckets {
+		add(families[6], "_bucket", float64(c.latencies[i]), [2]string{"le", strconv.FormatFloat(bound, 'g', -1, 64)})
+	}
+	add(families[6], "_bucket", float64(c.proofs), [2]string{"le", "+Inf"})
+	add(families[6], "_sum", c.latency)
+	add(families[6], "_count", float64(c.proofs))
+}
+
+// formatLabels formats labels as {name="value",...}, escaping the values.
+func formatLabels(labels [][2]string) string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		value := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(label[1])
+		fmt.Fprintf(&b, "%s=\"%s\"", label[0], value)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// countingWriter counts the bytes written to a writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,59 @@
+package metrics_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+	"github.com/noble-assets/imt/metrics"
+)
+
+func TestCollector(t *testing.T) {
+	collector := metrics.New("accounts")
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, nil, imt.WithMetrics(collector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector.SetSizeFunc(func() (int, uint64) { return tree.Size(), tree.Capacity() })
+	if err := tree.Insert(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.InsertMany([]uint64{2, 3}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Update(0, 4); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.CreateProof(0); err != nil {
+		t.Fatal(err)
+	}
+
+	var b strings.Builder
+	if _, err := collector.WriteTo(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`imt_tree_size{tree="accounts"} 3`,
+		`imt_tree_capacity{tree="accounts"} 16`,
+		`imt_inserts_total{tree="accounts"} 3`,
+		`imt_updates_total{tree="accounts"} 1`,
+		`imt_operations_total{tree="accounts",op="insert"} 1`,
+		`imt_operations_total{tree="accounts",op="insert_many"} 1`,
+		`imt_operations_total{tree="accounts",op="update"} 1`,
+		`imt_hash_calls_total{tree="accounts",op="insert_many"}`,
+		`imt_proof_latency_seconds_count{tree="accounts"} 1`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("missing %q in:\n%s", want, out)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	metrics.Handler(collector).ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Body.String() != out {
+		t.Fatalf("the handler served:\n%s\nwant:\n%s", recorder.Body, out)
+	}
+}
@@ -0,0 +1,96 @@
+package imt_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+// recordingMetrics records the calls of a tree to imt.Metrics.
+type recordingMetrics struct {
+	inserts   int
+	updates   int
+	hashCalls map[string][]int
+	proofs    int
+}
+
+func (m *recordingMetrics) IncInserts() { m.inserts++ }
+
+func (m *recordingMetrics) IncUpdates() { m.updates++ }
+
+func (m *recordingMetrics) ObserveHashCalls(op string, calls int) {
+	m.hashCalls[op] = append(m.hashCalls[op], calls)
+}
+
+func (m *recordingMetrics) ObserveProofLatency(time.Duration) { m.proofs++ }
+
+func TestMetrics(t *testing.T) {
+	tests := []struct {
+		name    string
+		op      func(tree *imt.IMT[uint64]) error
+		inserts int
+		updates int
+		ops     map[string]int
+	}{
+		{"insert", func(tree *imt.IMT[uint64]) error { return tree.Insert(1) }, 1, 0, map[string]int{imt.OpInsert: 1}},
+		{"insert many", func(tree *imt.IMT[uint64]) error { return tree.InsertMany([]uint64{1, 2, 3}) }, 3, 0, map[string]int{imt.OpInsertMany: 1}},
+		{"update", func(tree *imt.IMT[uint64]) error {
+			if err := tree.Insert(1); err != nil {
+				return err
+			}
+			return tree.Update(0, 2)
+		}, 1, 1, map[string]int{imt.OpInsert: 1, imt.OpUpdate: 1}},
+		{"delete", func(tree *imt.IMT[uint64]) error {
+			if err := tree.InsertMany([]uint64{1, 2}); err != nil {
+				return err
+			}
+			return tree.Delete(1)
+		}, 2, 1, map[string]int{imt.OpInsertMany: 1, imt.OpDelete: 1}},
+		{"failed insertion", func(tree *imt.IMT[uint64]) error {
+			tree.InsertMany(make([]uint64, 17))
+			return nil
+		}, 0, 0, map[string]int{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metrics := &recordingMetrics{hashCalls: make(map[string][]int)}
+			tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, nil, imt.WithMetrics(metrics))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.op(tree); err != nil {
+				t.Fatal(err)
+			}
+			if metrics.inserts != test.inserts || metrics.updates != test.updates {
+				t.Fatalf("got %d inserts and %d updates, want %d and %d", metrics.inserts, metrics.updates, test.inserts, test.updates)
+			}
+			if len(metrics.hashCalls) != len(test.ops) {
+				t.Fatalf("hash calls observed for %v, want %v", metrics.hashCalls, test.ops)
+			}
+			for op, count := range test.ops {
+				if len(metrics.hashCalls[op]) != count {
+					t.Fatalf("hash calls observed %d times for %s, want %d", len(metrics.hashCalls[op]), op, count)
+				}
+				for _, calls := range metrics.hashCalls[op] {
+					if calls <= 0 {
+						t.Fatalf("%d hash calls observed for %s", calls, op)
+					}
+				}
+			}
+		})
+	}
+
+	metrics := &recordingMetrics{hashCalls: make(map[string][]int)}
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2}, imt.WithMetrics(metrics))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.CreateProof(1); err != nil {
+		t.Fatal(err)
+	}
+	if metrics.proofs != 1 {
+		t.Fatalf("%d proof latencies observed, want 1", metrics.proofs)
+	}
+}
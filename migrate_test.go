@@ -0,0 +1,82 @@
+package imt_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+// otherHash is a hash function that differs from imttest.Uint64Hash.
+func otherHash(children []uint64) uint64 {
+	return imttest.Uint64Hash(children) ^ 0x5555
+}
+
+func TestMigrate(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 1, 0, 2, nil, imt.WithAutoGrow(0), imt.WithLeafDataCodec(stringData{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.InsertMany([]uint64{1, 2, 3, 4, 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.SetLeafData(2, "two"); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Unix(1000, 0)
+	if err := tree.SetExpiry(3, deadline); err != nil {
+		t.Fatal(err)
+	}
+	if len(tree.DepthIncreases()) == 0 {
+		t.Fatal("the tree did not grow")
+	}
+
+	migrated, err := tree.Migrate(otherHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reference, err := imt.New(otherHash, tree.Depth(), 0, 2, tree.Leaves())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if migrated.Root() != reference.Root() || migrated.Root() == tree.Root() {
+		t.Fatal("the migrated tree was not rehashed")
+	}
+	if migrated.Version() != tree.Version() || !slices.Equal(migrated.DeletedIndices(), tree.DeletedIndices()) {
+		t.Fatal("the state of the tree was not carried over")
+	}
+	if data, _ := migrated.LeafData(2); data != "two" {
+		t.Fatalf("LeafData(2) = %v, want two", data)
+	}
+	if got, ok := migrated.Expiry(3); !ok || !got.Equal(deadline) {
+		t.Fatalf("Expiry(3) = %v, %v, want %v", got, ok, deadline)
+	}
+	// The roots of the depth increases were computed with the previous hash
+	// function.
+	if increases := migrated.DepthIncreases(); len(increases) != 0 {
+		t.Fatalf("DepthIncreases = %v, want none", increases)
+	}
+
+	// The deadlines of both trees are independent.
+	if err := migrated.SetExpiry(4, deadline); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tree.Expiry(4); ok {
+		t.Fatal("a deadline set on the migrated tree changed the original")
+	}
+	cleared, err := migrated.SweepExpired(deadline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(cleared, []int{3, 4}) && !slices.Equal(cleared, []int{4, 3}) {
+		t.Fatalf("SweepExpired = %v, want 3 and 4", cleared)
+	}
+	if _, ok := tree.Expiry(3); !ok {
+		t.Fatal("sweeping the migrated tree changed the original")
+	}
+}
@@ -0,0 +1,111 @@
+package imt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MinimalDepth returns the smallest positive depth of a tree of the same
+// arity that holds the leaves of the tree, which is the depth of the tight
+// tree expected by verifiers that don't pad trees to a fixed depth.
+func (t *IMT[N]) MinimalDepth() int {
+	depth := 1
+	for depth < t.depth && capacity(t.arity, depth) < uint64(t.nodes[0].Len()) {
+		depth++
+	}
+	return depth
+}
+
+// MinimalRoot returns the root of the tree of minimal depth made of the same
+// leaves, which is the leftmost node of the tree at that level.
+func (t *IMT[N]) MinimalRoot() N {
+	return t.readNode(t.MinimalDepth(), 0)
+}
+
+// CreateMinimalProof creates a MerkleProof for a leaf of the tree against the
+// root returned by MinimalRoot.
+func (t *IMT[N]) CreateMinimalProof(index int) (*MerkleProof[N], error) {
+	return t.CreateProofAtDepth(index, t.MinimalDepth())
+}
+
+// PadRoot returns the root of the tree of the given arity and padded depth
+// whose leftmost subtree at the given depth has the given root and whose other
+// subtrees are empty. It converts the root of a tight tree, such as the one
+// returned by MinimalRoot, into the root of the tree padded with zero values.
+// The zero values are the ones returned by Zeroes, up to the padded depth.
+func PadRoot[N comparable](root N, arity, depth, paddedDepth int, zeroes []N, hash HashFunction[N]) (N, error) {
+	if depth < 0 || paddedDepth < depth || len(zeroes) < paddedDepth {
+		return root, errors.New("invalid depths or zero values")
+	}
+	for level := depth; level < paddedDepth; level++ {
+		children := make([]N, arity)
+		children[0] = root
+		for i := 1; i < arity; i++ {
+			children[i] = zeroes[level]
+		}
+		root = hash(children)
+	}
+	return root, nil
+}
+
+// PadProof converts a proof against the root of a tree into a proof against
+// the root of the same tree padded to a greater depth, by adding the zero
+// values of the levels above as siblings. The zero values are the ones
+// returned by Zeroes, up to the padded depth. The proof is not verified.
+func PadProof[N comparable](proof *MerkleProof[N], paddedDepth int, zeroes []N, hash HashFunction[N]) (*MerkleProof[N], error) {
+	arity, err := ProofArity(proof)
+	if err != nil {
+		return nil, err
+	}
+	depth := len(proof.Siblings)
+	if paddedDepth < depth || len(zeroes) < paddedDepth {
+		return nil, fmt.Errorf("cannot pad a proof of depth %d to depth %d with %d zero values", depth, paddedDepth, len(zeroes))
+	}
+
+	padded := proof.clone()
+	for level := depth; level < paddedDepth; level++ {
+		siblings := make([]N, arity-1)
+		for i := range siblings {
+			siblings[i] = zeroes[level]
+		}
+		padded.Siblings = append(padded.Siblings, siblings)
+		padded.PathIndices = append(padded.PathIndices, 0)
+	}
+	if padded.Root, err = PadRoot(proof.Root, arity, depth, paddedDepth, zeroes, hash); err != nil {
+		return nil, err
+	}
+	padded.Depth = paddedDepth
+	return padded, nil
+}
+
+// ShrinkProof converts a proof against the root of a tree padded with zero
+// values into a proof against the root of the tight tree of the given depth,
+// whose root is the ancestor of the leaf at that depth. It returns an error if
+// the leaf is not in the first subtree of that depth or if the other subtrees
+// are not empty, i.e. if the tree does not fit in that depth. The zero values
+// are the ones returned by Zeroes. The proof is not verified.
+func ShrinkProof[N comparable](proof *MerkleProof[N], depth int, zeroes []N, hash HashFunction[N]) (*MerkleProof[N], error) {
+	if _, err := ProofArity(proof); err != nil {
+		return nil, err
+	}
+	if depth < 1 || depth > len(proof.Siblings) || len(zeroes) < len(proof.Siblings) {
+		return nil, fmt.Errorf("cannot shrink a proof of depth %d to depth %d with %d zero values", len(proof.Siblings), depth, len(zeroes))
+	}
+	for level := depth; level < len(proof.Siblings); level++ {
+		if proof.PathIndices[level] != 0 {
+			return nil, fmt.Errorf("the leaf is not in the first subtree of depth %d", depth)
+		}
+		for _, sibling := range proof.Siblings[level] {
+			if sibling != zeroes[level] {
+				return nil, fmt.Errorf("the tree does not fit in depth %d", depth)
+			}
+		}
+	}
+
+	shrunk := proof.clone()
+	shrunk.Siblings = shrunk.Siblings[:depth]
+	shrunk.PathIndices = shrunk.PathIndices[:depth]
+	shrunk.Root, _ = proofRoot(shrunk, hash)
+	shrunk.Depth = depth
+	return shrunk, nil
+}
@@ -0,0 +1,125 @@
+package imt_test
+
+import (
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestMinimalRoot(t *testing.T) {
+	leaves := []uint64{1, 2, 3, 4, 5}
+	tree, err := imt.New(imttest.Uint64Hash, 5, 0, 2, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tight, err := imt.New(imttest.Uint64Hash, 3, 0, 2, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tree.MinimalDepth() != 3 || tree.MinimalRoot() != tight.Root() {
+		t.Fatalf("MinimalDepth = %d, with a root differing from the one of the tight tree", tree.MinimalDepth())
+	}
+	padded, err := imt.PadRoot(tree.MinimalRoot(), 2, 3, 5, tree.Zeroes(), imttest.Uint64Hash)
+	if err != nil || padded != tree.Root() {
+		t.Fatalf("PadRoot = %d, %v, want the root of the tree", padded, err)
+	}
+	if _, err := imt.PadRoot(tree.MinimalRoot(), 2, 3, 6, tree.Zeroes(), imttest.Uint64Hash); err == nil {
+		t.Fatal("PadRoot padded beyond the zero values")
+	}
+	if _, err := imt.PadRoot(tree.MinimalRoot(), 2, 3, 2, tree.Zeroes(), imttest.Uint64Hash); err == nil {
+		t.Fatal("PadRoot padded to a smaller depth")
+	}
+
+	empty, err := imt.New(imttest.Uint64Hash, 5, 0, 2, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if empty.MinimalDepth() != 1 || empty.MinimalRoot() != empty.Zeroes()[1] {
+		t.Fatalf("the minimal depth of an empty tree is %d", empty.MinimalDepth())
+	}
+}
+
+func TestPadAndShrinkProof(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 5, 0, 3, []uint64{1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	zeroes := tree.Zeroes()
+	for index := range tree.Size() {
+		minimal, err := tree.CreateMinimalProof(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		full, err := tree.CreateProof(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if minimal.Root != tree.MinimalRoot() || !imt.VerifyProof(minimal, imttest.Uint64Hash) {
+			t.Fatalf("the minimal proof of leaf %d was rejected", index)
+		}
+
+		padded, err := imt.PadProof(minimal, tree.Depth(), zeroes, imttest.Uint64Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !padded.Equal(full) {
+			t.Fatalf("the padded proof of leaf %d differs from the proof of the tree", index)
+		}
+		shrunk, err := imt.ShrinkProof(full, tree.MinimalDepth(), zeroes, imttest.Uint64Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !shrunk.Equal(minimal) {
+			t.Fatalf("the shrunk proof of leaf %d differs from the minimal proof", index)
+		}
+	}
+
+	full, err := tree.CreateProof(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	last, err := tree.CreateProof(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		shrink func() (*imt.MerkleProof[uint64], error)
+	}{
+		// The tree holds 5 leaves, which don't fit in depth 1.
+		{"tree larger than the depth", func() (*imt.MerkleProof[uint64], error) {
+			return imt.ShrinkProof(full, 1, zeroes, imttest.Uint64Hash)
+		}},
+		{"leaf beyond the depth", func() (*imt.MerkleProof[uint64], error) {
+			return imt.ShrinkProof(last, 1, zeroes, imttest.Uint64Hash)
+		}},
+		{"zero depth", func() (*imt.MerkleProof[uint64], error) {
+			return imt.ShrinkProof(full, 0, zeroes, imttest.Uint64Hash)
+		}},
+		{"greater depth", func() (*imt.MerkleProof[uint64], error) {
+			return imt.ShrinkProof(full, 6, zeroes, imttest.Uint64Hash)
+		}},
+		{"missing zero values", func() (*imt.MerkleProof[uint64], error) {
+			return imt.ShrinkProof(full, 2, zeroes[:4], imttest.Uint64Hash)
+		}},
+		{"padding to a smaller depth", func() (*imt.MerkleProof[uint64], error) {
+			return imt.PadProof(full, 4, zeroes, imttest.Uint64Hash)
+		}},
+		{"padding beyond the zero values", func() (*imt.MerkleProof[uint64], error) {
+			return imt.PadProof(full, 6, zeroes, imttest.Uint64Hash)
+		}},
+		{"malformed proof", func() (*imt.MerkleProof[uint64], error) {
+			malformed := *full
+			malformed.Siblings = malformed.Siblings[:3]
+			return imt.PadProof(&malformed, 6, append(zeroes, 0), imttest.Uint64Hash)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := test.shrink(); err == nil {
+				t.Fatal("the proof was converted")
+			}
+		})
+	}
+}
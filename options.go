@@ -0,0 +1,77 @@
+package imt
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Option configures optional behaviour of a tree. Options are passed to New
+// and to the other constructors of the package.
+type Option func(*options)
+
+// options holds the optional configuration of a tree.
+type options struct {
+	metrics    Metrics
+	hashID     string
+	interning  bool
+	arena      bool
+	leavesOnly bool
+	cacheSize  int
+
+	// The BatchHasher of the tree, whose type depends on the type of the
+	// nodes, and the number of lists of children of each batch.
+	batchHasher any
+	batchSize   int
+
+	levelTimer LevelTimer
+
+	parallel      bool
+	maxGoroutines int
+
+	debugChecks bool
+
+	leafDataCodec LeafDataCodec
+
+	insertionRecords bool
+	clock            func() time.Time
+
+	tracer Tracer
+	logger *slog.Logger
+	equal  any
+
+	zeroes     any
+	zeroChecks int
+
+	deletePolicy DeletePolicy
+
+	proofCacheSize int
+
+	memoSize int
+
+	growth GrowthPolicy
+
+	headroom float64
+
+	autoGrow bool
+	maxDepth int
+
+	uniqueLeaves bool
+}
+
+// newOptions applies a list of options to the default configuration.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithHashID sets an identifier of the hash function, such as "keccak256" or
+// "poseidon-bn254", which is embedded in proofs so that verifiers can reject
+// proofs generated with a different hash function.
+func WithHashID(id string) Option {
+	return func(o *options) {
+		o.hashID = id
+	}
+}
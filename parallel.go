@@ -0,0 +1,66 @@
+package imt
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minParallelHashes is the minimum number of hashes computed by each
+// goroutine of a parallel path, below which the overhead of the goroutines
+// outweighs the benefit.
+const minParallelHashes = 256
+
+// WithMaxGoroutines makes the bulk paths of the tree, the construction from a
+// list of leaves and InsertMany, hash the nodes of each level in parallel with
+// at most n goroutines, or runtime.GOMAXPROCS(0) if n is not positive. The
+// hash function must then be safe for concurrent use. Without this option,
+// the tree never hashes concurrently. It has no effect on trees with a
+// BatchHasher, which controls its own parallelism.
+func WithMaxGoroutines(n int) Option {
+	return func(o *options) {
+		o.parallel = true
+		o.maxGoroutines = n
+	}
+}
+
+// goroutines returns the maximum number of goroutines of the parallel paths.
+func (t *IMT[N]) goroutines() int {
+	switch {
+	case !t.options.parallel:
+		return 1
+	case t.options.maxGoroutines <= 0:
+		return runtime.GOMAXPROCS(0)
+	default:
+		return t.options.maxGoroutines
+	}
+}
+
+// hashParallel returns the hashes of count lists of children, where
+// children(i) returns the i-th list, splitting the work between up to the
+// maximum number of goroutines of the tree. children must be safe for
+// concurrent use.
+func (t *IMT[N]) hashParallel(count int, children func(i int) []N) []N {
+	hashes := make([]N, count)
+	workers := min(t.goroutines(), (count+minParallelHashes-1)/minParallelHashes)
+	if workers <= 1 {
+		for i := range hashes {
+			hashes[i] = t.hash(children(i))
+		}
+		return hashes
+	}
+
+	var wg sync.WaitGroup
+	size := (count + workers - 1) / workers
+	for start := 0; start < count; start += size {
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				hashes[i] = t.hash(children(i))
+			}
+		}(start, min(start+size, count))
+	}
+	wg.Wait()
+
+	return hashes
+}
@@ -0,0 +1,118 @@
+package imt
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pipelineSegment is the number of parents computed by each task of the
+// pipelined construction.
+const pipelineSegment = 1024
+
+// pipelined reports whether the construction of the tree from a list of
+// leaves is pipelined. It requires parallel hashing and levels that can be
+// written concurrently, which excludes interning, arenas, the leaves-only
+// mode and batch hashing.
+func (t *IMT[N]) pipelined() bool {
+	return t.goroutines() > 1 && t.batchHasher == nil && t.interner == nil && !t.options.arena && !t.options.leavesOnly
+}
+
+// pipelineTask computes a segment of the parents of a level.
+type pipelineTask struct {
+	level   int
+	segment int
+}
+
+// buildPipelined computes the internal levels of a tree from its leaves like
+// buildLevel, but does not wait for a level to be complete to start hashing
+// the level above it: the levels are split into segments of pipelineSegment
+// parents, and a segment is hashed as soon as the segments of its children
+// are, by a bounded pool of goroutines. The levels above the leaves are
+// allocated at their final size and only written with Set, so that the
+// finished segments can be read while the other ones are written. The level
+// timer is notified of the time from the start of the construction to the
+// end of each level, since the levels overlap.
+func (t *IMT[N]) buildPipelined() {
+	// The number of nodes and of segments of each level.
+	lengths := make([]int, t.depth+1)
+	segments := make([]int, t.depth+1)
+	lengths[0] = t.nodes[0].Len()
+	for level := 1; level <= t.depth; level++ {
+		lengths[level] = (lengths[level-1] + t.arity - 1) / t.arity
+		segments[level-1] = (lengths[level] + pipelineSegment - 1) / pipelineSegment
+		t.nodes[level] = t.newFilledLevel(lengths[level])
+	}
+
+	// The number of segments of children that each segment waits for, and
+	// the number of finished segments of each level.
+	waiting := make([][]atomic.Int32, t.depth)
+	finished := make([]atomic.Int32, t.depth)
+	total := segments[0]
+	for level := 1; level < t.depth; level++ {
+		waiting[level] = make([]atomic.Int32, segments[level])
+		for segment := range waiting[level] {
+			children := min(segments[level-1], (segment+1)*t.arity) - segment*t.arity
+			waiting[level][segment].Store(int32(children))
+		}
+		total += segments[level]
+	}
+
+	// The queue can hold every task, so that finishing a task never blocks.
+	tasks := make(chan pipelineTask, total)
+	for segment := range segments[0] {
+		tasks <- pipelineTask{level: 0, segment: segment}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(total)
+	go func() {
+		wg.Wait()
+		close(tasks)
+	}()
+
+	start := time.Now()
+	run := func(task pipelineTask) {
+		first := task.segment * pipelineSegment
+		last := min(first+pipelineSegment, lengths[task.level+1])
+		for index := first; index < last; index++ {
+			children := t.storedChildren(task.level, index)
+			parent, ok := t.zeroParent(task.level, children)
+			if !ok {
+				parent = t.hash(children)
+			}
+			t.nodes[task.level+1].Set(index, parent)
+		}
+
+		// Levels finish in order, since the last segment of a level waits
+		// for the last segment of the level below, so the level timer is
+		// never called concurrently.
+		if int(finished[task.level].Add(1)) == segments[task.level] && t.options.levelTimer != nil {
+			t.options.levelTimer(OpBuild, task.level, time.Since(start))
+		}
+		if parent := task.level + 1; parent < t.depth {
+			if waiting[parent][task.segment/t.arity].Add(-1) == 0 {
+				tasks <- pipelineTask{level: parent, segment: task.segment / t.arity}
+			}
+		}
+		wg.Done()
+	}
+
+	var workers sync.WaitGroup
+	for range t.goroutines() {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for task := range tasks {
+				labels := pprof.Labels("imt.op", OpBuild, "imt.level", strconv.Itoa(task.level))
+				pprof.Do(context.Background(), labels, func(context.Context) {
+					run(task)
+				})
+			}
+		}()
+	}
+	workers.Wait()
+}
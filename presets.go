@@ -0,0 +1,44 @@
+package imt
+
+import (
+	"crypto/sha256"
+
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+// Keccak256Hash hashes the concatenation of the children with Keccak-256, as
+// keccak256(abi.encodePacked(children)) does in Solidity. It is the hash
+// function of Hyperlane's MerkleLib and most Solidity trees.
+func Keccak256Hash(children [][32]byte) [32]byte {
+	data := make([][]byte, len(children))
+	for i := range children {
+		data[i] = children[i][:]
+	}
+	return keccak.Sum256(data...)
+}
+
+// SHA256Hash hashes the concatenation of the children with SHA-256. It is the
+// hash function of the Ethereum deposit contract.
+func SHA256Hash(children [][32]byte) [32]byte {
+	h := sha256.New()
+	for _, child := range children {
+		h.Write(child[:])
+	}
+	return [32]byte(h.Sum(nil))
+}
+
+// NewKeccak256Binary returns an empty binary tree of 32-byte nodes hashed with
+// Keccak256Hash, whose zero value is 32 zero bytes and whose hash identifier
+// is "keccak256". With a depth of 32, it computes the roots of Hyperlane's
+// MerkleLib.
+func NewKeccak256Binary(depth int, opts ...Option) (*IMT[[32]byte], error) {
+	return New(Keccak256Hash, depth, [32]byte{}, 2, nil, append([]Option{WithHashID("keccak256")}, opts...)...)
+}
+
+// NewSHA256Binary returns an empty binary tree of 32-byte nodes hashed with
+// SHA256Hash, whose zero value is 32 zero bytes and whose hash identifier is
+// "sha256". With a depth of 32, it computes the roots of the Ethereum deposit
+// contract before the length is mixed in.
+func NewSHA256Binary(depth int, opts ...Option) (*IMT[[32]byte], error) {
+	return New(SHA256Hash, depth, [32]byte{}, 2, nil, append([]Option{WithHashID("sha256")}, opts...)...)
+}
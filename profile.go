@@ -0,0 +1,54 @@
+package imt
+
+import (
+	"context"
+	"runtime/pprof"
+	"strconv"
+	"time"
+)
+
+// The operations of the bulk paths, used as the value of the "imt.op" pprof
+// label, reported to the level timer and used to name spans.
+const (
+	OpBuild        = "build"
+	OpInsertMany   = "insert_many"
+	OpStreamProofs = "stream_proofs"
+	OpVerifyProofs = "verify_proofs"
+	OpSnapshot     = "snapshot"
+	OpCompact      = "compact"
+)
+
+// LevelTimer is notified of the time spent on each level of the bulk paths
+// of the tree, identified by their operation.
+type LevelTimer func(op string, level int, elapsed time.Duration)
+
+// WithLevelTimer sets a LevelTimer notified of the time spent on each level
+// by the construction of the tree from a list of leaves and by InsertMany.
+func WithLevelTimer(timer LevelTimer) Option {
+	return func(o *options) {
+		o.levelTimer = timer
+	}
+}
+
+// profileLevel runs f, which processes a level of a bulk path, with the pprof
+// labels "imt.op" and "imt.level", so that CPU profiles attribute the time to
+// the phase of the operation, and reports its duration to the level timer.
+func (t *IMT[N]) profileLevel(op string, level int, f func() error) error {
+	start := time.Now()
+	var err error
+	pprof.Do(context.Background(), pprof.Labels("imt.op", op, "imt.level", strconv.Itoa(level)), func(context.Context) {
+		err = f()
+	})
+	if t.options.levelTimer != nil {
+		t.options.levelTimer(op, level, time.Since(start))
+	}
+	return err
+}
+
+// profile runs f, which performs a bulk operation that is not processed level
+// by level, with the pprof label "imt.op".
+func profile(op string, f func()) {
+	pprof.Do(context.Background(), pprof.Labels("imt.op", op), func(context.Context) {
+		f()
+	})
+}
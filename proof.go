@@ -0,0 +1,210 @@
+package imt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ProofVersion is the version of the proof layout written by the serialized
+// proof formats of this package. Decoders dispatch on the version, so the
+// layout can evolve without breaking verifiers that decode older proofs.
+//
+// Version 2 added the optional depth, arity and hash identifier of the tree.
+const ProofVersion = 2
+
+// merkleProofJSON is the JSON layout of a proof. Version 2 only added
+// optional fields to version 1, so both are decoded with the same layout.
+type merkleProofJSON[N comparable] struct {
+	Version     int    `json:"version"`
+	Root        N      `json:"root"`
+	Leaf        N      `json:"leaf"`
+	LeafIndex   int    `json:"leafIndex"`
+	Siblings    [][]N  `json:"siblings"`
+	PathIndices []int  `json:"pathIndices"`
+	Depth       int    `json:"depth,omitempty"`
+	Arity       int    `json:"arity,omitempty"`
+	HashID      string `json:"hashId,omitempty"`
+}
+
+// MarshalJSON encodes the proof as JSON, including a "version" field equal
+// to ProofVersion.
+func (p MerkleProof[N]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(merkleProofJSON[N]{
+		Version:     ProofVersion,
+		Root:        p.Root,
+		Leaf:        p.Leaf,
+		LeafIndex:   p.LeafIndex,
+		Siblings:    p.Siblings,
+		PathIndices: p.PathIndices,
+		Depth:       p.Depth,
+		Arity:       p.Arity,
+		HashID:      p.HashID,
+	})
+}
+
+// UnmarshalJSON decodes a JSON proof, dispatching on its "version" field.
+// Proofs without a version were produced before the field was introduced and
+// are decoded as version 1.
+func (p *MerkleProof[N]) UnmarshalJSON(b []byte) error {
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return err
+	}
+
+	switch header.Version {
+	case 0, 1, 2:
+		var v merkleProofJSON[N]
+		if err := json.Unmarshal(b, &v); err != nil {
+			return err
+		}
+		*p = MerkleProof[N]{
+			Root:        v.Root,
+			Leaf:        v.Leaf,
+			LeafIndex:   v.LeafIndex,
+			Siblings:    v.Siblings,
+			PathIndices: v.PathIndices,
+			Depth:       v.Depth,
+			Arity:       v.Arity,
+			HashID:      v.HashID,
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported proof version %d", header.Version)
+	}
+}
+
+// ProofArity validates the layout of a proof and returns the arity of the
+// tree it was generated from, inferred from the number of siblings. A proof is
+// well formed if it has at least one level, a path index for each level, the
+// same number of siblings at every level, path indices within [0, arity), a
+// leaf index matching the path indices and, if set, an Arity field matching the
+// inferred arity.
+func ProofArity[N comparable](proof *MerkleProof[N]) (int, error) {
+	if proof == nil {
+		return 0, errors.New("proof is nil")
+	}
+	if len(proof.Siblings) == 0 {
+		return 0, errors.New("the proof has no levels")
+	}
+	if len(proof.Siblings) != len(proof.PathIndices) {
+		return 0, errors.New("the proof must contain a path index for each level")
+	}
+
+	arity := len(proof.Siblings[0]) + 1
+	if proof.Arity != 0 && proof.Arity != arity {
+		return 0, fmt.Errorf("the proof declares arity %d but has arity %d", proof.Arity, arity)
+	}
+
+	leafIndex := 0
+	for level := len(proof.Siblings) - 1; level >= 0; level-- {
+		if len(proof.Siblings[level]) != arity-1 {
+			return 0, fmt.Errorf("the proof has %d siblings at level %d, expected %d", len(proof.Siblings[level]), level, arity-1)
+		}
+		pathIndex := proof.PathIndices[level]
+		if pathIndex < 0 || pathIndex >= arity {
+			return 0, fmt.Errorf("path index %d of level %d is out of range", pathIndex, level)
+		}
+		if leafIndex > (maxInt-pathIndex)/arity {
+			return 0, errors.New("the leaf index overflows int")
+		}
+		leafIndex = leafIndex*arity + pathIndex
+	}
+	if leafIndex != proof.LeafIndex {
+		return 0, fmt.Errorf("leaf index %d does not match the path indices", proof.LeafIndex)
+	}
+
+	return arity, nil
+}
+
+// Canonicalize normalizes the proof in place so that two proofs of the same
+// leaf under the same root have the same representation: the leaf index is
+// derived from the path indices, which are the authoritative positions used by
+// verification, and every slice is copied to an exact size, with empty slices
+// replaced by nil. It returns an error, leaving the proof untouched, if the
+// path indices don't match the siblings.
+func (p *MerkleProof[N]) Canonicalize() error {
+	if len(p.Siblings) != len(p.PathIndices) {
+		return errors.New("the proof must contain a path index for each level")
+	}
+
+	leafIndex := 0
+	for level := len(p.Siblings) - 1; level >= 0; level-- {
+		pathIndex := p.PathIndices[level]
+		if pathIndex < 0 || pathIndex > len(p.Siblings[level]) {
+			return fmt.Errorf("path index %d of level %d is out of range", pathIndex, level)
+		}
+		if leafIndex > (maxInt-pathIndex)/(len(p.Siblings[level])+1) {
+			return errors.New("the leaf index overflows int")
+		}
+		leafIndex = leafIndex*(len(p.Siblings[level])+1) + pathIndex
+	}
+
+	var siblings [][]N
+	var pathIndices []int
+	if len(p.Siblings) > 0 {
+		siblings = make([][]N, len(p.Siblings))
+		for level, nodes := range p.Siblings {
+			if len(nodes) > 0 {
+				siblings[level] = slices.Clone(nodes)
+			}
+		}
+		pathIndices = slices.Clone(p.PathIndices)
+	}
+
+	p.LeafIndex = leafIndex
+	p.Siblings = siblings
+	p.PathIndices = pathIndices
+
+	return nil
+}
+
+// Equal reports whether two proofs contain the same root, leaf, leaf index,
+// siblings, path indices and tree parameters. Nil and empty slices are considered equal, but
+// proofs are otherwise compared as is, so they should be canonicalized first
+// if they may come from different sources.
+func (p *MerkleProof[N]) Equal(other *MerkleProof[N]) bool {
+	if p == nil || other == nil {
+		return p == other
+	}
+	return p.Root == other.Root &&
+		p.Leaf == other.Leaf &&
+		p.LeafIndex == other.LeafIndex &&
+		slices.EqualFunc(p.Siblings, other.Siblings, func(a, b []N) bool { return slices.Equal(a, b) }) &&
+		slices.Equal(p.PathIndices, other.PathIndices) &&
+		p.Depth == other.Depth &&
+		p.Arity == other.Arity &&
+		p.HashID == other.HashID
+}
+
+// CompatibleWith checks that a proof was generated by a tree with the same
+// configuration as this one. The optional parameters embedded in the proof
+// must match the depth, arity and hash identifier of the tree when both are
+// set, and the shape of the siblings must match the depth and arity. This lets
+// verifiers report a configuration mismatch instead of a failed verification.
+func (t *IMT[N]) CompatibleWith(proof *MerkleProof[N]) error {
+	if proof == nil {
+		return errors.New("proof is nil")
+	}
+	if proof.Depth != 0 && proof.Depth != t.depth {
+		return fmt.Errorf("proof depth %d does not match tree depth %d", proof.Depth, t.depth)
+	}
+	if proof.Arity != 0 && proof.Arity != t.arity {
+		return fmt.Errorf("proof arity %d does not match tree arity %d", proof.Arity, t.arity)
+	}
+	if proof.HashID != "" && t.options.hashID != "" && proof.HashID != t.options.hashID {
+		return fmt.Errorf("proof hash %q does not match tree hash %q", proof.HashID, t.options.hashID)
+	}
+	if len(proof.Siblings) != t.depth || len(proof.PathIndices) != t.depth {
+		return fmt.Errorf("proof has %d levels, expected %d", len(proof.Siblings), t.depth)
+	}
+	for level, siblings := range proof.Siblings {
+		if len(siblings) != t.arity-1 {
+			return fmt.Errorf("proof has %d siblings at level %d, expected %d", len(siblings), level, t.arity-1)
+		}
+	}
+	return nil
+}
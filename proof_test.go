@@ -0,0 +1,317 @@
+package imt_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+// encodeProofV1 encodes a proof in the binary layout of version 1, which has
+// no tree parameters.
+func encodeProofV1(proof *imt.MerkleProof[uint64]) []byte {
+	node := func(b []byte, n uint64) []byte {
+		return binary.BigEndian.AppendUint64(append(b, 8), n)
+	}
+	b := node([]byte{1}, proof.Root)
+	b = node(b, proof.Leaf)
+	b = binary.AppendUvarint(b, uint64(proof.LeafIndex))
+	b = binary.AppendUvarint(b, uint64(len(proof.Siblings)))
+	for i, siblings := range proof.Siblings {
+		b = binary.AppendUvarint(b, uint64(len(siblings)))
+		for _, sibling := range siblings {
+			b = node(b, sibling)
+		}
+		b = binary.AppendUvarint(b, uint64(proof.PathIndices[i]))
+	}
+	return b
+}
+
+func TestProofVersions(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 3, 0, 3, []uint64{1, 2, 3, 4, 5}, imt.WithHashID("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof, err := tree.CreateProof(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The proofs of version 1 have no tree parameters.
+	v1 := *proof
+	v1.Depth, v1.Arity, v1.HashID = 0, 0, ""
+
+	codec := imt.NewProofValueCodec[uint64](uint64Codec{})
+	b, err := codec.Encode(*proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b[0] != imt.ProofVersion {
+		t.Fatalf("the encoding starts with version %d, want %d", b[0], imt.ProofVersion)
+	}
+	decoded, err := codec.Decode(encodeProofV1(proof))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Equal(&v1) || !tree.VerifyProof(&decoded) {
+		t.Fatal("the proof of version 1 was not decoded")
+	}
+	for _, version := range []byte{0, imt.ProofVersion + 1} {
+		b[0] = version
+		if _, err := codec.Decode(b); err == nil {
+			t.Fatalf("decoded a proof of version %d", version)
+		}
+	}
+
+	encoded, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(encoded), `"version":2`) {
+		t.Fatalf("the JSON proof has no version: %s", encoded)
+	}
+	tests := []struct {
+		name  string
+		json  string
+		want  *imt.MerkleProof[uint64]
+		valid bool
+	}{
+		{"current", string(encoded), proof, true},
+		{"version 1", strings.Replace(string(encoded), `"version":2`, `"version":1`, 1), proof, true},
+		{"without version", `{"root":1,"leaf":2,"leafIndex":1,"siblings":[[3]],"pathIndices":[1]}`,
+			&imt.MerkleProof[uint64]{Root: 1, Leaf: 2, LeafIndex: 1, Siblings: [][]uint64{{3}}, PathIndices: []int{1}}, true},
+		{"future version", strings.Replace(string(encoded), `"version":2`, `"version":3`, 1), nil, false},
+		{"invalid version", `{"version":"2"}`, nil, false},
+	}
+	for _, test := range tests {
+		var decoded imt.MerkleProof[uint64]
+		err := json.Unmarshal([]byte(test.json), &decoded)
+		if (err == nil) != test.valid {
+			t.Fatalf("%s: got error %v, want valid %v", test.name, err, test.valid)
+		}
+		if test.valid && !decoded.Equal(test.want) {
+			t.Fatalf("%s: decoded %+v, want %+v", test.name, decoded, *test.want)
+		}
+	}
+}
+
+func TestProofArity(t *testing.T) {
+	tests := []struct {
+		name  string
+		proof *imt.MerkleProof[uint64]
+		arity int
+	}{
+		{"binary", &imt.MerkleProof[uint64]{LeafIndex: 2, Siblings: [][]uint64{{1}, {2}}, PathIndices: []int{0, 1}}, 2},
+		{"ternary", &imt.MerkleProof[uint64]{LeafIndex: 5, Siblings: [][]uint64{{1, 2}, {3, 4}}, PathIndices: []int{2, 1}}, 3},
+		{"declared arity", &imt.MerkleProof[uint64]{Siblings: [][]uint64{{1, 2}}, PathIndices: []int{0}, Arity: 3}, 3},
+		{"nil", nil, 0},
+		{"no levels", &imt.MerkleProof[uint64]{}, 0},
+		{"missing path index", &imt.MerkleProof[uint64]{Siblings: [][]uint64{{1}, {2}}, PathIndices: []int{0}}, 0},
+		{"uneven siblings", &imt.MerkleProof[uint64]{Siblings: [][]uint64{{1}, {2, 3}}, PathIndices: []int{0, 0}}, 0},
+		{"path index out of range", &imt.MerkleProof[uint64]{LeafIndex: 2, Siblings: [][]uint64{{1}}, PathIndices: []int{2}}, 0},
+		{"negative path index", &imt.MerkleProof[uint64]{LeafIndex: -1, Siblings: [][]uint64{{1}}, PathIndices: []int{-1}}, 0},
+		{"leaf index mismatch", &imt.MerkleProof[uint64]{LeafIndex: 1, Siblings: [][]uint64{{1}, {2}}, PathIndices: []int{0, 1}}, 0},
+		{"declared arity mismatch", &imt.MerkleProof[uint64]{Siblings: [][]uint64{{1}}, PathIndices: []int{0}, Arity: 3}, 0},
+	}
+	for _, test := range tests {
+		arity, err := imt.ProofArity(test.proof)
+		if (err == nil) != (test.arity != 0) || arity != test.arity {
+			t.Errorf("%s: ProofArity = %d, %v, want %d", test.name, arity, err, test.arity)
+		}
+		if test.arity == 0 && test.proof != nil && imt.VerifyProof(test.proof, imttest.Uint64Hash) {
+			t.Errorf("%s: malformed proof verified", test.name)
+		}
+	}
+}
+
+func TestVerifyProofs(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 3, 0, 2, []uint64{1, 2, 3, 4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := imt.New(imttest.Uint64Hash, 2, 0, 3, []uint64{7, 8, 9, 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// proofs returns fresh proofs of the leaves of a tree.
+	proofs := func(tree *imt.IMT[uint64], indices ...int) []*imt.MerkleProof[uint64] {
+		var proofs []*imt.MerkleProof[uint64]
+		for _, index := range indices {
+			proof, err := tree.CreateProof(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			proofs = append(proofs, proof)
+		}
+		return proofs
+	}
+
+	tests := []struct {
+		name   string
+		proofs []*imt.MerkleProof[uint64]
+		forge  func(proofs []*imt.MerkleProof[uint64])
+		valid  bool
+	}{
+		{"all leaves", proofs(tree, 0, 1, 2, 3, 4, 5), nil, true},
+		{"repeated leaf", proofs(tree, 2, 2, 3), nil, true},
+		{"two trees", append(proofs(tree, 0, 1), proofs(other, 0, 3)...), nil, true},
+		{"altered leaf after its sibling", proofs(tree, 0, 1), func(p []*imt.MerkleProof[uint64]) { p[1].Leaf++ }, false},
+		{"altered sibling below a verified node", proofs(tree, 0, 1), func(p []*imt.MerkleProof[uint64]) { p[1].Siblings[0][0]++ }, false},
+		{"altered sibling above a verified node", proofs(tree, 0, 1), func(p []*imt.MerkleProof[uint64]) { p[1].Siblings[2][0]++ }, false},
+		{"altered root", proofs(tree, 0, 1), func(p []*imt.MerkleProof[uint64]) { p[1].Root++ }, false},
+		{"altered leaf index", proofs(tree, 0, 1), func(p []*imt.MerkleProof[uint64]) { p[1].LeafIndex = 0 }, false},
+		{"no levels", proofs(tree, 0), func(p []*imt.MerkleProof[uint64]) {
+			p[0].Siblings, p[0].PathIndices, p[0].LeafIndex, p[0].Leaf = nil, nil, 0, p[0].Root
+		}, false},
+		{"truncated after a full proof", proofs(tree, 0, 0), func(p []*imt.MerkleProof[uint64]) {
+			// The second proof stops at the parent of the level-2 node of
+			// the first one, claiming the root of the tree.
+			p[1].Siblings, p[1].PathIndices = p[1].Siblings[:2], p[1].PathIndices[:2]
+			p[1].Depth = 2
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.forge != nil {
+				test.forge(test.proofs)
+			}
+			if got := imt.VerifyProofs(test.proofs, imttest.Uint64Hash); got != test.valid {
+				t.Fatalf("VerifyProofs = %v, want %v", got, test.valid)
+			}
+			// The batch agrees with the proofs verified one by one.
+			valid := true
+			for _, proof := range test.proofs {
+				valid = valid && imt.VerifyProof(proof, imttest.Uint64Hash)
+			}
+			if valid != test.valid {
+				t.Fatalf("VerifyProof of each proof = %v, want %v", valid, test.valid)
+			}
+		})
+	}
+}
+
+func TestCanonicalizeAndEqual(t *testing.T) {
+	proof := &imt.MerkleProof[uint64]{
+		Root:        1,
+		Leaf:        2,
+		LeafIndex:   0,
+		Siblings:    [][]uint64{{3, 4}, {}},
+		PathIndices: []int{1, 0},
+		Depth:       2,
+	}
+	if err := proof.Canonicalize(); err != nil {
+		t.Fatal(err)
+	}
+	// The leaf index is derived from the path indices and the arity of
+	// each level.
+	want := &imt.MerkleProof[uint64]{Root: 1, Leaf: 2, LeafIndex: 1, Siblings: [][]uint64{{3, 4}, nil}, PathIndices: []int{1, 0}, Depth: 2}
+	if !proof.Equal(want) || proof.Siblings[1] != nil {
+		t.Fatalf("canonicalized proof %+v", *proof)
+	}
+
+	invalid := []struct {
+		name  string
+		proof *imt.MerkleProof[uint64]
+	}{
+		{"missing path index", &imt.MerkleProof[uint64]{LeafIndex: 7, Siblings: [][]uint64{{1}, {2}}, PathIndices: []int{0}}},
+		{"path index out of range", &imt.MerkleProof[uint64]{LeafIndex: 7, Siblings: [][]uint64{{1}}, PathIndices: []int{2}}},
+		{"negative path index", &imt.MerkleProof[uint64]{LeafIndex: 7, Siblings: [][]uint64{{1}}, PathIndices: []int{-1}}},
+	}
+	for _, test := range invalid {
+		if err := test.proof.Canonicalize(); err == nil || test.proof.LeafIndex != 7 {
+			t.Fatalf("%s: Canonicalize = %v, leaving leaf index %d", test.name, err, test.proof.LeafIndex)
+		}
+	}
+
+	empty := &imt.MerkleProof[uint64]{Siblings: [][]uint64{}, PathIndices: []int{}}
+	if err := empty.Canonicalize(); err != nil || empty.Siblings != nil || empty.PathIndices != nil {
+		t.Fatalf("canonicalized empty proof %+v, %v", *empty, err)
+	}
+
+	fields := []struct {
+		name   string
+		change func(p *imt.MerkleProof[uint64])
+	}{
+		{"root", func(p *imt.MerkleProof[uint64]) { p.Root++ }},
+		{"leaf", func(p *imt.MerkleProof[uint64]) { p.Leaf++ }},
+		{"leaf index", func(p *imt.MerkleProof[uint64]) { p.LeafIndex++ }},
+		{"sibling", func(p *imt.MerkleProof[uint64]) { p.Siblings = [][]uint64{{3, 5}, nil} }},
+		{"path index", func(p *imt.MerkleProof[uint64]) { p.PathIndices = []int{1, 1} }},
+		{"depth", func(p *imt.MerkleProof[uint64]) { p.Depth++ }},
+		{"arity", func(p *imt.MerkleProof[uint64]) { p.Arity = 3 }},
+		{"hash", func(p *imt.MerkleProof[uint64]) { p.HashID = "other" }},
+	}
+	for _, field := range fields {
+		changed := *want
+		field.change(&changed)
+		if want.Equal(&changed) || changed.Equal(want) {
+			t.Fatalf("proofs with different %ss are equal", field.name)
+		}
+	}
+	var none *imt.MerkleProof[uint64]
+	if !none.Equal(nil) || none.Equal(want) || want.Equal(nil) {
+		t.Fatal("Equal mishandles nil proofs")
+	}
+}
+
+func TestCompatibleWith(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 3, 0, 3, []uint64{1, 2, 3, 4}, imt.WithHashID("test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		change     func(p *imt.MerkleProof[uint64])
+		compatible bool
+	}{
+		{"own proof", nil, true},
+		{"without parameters", func(p *imt.MerkleProof[uint64]) { p.Depth, p.Arity, p.HashID = 0, 0, "" }, true},
+		{"other depth", func(p *imt.MerkleProof[uint64]) { p.Depth = 4 }, false},
+		{"other arity", func(p *imt.MerkleProof[uint64]) { p.Arity = 2 }, false},
+		{"other hash", func(p *imt.MerkleProof[uint64]) { p.HashID = "other" }, false},
+		{"missing level", func(p *imt.MerkleProof[uint64]) {
+			p.Depth, p.Siblings, p.PathIndices = 0, p.Siblings[:2], p.PathIndices[:2]
+		}, false},
+		{"missing sibling", func(p *imt.MerkleProof[uint64]) { p.Arity, p.Siblings[1] = 0, p.Siblings[1][:1] }, false},
+	}
+	for _, test := range tests {
+		proof, err := tree.CreateProof(3)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.change != nil {
+			test.change(proof)
+		}
+		if err := tree.CompatibleWith(proof); (err == nil) != test.compatible {
+			t.Errorf("%s: CompatibleWith = %v, want compatible %v", test.name, err, test.compatible)
+		}
+	}
+	if tree.CompatibleWith(nil) == nil {
+		t.Error("nil proof compatible")
+	}
+}
+
+func TestProofJSONRejectsMalformedProofs(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"leaf index mismatch", `{"version":2,"root":1,"leaf":2,"leafIndex":0,"siblings":[[3]],"pathIndices":[1]}`},
+		{"no levels", `{"version":1,"root":2,"leaf":2,"leafIndex":0,"siblings":[],"pathIndices":[]}`},
+		{"no levels without version", `{"root":2,"leaf":2}`},
+		{"declared arity mismatch", `{"version":2,"root":1,"leaf":2,"leafIndex":1,"siblings":[[3]],"pathIndices":[1],"arity":3}`},
+	}
+	for _, test := range tests {
+		// Malformed proofs decode, but don't verify.
+		var proof imt.MerkleProof[uint64]
+		if err := json.Unmarshal([]byte(test.json), &proof); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		proof.Root = imttest.Uint64Hash([]uint64{proof.Leaf, 3})
+		if imt.VerifyProof(&proof, imttest.Uint64Hash) {
+			t.Errorf("%s: malformed proof verified", test.name)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package imt
+
+import "container/list"
+
+// WithProofCache makes CreateProof keep the proofs of up to size leaves in a
+// cache with a least-recently-used eviction policy, so that repeated requests
+// for the proofs of popular leaves don't read the tree again. The proofs are
+// cached with the version of the tree, and a cached proof is only returned
+// while the version is unchanged, since any write to the tree changes the
+// root, which is an ancestor of every leaf. CreateProof returns copies of
+// the cached proofs, which callers may modify. Cached proofs do not notify
+// the gas meter.
+func WithProofCache(size int) Option {
+	return func(o *options) {
+		o.proofCacheSize = size
+	}
+}
+
+// proofCache is a bounded cache of the proofs of the leaves of a tree with a
+// least-recently-used eviction policy. It counts its hits and misses.
+type proofCache[N comparable] struct {
+	size    int
+	entries map[int]*list.Element
+	order   *list.List
+
+	hits   uint64
+	misses uint64
+}
+
+// proofEntry is the value of the elements of the order of a proofCache.
+type proofEntry[N comparable] struct {
+	index   int
+	version uint64
+	proof   *MerkleProof[N]
+}
+
+func newProofCache[N comparable](size int) *proofCache[N] {
+	return &proofCache[N]{
+		size:    size,
+		entries: make(map[int]*list.Element),
+		order:   list.New(),
+	}
+}
+
+// get returns a copy of the cached proof of a leaf at the given version of
+// the tree, if any. Proofs of other versions are evicted.
+func (c *proofCache[N]) get(index int, version uint64) (*MerkleProof[N], bool) {
+	element, ok := c.entries[index]
+	if ok && element.Value.(*proofEntry[N]).version != version {
+		c.order.Remove(element)
+		delete(c.entries, index)
+		ok = false
+	}
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	return element.Value.(*proofEntry[N]).proof.clone(), true
+}
+
+// put caches a copy of the proof of a leaf at the given version of the tree,
+// evicting the least recently used proof if the cache is full.
+func (c *proofCache[N]) put(index int, version uint64, proof *MerkleProof[N]) {
+	entry := &proofEntry[N]{index: index, version: version, proof: proof.clone()}
+	if element, ok := c.entries[index]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	if c.order.Len() >= c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*proofEntry[N]).index)
+	}
+	c.entries[index] = c.order.PushFront(entry)
+}
+
+// clear evicts every proof, for the changes of the nodes that don't change
+// the version of the tree.
+func (c *proofCache[N]) clear() {
+	clear(c.entries)
+	c.order.Init()
+}
+
+// clone returns a deep copy of the proof.
+func (p *MerkleProof[N]) clone() *MerkleProof[N] {
+	clone := *p
+	clone.Siblings = make([][]N, len(p.Siblings))
+	for i, siblings := range p.Siblings {
+		clone.Siblings[i] = append([]N(nil), siblings...)
+	}
+	clone.PathIndices = append([]int(nil), p.PathIndices...)
+	return &clone
+}
@@ -0,0 +1,203 @@
+package imt
+
+import (
+	"maps"
+	"slices"
+)
+
+// PrunedIMT is a tree that only retains its append frontier, which is enough
+// to append leaves and compute the root, and the proofs of a set of pinned
+// leaves, which are updated as leaves are appended. Its memory is bounded by
+// the depth of the tree and the number of pinned leaves, so long-running
+// relayers that only serve the proofs of recent leaves don't hold the whole
+// tree.
+type PrunedIMT[N comparable] struct {
+	hash   HashFunction[N]
+	depth  int
+	arity  int
+	zeroes []N
+	hashID string
+
+	// The complete children of the rightmost node of each level.
+	frontier [][]N
+	size     int
+	root     N
+
+	// The proofs of the pinned leaves, the leaves pinned until unpinned, and
+	// the number of last leaves that are pinned as they are appended.
+	proofs     map[int]*MerkleProof[N]
+	pinned     map[int]bool
+	keepRecent int
+}
+
+var _ Prover[int] = (*PrunedIMT[int])(nil)
+
+// Prune returns a PrunedIMT with the leaves of the tree, pinning the leaves
+// at the given indices until they are unpinned, and the keepRecent last
+// leaves, including the ones appended later. The tree itself is left
+// untouched and can be discarded.
+func (t *IMT[N]) Prune(keepRecent int, pinned ...int) (*PrunedIMT[N], error) {
+	p := &PrunedIMT[N]{
+		hash:       t.hashFunc,
+		depth:      t.depth,
+		arity:      t.arity,
+		zeroes:     slices.Clone(t.zeroes),
+		hashID:     t.options.hashID,
+		frontier:   make([][]N, t.depth),
+		size:       t.nodes[0].Len(),
+		root:       t.Root(),
+		proofs:     make(map[int]*MerkleProof[N]),
+		pinned:     make(map[int]bool),
+		keepRecent: keepRecent,
+	}
+	for level, complete := 0, p.size; level < t.depth; level++ {
+		for index := complete - complete%t.arity; index < complete; index++ {
+			p.frontier[level] = append(p.frontier[level], t.readNode(level, index))
+		}
+		complete /= t.arity
+	}
+
+	for _, index := range pinned {
+		proof, err := t.CreateProof(index)
+		if err != nil {
+			return nil, err
+		}
+		p.proofs[index] = proof
+		p.pinned[index] = true
+	}
+	for index := max(0, p.size-keepRecent); index < p.size; index++ {
+		if _, ok := p.proofs[index]; !ok {
+			p.proofs[index] = t.createProof(0, index)
+		}
+	}
+	return p, nil
+}
+
+// Root returns the root of the tree.
+func (p *PrunedIMT[N]) Root() N {
+	return p.root
+}
+
+// Size returns the number of leaves of the tree.
+func (p *PrunedIMT[N]) Size() int {
+	return p.size
+}
+
+// Depth returns the depth of the tree.
+func (p *PrunedIMT[N]) Depth() int {
+	return p.depth
+}
+
+// Pinned returns the indices of the pinned leaves, in increasing order.
+func (p *PrunedIMT[N]) Pinned() []int {
+	return slices.Sorted(maps.Keys(p.proofs))
+}
+
+// Unpin discards the proof of a leaf, unless it is one of the last
+// keepRecent leaves.
+func (p *PrunedIMT[N]) Unpin(index int) {
+	delete(p.pinned, index)
+	if index < p.size-p.keepRecent {
+		delete(p.proofs, index)
+	}
+}
+
+// Insert appends a leaf, updating the frontier, the root and the proofs of
+// the pinned leaves.
+func (p *PrunedIMT[N]) Insert(leaf N) error {
+	if uint64(p.size) >= capacity(p.arity, p.depth) {
+		return ErrTreeFull
+	}
+
+	// The path of the new leaf, whose left siblings are the frontier and
+	// whose right siblings are zero values.
+	index := p.size
+	path := make([]N, p.depth+1)
+	siblings := make([][]N, p.depth)
+	path[0] = leaf
+	for level := 0; level < p.depth; level++ {
+		position := len(p.frontier[level])
+		children := make([]N, 0, p.arity)
+		children = append(children, p.frontier[level]...)
+		children = append(children, path[level])
+		for len(children) < p.arity {
+			children = append(children, p.zeroes[level])
+		}
+		path[level+1] = p.hash(children)
+		siblings[level] = slices.Delete(children, position, position+1)
+	}
+
+	// The nodes of the path that the leaf completes join the frontier.
+	for level, span := 0, 1; level < p.depth && (index+1)%span == 0; level, span = level+1, span*p.arity {
+		p.frontier[level] = append(p.frontier[level], path[level])
+		if len(p.frontier[level]) == p.arity {
+			p.frontier[level] = p.frontier[level][:0]
+		}
+	}
+	p.size++
+	p.root = path[p.depth]
+
+	for pinned, proof := range p.proofs {
+		p.updateProof(proof, pinned, index, path)
+	}
+	if p.keepRecent > 0 {
+		p.proofs[index] = &MerkleProof[N]{
+			Root:        p.root,
+			Leaf:        leaf,
+			LeafIndex:   index,
+			Siblings:    siblings,
+			PathIndices: pathIndices(index, p.arity, p.depth),
+			Depth:       p.depth,
+			Arity:       p.arity,
+			HashID:      p.hashID,
+		}
+		if old := index - p.keepRecent; old >= 0 && !p.pinned[old] {
+			delete(p.proofs, old)
+		}
+	}
+	return nil
+}
+
+// CreateProof returns the proof of a pinned leaf.
+func (p *PrunedIMT[N]) CreateProof(index int) (*MerkleProof[N], error) {
+	proof, ok := p.proofs[index]
+	if !ok {
+		return nil, ErrLeafNotFound
+	}
+	return proof.clone(), nil
+}
+
+// VerifyProof verifies a proof with the hash function of the tree.
+func (p *PrunedIMT[N]) VerifyProof(proof *MerkleProof[N]) bool {
+	return VerifyProof(proof, p.hash)
+}
+
+// updateProof updates the proof of a leaf after a leaf was appended at the
+// given index with the given path: the path of the new leaf meets the path of
+// the proven leaf at a single level, where its node is a sibling.
+func (p *PrunedIMT[N]) updateProof(proof *MerkleProof[N], pinned, index int, path []N) {
+	proof.Root = p.root
+	for level := 0; level < p.depth; level++ {
+		if pinned/p.arity == index/p.arity {
+			position := index % p.arity
+			if position > pinned%p.arity {
+				position--
+			}
+			proof.Siblings[level][position] = path[level]
+			return
+		}
+		pinned /= p.arity
+		index /= p.arity
+	}
+}
+
+// pathIndices returns the positions of the path of a leaf among their
+// siblings.
+func pathIndices(index, arity, depth int) []int {
+	indices := make([]int, depth)
+	for level := range indices {
+		indices[level] = index % arity
+		index /= arity
+	}
+	return indices
+}
@@ -0,0 +1,127 @@
+package imt_test
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestPrunedIMT(t *testing.T) {
+	tests := []struct {
+		arity      int
+		size       int
+		keepRecent int
+		pinned     []int
+	}{
+		{2, 0, 0, nil},
+		{2, 0, 3, nil},
+		{2, 5, 0, []int{0, 4}},
+		{2, 5, 2, []int{1}},
+		{3, 7, 1, []int{0, 3, 6}},
+		{3, 9, 4, []int{8}},
+		{4, 1, 0, []int{0}},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("arity %d size %d recent %d pinned %v", test.arity, test.size, test.keepRecent, test.pinned), func(t *testing.T) {
+			r := imttest.Rand(uint64(test.size))
+			tree, err := imt.New(imttest.Uint64Hash, 3, 0, test.arity, imttest.RandomLeaves(r, test.size, imttest.Uint64Leaf))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pruned, err := tree.Prune(test.keepRecent, test.pinned...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for int(tree.Capacity()) > tree.Size() {
+				leaf := imttest.Uint64Leaf(r)
+				if err := tree.Insert(leaf); err != nil {
+					t.Fatal(err)
+				}
+				if err := pruned.Insert(leaf); err != nil {
+					t.Fatal(err)
+				}
+				if pruned.Root() != tree.Root() || pruned.Size() != tree.Size() {
+					t.Fatalf("size %d: the pruned tree differs", tree.Size())
+				}
+
+				want := slices.Clone(test.pinned)
+				for index := max(0, tree.Size()-test.keepRecent); index < tree.Size(); index++ {
+					want = append(want, index)
+				}
+				slices.Sort(want)
+				want = slices.Compact(want)
+				if !slices.Equal(pruned.Pinned(), want) {
+					t.Fatalf("size %d: Pinned = %v, want %v", tree.Size(), pruned.Pinned(), want)
+				}
+				for _, index := range want {
+					proof, err := pruned.CreateProof(index)
+					if err != nil {
+						t.Fatal(err)
+					}
+					expected, err := tree.CreateProof(index)
+					if err != nil {
+						t.Fatal(err)
+					}
+					if !proof.Equal(expected) || !pruned.VerifyProof(proof) {
+						t.Fatalf("size %d: the proof of leaf %d differs from the one of the tree", tree.Size(), index)
+					}
+				}
+			}
+			if err := pruned.Insert(1); !errors.Is(err, imt.ErrTreeFull) {
+				t.Fatalf("inserting in a full tree: got error %v", err)
+			}
+		})
+	}
+}
+
+func TestPrunedIMTUnpin(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3, 4, 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pruned, err := tree.Prune(2, 0, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pruned.Pinned(), []int{0, 3, 4}) {
+		t.Fatalf("Pinned = %v", pruned.Pinned())
+	}
+
+	// Leaf 4 is one of the recent leaves, so its proof is kept until it is
+	// no longer recent.
+	pruned.Unpin(0)
+	pruned.Unpin(4)
+	if !slices.Equal(pruned.Pinned(), []int{3, 4}) {
+		t.Fatalf("Pinned after Unpin = %v", pruned.Pinned())
+	}
+	if _, err := pruned.CreateProof(0); !errors.Is(err, imt.ErrLeafNotFound) {
+		t.Fatalf("CreateProof of an unpinned leaf: got error %v", err)
+	}
+	if err := pruned.Insert(6); err != nil {
+		t.Fatal(err)
+	}
+	if err := pruned.Insert(7); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(pruned.Pinned(), []int{5, 6}) {
+		t.Fatalf("Pinned after insertions = %v", pruned.Pinned())
+	}
+
+	// The proofs returned are copies.
+	proof, err := pruned.CreateProof(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proof.Siblings[0][0]++
+	if again, _ := pruned.CreateProof(6); !pruned.VerifyProof(again) {
+		t.Fatal("altering a returned proof altered the pinned proof")
+	}
+
+	if _, err := tree.Prune(0, 5); err == nil {
+		t.Fatal("pinned a leaf that does not exist")
+	}
+}
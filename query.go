@@ -0,0 +1,176 @@
+package imt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// DefaultPageLimit is the number of leaves returned by Leaves when the
+// pagination limit is not set, as in the Cosmos SDK.
+const DefaultPageLimit = 100
+
+// Querier implements the Query service defined in
+// proto/noble/imt/v1/query.proto on top of a tree. The arguments and
+// response types mirror the protobuf messages field by field, with nodes
+// encoded by the node codec, so the server generated in a module only needs to
+// convert the messages and delegate each call.
+type Querier[N comparable] struct {
+	tree  *IMT[N]
+	codec NodeCodec[N]
+}
+
+// NewQuerier returns a Querier serving the given tree.
+func NewQuerier[N comparable](tree *IMT[N], codec NodeCodec[N]) *Querier[N] {
+	return &Querier[N]{tree: tree, codec: codec}
+}
+
+// QueryRootResponse mirrors the QueryRootResponse message.
+type QueryRootResponse struct {
+	Root []byte
+}
+
+// QuerySizeResponse mirrors the QuerySizeResponse message.
+type QuerySizeResponse struct {
+	Size uint64
+}
+
+// QueryProofResponse mirrors the QueryProofResponse message.
+type QueryProofResponse struct {
+	Root        []byte
+	Leaf        []byte
+	LeafIndex   uint64
+	Siblings    [][][]byte
+	PathIndices []uint32
+}
+
+// PageRequest mirrors the Cosmos SDK PageRequest message. Key, if set, is the
+// big-endian index of the first leaf to return and takes precedence over
+// Offset. Reverse iteration is not supported.
+type PageRequest struct {
+	Key        []byte
+	Offset     uint64
+	Limit      uint64
+	CountTotal bool
+}
+
+// PageResponse mirrors the Cosmos SDK PageResponse message.
+type PageResponse struct {
+	NextKey []byte
+	Total   uint64
+}
+
+// QueryLeavesResponse mirrors the QueryLeavesResponse message.
+type QueryLeavesResponse struct {
+	Leaves     [][]byte
+	Pagination *PageResponse
+}
+
+// Root returns the encoded root of the tree.
+func (q *Querier[N]) Root(_ context.Context) (*QueryRootResponse, error) {
+	root, err := q.codec.Encode(q.tree.Root())
+	if err != nil {
+		return nil, err
+	}
+	return &QueryRootResponse{Root: root}, nil
+}
+
+// Size returns the number of leaves in the tree.
+func (q *Querier[N]) Size(_ context.Context) (*QuerySizeResponse, error) {
+	return &QuerySizeResponse{Size: uint64(q.tree.Size())}, nil
+}
+
+// ProofByIndex returns the proof of the leaf at the given index.
+func (q *Querier[N]) ProofByIndex(_ context.Context, index uint64) (*QueryProofResponse, error) {
+	if index >= uint64(q.tree.Size()) {
+		return nil, ErrLeafNotFound
+	}
+	return q.proof(int(index))
+}
+
+// ProofByLeaf returns the proof of the first occurrence of an encoded leaf.
+func (q *Querier[N]) ProofByLeaf(_ context.Context, leaf []byte) (*QueryProofResponse, error) {
+	node, err := q.codec.Decode(leaf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid leaf: %w", err)
+	}
+	index := q.tree.IndexOf(node)
+	if index < 0 {
+		return nil, ErrLeafNotFound
+	}
+	return q.proof(index)
+}
+
+// Leaves returns a page of encoded leaves, in insertion order.
+func (q *Querier[N]) Leaves(_ context.Context, pagination *PageRequest) (*QueryLeavesResponse, error) {
+	if pagination == nil {
+		pagination = &PageRequest{}
+	}
+
+	size := uint64(q.tree.Size())
+	start := pagination.Offset
+	if len(pagination.Key) > 0 {
+		if len(pagination.Key) != 8 {
+			return nil, errors.New("invalid pagination key")
+		}
+		start = binary.BigEndian.Uint64(pagination.Key)
+	}
+	limit := pagination.Limit
+	if limit == 0 {
+		limit = DefaultPageLimit
+	}
+
+	start = min(start, size)
+	end := start + min(limit, size-start)
+
+	leaves := make([][]byte, 0, end-start)
+	for i := start; i < end; i++ {
+		leaf, err := q.codec.Encode(q.tree.readNode(0, int(i)))
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, leaf)
+	}
+
+	page := &PageResponse{}
+	if end < size {
+		page.NextKey = binary.BigEndian.AppendUint64(nil, end)
+	}
+	if pagination.CountTotal {
+		page.Total = size
+	}
+
+	return &QueryLeavesResponse{Leaves: leaves, Pagination: page}, nil
+}
+
+// proof creates the proof of a leaf and encodes its nodes.
+func (q *Querier[N]) proof(index int) (*QueryProofResponse, error) {
+	proof, err := q.tree.CreateProof(index)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &QueryProofResponse{
+		LeafIndex:   uint64(proof.LeafIndex),
+		Siblings:    make([][][]byte, len(proof.Siblings)),
+		PathIndices: make([]uint32, len(proof.PathIndices)),
+	}
+	if res.Root, err = q.codec.Encode(proof.Root); err != nil {
+		return nil, err
+	}
+	if res.Leaf, err = q.codec.Encode(proof.Leaf); err != nil {
+		return nil, err
+	}
+	for level, siblings := range proof.Siblings {
+		res.Siblings[level] = make([][]byte, len(siblings))
+		for i, sibling := range siblings {
+			if res.Siblings[level][i], err = q.codec.Encode(sibling); err != nil {
+				return nil, err
+			}
+		}
+		res.PathIndices[level] = uint32(proof.PathIndices[level])
+	}
+
+	return res, nil
+}
@@ -0,0 +1,75 @@
+package imt
+
+import (
+	"errors"
+	"time"
+)
+
+// InsertionRecord records when a leaf was inserted into a tree.
+type InsertionRecord struct {
+	Sequence  uint64    // The version of the tree right after the insertion.
+	Time      time.Time // The wall-clock time of the insertion.
+	Reference string    // An optional external reference, such as a transaction hash.
+}
+
+// WithInsertionRecords makes the tree record the sequence number and time of
+// each insertion, read from clock, or from time.Now if clock is nil. Records
+// are kept for the leaves inserted from the creation of the tree, including
+// the leaves it is created with, and are included in snapshots.
+func WithInsertionRecords(clock func() time.Time) Option {
+	return func(o *options) {
+		o.insertionRecords = true
+		o.clock = clock
+	}
+}
+
+// InsertWithReference inserts a leaf like Insert, and attaches an external
+// reference to its insertion record. The tree must record insertions.
+func (t *IMT[N]) InsertWithReference(leaf N, reference string) error {
+	if !t.options.insertionRecords {
+		return errors.New("the tree does not record insertions")
+	}
+	index := t.NextIndex()
+	if err := t.Insert(leaf); err != nil {
+		return err
+	}
+	if index >= t.recordsStart && index < t.recordsStart+len(t.records) {
+		t.records[index-t.recordsStart].Reference = reference
+	}
+	return nil
+}
+
+// InsertionRecord returns the insertion record of a leaf, and whether there is
+// one. Leaves inserted before the tree recorded insertions have none.
+func (t *IMT[N]) InsertionRecord(index int) (InsertionRecord, bool) {
+	if index < t.recordsStart || index >= t.recordsStart+len(t.records) {
+		return InsertionRecord{}, false
+	}
+	return t.records[index-t.recordsStart], true
+}
+
+// now returns the time of an insertion.
+func (t *IMT[N]) now() time.Time {
+	if t.options.clock != nil {
+		return t.options.clock()
+	}
+	return time.Now()
+}
+
+// recordInsertions records the insertion of the last count leaves, which
+// advanced the version of the tree by count.
+func (t *IMT[N]) recordInsertions(count int) {
+	if !t.options.insertionRecords {
+		return
+	}
+	if len(t.records) == 0 {
+		t.recordsStart = t.nodes[0].Len() - count
+	}
+	at := t.now()
+	for i := range count {
+		t.records = append(t.records, InsertionRecord{
+			Sequence: t.version - uint64(count-1-i),
+			Time:     at,
+		})
+	}
+}
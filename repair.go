@@ -0,0 +1,63 @@
+package imt
+
+import "log/slog"
+
+// RepairedNode is an internal node rewritten by Repair.
+type RepairedNode[N comparable] struct {
+	Level int
+	Index int
+	Old   N // The corrupted value.
+	New   N // The hash of the children of the node.
+}
+
+// Repair recomputes the internal nodes of the tree bottom-up from its leaves,
+// which are treated as authoritative, and rewrites every node that is not the
+// hash of its children, such as the ones reported by Validate or HealthCheck.
+// It returns the rewritten nodes, from the lowest level to the root. If it
+// rewrote any node, the repair is committed like a write: the version of the
+// tree is incremented, subscribers are notified, and an OpRepair entry is
+// passed to the log, so that followers, backups and checkpointers see the
+// repaired root. Proofs created before the repair may be invalid. The levels
+// must have the lengths implied by the number of leaves, which Repair cannot
+// fix. Like Validate, it does not notify the gas meter.
+func (t *IMT[N]) Repair() ([]RepairedNode[N], error) {
+	return t.repair(false)
+}
+
+// repair implements Repair, and commits the repair even if it rewrote no
+// node if always is set, so that a follower replaying the repair of its
+// leader reaches its version.
+func (t *IMT[N]) repair(always bool) ([]RepairedNode[N], error) {
+	if err := t.validateLevels(); err != nil {
+		return nil, err
+	}
+
+	oldRoot := t.Root()
+	var repaired []RepairedNode[N]
+	for level := 0; level < t.depth; level++ {
+		for index := range t.nodes[level+1].Len() {
+			expected := t.hash(t.storedChildren(level, index))
+			if node := t.nodes[level+1].Get(index); !t.equal(node, expected) {
+				t.nodes[level+1].Set(index, expected)
+				repaired = append(repaired, RepairedNode[N]{Level: level + 1, Index: index, Old: node, New: expected})
+			}
+		}
+	}
+	if len(repaired) == 0 && !always {
+		return nil, nil
+	}
+
+	if t.proofs != nil {
+		t.proofs.clear()
+	}
+	t.version++
+	t.notifyRoots(oldRoot, 1)
+	t.logCommit(OpRepair, len(repaired))
+	if t.logs.active() {
+		t.emitLog(LogEntry[N]{Op: OpRepair})
+	}
+	if len(repaired) > 0 {
+		t.log(slog.LevelWarn, "imt: repaired corrupted nodes", slog.Int("count", len(repaired)), slog.Any("root", t.Root()))
+	}
+	return repaired, nil
+}
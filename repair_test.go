@@ -0,0 +1,59 @@
+package imt_test
+
+import (
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestRepairIsReplicated(t *testing.T) {
+	leaves := []uint64{1, 2, 3, 4, 5}
+	tree, err := imt.New(imttest.Uint64Hash, 3, 0, 2, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica, err := imt.New(imttest.Uint64Hash, 3, 0, 2, leaves)
+	if err != nil {
+		t.Fatal(err)
+	}
+	follower := imt.NewFollower(replica)
+	var entries []imt.LogEntry[uint64]
+	defer tree.OnLogEntry(func(entry imt.LogEntry[uint64]) {
+		entries = append(entries, entry)
+		if err := follower.Apply(entry); err != nil {
+			t.Error(err)
+		}
+	})()
+	updates, cancel := tree.SubscribeRoots(4)
+	defer cancel()
+
+	// A healthy tree is not repaired.
+	if repaired, err := tree.Repair(); err != nil || len(repaired) != 0 || len(entries) != 0 {
+		t.Fatalf("Repair of a healthy tree: %v, %v, %d entries", repaired, err, len(entries))
+	}
+
+	root, version := tree.Root(), tree.Version()
+	tree.CorruptNode(3, 0, root+1)
+	repaired, err := tree.Repair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(repaired) != 1 || tree.Root() != root || tree.Version() != version+1 {
+		t.Fatalf("Repair rewrote %v, leaving version %d", repaired, tree.Version())
+	}
+	if len(entries) != 1 || entries[0].Op != imt.OpRepair || entries[0].Root != root {
+		t.Fatalf("log entries %+v", entries)
+	}
+	if update := <-updates; update.OldRoot != root+1 || update.NewRoot != root || update.Version != version+1 {
+		t.Fatalf("unexpected root update %+v", update)
+	}
+
+	// The follower stays in step with the repaired tree.
+	if err := tree.Insert(6); err != nil {
+		t.Fatal(err)
+	}
+	if follower.Err() != nil || replica.Version() != tree.Version() || replica.Root() != tree.Root() {
+		t.Fatalf("the follower is at version %d, the tree at version %d", replica.Version(), tree.Version())
+	}
+}
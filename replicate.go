@@ -0,0 +1,260 @@
+package imt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"sync"
+	"sync/atomic"
+)
+
+// maxLogEntrySize bounds the size of an encoded log entry read from a stream.
+const maxLogEntrySize = 1 << 28
+
+// ErrDiverged is wrapped by the errors of a Follower whose tree no longer
+// matches the tree of its leader.
+var ErrDiverged = errors.New("the follower diverged from its leader")
+
+// LogEntry is an operation committed by a tree, as emitted to the callbacks
+// registered with OnLogEntry and applied by a Follower.
+type LogEntry[N comparable] struct {
+	Op      string // OpInsert, OpInsertMany, OpUpdate, OpDelete, OpCompact or OpRepair.
+	Index   int    // The index of the updated or deleted leaf.
+	Leaves  []N    // The inserted leaves, or the new value of the updated leaf.
+	Version uint64 // The version of the tree after the operation.
+	Root    N      // The root of the tree after the operation.
+}
+
+// logCallbacks holds the functions notified of the operations of a tree. The
+// number of callbacks is also kept in an atomic counter, so that writes don't
+// build log entries nobody receives.
+type logCallbacks[N comparable] struct {
+	mu        sync.Mutex
+	next      int
+	callbacks map[int]func(entry LogEntry[N])
+	count     atomic.Int32
+}
+
+// active reports whether any callback is registered.
+func (c *logCallbacks[N]) active() bool {
+	return c.count.Load() > 0
+}
+
+// OnLogEntry registers a function called with a LogEntry after every
+// insertion, update, deletion, compaction and repair of the tree, in the
+// goroutine writing to the tree, so that the operations can be replayed on
+// follower trees. The callback may block, e.g. to keep the followers in lockstep, which
+// blocks the write. Leaf data, insertion records and expiry deadlines are not
+// part of the log. It returns a function unregistering the callback.
+func (t *IMT[N]) OnLogEntry(callback func(entry LogEntry[N])) func() {
+	c := &t.logs
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.callbacks == nil {
+		c.callbacks = make(map[int]func(LogEntry[N]))
+	}
+	id := c.next
+	c.next++
+	c.callbacks[id] = callback
+	c.count.Add(1)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			delete(c.callbacks, id)
+			c.count.Add(-1)
+		})
+	}
+}
+
+// emitLog completes a log entry with the version and root of the tree and
+// passes it to the callbacks. Callers check that the log is active first.
+func (t *IMT[N]) emitLog(entry LogEntry[N]) {
+	c := &t.logs
+	c.mu.Lock()
+	callbacks := make([]func(LogEntry[N]), 0, len(c.callbacks))
+	for _, callback := range c.callbacks {
+		callbacks = append(callbacks, callback)
+	}
+	c.mu.Unlock()
+	if len(callbacks) == 0 {
+		return
+	}
+
+	entry.Version, entry.Root = t.version, t.Root()
+	for _, callback := range callbacks {
+		callback(entry)
+	}
+}
+
+// WriteLogEntry writes a log entry to w, encoded with the codec and prefixed
+// with its length as an unsigned varint, so that a leader can stream its log
+// to followers over a connection or a file.
+func WriteLogEntry[N comparable](w io.Writer, codec NodeCodec[N], entry LogEntry[N]) error {
+	if entry.Index < 0 {
+		return errors.New("the leaf index cannot be negative")
+	}
+	b := binary.AppendUvarint(nil, uint64(len(entry.Op)))
+	b = append(b, entry.Op...)
+	b = binary.AppendUvarint(b, uint64(entry.Index))
+	b, err := appendNodes(b, codec, entry.Leaves)
+	if err != nil {
+		return err
+	}
+	b = binary.AppendUvarint(b, entry.Version)
+	if b, err = appendNode(b, codec, entry.Root); err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if err := writeRecord(bw, b); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// ReadLogEntries returns an iterator over the log entries written by
+// WriteLogEntry. The iteration stops at the end of the stream or after
+// yielding an error.
+func ReadLogEntries[N comparable](r io.Reader, codec NodeCodec[N]) iter.Seq2[LogEntry[N], error] {
+	return func(yield func(LogEntry[N], error) bool) {
+		br := bufio.NewReader(r)
+		for {
+			b, err := readRecord(br, maxLogEntrySize)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(LogEntry[N]{}, err)
+				return
+			}
+			reader := &byteReader{b: b}
+			entry := LogEntry[N]{
+				Op:     string(reader.bytes(reader.length())),
+				Index:  reader.int(),
+				Leaves: readNodes(reader, codec),
+			}
+			entry.Version = reader.uvarint()
+			entry.Root = readNode(reader, codec)
+			if err := reader.done(); err != nil {
+				yield(LogEntry[N]{}, err)
+				return
+			}
+			if !yield(entry, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Follower applies the log of a leader tree to a follower tree, checking
+// after each entry that the version and the root of the follower match the
+// ones of the leader. Once they diverge, the follower refuses further
+// entries, since its proofs can no longer be trusted.
+type Follower[N comparable] struct {
+	tree *IMT[N]
+	err  error
+}
+
+// NewFollower returns a Follower applying entries to the tree, which must
+// start in the state of the leader before the first entry, e.g. restored from
+// a snapshot of the leader.
+func NewFollower[N comparable](tree *IMT[N]) *Follower[N] {
+	return &Follower[N]{tree: tree}
+}
+
+// Tree returns the follower tree.
+func (f *Follower[N]) Tree() *IMT[N] {
+	return f.tree
+}
+
+// Err returns the error that stopped the follower, if any.
+func (f *Follower[N]) Err() error {
+	return f.err
+}
+
+// Apply applies a batch of log entries in order, returning an error wrapping
+// ErrDiverged if the version or the root of the tree differs from the ones of
+// the leader after an entry.
+func (f *Follower[N]) Apply(entries ...LogEntry[N]) error {
+	if f.err != nil {
+		return f.err
+	}
+	for _, entry := range entries {
+		if err := f.apply(entry); err != nil {
+			f.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// apply applies a single log entry.
+func (f *Follower[N]) apply(entry LogEntry[N]) error {
+	t := f.tree
+	var err error
+	switch entry.Op {
+	case OpInsert, OpInsertMany:
+		err = t.InsertMany(entry.Leaves)
+	case OpUpdate:
+		if len(entry.Leaves) != 1 {
+			return fmt.Errorf("%w: update of version %d has %d leaves", ErrDiverged, entry.Version, len(entry.Leaves))
+		}
+		err = t.Update(entry.Index, entry.Leaves[0])
+	case OpDelete:
+		err = t.Delete(entry.Index)
+	case OpCompact:
+		_, err = t.Compact()
+	case OpRepair:
+		_, err = t.repair(true)
+	default:
+		return fmt.Errorf("unknown operation %q in the log entry of version %d", entry.Op, entry.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %s of version %d failed: %w", ErrDiverged, entry.Op, entry.Version, err)
+	}
+	if t.version != entry.Version || !t.equal(t.Root(), entry.Root) {
+		return fmt.Errorf("%w: after %s, the follower is at version %d with root %v, the leader at version %d with root %v", ErrDiverged, entry.Op, t.version, t.Root(), entry.Version, entry.Root)
+	}
+	return nil
+}
+
+// Follow applies the entries received from a channel, such as one fed by a
+// callback registered with OnLogEntry, until the channel is closed, ctx is
+// done or the follower diverges.
+func (f *Follower[N]) Follow(ctx context.Context, entries <-chan LogEntry[N]) error {
+	for {
+		select {
+		case entry, ok := <-entries:
+			if !ok {
+				return nil
+			}
+			if err := f.Apply(entry); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// FollowReader applies the entries read from r, as written by WriteLogEntry,
+// until the end of the stream or the follower diverges.
+func (f *Follower[N]) FollowReader(r io.Reader, codec NodeCodec[N]) error {
+	for entry, err := range ReadLogEntries(r, codec) {
+		if err != nil {
+			return err
+		}
+		if err := f.Apply(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
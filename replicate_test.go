@@ -0,0 +1,109 @@
+package imt_test
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func TestFollowerReplaysTheLog(t *testing.T) {
+	rng := imttest.Rand(1)
+	tree, err := imt.New(imttest.Uint64Hash, 6, 0, 3, imttest.RandomLeaves(rng, 10, imttest.Uint64Leaf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The follower starts from a snapshot of the leader.
+	replica, err := restore(snapshot(t, tree, 4))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var log bytes.Buffer
+	var versions []uint64
+	defer tree.OnLogEntry(func(entry imt.LogEntry[uint64]) {
+		versions = append(versions, entry.Version)
+		if err := imt.WriteLogEntry(&log, uint64Codec{}, entry); err != nil {
+			t.Error(err)
+		}
+	})()
+
+	steps := []func() error{
+		func() error { return tree.Insert(rng.Uint64()) },
+		func() error { return tree.InsertMany(imttest.RandomLeaves(rng, 5, imttest.Uint64Leaf)) },
+		func() error { return tree.Update(2, rng.Uint64()) },
+		func() error { return tree.Delete(4) },
+		func() error { return tree.Delete(11) },
+		func() error { return tree.InsertMany(imttest.RandomLeaves(rng, 3, imttest.Uint64Leaf)) },
+		func() error { _, err := tree.Compact(); return err },
+		func() error { return tree.Update(0, rng.Uint64()) },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+	}
+	if !slices.IsSorted(versions) || len(versions) != len(steps) {
+		t.Fatalf("the log entries have versions %v", versions)
+	}
+
+	follower := imt.NewFollower(replica)
+	if err := follower.FollowReader(bytes.NewReader(log.Bytes()), uint64Codec{}); err != nil {
+		t.Fatal(err)
+	}
+	if replica.Root() != tree.Root() || replica.Version() != tree.Version() {
+		t.Fatalf("the follower is at version %d with root %d, the leader at version %d with root %d", replica.Version(), replica.Root(), tree.Version(), tree.Root())
+	}
+	if !slices.Equal(replica.Leaves(), tree.Leaves()) || !slices.Equal(replica.DeletedIndices(), tree.DeletedIndices()) {
+		t.Fatal("the leaves of the follower differ from the ones of the leader")
+	}
+}
+
+func TestFollowerDetectsDivergence(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []imt.LogEntry[uint64]
+	defer tree.OnLogEntry(func(entry imt.LogEntry[uint64]) {
+		entries = append(entries, entry)
+	})()
+	for _, leaf := range []uint64{4, 5} {
+		if err := tree.Insert(leaf); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(entry *imt.LogEntry[uint64])
+	}{
+		{"leaf", func(entry *imt.LogEntry[uint64]) { entry.Leaves = []uint64{6} }},
+		{"root", func(entry *imt.LogEntry[uint64]) { entry.Root++ }},
+		{"version", func(entry *imt.LogEntry[uint64]) { entry.Version++ }},
+		{"operation", func(entry *imt.LogEntry[uint64]) { entry.Op = imt.OpDelete }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			replica, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tampered := slices.Clone(entries)
+			tampered[0].Leaves = slices.Clone(tampered[0].Leaves)
+			test.tamper(&tampered[0])
+
+			follower := imt.NewFollower(replica)
+			if err := follower.Apply(tampered[0]); !errors.Is(err, imt.ErrDiverged) {
+				t.Fatalf("Apply of a tampered entry returned %v", err)
+			}
+			// A diverged follower refuses the following entries.
+			if err := follower.Apply(entries[1]); !errors.Is(err, imt.ErrDiverged) || follower.Err() == nil {
+				t.Fatalf("Apply after the divergence returned %v", err)
+			}
+		})
+	}
+}
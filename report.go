@@ -0,0 +1,55 @@
+package imt
+
+// Report summarizes the contents of a tree, for logging and metrics.
+type Report struct {
+	Size     int     // The number of leaves.
+	Capacity uint64  // The maximum number of leaves.
+	Fill     float64 // The number of leaves divided by the capacity.
+	// The number of leaves equal to the zero value, deleted or not.
+	ZeroLeaves int
+	// The number of leaves deleted and not updated since.
+	DeletedLeaves int
+	// The number of distinct leaf values, including the zero value if some
+	// leaves are equal to it.
+	DistinctLeaves int
+	// The number of nodes of each level, from the leaves to the root.
+	LevelSizes []int
+	// The version of the tree when it was last modified.
+	Version uint64
+}
+
+// Version returns the number of leaf writes since the tree was created, which
+// increases every time a leaf is inserted, updated or deleted. Trees restored
+// from a snapshot with insertion records keep the version of the original
+// tree.
+func (t *IMT[N]) Version() uint64 {
+	return t.version
+}
+
+// Report returns a summary of the contents of the tree. It reads every leaf,
+// so its cost is proportional to the size of the tree.
+func (t *IMT[N]) Report() Report {
+	report := Report{
+		Size:       t.nodes[0].Len(),
+		Capacity:   t.Capacity(),
+		Fill:       float64(t.nodes[0].Len()) / float64(t.Capacity()),
+		LevelSizes: make([]int, t.depth+1),
+		Version:    t.version,
+	}
+	report.DeletedLeaves = t.tombstones.count
+	for level, nodes := range t.nodes {
+		report.LevelSizes[level] = nodes.Len()
+	}
+
+	distinct := make(map[N]struct{})
+	for i := 0; i < t.nodes[0].Len(); i++ {
+		leaf := t.nodes[0].Get(i)
+		if leaf == t.zeroes[0] {
+			report.ZeroLeaves++
+		}
+		distinct[leaf] = struct{}{}
+	}
+	report.DistinctLeaves = len(distinct)
+
+	return report
+}
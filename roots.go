@@ -0,0 +1,73 @@
+package imt
+
+// InsertManyWithRoots inserts a list of leaves like InsertMany, and calls emit
+// with the size and the root of the tree after each of the leaves, in order,
+// as if they had been inserted one by one with Insert, so that checkpoint
+// signers can attest every intermediate root. The roots are computed from the
+// right edge of the tree before the leaves are written, with depth hashes per
+// leaf, and emitted once the insertion succeeded.
+func (t *IMT[N]) InsertManyWithRoots(leaves []N, emit func(size int, root N)) error {
+	if len(leaves) == 0 {
+		return nil
+	}
+
+	free := 0
+	if t.options.deletePolicy == DeleteFreeList {
+		free = min(t.tombstones.count, len(leaves))
+	}
+	depth, err := t.neededDepth(OpInsertMany, len(leaves)-free)
+	if err != nil {
+		return err
+	}
+
+	// Leaves filling deleted positions, or growing the tree, are inserted
+	// one by one.
+	if free > 0 || depth > t.depth {
+		for _, leaf := range leaves {
+			if err := t.Insert(leaf); err != nil {
+				return err
+			}
+			emit(t.nodes[0].Len(), t.Root())
+		}
+		return nil
+	}
+	// The complete children of the rightmost node of each level, whose
+	// subtrees no later leaf can change.
+	size := t.nodes[0].Len()
+	frontier := make([][]N, t.depth)
+	for level, complete := 0, size; level < t.depth; level++ {
+		for index := complete - complete%t.arity; index < complete; index++ {
+			frontier[level] = append(frontier[level], t.readNode(level, index))
+		}
+		complete /= t.arity
+	}
+
+	roots := make([]N, len(leaves))
+	for i, leaf := range leaves {
+		node, complete := leaf, true
+		for level := 0; level < t.depth; level++ {
+			children := make([]N, 0, t.arity)
+			children = append(children, frontier[level]...)
+			children = append(children, node)
+			for len(children) < t.arity {
+				children = append(children, t.zeroes[level])
+			}
+			if complete {
+				frontier[level] = append(frontier[level], node)
+				if complete = len(frontier[level]) == t.arity; complete {
+					frontier[level] = frontier[level][:0]
+				}
+			}
+			node = t.hashChildren(children)
+		}
+		roots[i] = node
+	}
+
+	if err := t.InsertMany(leaves); err != nil {
+		return err
+	}
+	for i, root := range roots {
+		emit(size+i+1, root)
+	}
+	return nil
+}
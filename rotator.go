@@ -0,0 +1,129 @@
+package imt
+
+import (
+	"errors"
+	"sort"
+)
+
+// Epoch describes a tree of a Rotator.
+type Epoch[N comparable] struct {
+	Number int    // The number of the epoch, starting from 0.
+	Start  uint64 // The sequence number of the first leaf of the epoch.
+	Size   int    // The number of leaves of the epoch.
+	Root   N      // The final root if the epoch is sealed, the current one otherwise.
+	Sealed bool   // Whether the epoch is sealed.
+}
+
+// Rotator manages the lifecycle of fixed-depth trees used as epochs: leaves
+// are inserted in the tree of the current epoch, which is sealed when it is
+// full or holds epochSize leaves, or when Seal is called, e.g. on a time
+// boundary. Sealing records the final root of the tree and starts a fresh
+// tree. Leaves are numbered by a sequence number across the epochs, by which
+// proofs are routed to the tree of their epoch. The trees of sealed epochs
+// are kept to create proofs and must not be modified.
+type Rotator[N comparable] struct {
+	newTree   func() (*IMT[N], error)
+	epochSize int
+	onSeal    func(Epoch[N])
+
+	epochs []Epoch[N]
+	trees  []*IMT[N]
+}
+
+// NewRotator returns a Rotator creating the tree of each epoch with newTree.
+// The epochs are sealed when their tree is full, or when they hold epochSize
+// leaves if it is positive, and onSeal, if not nil, is called with every
+// sealed epoch.
+func NewRotator[N comparable](newTree func() (*IMT[N], error), epochSize int, onSeal func(Epoch[N])) (*Rotator[N], error) {
+	r := &Rotator[N]{newTree: newTree, epochSize: epochSize, onSeal: onSeal}
+	if err := r.start(0); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Current returns the tree of the current epoch, for read-only access.
+func (r *Rotator[N]) Current() *IMT[N] {
+	return r.trees[len(r.trees)-1]
+}
+
+// Epochs returns the epochs, from the first one to the current one.
+func (r *Rotator[N]) Epochs() []Epoch[N] {
+	epochs := make([]Epoch[N], len(r.epochs))
+	copy(epochs, r.epochs)
+	current := &epochs[len(epochs)-1]
+	current.Size, current.Root = r.Current().Size(), r.Current().Root()
+	return epochs
+}
+
+// Insert inserts a leaf in the current epoch and returns its sequence
+// number. The epoch is sealed once the leaf fills it.
+func (r *Rotator[N]) Insert(leaf N) (uint64, error) {
+	tree := r.Current()
+	index := tree.NextIndex()
+	if err := tree.Insert(leaf); err != nil {
+		return 0, err
+	}
+	sequence := r.epochs[len(r.epochs)-1].Start + uint64(index)
+	if r.full() {
+		if err := r.Seal(); err != nil {
+			return sequence, err
+		}
+	}
+	return sequence, nil
+}
+
+// Seal seals the current epoch, if it holds any leaf, and starts a new one.
+func (r *Rotator[N]) Seal() error {
+	tree := r.Current()
+	if tree.Size() == 0 {
+		return nil
+	}
+	epoch := &r.epochs[len(r.epochs)-1]
+	epoch.Size, epoch.Root, epoch.Sealed = tree.Size(), tree.Root(), true
+	if err := r.start(epoch.Start + uint64(epoch.Size)); err != nil {
+		epoch.Sealed = false
+		return err
+	}
+	if r.onSeal != nil {
+		r.onSeal(*epoch)
+	}
+	return nil
+}
+
+// CreateProof creates the proof of the leaf with the given sequence number,
+// against the root of its epoch, and returns the number of the epoch.
+func (r *Rotator[N]) CreateProof(sequence uint64) (*MerkleProof[N], int, error) {
+	number := sort.Search(len(r.epochs), func(i int) bool {
+		return r.epochs[i].Start > sequence
+	}) - 1
+	if number < 0 {
+		return nil, 0, errors.New("the sequence number is not in any epoch")
+	}
+	proof, err := r.trees[number].CreateProof(int(sequence - r.epochs[number].Start))
+	if err != nil {
+		return nil, 0, err
+	}
+	return proof, number, nil
+}
+
+// full reports whether the current epoch must be sealed.
+func (r *Rotator[N]) full() bool {
+	tree := r.Current()
+	if r.epochSize > 0 && tree.Size() >= r.epochSize {
+		return true
+	}
+	_, free := tree.freeIndex()
+	return !free && uint64(tree.Size()) >= tree.Capacity()
+}
+
+// start starts a new epoch whose first leaf has the given sequence number.
+func (r *Rotator[N]) start(sequence uint64) error {
+	tree, err := r.newTree()
+	if err != nil {
+		return err
+	}
+	r.epochs = append(r.epochs, Epoch[N]{Number: len(r.epochs), Start: sequence, Root: tree.Root()})
+	r.trees = append(r.trees, tree)
+	return nil
+}
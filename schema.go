@@ -0,0 +1,150 @@
+package imt
+
+import (
+	"encoding"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema dialect of the schemas returned by
+// ProofSchemas.
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// ProofSchemas returns the JSON Schemas of the JSON encodings of the proof
+// types of the package, keyed by type name, derived from the Go types by
+// reflection. Nodes are described by the given schema, such as
+// {"type": "string", "pattern": "^0x[0-9a-f]{64}$"} for nodes encoded as hex
+// strings, or derived from the type N if it is nil.
+func ProofSchemas[N comparable](node map[string]any) map[string]any {
+	g := &schemaGenerator{
+		node:       reflect.TypeFor[N](),
+		nodeSchema: node,
+		layouts: map[reflect.Type]reflect.Type{
+			reflect.TypeFor[MerkleProof[N]](): reflect.TypeFor[merkleProofJSON[N]](),
+		},
+	}
+	return map[string]any{
+		"MerkleProof":   g.schema(reflect.TypeFor[MerkleProof[N]]()),
+		"AppendProof":   g.schema(reflect.TypeFor[AppendProof[N]]()),
+		"CombinedProof": g.schema(reflect.TypeFor[CombinedProof[N]]()),
+		"CalldataProof": g.schema(reflect.TypeFor[CalldataProof[N]]()),
+		"SimpleProof":   g.schema(reflect.TypeFor[SimpleProof]()),
+		"BitcoinProof":  g.schema(reflect.TypeFor[BitcoinProof]()),
+	}
+}
+
+// ProofJSONSchema returns a standalone JSON Schema document of a proof type
+// returned by ProofSchemas, or nil if there is no such type.
+func ProofJSONSchema[N comparable](name string, node map[string]any) map[string]any {
+	schema, ok := ProofSchemas[N](node)[name].(map[string]any)
+	if !ok {
+		return nil
+	}
+	document := map[string]any{"$schema": JSONSchemaDialect, "title": name}
+	for key, value := range schema {
+		document[key] = value
+	}
+	return document
+}
+
+// OpenAPIComponents returns the schemas of ProofSchemas as the components
+// object of an OpenAPI 3.1 document, whose schemas are JSON Schemas.
+func OpenAPIComponents[N comparable](node map[string]any) map[string]any {
+	return map[string]any{"schemas": ProofSchemas[N](node)}
+}
+
+// schemaGenerator derives JSON Schemas from Go types, following the rules of
+// encoding/json. Types with a custom JSON encoding are described by the type
+// of their layout, if any.
+type schemaGenerator struct {
+	node       reflect.Type
+	nodeSchema map[string]any
+	layouts    map[reflect.Type]reflect.Type
+}
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	bigIntType        = reflect.TypeFor[big.Int]()
+)
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]any {
+	if t == g.node && g.nodeSchema != nil {
+		return g.nodeSchema
+	}
+	if t.Kind() == reflect.Pointer {
+		return g.schema(t.Elem())
+	}
+	if layout, ok := g.layouts[t]; ok {
+		return g.schema(layout)
+	}
+
+	switch {
+	case t == bigIntType:
+		return map[string]any{"type": "integer"}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return map[string]any{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]any{"type": "integer", "minimum": 0, "maximum": uint64(1)<<t.Bits() - 1}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": []string{"string", "null"}, "contentEncoding": "base64"}
+		}
+		return map[string]any{"type": []string{"array", "null"}, "items": g.schema(t.Elem())}
+	case reflect.Array:
+		return map[string]any{"type": "array", "items": g.schema(t.Elem()), "minItems": t.Len(), "maxItems": t.Len()}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.object(t)
+	default:
+		return map[string]any{}
+	}
+}
+
+// object returns the schema of a struct, whose properties are its exported
+// fields named by their json tags. Fields without omitempty are required.
+func (g *schemaGenerator) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(","+options+",", ",omitempty,") {
+			required = append(required, name)
+		}
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
@@ -0,0 +1,178 @@
+package imt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/bits"
+)
+
+// Domain separation prefixes of RFC 6962, used to prevent second preimage
+// attacks between leaves and inner nodes.
+var (
+	leafPrefix  = []byte{0x00}
+	innerPrefix = []byte{0x01}
+)
+
+// SimpleProof is a Merkle proof compatible with CometBFT's `merkle.Proof`.
+// The aunts are the sibling hashes from the leaf up to the root.
+type SimpleProof struct {
+	Total    int64    `json:"total"`     // The number of items in the tree.
+	Index    int64    `json:"index"`     // The index of the item being proven.
+	LeafHash []byte   `json:"leaf_hash"` // The hash of the item being proven.
+	Aunts    [][]byte `json:"aunts"`     // Sibling hashes from the leaf up.
+}
+
+// SimpleHashFromByteSlices computes the root of a list of items in the same
+// way as CometBFT's `merkle.HashFromByteSlices`, formerly known as
+// `SimpleHashFromByteSlices`. The tree is unbalanced: a list of n items is
+// split at the largest power of two smaller than n, and leaves and inner nodes
+// are hashed with SHA-256 and the RFC 6962 prefixes. This is the calculation
+// behind the hash fields of CometBFT block headers.
+func SimpleHashFromByteSlices(items [][]byte) []byte {
+	switch len(items) {
+	case 0:
+		return emptyHash()
+	case 1:
+		return simpleLeafHash(items[0])
+	default:
+		k := splitPoint(len(items))
+		left := SimpleHashFromByteSlices(items[:k])
+		right := SimpleHashFromByteSlices(items[k:])
+		return simpleInnerHash(left, right)
+	}
+}
+
+// SimpleProofsFromByteSlices computes the root of a list of items together
+// with a proof for each of them, as CometBFT's `merkle.ProofsFromByteSlices`.
+func SimpleProofsFromByteSlices(items [][]byte) ([]byte, []*SimpleProof) {
+	root, aunts := simpleAunts(items)
+
+	proofs := make([]*SimpleProof, len(items))
+	for i, item := range items {
+		proofs[i] = &SimpleProof{
+			Total:    int64(len(items)),
+			Index:    int64(i),
+			LeafHash: simpleLeafHash(item),
+			Aunts:    aunts[i],
+		}
+	}
+
+	return root, proofs
+}
+
+// Verify checks that the proof commits the leaf under the given root.
+func (p *SimpleProof) Verify(root []byte, leaf []byte) error {
+	if p == nil {
+		return errors.New("proof is nil")
+	}
+	if p.Total < 0 {
+		return errors.New("proof total must be positive")
+	}
+	if p.Index < 0 {
+		return errors.New("proof index cannot be negative")
+	}
+	if !bytes.Equal(p.LeafHash, simpleLeafHash(leaf)) {
+		return fmt.Errorf("invalid leaf hash: wanted %X got %X", simpleLeafHash(leaf), p.LeafHash)
+	}
+	computed := p.ComputeRootHash()
+	if computed == nil {
+		return errors.New("invalid proof layout")
+	}
+	if !bytes.Equal(computed, root) {
+		return fmt.Errorf("invalid root hash: wanted %X got %X", root, computed)
+	}
+	return nil
+}
+
+// ComputeRootHash computes the root committed to by the proof. It returns nil
+// if the aunts don't match the shape of a tree with Total items.
+func (p *SimpleProof) ComputeRootHash() []byte {
+	return computeHashFromAunts(p.Index, p.Total, p.LeafHash, p.Aunts)
+}
+
+// simpleAunts returns the root of the items and the aunts of each item.
+func simpleAunts(items [][]byte) ([]byte, [][][]byte) {
+	switch len(items) {
+	case 0:
+		return emptyHash(), nil
+	case 1:
+		return simpleLeafHash(items[0]), [][][]byte{nil}
+	default:
+		k := splitPoint(len(items))
+		left, leftAunts := simpleAunts(items[:k])
+		right, rightAunts := simpleAunts(items[k:])
+		for i := range leftAunts {
+			leftAunts[i] = append(leftAunts[i], right)
+		}
+		for i := range rightAunts {
+			rightAunts[i] = append(rightAunts[i], left)
+		}
+		return simpleInnerHash(left, right), append(leftAunts, rightAunts...)
+	}
+}
+
+// computeHashFromAunts walks the unbalanced tree from the top, consuming the
+// aunts from the last one, which is the sibling of the root's child.
+func computeHashFromAunts(index, total int64, leafHash []byte, aunts [][]byte) []byte {
+	if index >= total || index < 0 || total <= 0 {
+		return nil
+	}
+	if total == 1 {
+		if len(aunts) != 0 {
+			return nil
+		}
+		return leafHash
+	}
+	if len(aunts) == 0 {
+		return nil
+	}
+
+	last := aunts[len(aunts)-1]
+	numLeft := int64(splitPoint(int(total)))
+	if index < numLeft {
+		left := computeHashFromAunts(index, numLeft, leafHash, aunts[:len(aunts)-1])
+		if left == nil {
+			return nil
+		}
+		return simpleInnerHash(left, last)
+	}
+	right := computeHashFromAunts(index-numLeft, total-numLeft, leafHash, aunts[:len(aunts)-1])
+	if right == nil {
+		return nil
+	}
+	return simpleInnerHash(last, right)
+}
+
+// splitPoint returns the largest power of two strictly smaller than n.
+func splitPoint(n int) int {
+	k := 1 << (bits.Len(uint(n)) - 1)
+	if k == n {
+		k >>= 1
+	}
+	return k
+}
+
+// emptyHash returns the SHA-256 hash of an empty input.
+func emptyHash() []byte {
+	h := sha256.Sum256(nil)
+	return h[:]
+}
+
+// simpleLeafHash returns SHA-256(0x00 || leaf).
+func simpleLeafHash(leaf []byte) []byte {
+	h := sha256.New()
+	h.Write(leafPrefix)
+	h.Write(leaf)
+	return h.Sum(nil)
+}
+
+// simpleInnerHash returns SHA-256(0x01 || left || right).
+func simpleInnerHash(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write(innerPrefix)
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
@@ -0,0 +1,74 @@
+package imt_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/noble-assets/imt"
+)
+
+func TestSimpleHashFromByteSlices(t *testing.T) {
+	// The vectors of the tests of CometBFT's merkle package.
+	tests := []struct {
+		name  string
+		items [][]byte
+		want  string
+	}{
+		{"nil", nil, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"empty", [][]byte{}, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+		{"single", [][]byte{{1, 2, 3}}, "054edec1d0211f624fed0cbca9d4f9400b0e491c43742af2c5b0abebf0c990d8"},
+		{"single blank", [][]byte{{}}, "6e340b9cffb37a989ca544e6bb780a2c78901d3fb33738768511a30617afa01d"},
+		{"two", [][]byte{{1, 2, 3}, {4, 5, 6}}, "82e6cfce00453804379b53962939eaa7906b39904be0813fcadd31b100773c4b"},
+		{"many", [][]byte{{1, 2}, {3, 4}, {5, 6}, {7, 8}, {9, 10}}, "f326493eceab4f2d9ffbc78c59432a0a005d6ea98392045c74df5d14a113be18"},
+	}
+	for _, test := range tests {
+		if got := hex.EncodeToString(imt.SimpleHashFromByteSlices(test.items)); got != test.want {
+			t.Errorf("%s: got %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestSimpleProofs(t *testing.T) {
+	for _, total := range []int{1, 2, 3, 5, 8, 13} {
+		t.Run(fmt.Sprint(total, " items"), func(t *testing.T) {
+			items := make([][]byte, total)
+			for i := range items {
+				items[i] = []byte{byte(i), byte(i * 7)}
+			}
+			root, proofs := imt.SimpleProofsFromByteSlices(items)
+			if hex.EncodeToString(root) != hex.EncodeToString(imt.SimpleHashFromByteSlices(items)) {
+				t.Fatal("the roots of the proofs and of the items differ")
+			}
+			for i, proof := range proofs {
+				if err := proof.Verify(root, items[i]); err != nil {
+					t.Fatalf("item %d: %v", i, err)
+				}
+				if err := proof.Verify(root, []byte("other")); err == nil {
+					t.Fatalf("item %d: proof accepted for another item", i)
+				}
+
+				forged := *proof
+				forged.Index = (proof.Index + 1) % proof.Total
+				if total > 1 && forged.Verify(root, items[i]) == nil {
+					t.Fatalf("item %d: proof accepted at index %d", i, forged.Index)
+				}
+				forged = *proof
+				forged.Aunts = append(forged.Aunts, root)
+				if forged.Verify(root, items[i]) == nil {
+					t.Fatalf("item %d: proof with an extra aunt accepted", i)
+				}
+				forged = *proof
+				forged.Total = -1
+				if forged.Verify(root, items[i]) == nil {
+					t.Fatalf("item %d: proof with a negative total accepted", i)
+				}
+			}
+		})
+	}
+
+	var proof *imt.SimpleProof
+	if proof.Verify(nil, nil) == nil {
+		t.Fatal("nil proof accepted")
+	}
+}
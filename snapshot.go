@@ -0,0 +1,491 @@
+package imt
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"time"
+)
+
+// SnapshotFormat is the format of the chunks produced by Snapshot. It is meant
+// to be returned by the `SnapshotFormat` and `SupportedFormats` methods of a
+// Cosmos SDK extension snapshotter.
+const SnapshotFormat uint32 = 1
+
+// Snapshot splits the tree into chunks suitable for state-sync. The first
+// chunk is a header containing the parameters and the root of the tree, and
+// each following chunk contains chunkSize leaves, except for the last one.
+// Chunk boundaries only depend on the leaves and chunkSize, so two nodes with
+// the same tree produce the same chunks. Each chunk is prefixed with the
+// SHA-256 hash of its content, which is checked when the chunk is restored.
+//
+// The data associated with the leaves, if any, follows the leaves in chunks
+// of chunkSize entries, encoded with the codec set by WithLeafDataCodec, and
+// is followed by the insertion records, if any, in chunks of chunkSize
+// records, by the version of the tree and the indices of its deleted leaves
+// in chunks of chunkSize indices, unless they are the ones of a tree built
+// from the leaves, and finally by the expiry deadlines of the leaves, if any,
+// in chunks of chunkSize deadlines.
+//
+// The chunks are passed to write in order, so they can be streamed to the
+// snapshot writer without being held in memory.
+func (t *IMT[N]) Snapshot(codec NodeCodec[N], chunkSize int, write func(chunk []byte) error) (err error) {
+	span := t.startSpan(OpSnapshot, t.nodes[0].Len())
+	defer func() {
+		span.End(err)
+	}()
+	if chunkSize <= 0 {
+		return errors.New("chunk size must be positive")
+	}
+
+	header := binary.AppendUvarint(nil, uint64(t.depth))
+	header = binary.AppendUvarint(header, uint64(t.arity))
+	header = binary.AppendUvarint(header, uint64(t.nodes[0].Len()))
+	header = binary.AppendUvarint(header, uint64(chunkSize))
+	header, err = appendNode(header, codec, t.Root())
+	if err != nil {
+		return err
+	}
+	if err := write(sealChunk(header)); err != nil {
+		return err
+	}
+
+	for start := 0; start < t.nodes[0].Len(); start += chunkSize {
+		end := min(start+chunkSize, t.nodes[0].Len())
+		body := binary.AppendUvarint(nil, uint64(start/chunkSize))
+		if body, err = appendNodes(body, codec, levelNodes(t.nodes[0], start, end)); err != nil {
+			return err
+		}
+		if err := write(sealChunk(body)); err != nil {
+			return err
+		}
+	}
+
+	chunk := (t.nodes[0].Len() + chunkSize - 1) / chunkSize
+	if len(t.leafData) > 0 {
+		if t.options.leafDataCodec == nil {
+			return errors.New("the tree carries leaf data but has no leaf data codec")
+		}
+		indices := t.leafDataIndices()
+		for start := 0; start < len(indices); start += chunkSize {
+			end := min(start+chunkSize, len(indices))
+			body := binary.AppendUvarint(nil, uint64(chunk))
+			body = binary.AppendUvarint(body, snapshotLeafData)
+			body = binary.AppendUvarint(body, uint64(end-start))
+			for _, index := range indices[start:end] {
+				data, err := t.options.leafDataCodec.Encode(t.leafData[index])
+				if err != nil {
+					return err
+				}
+				body = binary.AppendUvarint(body, uint64(index))
+				body = binary.AppendUvarint(body, uint64(len(data)))
+				body = append(body, data...)
+			}
+			if err := write(sealChunk(body)); err != nil {
+				return err
+			}
+			chunk++
+		}
+	}
+
+	for start := 0; start < len(t.records); start += chunkSize {
+		end := min(start+chunkSize, len(t.records))
+		body := binary.AppendUvarint(nil, uint64(chunk))
+		body = binary.AppendUvarint(body, snapshotInsertionRecords)
+		body = binary.AppendUvarint(body, t.version)
+		body = binary.AppendUvarint(body, uint64(t.recordsStart+start))
+		body = binary.AppendUvarint(body, uint64(end-start))
+		for _, record := range t.records[start:end] {
+			body = binary.AppendUvarint(body, record.Sequence)
+			body = binary.AppendVarint(body, record.Time.UnixNano())
+			body = binary.AppendUvarint(body, uint64(len(record.Reference)))
+			body = append(body, record.Reference...)
+		}
+		if err := write(sealChunk(body)); err != nil {
+			return err
+		}
+		chunk++
+	}
+
+	// The version and the deleted leaves only need to be restored if they
+	// differ from the ones of a tree built from the leaves.
+	if t.version != uint64(t.nodes[0].Len()) || t.tombstones.count > 0 {
+		deleted := t.tombstones.indices()
+		for start := 0; start == 0 || start < len(deleted); start += chunkSize {
+			end := min(start+chunkSize, len(deleted))
+			body := binary.AppendUvarint(nil, uint64(chunk))
+			body = binary.AppendUvarint(body, snapshotState)
+			body = binary.AppendUvarint(body, t.version)
+			body = binary.AppendUvarint(body, uint64(end-start))
+			for _, index := range deleted[start:end] {
+				body = binary.AppendUvarint(body, uint64(index))
+			}
+			if err := write(sealChunk(body)); err != nil {
+				return err
+			}
+			chunk++
+		}
+	}
+
+	indices := slices.Sorted(maps.Keys(t.expiries.positions))
+	for start := 0; start < len(indices); start += chunkSize {
+		end := min(start+chunkSize, len(indices))
+		body := binary.AppendUvarint(nil, uint64(chunk))
+		body = binary.AppendUvarint(body, snapshotExpiries)
+		body = binary.AppendUvarint(body, uint64(end-start))
+		for _, index := range indices[start:end] {
+			deadline, _ := t.Expiry(index)
+			body = binary.AppendUvarint(body, uint64(index))
+			body = binary.AppendVarint(body, deadline.UnixNano())
+		}
+		if err := write(sealChunk(body)); err != nil {
+			return err
+		}
+		chunk++
+	}
+
+	return nil
+}
+
+// The kinds of the chunks that follow the chunks of leaves.
+const (
+	snapshotLeafData         = 1
+	snapshotInsertionRecords = 2
+	snapshotState            = 3
+	snapshotExpiries         = 4
+)
+
+// SnapshotRestorer rebuilds a tree from the chunks produced by Snapshot. The
+// chunks must be added in the order they were produced.
+type SnapshotRestorer[N comparable] struct {
+	hash      HashFunction[N]
+	zeroValue N
+	codec     NodeCodec[N]
+	options   options
+
+	header       bool
+	metadata     Metadata
+	chunkSize    int
+	root         N
+	leaves       []N
+	leafData     map[int]any
+	records      []InsertionRecord
+	recordsStart int
+	version      uint64
+	chunks       int
+
+	// Whether the version and the deleted leaves were restored, and the last
+	// deleted leaf restored.
+	state       bool
+	deleted     tombstones
+	lastDeleted int
+
+	// The restored expiry deadlines, and the last leaf they were restored
+	// for.
+	expiries   expiries
+	lastExpiry int
+}
+
+// NewSnapshotRestorer returns a restorer that rebuilds the tree with the given
+// hash function, zero value and options, which are not part of the snapshot.
+// Restoring a snapshot with leaf data requires the WithLeafDataCodec option.
+func NewSnapshotRestorer[N comparable](hash HashFunction[N], zeroValue N, codec NodeCodec[N], opts ...Option) *SnapshotRestorer[N] {
+	return &SnapshotRestorer[N]{
+		hash:      hash,
+		zeroValue: zeroValue,
+		codec:     codec,
+		options:   newOptions(opts),
+	}
+}
+
+// Add verifies the hash of the next chunk and applies it.
+func (r *SnapshotRestorer[N]) Add(chunk []byte) error {
+	if len(chunk) < sha256.Size {
+		return errors.New("chunk is too short")
+	}
+	body := chunk[sha256.Size:]
+	if sha256.Sum256(body) != [sha256.Size]byte(chunk[:sha256.Size]) {
+		return errors.New("chunk hash mismatch")
+	}
+
+	reader := &byteReader{b: body}
+
+	if !r.header {
+		r.metadata = Metadata{
+			Depth: reader.int(),
+			Arity: reader.int(),
+			Size:  reader.int(),
+		}
+		r.chunkSize = reader.int()
+		r.root = readNode(reader, r.codec)
+		if err := reader.done(); err != nil {
+			return fmt.Errorf("invalid header chunk: %w", err)
+		}
+		if err := validatePeerMetadata(r.metadata, r.hash); err != nil {
+			return fmt.Errorf("invalid header chunk: %w", err)
+		}
+		if r.chunkSize <= 0 {
+			return errors.New("chunk size must be positive")
+		}
+		// The size comes from a peer, so it only bounds the initial capacity.
+		r.leaves = make([]N, 0, min(r.metadata.Size, len(chunk)))
+		r.header = true
+		return nil
+	}
+
+	if len(r.leaves) == r.metadata.Size {
+		return r.addTrailer(reader)
+	}
+
+	index := reader.int()
+	leaves := readNodes(reader, r.codec)
+	if err := reader.done(); err != nil {
+		return fmt.Errorf("invalid chunk %d: %w", r.chunks, err)
+	}
+	if index != r.chunks {
+		return fmt.Errorf("expected chunk %d, got %d", r.chunks, index)
+	}
+	if len(leaves) != min(r.chunkSize, r.metadata.Size-len(r.leaves)) || len(leaves) == 0 {
+		return fmt.Errorf("chunk %d has an unexpected number of leaves", index)
+	}
+
+	r.leaves = append(r.leaves, leaves...)
+	r.chunks++
+
+	return nil
+}
+
+// Finish rebuilds the tree from the restored leaves and checks that its root
+// matches the root recorded in the header.
+func (r *SnapshotRestorer[N]) Finish() (*IMT[N], error) {
+	if !r.header {
+		return nil, errors.New("the header chunk is missing")
+	}
+	if len(r.leaves) != r.metadata.Size {
+		return nil, fmt.Errorf("expected %d leaves, got %d", r.metadata.Size, len(r.leaves))
+	}
+
+	// The insertion records come from the snapshot rather than from the
+	// construction of the tree.
+	opts := r.options
+	opts.insertionRecords = false
+	t, err := newWithOptions(r.hash, r.metadata.Depth, r.zeroValue, r.metadata.Arity, r.leaves, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !t.equal(t.Root(), r.root) {
+		t.log(slog.LevelError, "imt: integrity check failed", slog.String("op", OpSnapshot), slog.Any("root", t.Root()), slog.Any("expected", r.root))
+		return nil, errors.New("the restored root does not match the snapshot")
+	}
+	t.options.insertionRecords = r.options.insertionRecords || len(r.records) > 0
+	t.leafData = r.leafData
+	t.records, t.recordsStart = r.records, r.recordsStart
+	if len(r.records) > 0 || r.state {
+		t.version = r.version
+	}
+	t.tombstones = r.deleted
+	t.expiries = r.expiries
+	t.log(slog.LevelInfo, "imt: restored tree from snapshot", slog.Uint64("version", t.version), slog.Any("root", t.Root()))
+
+	return t, nil
+}
+
+// addTrailer applies a chunk following the chunks of leaves.
+func (r *SnapshotRestorer[N]) addTrailer(reader *byteReader) error {
+	index := reader.int()
+	if index != r.chunks && reader.err == nil {
+		return fmt.Errorf("expected chunk %d, got %d", r.chunks, index)
+	}
+	var err error
+	switch kind := reader.uvarint(); {
+	case reader.err != nil:
+		err = reader.err
+	case kind == snapshotLeafData && len(r.records) == 0 && !r.state && r.expiries.Len() == 0:
+		err = r.addLeafData(reader)
+	case kind == snapshotInsertionRecords && !r.state && r.expiries.Len() == 0:
+		err = r.addInsertionRecords(reader)
+	case kind == snapshotState && r.expiries.Len() == 0:
+		err = r.addState(reader)
+	case kind == snapshotExpiries:
+		err = r.addExpiries(reader)
+	default:
+		err = fmt.Errorf("unexpected chunk kind %d", kind)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid chunk %d: %w", index, err)
+	}
+	r.chunks++
+
+	return nil
+}
+
+// addLeafData applies a chunk of leaf data.
+func (r *SnapshotRestorer[N]) addLeafData(reader *byteReader) error {
+	if r.options.leafDataCodec == nil {
+		return errors.New("the snapshot carries leaf data but the restorer has no leaf data codec")
+	}
+	count := reader.length()
+	if reader.err == nil && (count <= 0 || count > r.chunkSize) {
+		return errors.New("unexpected number of leaf data entries")
+	}
+	leaves := make([]int, 0, count)
+	encoded := make([][]byte, 0, count)
+	for i := 0; i < count && reader.err == nil; i++ {
+		leaf := reader.int()
+		data := reader.bytes(reader.length())
+		if reader.err == nil && (leaf >= r.metadata.Size || len(leaves) > 0 && leaf <= leaves[len(leaves)-1]) {
+			return fmt.Errorf("leaf data for an unexpected leaf %d", leaf)
+		}
+		leaves, encoded = append(leaves, leaf), append(encoded, data)
+	}
+	if err := reader.done(); err != nil {
+		return err
+	}
+
+	if r.leafData == nil {
+		r.leafData = make(map[int]any)
+	}
+	for i, leaf := range leaves {
+		if _, ok := r.leafData[leaf]; ok {
+			return fmt.Errorf("leaf data for an unexpected leaf %d", leaf)
+		}
+		data, err := r.options.leafDataCodec.Decode(encoded[i])
+		if err != nil {
+			return err
+		}
+		r.leafData[leaf] = data
+	}
+
+	return nil
+}
+
+// addInsertionRecords applies a chunk of insertion records, which must cover
+// the last leaves of the tree contiguously.
+func (r *SnapshotRestorer[N]) addInsertionRecords(reader *byteReader) error {
+	version := reader.uvarint()
+	start := reader.int()
+	count := reader.length()
+	if reader.err != nil {
+		return reader.err
+	}
+	if count <= 0 || count > r.chunkSize {
+		return errors.New("unexpected number of insertion records")
+	}
+	if len(r.records) == 0 {
+		r.recordsStart, r.version = start, version
+	}
+	if start != r.recordsStart+len(r.records) || version != r.version || count > r.metadata.Size-start {
+		return fmt.Errorf("insertion records for unexpected leaves %d to %d", start, start+count-1)
+	}
+
+	records := make([]InsertionRecord, 0, count)
+	for i := 0; i < count && reader.err == nil; i++ {
+		sequence := reader.uvarint()
+		at := reader.varint()
+		reference := reader.bytes(reader.length())
+		records = append(records, InsertionRecord{
+			Sequence:  sequence,
+			Time:      time.Unix(0, at).UTC(),
+			Reference: string(reference),
+		})
+	}
+	if err := reader.done(); err != nil {
+		return err
+	}
+	r.records = append(r.records, records...)
+
+	return nil
+}
+
+// addState applies a chunk of the version and the deleted leaves of the tree.
+func (r *SnapshotRestorer[N]) addState(reader *byteReader) error {
+	version := reader.uvarint()
+	count := reader.int()
+	if reader.err != nil {
+		return reader.err
+	}
+	if r.state && (version != r.version || count == 0) || count > r.chunkSize {
+		return errors.New("unexpected number of deleted leaves")
+	}
+	if len(r.records) > 0 && version != r.version {
+		return fmt.Errorf("unexpected version %d", version)
+	}
+	last := -1
+	if r.deleted.count > 0 {
+		last = r.lastDeleted
+	}
+	for i := 0; i < count && reader.err == nil; i++ {
+		index := reader.int()
+		if reader.err == nil && (index <= last || index >= r.metadata.Size) {
+			return fmt.Errorf("unexpected deleted leaf %d", index)
+		}
+		r.deleted.set(index)
+		last = index
+	}
+	if err := reader.done(); err != nil {
+		return err
+	}
+	r.version, r.state, r.lastDeleted = version, true, last
+
+	return nil
+}
+
+// addExpiries applies a chunk of the expiry deadlines of the leaves, which
+// must follow the deleted leaves, since deleted leaves have no deadline.
+func (r *SnapshotRestorer[N]) addExpiries(reader *byteReader) error {
+	count := reader.int()
+	if reader.err == nil && (count <= 0 || count > r.chunkSize) {
+		return errors.New("unexpected number of expiry deadlines")
+	}
+	last := -1
+	if r.expiries.Len() > 0 {
+		last = r.lastExpiry
+	}
+	leaves := make([]int, 0, count)
+	deadlines := make([]time.Time, 0, count)
+	for i := 0; i < count && reader.err == nil; i++ {
+		leaf := reader.int()
+		at := reader.varint()
+		if reader.err == nil && (leaf <= last || leaf >= r.metadata.Size || r.deleted.has(leaf)) {
+			return fmt.Errorf("expiry deadline for an unexpected leaf %d", leaf)
+		}
+		leaves, deadlines = append(leaves, leaf), append(deadlines, time.Unix(0, at).UTC())
+		last = leaf
+	}
+	if err := reader.done(); err != nil {
+		return err
+	}
+	for i, leaf := range leaves {
+		r.expiries.set(leaf, deadlines[i])
+	}
+	r.lastExpiry = last
+
+	return nil
+}
+
+// The largest depth and arity of a tree whose parameters come from a peer,
+// which bound what is allocated for them. Trees of arity 2 or more cannot be
+// deeper than maxPeerDepth anyway, see ValidateConfig.
+const (
+	maxPeerDepth = 64
+	maxPeerArity = 1 << 16
+)
+
+// validatePeerMetadata checks the parameters of a tree received from a peer,
+// before anything is allocated for them.
+func validatePeerMetadata[N comparable](metadata Metadata, hash HashFunction[N]) error {
+	if metadata.Depth > maxPeerDepth || metadata.Arity > maxPeerArity {
+		return fmt.Errorf("the depth and the arity of the tree must be at most %d and %d", maxPeerDepth, maxPeerArity)
+	}
+	return validateConfig(metadata.Depth, metadata.Arity, hash, metadata.Size)
+}
+
+// sealChunk prefixes a chunk body with its SHA-256 hash.
+func sealChunk(body []byte) []byte {
+	hash := sha256.Sum256(body)
+	return append(hash[:], body...)
+}
@@ -0,0 +1,291 @@
+package imt_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+// stringData encodes string leaf data.
+type stringData struct{}
+
+func (stringData) Encode(data any) ([]byte, error) {
+	s, ok := data.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	return []byte(s), nil
+}
+
+func (stringData) Decode(b []byte) (any, error) {
+	return string(b), nil
+}
+
+func snapshot(t *testing.T, tree *imt.IMT[uint64], chunkSize int) [][]byte {
+	t.Helper()
+	var chunks [][]byte
+	if err := tree.Snapshot(uint64Codec{}, chunkSize, func(chunk []byte) error {
+		chunks = append(chunks, chunk)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return chunks
+}
+
+func restore(chunks [][]byte, opts ...imt.Option) (*imt.IMT[uint64], error) {
+	restorer := imt.NewSnapshotRestorer(imttest.Uint64Hash, 0, uint64Codec{}, opts...)
+	for _, chunk := range chunks {
+		if err := restorer.Add(chunk); err != nil {
+			return nil, err
+		}
+	}
+	return restorer.Finish()
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		leaves int
+		setup  func(tree *imt.IMT[uint64]) error
+	}{
+		{"empty", 0, nil},
+		{"one leaf", 1, nil},
+		{"partial chunk", 10, nil},
+		{"full chunks", 12, nil},
+		{"deleted leaves", 10, func(tree *imt.IMT[uint64]) error {
+			if err := tree.Delete(3); err != nil {
+				return err
+			}
+			return tree.Delete(7)
+		}},
+		{"leaf data", 10, func(tree *imt.IMT[uint64]) error {
+			if err := tree.SetLeafData(2, "two"); err != nil {
+				return err
+			}
+			return tree.SetLeafData(9, "nine")
+		}},
+		{"expiry deadlines", 10, func(tree *imt.IMT[uint64]) error {
+			if err := tree.Delete(1); err != nil {
+				return err
+			}
+			for i, index := range []int{8, 0, 5, 3, 9, 6} {
+				if err := tree.SetExpiry(index, time.Unix(int64(100-i), 0)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			leaves := imttest.RandomLeaves(imttest.Rand(1), test.leaves, imttest.Uint64Leaf)
+			tree, err := imt.New(imttest.Uint64Hash, 5, 0, 3, leaves, imt.WithLeafDataCodec(stringData{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.setup != nil {
+				if err := test.setup(tree); err != nil {
+					t.Fatal(err)
+				}
+			}
+			chunks := snapshot(t, tree, 4)
+			if again := snapshot(t, tree, 4); !slices.EqualFunc(chunks, again, slices.Equal) {
+				t.Fatal("snapshots of the same tree differ")
+			}
+
+			restored, err := restore(chunks, imt.WithLeafDataCodec(stringData{}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if restored.Root() != tree.Root() || restored.Size() != tree.Size() || restored.Version() != tree.Version() {
+				t.Fatal("the restored tree differs from the original")
+			}
+			if !slices.Equal(restored.DeletedIndices(), tree.DeletedIndices()) {
+				t.Fatal("the deleted leaves were not restored")
+			}
+			for index := range tree.Size() {
+				want, _ := tree.LeafData(index)
+				if got, _ := restored.LeafData(index); got != want {
+					t.Fatalf("leaf %d: restored data %v, want %v", index, got, want)
+				}
+				deadline, ok := tree.Expiry(index)
+				if got, restored := restored.Expiry(index); restored != ok || !got.Equal(deadline) {
+					t.Fatalf("leaf %d: restored deadline %v, want %v", index, got, deadline)
+				}
+			}
+		})
+	}
+}
+
+func TestSnapshotRejectsCorruptChunks(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 5, 0, 2, []uint64{1, 2, 3, 4, 5, 6, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := snapshot(t, tree, 3)
+
+	tests := []struct {
+		name    string
+		corrupt func(chunks [][]byte) [][]byte
+	}{
+		{"altered leaf", func(chunks [][]byte) [][]byte {
+			chunks[1][len(chunks[1])-1] ^= 1
+			return chunks
+		}},
+		{"resealed altered leaf", func(chunks [][]byte) [][]byte {
+			body := slices.Clone(chunks[1][sha256.Size:])
+			body[len(body)-1] ^= 1
+			chunks[1] = seal(body)
+			return chunks
+		}},
+		{"missing chunk", func(chunks [][]byte) [][]byte { return slices.Delete(chunks, 2, 3) }},
+		{"swapped chunks", func(chunks [][]byte) [][]byte {
+			chunks[1], chunks[2] = chunks[2], chunks[1]
+			return chunks
+		}},
+		{"truncated chunk", func(chunks [][]byte) [][]byte {
+			chunks[1] = chunks[1][:10]
+			return chunks
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			copied := make([][]byte, len(chunks))
+			for i, chunk := range chunks {
+				copied[i] = slices.Clone(chunk)
+			}
+			if _, err := restore(test.corrupt(copied)); err == nil {
+				t.Fatal("corrupt snapshot restored")
+			}
+		})
+	}
+}
+
+// seal prefixes a chunk body with its SHA-256 hash, like Snapshot.
+func seal(body []byte) []byte {
+	hash := sha256.Sum256(body)
+	return append(hash[:], body...)
+}
+
+// header returns a sealed header chunk with the given parameters.
+func header(depth, arity, size, chunkSize uint64) []byte {
+	body := binary.AppendUvarint(nil, depth)
+	body = binary.AppendUvarint(body, arity)
+	body = binary.AppendUvarint(body, size)
+	body = binary.AppendUvarint(body, chunkSize)
+	body = binary.AppendUvarint(body, 8)
+	body = binary.BigEndian.AppendUint64(body, 0)
+	return seal(body)
+}
+
+func TestSnapshotRejectsHostileHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{"huge depth", header(1<<40, 2, 1, 1)},
+		{"deep unary tree", header(1<<20, 1, 1, 1)},
+		{"huge arity", header(1, 1<<40, 1, 1)},
+		{"capacity overflowing int", header(200, 2, 1, 1)},
+		{"size beyond capacity", header(2, 2, 5, 1)},
+		{"huge size", header(4, 2, 1<<62, 1)},
+		{"zero depth", header(0, 2, 0, 1)},
+		{"zero arity", header(4, 0, 0, 1)},
+		{"zero chunk size", header(4, 2, 1, 0)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			restorer := imt.NewSnapshotRestorer(imttest.Uint64Hash, 0, uint64Codec{})
+			if err := restorer.Add(test.header); err == nil {
+				t.Fatal("hostile header accepted")
+			}
+		})
+	}
+
+	// A trailer chunk announcing more entries than it holds must not
+	// allocate them.
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := snapshot(t, tree, math.MaxInt32)
+	trailers := []struct {
+		name   string
+		fields []uint64
+	}{
+		{"leaf data", []uint64{1, 1, 1 << 40}},
+		{"insertion records", []uint64{1, 2, 2, 0, 1 << 40}},
+		{"deleted leaves", []uint64{1, 3, 2, 1 << 40}},
+		{"expiry deadlines", []uint64{1, 4, 1 << 40}},
+	}
+	for _, trailer := range trailers {
+		t.Run(trailer.name, func(t *testing.T) {
+			var body []byte
+			for _, field := range trailer.fields {
+				body = binary.AppendUvarint(body, field)
+			}
+			restorer := imt.NewSnapshotRestorer(imttest.Uint64Hash, 0, uint64Codec{}, imt.WithLeafDataCodec(stringData{}))
+			for _, chunk := range chunks {
+				if err := restorer.Add(chunk); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := restorer.Add(seal(body)); err == nil {
+				t.Fatal("hostile trailer accepted")
+			}
+		})
+	}
+}
+
+func TestSnapshotRejectsInvalidDeadlines(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.SetExpiry(1, time.Unix(1, 0)); err != nil {
+		t.Fatal(err)
+	}
+	// The header, the chunk of leaves, the deleted leaves and the deadlines.
+	chunks := snapshot(t, tree, 4)
+	if len(chunks) != 4 {
+		t.Fatalf("the snapshot has %d chunks", len(chunks))
+	}
+
+	// The chunk of the deleted leaves, renumbered to follow the deadlines.
+	state := seal(append([]byte{2}, chunks[2][sha256.Size+1:]...))
+	deadline := func(chunk, index uint64) []byte {
+		body := binary.AppendUvarint(nil, chunk)
+		body = binary.AppendUvarint(body, 4)
+		body = binary.AppendUvarint(body, 1)
+		body = binary.AppendUvarint(body, index)
+		body = binary.AppendVarint(body, 1)
+		return seal(body)
+	}
+	tests := []struct {
+		name   string
+		chunks [][]byte
+	}{
+		{"deleted leaf", [][]byte{chunks[0], chunks[1], chunks[2], deadline(2, 0)}},
+		{"missing leaf", [][]byte{chunks[0], chunks[1], chunks[2], deadline(2, 3)}},
+		{"repeated leaf", [][]byte{chunks[0], chunks[1], chunks[2], chunks[3], deadline(3, 1)}},
+		{"deleted leaves after the deadlines", [][]byte{chunks[0], chunks[1], deadline(1, 1), state}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := restore(test.chunks); err == nil {
+				t.Fatal("invalid deadlines restored")
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package imt
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+// SolanaHash is the hash of the concurrent Merkle trees of Solana's
+// spl-account-compression program: the Keccak-256 hash of the concatenation
+// of the children.
+func SolanaHash(children [][32]byte) [32]byte {
+	data := make([][]byte, len(children))
+	for i := range children {
+		data[i] = children[i][:]
+	}
+	return keccak.Sum256(data...)
+}
+
+// ChangeLog records a change of a ConcurrentMerkleTree: the new root, the new
+// nodes on the path from the changed leaf up to the root, excluded, and the
+// index of the leaf.
+type ChangeLog struct {
+	Root  [32]byte
+	Path  [][32]byte
+	Index uint32
+}
+
+// ConcurrentMerkleTree mirrors a concurrent Merkle tree of Solana's
+// spl-account-compression program, such as the trees of compressed NFTs. Like
+// the on-chain tree, it keeps a buffer of the changelogs of the most recent
+// changes, so that a proof created against any of the roots of the buffer
+// can be fast-forwarded to the current root by patching the nodes changed
+// since. Unlike the on-chain tree, it keeps every leaf, so it can create the
+// proof of any leaf.
+type ConcurrentMerkleTree struct {
+	tree          *IMT[[32]byte]
+	maxBufferSize int
+	sequence      uint64
+
+	// The ring buffer of the changelogs, where active is the index of the
+	// most recent one and size the number of changelogs in the buffer.
+	changeLogs []ChangeLog
+	active     int
+	size       int
+}
+
+// NewConcurrentMerkleTree returns an empty tree of the given depth, which is
+// at most 32, keeping the changelogs of the last maxBufferSize changes.
+func NewConcurrentMerkleTree(maxDepth, maxBufferSize int) (*ConcurrentMerkleTree, error) {
+	if maxDepth <= 0 || maxDepth > 32 {
+		return nil, errors.New("depth must be between 1 and 32")
+	}
+	if maxBufferSize <= 0 {
+		return nil, errors.New("buffer size must be positive")
+	}
+	tree, err := New(SolanaHash, maxDepth, [32]byte{}, 2, nil, WithHashID("keccak256"))
+	if err != nil {
+		return nil, err
+	}
+	t := &ConcurrentMerkleTree{
+		tree:          tree,
+		maxBufferSize: maxBufferSize,
+		changeLogs:    make([]ChangeLog, maxBufferSize),
+		size:          1,
+	}
+	t.changeLogs[0] = ChangeLog{Root: tree.Root(), Path: tree.Zeroes()}
+	return t, nil
+}
+
+// Root returns the current root of the tree.
+func (t *ConcurrentMerkleTree) Root() [32]byte {
+	return t.tree.Root()
+}
+
+// Sequence returns the number of changes applied to the tree.
+func (t *ConcurrentMerkleTree) Sequence() uint64 {
+	return t.sequence
+}
+
+// RightmostIndex returns the index of the next appended leaf.
+func (t *ConcurrentMerkleTree) RightmostIndex() uint32 {
+	return uint32(t.tree.Size())
+}
+
+// Leaf returns the leaf at the given index, which is zero if it was removed
+// or never appended.
+func (t *ConcurrentMerkleTree) Leaf(index uint32) [32]byte {
+	if int(index) >= t.tree.Size() {
+		return [32]byte{}
+	}
+	return t.tree.nodes[0].Get(int(index))
+}
+
+// ChangeLogs returns the changelogs of the buffer, from the oldest to the
+// most recent one.
+func (t *ConcurrentMerkleTree) ChangeLogs() []ChangeLog {
+	changeLogs := make([]ChangeLog, 0, t.size)
+	for i := t.size - 1; i >= 0; i-- {
+		changeLogs = append(changeLogs, t.changeLogs[t.bufferIndex(i)])
+	}
+	return changeLogs
+}
+
+// Proof returns the proof of the leaf at the given index against the current
+// root: the siblings from the leaf up to the root, excluded.
+func (t *ConcurrentMerkleTree) Proof(index uint32) ([][32]byte, error) {
+	if uint64(index) >= t.tree.Capacity() {
+		return nil, errors.New("the position is outside the capacity of the tree")
+	}
+	proof := make([][32]byte, 0, t.tree.depth)
+	position := int(index)
+	for level := 0; level < t.tree.depth; level++ {
+		proof = append(proof, t.tree.readNode(level, position^1))
+		position /= 2
+	}
+	return proof, nil
+}
+
+// Append appends a leaf, which must not be zero.
+func (t *ConcurrentMerkleTree) Append(leaf [32]byte) error {
+	if leaf == ([32]byte{}) {
+		return errors.New("cannot append an empty leaf")
+	}
+	if err := t.tree.Insert(leaf); err != nil {
+		return err
+	}
+	t.recordChange(uint32(t.tree.Size() - 1))
+	return nil
+}
+
+// ReplaceLeaf replaces the leaf at the given index, given its previous value
+// and its proof against a root of the changelog buffer. The proof is
+// fast-forwarded to the current root with the changelogs of the changes
+// applied since that root, which fails if one of them changed the leaf.
+func (t *ConcurrentMerkleTree) ReplaceLeaf(root, previousLeaf, newLeaf [32]byte, proof [][32]byte, index uint32) error {
+	if int(index) >= t.tree.Size() {
+		return ErrLeafNotFound
+	}
+	if err := t.fastForward(root, previousLeaf, proof, index); err != nil {
+		return err
+	}
+	if err := t.tree.Update(int(index), newLeaf); err != nil {
+		return err
+	}
+	t.recordChange(index)
+	return nil
+}
+
+// RemoveLeaf replaces the leaf at the given index with zero, like ReplaceLeaf.
+func (t *ConcurrentMerkleTree) RemoveLeaf(root, previousLeaf [32]byte, proof [][32]byte, index uint32) error {
+	return t.ReplaceLeaf(root, previousLeaf, [32]byte{}, proof, index)
+}
+
+// ProveLeaf checks that a leaf is at the given index of the current tree,
+// given its proof against a root of the changelog buffer, like the
+// verify_leaf instruction of spl-account-compression.
+func (t *ConcurrentMerkleTree) ProveLeaf(root, leaf [32]byte, proof [][32]byte, index uint32) error {
+	if int(index) >= t.tree.Size() {
+		return ErrLeafNotFound
+	}
+	return t.fastForward(root, leaf, proof, index)
+}
+
+// fastForward patches a proof of a leaf against a root of the buffer with the
+// changelogs that follow it, and checks the patched proof against the current
+// root.
+func (t *ConcurrentMerkleTree) fastForward(root, leaf [32]byte, proof [][32]byte, index uint32) error {
+	depth := t.tree.depth
+	if len(proof) != depth {
+		return fmt.Errorf("the proof has %d nodes, expected %d", len(proof), depth)
+	}
+
+	found := -1
+	for i := 0; i < t.size; i++ {
+		if t.changeLogs[t.bufferIndex(i)].Root == root {
+			found = i
+			break
+		}
+	}
+	if found < 0 {
+		return errors.New("the root is not in the changelog buffer")
+	}
+
+	patched := append([][32]byte(nil), proof...)
+	updated := leaf
+	for i := found - 1; i >= 0; i-- {
+		changeLog := t.changeLogs[t.bufferIndex(i)]
+		if changeLog.Index == index {
+			updated = changeLog.Path[0]
+			continue
+		}
+		// The paths of the two leaves meet above the level of their most
+		// significant differing bit, where the changed node is a sibling.
+		critbit := depth - 1 - bits.LeadingZeros32((index^changeLog.Index)<<(32-depth))
+		patched[critbit] = changeLog.Path[critbit]
+	}
+	if updated != leaf {
+		return errors.New("the leaf was modified since the root of the proof")
+	}
+
+	node := leaf
+	for level, sibling := range patched {
+		if index>>level&1 == 0 {
+			node = SolanaHash([][32]byte{node, sibling})
+		} else {
+			node = SolanaHash([][32]byte{sibling, node})
+		}
+	}
+	if node != t.tree.Root() {
+		return errors.New("invalid proof")
+	}
+	return nil
+}
+
+// recordChange appends the changelog of a change of the leaf at the given
+// index to the buffer.
+func (t *ConcurrentMerkleTree) recordChange(index uint32) {
+	path := make([][32]byte, t.tree.depth)
+	position := int(index)
+	for level := range path {
+		path[level] = t.tree.readNode(level, position)
+		position /= 2
+	}
+
+	t.active = (t.active + 1) % t.maxBufferSize
+	t.size = min(t.size+1, t.maxBufferSize)
+	t.sequence++
+	t.changeLogs[t.active] = ChangeLog{Root: t.tree.Root(), Path: path, Index: index}
+}
+
+// bufferIndex returns the position in the ring buffer of the changelog that
+// is age changes older than the most recent one.
+func (t *ConcurrentMerkleTree) bufferIndex(age int) int {
+	return (t.active - age + t.maxBufferSize) % t.maxBufferSize
+}
+
+// Canopy returns the nodes of the top canopyDepth levels of the tree, below
+// the root, in the layout of the canopy of an spl-account-compression tree
+// account: the node of heap index h, where the root has index 1 and the
+// children of node h are 2h and 2h+1, is at position h-2. Proofs truncated to
+// their first depth-canopyDepth nodes are completed from the canopy by
+// FillProofFromCanopy.
+func (t *ConcurrentMerkleTree) Canopy(canopyDepth int) ([][32]byte, error) {
+	depth := t.tree.depth
+	if canopyDepth < 0 || canopyDepth > depth {
+		return nil, errors.New("the canopy cannot be deeper than the tree")
+	}
+	canopy := make([][32]byte, 0, 1<<(canopyDepth+1)-2)
+	for height := 1; height <= canopyDepth; height++ {
+		level := depth - height
+		for i := 0; i < 1<<height; i++ {
+			canopy = append(canopy, t.tree.readNode(level, i))
+		}
+	}
+	return canopy, nil
+}
+
+// FillProofFromCanopy completes a proof of the leaf at the given index of a
+// tree of the given depth, truncated to its first depth-canopyDepth nodes,
+// with the nodes of the canopy. Zero nodes of the canopy are replaced by the
+// zero value of their level, as the on-chain program does.
+func FillProofFromCanopy(proof [][32]byte, index uint32, canopy [][32]byte, depth int) ([][32]byte, error) {
+	canopyDepth := depth - len(proof)
+	if canopyDepth < 0 || len(canopy) != 1<<(canopyDepth+1)-2 {
+		return nil, errors.New("the canopy does not match the proof")
+	}
+	zeroes := make([][32]byte, depth)
+	for level := 1; level < depth; level++ {
+		zeroes[level] = SolanaHash([][32]byte{zeroes[level-1], zeroes[level-1]})
+	}
+
+	filled := append([][32]byte(nil), proof...)
+	node := (uint64(1)<<depth + uint64(index)) >> (depth - canopyDepth)
+	for level := len(proof); node > 1; level++ {
+		sibling := canopy[(node^1)-2]
+		if sibling == ([32]byte{}) {
+			sibling = zeroes[level]
+		}
+		filled = append(filled, sibling)
+		node >>= 1
+	}
+	return filled, nil
+}
@@ -0,0 +1,196 @@
+package imt_test
+
+import (
+	"fmt"
+	"math/bits"
+	"slices"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+func TestConcurrentMerkleTreeEmptyRoots(t *testing.T) {
+	// The empty nodes of spl-account-compression are the Keccak-256 zero
+	// hashes, also published by Hyperlane's MerkleLib.
+	roots := map[int]string{
+		1: "ad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5",
+		2: "b4c11951957c6f8f642c4af61cd6b24640fec6dc7fc607ee8206a99e92410d30",
+	}
+	// The capacity of a tree of depth 32 only fits in 64-bit ints.
+	if bits.UintSize == 64 {
+		roots[32] = "27ae5ba08d7291c96c8cbddcc148bf48a6d68c7974b94356f53754ef6171d757"
+	}
+	for depth, want := range roots {
+		tree, err := imt.NewConcurrentMerkleTree(depth, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root := tree.Root(); root != decodeChunk(t, want) {
+			t.Fatalf("empty root of depth %d: got %x, want %s", depth, root, want)
+		}
+	}
+
+	for _, config := range [][2]int{{0, 8}, {33, 8}, {14, 0}} {
+		if _, err := imt.NewConcurrentMerkleTree(config[0], config[1]); err == nil {
+			t.Fatalf("created a tree of depth %d and buffer size %d", config[0], config[1])
+		}
+	}
+}
+
+func solanaLeaf(i int) [32]byte {
+	return keccak.Sum256([]byte(fmt.Sprint("leaf ", i)))
+}
+
+func TestConcurrentMerkleTreeFastForward(t *testing.T) {
+	newTree := func(t *testing.T) *imt.ConcurrentMerkleTree {
+		t.Helper()
+		tree, err := imt.NewConcurrentMerkleTree(4, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range 6 {
+			if err := tree.Append(solanaLeaf(i)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return tree
+	}
+
+	tests := []struct {
+		name string
+		// changes applies changes after the proof of leaf 2 was created.
+		changes func(tree *imt.ConcurrentMerkleTree) error
+		valid   bool
+	}{
+		{"current root", func(*imt.ConcurrentMerkleTree) error { return nil }, true},
+		{"appended leaves", func(tree *imt.ConcurrentMerkleTree) error {
+			if err := tree.Append(solanaLeaf(6)); err != nil {
+				return err
+			}
+			return tree.Append(solanaLeaf(7))
+		}, true},
+		{"replaced sibling", func(tree *imt.ConcurrentMerkleTree) error {
+			proof, err := tree.Proof(3)
+			if err != nil {
+				return err
+			}
+			return tree.ReplaceLeaf(tree.Root(), solanaLeaf(3), solanaLeaf(30), proof, 3)
+		}, true},
+		{"replaced leaf", func(tree *imt.ConcurrentMerkleTree) error {
+			proof, err := tree.Proof(2)
+			if err != nil {
+				return err
+			}
+			return tree.RemoveLeaf(tree.Root(), solanaLeaf(2), proof, 2)
+		}, false},
+		{"root evicted from the buffer", func(tree *imt.ConcurrentMerkleTree) error {
+			for i := 6; i < 10; i++ {
+				if err := tree.Append(solanaLeaf(i)); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := newTree(t)
+			root := tree.Root()
+			proof, err := tree.Proof(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := test.changes(tree); err != nil {
+				t.Fatal(err)
+			}
+			err = tree.ProveLeaf(root, solanaLeaf(2), proof, 2)
+			if (err == nil) != test.valid {
+				t.Fatalf("ProveLeaf: got error %v, want valid %v", err, test.valid)
+			}
+			if !test.valid {
+				return
+			}
+			if err := tree.ReplaceLeaf(root, solanaLeaf(2), solanaLeaf(20), proof, 2); err != nil {
+				t.Fatal(err)
+			}
+			if tree.Leaf(2) != solanaLeaf(20) {
+				t.Fatal("the leaf was not replaced")
+			}
+			current, err := tree.Proof(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tree.ProveLeaf(tree.Root(), solanaLeaf(20), current, 2); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	tree := newTree(t)
+	proof, err := tree.Proof(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.ProveLeaf(tree.Root(), solanaLeaf(3), proof, 2); err == nil {
+		t.Fatal("proof accepted for another leaf")
+	}
+	if err := tree.ProveLeaf(tree.Root(), solanaLeaf(2), proof[1:], 2); err == nil {
+		t.Fatal("truncated proof accepted")
+	}
+	if err := tree.ProveLeaf(tree.Root(), solanaLeaf(2), proof, 8); err == nil {
+		t.Fatal("proof accepted for a leaf that was never appended")
+	}
+	if err := tree.Append([32]byte{}); err == nil {
+		t.Fatal("appended an empty leaf")
+	}
+	if changeLogs := tree.ChangeLogs(); len(changeLogs) != 4 || changeLogs[3].Root != tree.Root() || changeLogs[3].Index != 5 {
+		t.Fatalf("ChangeLogs = %v", changeLogs)
+	}
+	if tree.Sequence() != 6 || tree.RightmostIndex() != 6 {
+		t.Fatalf("Sequence = %d, RightmostIndex = %d", tree.Sequence(), tree.RightmostIndex())
+	}
+}
+
+func TestFillProofFromCanopy(t *testing.T) {
+	const depth = 5
+	tree, err := imt.NewConcurrentMerkleTree(depth, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 11 {
+		if err := tree.Append(solanaLeaf(i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for canopyDepth := 0; canopyDepth <= depth; canopyDepth++ {
+		canopy, err := tree.Canopy(canopyDepth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, index := range []uint32{0, 7, 10, 31} {
+			proof, err := tree.Proof(index)
+			if err != nil {
+				t.Fatal(err)
+			}
+			filled, err := imt.FillProofFromCanopy(proof[:depth-canopyDepth], index, canopy, depth)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(filled, proof) {
+				t.Fatalf("canopy depth %d, leaf %d: the filled proof differs", canopyDepth, index)
+			}
+		}
+	}
+
+	canopy, err := tree.Canopy(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imt.FillProofFromCanopy(make([][32]byte, 2), 0, canopy, depth); err == nil {
+		t.Fatal("filled a proof with a canopy of another depth")
+	}
+	if _, err := tree.Canopy(depth + 1); err == nil {
+		t.Fatal("created a canopy deeper than the tree")
+	}
+}
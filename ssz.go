@@ -0,0 +1,144 @@
+package imt
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// SSZChunkSize is the size of the chunks merkleized by SSZ.
+const SSZChunkSize = 32
+
+// SSZHash is the hash function of SSZ merkleization: the SHA-256 hash of the
+// concatenation of two chunks. Trees using it with arity 2 and the zero chunk
+// as zero value compute the roots of SSZ merkleization, since their zero
+// values are the SSZ zero hashes.
+func SSZHash(children [][32]byte) [32]byte {
+	h := sha256.New()
+	for _, child := range children {
+		h.Write(child[:])
+	}
+	return [32]byte(h.Sum(nil))
+}
+
+// SSZPack splits serialized data into chunks, padding the last one with
+// zeroes.
+func SSZPack(data []byte) [][32]byte {
+	chunks := make([][32]byte, (len(data)+SSZChunkSize-1)/SSZChunkSize)
+	for i := range chunks {
+		copy(chunks[i][:], data[i*SSZChunkSize:])
+	}
+	return chunks
+}
+
+// SSZPackUint64s packs uint64 values, serialized in little-endian order, into
+// chunks, as the basic values of SSZ lists and vectors.
+func SSZPackUint64s(values []uint64) [][32]byte {
+	data := make([]byte, 0, len(values)*8)
+	for _, value := range values {
+		data = binary.LittleEndian.AppendUint64(data, value)
+	}
+	return SSZPack(data)
+}
+
+// NewSSZTree returns a tree whose root is the SSZ merkleization of the chunks
+// padded to limit chunks, which must be at least 2. Its proofs are SSZ Merkle
+// branches, and chunks can be appended and updated incrementally.
+func NewSSZTree(chunks [][32]byte, limit uint64) (*IMT[[32]byte], error) {
+	if limit < 2 {
+		return nil, errors.New("the limit must be at least 2 chunks")
+	}
+	if uint64(len(chunks)) > limit {
+		return nil, errors.New("the number of chunks exceeds the limit")
+	}
+	return New(SSZHash, sszDepth(limit), [32]byte{}, 2, chunks, WithHashID("sha256"))
+}
+
+// SSZMerkleize computes the SSZ merkleization of the chunks, padded with zero
+// chunks to the next power of two of limit, or of the number of chunks if
+// limit is 0.
+func SSZMerkleize(chunks [][32]byte, limit uint64) ([32]byte, error) {
+	if limit == 0 {
+		limit = uint64(len(chunks))
+	}
+	if uint64(len(chunks)) > limit {
+		return [32]byte{}, errors.New("the number of chunks exceeds the limit")
+	}
+	if limit <= 1 {
+		if len(chunks) == 0 {
+			return [32]byte{}, nil
+		}
+		return chunks[0], nil
+	}
+	t, err := NewSSZTree(chunks, limit)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return t.Root(), nil
+}
+
+// SSZMixInLength mixes the length of a list into its root.
+func SSZMixInLength(root [32]byte, length uint64) [32]byte {
+	return SSZHash([][32]byte{root, sszLengthChunk(length)})
+}
+
+// SSZMixInLengthProof extends a proof of a chunk of a list, created by the
+// tree returned by NewSSZTree, to the root of the list with its length mixed
+// in. The extended proof has one more level and is verified with SSZHash.
+func SSZMixInLengthProof(proof *MerkleProof[[32]byte], length uint64) *MerkleProof[[32]byte] {
+	extended := *proof
+	extended.Root = SSZMixInLength(proof.Root, length)
+	extended.Siblings = append(append([][][32]byte(nil), proof.Siblings...), [][32]byte{sszLengthChunk(length)})
+	extended.PathIndices = append(append([]int(nil), proof.PathIndices...), 0)
+	if proof.Depth != 0 {
+		extended.Depth = proof.Depth + 1
+	}
+	return &extended
+}
+
+// SSZHashTreeRootByteVector returns the hash tree root of a ByteVector, whose
+// length is fixed by its type.
+func SSZHashTreeRootByteVector(data []byte) [32]byte {
+	root, _ := SSZMerkleize(SSZPack(data), 0)
+	return root
+}
+
+// SSZHashTreeRootByteList returns the hash tree root of a ByteList of at most
+// maxLength bytes.
+func SSZHashTreeRootByteList(data []byte, maxLength uint64) ([32]byte, error) {
+	if uint64(len(data)) > maxLength {
+		return [32]byte{}, errors.New("the data exceeds the maximum length")
+	}
+	root, err := SSZMerkleize(SSZPack(data), (maxLength+SSZChunkSize-1)/SSZChunkSize)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return SSZMixInLength(root, uint64(len(data))), nil
+}
+
+// SSZHashTreeRootUint64List returns the hash tree root of a List[uint64] of
+// at most maxLength values.
+func SSZHashTreeRootUint64List(values []uint64, maxLength uint64) ([32]byte, error) {
+	if uint64(len(values)) > maxLength {
+		return [32]byte{}, errors.New("the list exceeds the maximum length")
+	}
+	root, err := SSZMerkleize(SSZPackUint64s(values), (maxLength*8+SSZChunkSize-1)/SSZChunkSize)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	return SSZMixInLength(root, uint64(len(values))), nil
+}
+
+// sszDepth returns the depth of the tree of limit chunks padded to the next
+// power of two.
+func sszDepth(limit uint64) int {
+	return bits.Len64(limit - 1)
+}
+
+// sszLengthChunk serializes a length as a chunk.
+func sszLengthChunk(length uint64) [32]byte {
+	var chunk [32]byte
+	binary.LittleEndian.PutUint64(chunk[:], length)
+	return chunk
+}
@@ -0,0 +1,148 @@
+package imt_test
+
+import (
+	"encoding/hex"
+	"math/bits"
+	"testing"
+
+	"github.com/noble-assets/imt"
+)
+
+func decodeChunk(t *testing.T, s string) [32]byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != 32 {
+		t.Fatalf("invalid chunk %q", s)
+	}
+	return [32]byte(b)
+}
+
+func TestSSZMerkleize(t *testing.T) {
+	// The zero hashes of the Ethereum consensus specifications, which are the
+	// roots of limits of 2, 4, 8 and 16 zero chunks.
+	zeroHashes := []string{
+		"f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b",
+		"db56114e00fdd4c1f85c892bf35ac9a89289aaecb1ebd0a96cde606a748b5d71",
+		"c78009fdf07fc56a11f122370658a353aaa542ed63e44c4bc15ff4cd105ab33c",
+		"536d98837f2dd165a55d5eeae91485954472d56f246df256bf3cae19352a123c",
+	}
+	for i, want := range zeroHashes {
+		root, err := imt.SSZMerkleize(nil, 2<<i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if root != decodeChunk(t, want) {
+			t.Fatalf("root of %d zero chunks: got %x, want %s", 2<<i, root, want)
+		}
+	}
+
+	// The root of the empty deposit tree of the deposit contract, a list of
+	// at most 2^32 deposits, whose capacity only fits in 64-bit ints.
+	if bits.UintSize == 64 {
+		root, err := imt.SSZMerkleize(nil, 1<<32)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := decodeChunk(t, "d70a234731285c6804c2a4f56711ddb8c82c99740f207854891028af34e27e5e")
+		if got := imt.SSZMixInLength(root, 0); got != want {
+			t.Fatalf("empty deposit root: got %x, want %x", got, want)
+		}
+	}
+
+	a, b, c := [32]byte{1}, [32]byte{2}, [32]byte{3}
+	tests := []struct {
+		name   string
+		chunks [][32]byte
+		limit  uint64
+		want   [32]byte
+	}{
+		{"no chunk", nil, 0, [32]byte{}},
+		{"single chunk", [][32]byte{a}, 0, a},
+		{"single chunk with a limit of 1", [][32]byte{a}, 1, a},
+		{"two chunks", [][32]byte{a, b}, 0, imt.SSZHash([][32]byte{a, b})},
+		{"padded to a power of two", [][32]byte{a, b, c}, 0, imt.SSZHash([][32]byte{imt.SSZHash([][32]byte{a, b}), imt.SSZHash([][32]byte{c, {}})})},
+		{"padded to the limit", [][32]byte{a}, 3, imt.SSZHash([][32]byte{imt.SSZHash([][32]byte{a, {}}), decodeChunk(t, zeroHashes[0])})},
+	}
+	for _, test := range tests {
+		got, err := imt.SSZMerkleize(test.chunks, test.limit)
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if got != test.want {
+			t.Fatalf("%s: got %x, want %x", test.name, got, test.want)
+		}
+	}
+	if _, err := imt.SSZMerkleize([][32]byte{a, b, c}, 2); err == nil {
+		t.Fatal("merkleized more chunks than the limit")
+	}
+}
+
+func TestSSZHashTreeRoots(t *testing.T) {
+	chunk := func(prefix ...byte) [32]byte {
+		var c [32]byte
+		copy(c[:], prefix)
+		return c
+	}
+
+	packed := imt.SSZPackUint64s([]uint64{1, 2, 3, 4, 5})
+	if len(packed) != 2 || packed[0] != chunk(1, 0, 0, 0, 0, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 4) || packed[1] != chunk(5) {
+		t.Fatalf("SSZPackUint64s = %x", packed)
+	}
+
+	// A List[uint64, 8] of 5 values takes 2 chunks, mixed with its length.
+	root, err := imt.SSZHashTreeRootUint64List([]uint64{1, 2, 3, 4, 5}, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := imt.SSZHash([][32]byte{imt.SSZHash(packed), chunk(5)}); root != want {
+		t.Fatalf("List[uint64, 8] root: got %x, want %x", root, want)
+	}
+	if _, err := imt.SSZHashTreeRootUint64List(make([]uint64, 9), 8); err == nil {
+		t.Fatal("a list longer than its maximum length was accepted")
+	}
+
+	// A ByteVector[32] is its own root, and a ByteList mixes in its length.
+	data := []byte("hash tree root of a byte list!!!")
+	if root := imt.SSZHashTreeRootByteVector(data); root != [32]byte(data) {
+		t.Fatalf("ByteVector[32] root: got %x", root)
+	}
+	root, err = imt.SSZHashTreeRootByteList(data[:5], 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := imt.SSZHash([][32]byte{imt.SSZHash([][32]byte{chunk(data[:5]...), {}}), chunk(5)}); root != want {
+		t.Fatalf("ByteList[64] root: got %x, want %x", root, want)
+	}
+}
+
+func TestSSZMixInLengthProof(t *testing.T) {
+	chunks := imt.SSZPackUint64s([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	tree, err := imt.NewSSZTree(chunks, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := imt.SSZHashTreeRootUint64List([]uint64{1, 2, 3, 4, 5, 6, 7, 8, 9}, 32)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for index := range chunks {
+		proof, err := tree.CreateProof(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		extended := imt.SSZMixInLengthProof(proof, 9)
+		if extended.Root != root || !imt.VerifyProof(extended, imt.SSZHash) {
+			t.Fatalf("chunk %d: the extended proof does not match the list root", index)
+		}
+		if imt.SSZMixInLengthProof(proof, 10).Root == root {
+			t.Fatalf("chunk %d: the proof matches another length", index)
+		}
+	}
+
+	if _, err := imt.NewSSZTree(chunks, 1); err == nil {
+		t.Fatal("created a tree with a limit of 1 chunk")
+	}
+	if _, err := imt.NewSSZTree(chunks, 2); err == nil {
+		t.Fatal("created a tree with more chunks than the limit")
+	}
+}
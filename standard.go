@@ -0,0 +1,299 @@
+package imt
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+// StandardMerkleTreeFormat is the format identifier of dumps produced by
+// OpenZeppelin's StandardMerkleTree.
+const StandardMerkleTreeFormat = "standard-v1"
+
+// StandardMerkleTree is a Go port of OpenZeppelin's StandardMerkleTree from
+// the @openzeppelin/merkle-tree package. Unlike IMT, it is a complete binary
+// tree whose leaves are the double Keccak-256 hashes of ABI encoded values and
+// whose pairs are sorted before hashing, which makes its proofs verifiable by
+// OpenZeppelin's MerkleProof Solidity library.
+//
+// The tree is stored as a flat array, where the root is at index 0 and the
+// children of node i are at indices 2i+1 and 2i+2. The leaves occupy the end
+// of the array in reverse order.
+type StandardMerkleTree struct {
+	tree         [][32]byte
+	values       []StandardMerkleTreeValue
+	leafEncoding []string
+}
+
+// StandardMerkleTreeValue is a value of the tree together with the position
+// of its leaf in the flat tree array.
+type StandardMerkleTreeValue struct {
+	Value     []any `json:"value"`     // The values being committed to.
+	TreeIndex int   `json:"treeIndex"` // The index of the leaf in the tree.
+}
+
+// StandardMerkleTreeDump is the JSON representation of a StandardMerkleTree,
+// as produced by `tree.dump()` in JavaScript.
+type StandardMerkleTreeDump struct {
+	Format       string                    `json:"format"`       // Always "standard-v1".
+	LeafEncoding []string                  `json:"leafEncoding"` // The ABI types of each value.
+	Tree         []string                  `json:"tree"`         // The hex encoded tree nodes.
+	Values       []StandardMerkleTreeValue `json:"values"`       // The values and their leaf positions.
+}
+
+// NewStandardMerkleTree builds a tree from a list of values, each of which
+// is a tuple encoded with the ABI types in leafEncoding. As in JavaScript,
+// sortLeaves should be true unless the order of the leaves must be preserved.
+func NewStandardMerkleTree(values [][]any, leafEncoding []string, sortLeaves bool) (*StandardMerkleTree, error) {
+	if len(values) == 0 {
+		return nil, errors.New("expected non-zero number of leaves")
+	}
+
+	type hashedValue struct {
+		index int
+		hash  [32]byte
+	}
+
+	hashed := make([]hashedValue, len(values))
+	for i, value := range values {
+		hash, err := StandardLeafHash(leafEncoding, value)
+		if err != nil {
+			return nil, fmt.Errorf("leaf %d: %w", i, err)
+		}
+		hashed[i] = hashedValue{index: i, hash: hash}
+	}
+
+	if sortLeaves {
+		slices.SortStableFunc(hashed, func(a, b hashedValue) int {
+			return bytes.Compare(a.hash[:], b.hash[:])
+		})
+	}
+
+	leaves := make([][32]byte, len(hashed))
+	for i, h := range hashed {
+		leaves[i] = h.hash
+	}
+	tree := makeStandardTree(leaves)
+
+	indexed := make([]StandardMerkleTreeValue, len(values))
+	for leafIndex, h := range hashed {
+		indexed[h.index] = StandardMerkleTreeValue{
+			Value:     slices.Clone(values[h.index]),
+			TreeIndex: len(tree) - leafIndex - 1,
+		}
+	}
+
+	return &StandardMerkleTree{
+		tree:         tree,
+		values:       indexed,
+		leafEncoding: slices.Clone(leafEncoding),
+	}, nil
+}
+
+// LoadStandardMerkleTree loads a tree from its JSON dump and validates its
+// integrity, so trees built with the JavaScript tooling can be used in Go.
+func LoadStandardMerkleTree(dump StandardMerkleTreeDump) (*StandardMerkleTree, error) {
+	if dump.Format != StandardMerkleTreeFormat {
+		return nil, fmt.Errorf("unknown format %q", dump.Format)
+	}
+
+	tree := make([][32]byte, len(dump.Tree))
+	for i, node := range dump.Tree {
+		b, err := hex.DecodeString(strings.TrimPrefix(node, "0x"))
+		if err != nil || len(b) != 32 {
+			return nil, fmt.Errorf("invalid tree node %d: %q", i, node)
+		}
+		copy(tree[i][:], b)
+	}
+
+	t := &StandardMerkleTree{
+		tree:         tree,
+		values:       slices.Clone(dump.Values),
+		leafEncoding: slices.Clone(dump.LeafEncoding),
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Dump returns the JSON representation of the tree, which can be loaded by
+// `StandardMerkleTree.load` in JavaScript.
+func (t *StandardMerkleTree) Dump() StandardMerkleTreeDump {
+	tree := make([]string, len(t.tree))
+	for i, node := range t.tree {
+		tree[i] = "0x" + hex.EncodeToString(node[:])
+	}
+
+	return StandardMerkleTreeDump{
+		Format:       StandardMerkleTreeFormat,
+		LeafEncoding: slices.Clone(t.leafEncoding),
+		Tree:         tree,
+		Values:       slices.Clone(t.values),
+	}
+}
+
+// Extend returns a new tree containing the values of this tree followed by
+// the given values. The original tree is left untouched, since adding leaves
+// changes the shape of the tree and, when sorting, the position of every leaf.
+func (t *StandardMerkleTree) Extend(values [][]any, sortLeaves bool) (*StandardMerkleTree, error) {
+	all := make([][]any, 0, len(t.values)+len(values))
+	for _, v := range t.values {
+		all = append(all, v.Value)
+	}
+	all = append(all, values...)
+
+	return NewStandardMerkleTree(all, t.leafEncoding, sortLeaves)
+}
+
+// Root returns the root of the tree.
+func (t *StandardMerkleTree) Root() [32]byte {
+	return t.tree[0]
+}
+
+// LeafEncoding returns the ABI types used to encode the values.
+func (t *StandardMerkleTree) LeafEncoding() []string {
+	return slices.Clone(t.leafEncoding)
+}
+
+// Len returns the number of values in the tree.
+func (t *StandardMerkleTree) Len() int {
+	return len(t.values)
+}
+
+// Value returns the value at the given index, in insertion order.
+func (t *StandardMerkleTree) Value(index int) ([]any, error) {
+	if index < 0 || index >= len(t.values) {
+		return nil, errors.New("index out of bounds")
+	}
+	return slices.Clone(t.values[index].Value), nil
+}
+
+// IndexOf returns the index of the first value whose leaf hash is equal to
+// the leaf hash of the given value. If the value does not exist it returns -1.
+func (t *StandardMerkleTree) IndexOf(value []any) (int, error) {
+	hash, err := StandardLeafHash(t.leafEncoding, value)
+	if err != nil {
+		return -1, err
+	}
+	for i, v := range t.values {
+		if t.tree[v.TreeIndex] == hash {
+			return i, nil
+		}
+	}
+	return -1, nil
+}
+
+// CreateProof returns the proof of the value at the given index, in insertion
+// order. The proof is a list of sibling hashes from the leaf up to the root.
+func (t *StandardMerkleTree) CreateProof(index int) ([][32]byte, error) {
+	if index < 0 || index >= len(t.values) {
+		return nil, errors.New("index out of bounds")
+	}
+
+	treeIndex := t.values[index].TreeIndex
+	var proof [][32]byte
+	for treeIndex > 0 {
+		sibling := treeIndex - 1
+		if treeIndex%2 == 1 {
+			sibling = treeIndex + 1
+		}
+		proof = append(proof, t.tree[sibling])
+		treeIndex = (treeIndex - 1) / 2
+	}
+
+	return proof, nil
+}
+
+// VerifyProof verifies a proof of a value against the root of this tree.
+func (t *StandardMerkleTree) VerifyProof(value []any, proof [][32]byte) (bool, error) {
+	return VerifyStandardProof(t.Root(), t.leafEncoding, value, proof)
+}
+
+// Validate checks that every internal node is the hash of its children and
+// that every value hashes to the leaf it is mapped to.
+func (t *StandardMerkleTree) Validate() error {
+	if len(t.tree) == 0 {
+		return errors.New("expected non-zero number of nodes")
+	}
+	for i := len(t.tree) - 1; i >= 0; i-- {
+		left, right := 2*i+1, 2*i+2
+		if left >= len(t.tree) {
+			continue
+		}
+		if right >= len(t.tree) {
+			return errors.New("merkle tree is invalid")
+		}
+		if t.tree[i] != standardHashPair(t.tree[left], t.tree[right]) {
+			return fmt.Errorf("merkle tree is invalid at node %d", i)
+		}
+	}
+
+	firstLeaf := len(t.tree) / 2
+	for i, v := range t.values {
+		if v.TreeIndex < firstLeaf || v.TreeIndex >= len(t.tree) {
+			return fmt.Errorf("value %d points to a non-leaf node", i)
+		}
+		hash, err := StandardLeafHash(t.leafEncoding, v.Value)
+		if err != nil {
+			return fmt.Errorf("value %d: %w", i, err)
+		}
+		if t.tree[v.TreeIndex] != hash {
+			return fmt.Errorf("value %d does not match its leaf", i)
+		}
+	}
+
+	return nil
+}
+
+// StandardLeafHash returns the leaf hash of a value, which is the Keccak-256
+// hash of the Keccak-256 hash of its ABI encoding.
+func StandardLeafHash(leafEncoding []string, value []any) ([32]byte, error) {
+	encoded, err := abiEncode(leafEncoding, value)
+	if err != nil {
+		return [32]byte{}, err
+	}
+	inner := keccak.Sum256(encoded)
+	return keccak.Sum256(inner[:]), nil
+}
+
+// VerifyStandardProof verifies a proof of a value against a root. It is
+// equivalent to `StandardMerkleTree.verify` in JavaScript and to
+// `MerkleProof.verify` in Solidity.
+func VerifyStandardProof(root [32]byte, leafEncoding []string, value []any, proof [][32]byte) (bool, error) {
+	node, err := StandardLeafHash(leafEncoding, value)
+	if err != nil {
+		return false, err
+	}
+	for _, sibling := range proof {
+		node = standardHashPair(node, sibling)
+	}
+	return node == root, nil
+}
+
+// makeStandardTree builds the flat tree array from a list of leaf hashes.
+func makeStandardTree(leaves [][32]byte) [][32]byte {
+	tree := make([][32]byte, 2*len(leaves)-1)
+	for i, leaf := range leaves {
+		tree[len(tree)-1-i] = leaf
+	}
+	for i := len(tree) - 1 - len(leaves); i >= 0; i-- {
+		tree[i] = standardHashPair(tree[2*i+1], tree[2*i+2])
+	}
+	return tree
+}
+
+// standardHashPair hashes two nodes after sorting them, so that proofs don't
+// need to carry path indices.
+func standardHashPair(a, b [32]byte) [32]byte {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return keccak.Sum256(a[:], b[:])
+}
@@ -0,0 +1,129 @@
+package imt_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/internal/keccak"
+)
+
+// The example of the README of @openzeppelin/merkle-tree.
+var standardValues = [][]any{
+	{"0x1111111111111111111111111111111111111111", "5000000000000000000"},
+	{"0x2222222222222222222222222222222222222222", "2500000000000000000"},
+}
+
+const standardRoot = "d4dee0beab2d53f2cc83e567171bd2820e49898130a22622b10ead383e90bd77"
+
+func TestStandardMerkleTree(t *testing.T) {
+	tree, err := imt.NewStandardMerkleTree(standardValues, []string{"address", "uint256"}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root := tree.Root(); hex.EncodeToString(root[:]) != standardRoot {
+		t.Fatalf("Root = %x, want %s", root, standardRoot)
+	}
+	for i, value := range standardValues {
+		proof, err := tree.CreateProof(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok, err := tree.VerifyProof(value, proof); err != nil || !ok {
+			t.Fatalf("value %d: valid proof rejected: %v", i, err)
+		}
+		other := standardValues[1-i]
+		if ok, _ := tree.VerifyProof(other, proof); ok {
+			t.Fatalf("value %d: proof accepted for another value", i)
+		}
+		if index, err := tree.IndexOf(value); err != nil || index != i {
+			t.Fatalf("IndexOf(value %d) = %d, %v", i, index, err)
+		}
+	}
+
+	// A dump in JSON, as decoded from the JavaScript tooling, loads into the
+	// same tree.
+	encoded, err := json.Marshal(tree.Dump())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dump imt.StandardMerkleTreeDump
+	decoder := json.NewDecoder(strings.NewReader(string(encoded)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&dump); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := imt.LoadStandardMerkleTree(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Root() != tree.Root() || loaded.Len() != tree.Len() {
+		t.Fatal("the loaded tree differs from the dumped one")
+	}
+
+	dump.Tree[len(dump.Tree)-1] = "0x" + strings.Repeat("00", 32)
+	if _, err := imt.LoadStandardMerkleTree(dump); err == nil {
+		t.Fatal("a dump with an altered leaf was loaded")
+	}
+}
+
+func TestStandardLeafHash(t *testing.T) {
+	word := func(s string) string { return strings.Repeat("0", 64-len(s)) + s }
+	tests := []struct {
+		name     string
+		encoding []string
+		value    []any
+		abi      string // The hex ABI encoding of the value.
+	}{
+		{"address and uint256", []string{"address", "uint256"}, standardValues[0],
+			word("1111111111111111111111111111111111111111") + word("4563918244f40000")},
+		{"bool and negative int", []string{"bool", "int8"}, []any{true, -1},
+			word("1") + strings.Repeat("f", 64)},
+		{"bytes4", []string{"bytes4"}, []any{"0xa9059cbb"},
+			"a9059cbb" + strings.Repeat("0", 56)},
+		{"dynamic string", []string{"uint256", "string"}, []any{1, "Hello, world!"},
+			word("1") + word("40") + word("d") + hex.EncodeToString([]byte("Hello, world!")) + strings.Repeat("0", 38)},
+		{"dynamic bytes", []string{"bytes", "bytes"}, []any{"0x", "0x0102"},
+			word("40") + word("60") + word("0") + word("2") + "0102" + strings.Repeat("0", 60)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			abi, err := hex.DecodeString(test.abi)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inner := keccak.Sum256(abi)
+			want := keccak.Sum256(inner[:])
+			got, err := imt.StandardLeafHash(test.encoding, test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != want {
+				t.Fatalf("StandardLeafHash = %x, want %x", got, want)
+			}
+		})
+	}
+
+	invalid := []struct {
+		name     string
+		encoding []string
+		value    []any
+	}{
+		{"missing value", []string{"address", "uint256"}, standardValues[0][:1]},
+		{"short address", []string{"address"}, []any{"0x1111"}},
+		{"address without prefix", []string{"address"}, []any{"1111111111111111111111111111111111111111"}},
+		{"uint8 overflow", []string{"uint8"}, []any{256}},
+		{"negative uint", []string{"uint256"}, []any{-1}},
+		{"int8 overflow", []string{"int8"}, []any{128}},
+		{"bool as string", []string{"bool"}, []any{"true"}},
+		{"unsupported type", []string{"uint256[]"}, []any{1}},
+		{"invalid size", []string{"uint7"}, []any{1}},
+	}
+	for _, test := range invalid {
+		if _, err := imt.StandardLeafHash(test.encoding, test.value); err == nil {
+			t.Errorf("%s: value accepted", test.name)
+		}
+	}
+}
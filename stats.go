@@ -0,0 +1,105 @@
+package imt
+
+// Stats reports the memory usage and the hashing work of a tree.
+type Stats struct {
+	// The number of nodes of each level, from the leaves to the root,
+	// including the nodes that are computed on demand in leaves-only mode.
+	Nodes []int
+	// The number of nodes held in memory, including cached nodes.
+	StoredNodes int
+	// The estimated number of bytes used by the nodes, given the size of a
+	// node. It does not include the overhead of the Go runtime.
+	Bytes uint64
+	// The number of distinct node values, if interning is enabled.
+	DistinctNodes int
+	// The number of hits and misses of the node cache, if the tree has one.
+	CacheHits   uint64
+	CacheMisses uint64
+	// The number of proofs held by the proof cache, and its hits and
+	// misses, if the tree has one.
+	CachedProofs     int
+	ProofCacheHits   uint64
+	ProofCacheMisses uint64
+	// The number of hashes held by the memo of the hash function, and its
+	// hits and misses, if the tree has one.
+	MemoizedHashes int
+	MemoHits       uint64
+	MemoMisses     uint64
+	// The number of calls to the hash function since the tree was created,
+	// and the operation of the tree that completed last with its number of
+	// calls. Hashes served by the memo are not calls.
+	HashCalls       uint64
+	LastOp          string
+	LastOpHashCalls uint64
+	// The number of leaves divided by the capacity of the tree.
+	FillRatio float64
+}
+
+// CacheHitRate returns the fraction of the cache lookups that were hits, or 0
+// if the cache was never used.
+func (s Stats) CacheHitRate() float64 {
+	if s.CacheHits+s.CacheMisses == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(s.CacheHits+s.CacheMisses)
+}
+
+// MemoHitRate returns the fraction of the lookups of the memo of the hash
+// function that were hits, or 0 if the memo was never used.
+func (s Stats) MemoHitRate() float64 {
+	if s.MemoHits+s.MemoMisses == 0 {
+		return 0
+	}
+	return float64(s.MemoHits) / float64(s.MemoHits+s.MemoMisses)
+}
+
+// Stats returns the memory usage of the tree, where nodeSize is the size of a
+// node in bytes, e.g. 32 for [32]byte nodes.
+func (t *IMT[N]) Stats(nodeSize int) Stats {
+	stats := Stats{
+		Nodes:     make([]int, t.depth+1),
+		HashCalls: t.hashCalls.Load(),
+		FillRatio: float64(t.nodes[0].Len()) / float64(t.Capacity()),
+	}
+	if last := t.lastOp.Load(); last != nil {
+		stats.LastOp, stats.LastOpHashCalls = last.op, last.calls
+	}
+
+	for level, nodes := range t.nodes {
+		stats.Nodes[level] = nodes.Len()
+		switch nodes := nodes.(type) {
+		case *sliceLevel[N]:
+			stats.StoredNodes += nodes.Len()
+			stats.Bytes += uint64(cap(nodes.nodes)) * uint64(nodeSize)
+		case *chunkedLevel[N]:
+			stats.StoredNodes += nodes.Len()
+			for _, chunk := range nodes.chunks {
+				stats.Bytes += uint64(cap(chunk)) * uint64(nodeSize)
+			}
+		case *internedLevel[N]:
+			stats.Bytes += uint64(cap(nodes.handles)) * 4
+		}
+	}
+
+	if t.interner != nil {
+		stats.DistinctNodes = t.interner.distinct()
+		stats.StoredNodes += stats.DistinctNodes
+		stats.Bytes += uint64(stats.DistinctNodes) * uint64(nodeSize)
+	}
+	if t.cache != nil {
+		stats.StoredNodes += t.cache.order.Len()
+		stats.Bytes += uint64(t.cache.order.Len()) * uint64(nodeSize)
+		stats.CacheHits = t.cache.hits
+		stats.CacheMisses = t.cache.misses
+	}
+	if t.memo != nil {
+		stats.MemoizedHashes, stats.MemoHits, stats.MemoMisses = t.memo.stats()
+	}
+	if t.proofs != nil {
+		stats.CachedProofs = t.proofs.order.Len()
+		stats.ProofCacheHits = t.proofs.hits
+		stats.ProofCacheMisses = t.proofs.misses
+	}
+
+	return stats
+}
@@ -0,0 +1,215 @@
+package imt
+
+import "slices"
+
+// levelStore stores the nodes of a level of the tree. The tree reads and
+// writes its nodes through this interface, so that they can be kept in
+// different representations depending on the options of the tree.
+type levelStore[N comparable] interface {
+	// Len returns the number of nodes of the level.
+	Len() int
+	// Get returns the node at the given index, which must be less than Len.
+	Get(index int) N
+	// Set replaces the node at the given index, which must be less than Len.
+	Set(index int, node N)
+	// Append adds a node at the end of the level.
+	Append(node N)
+	// Grow makes room for n more nodes, so that appending them doesn't
+	// grow the level again.
+	Grow(n int)
+	// Truncate removes the nodes from the given index, which must not be
+	// greater than Len.
+	Truncate(length int)
+}
+
+// GrowthPolicy selects how the levels of a tree grow when leaves are added.
+type GrowthPolicy int
+
+const (
+	// GrowChunked stores each level in chunks of levelChunkSize nodes and
+	// allocates a new chunk when the last one is full, so growing never
+	// copies the level. It is the default policy.
+	GrowChunked GrowthPolicy = iota
+	// GrowDoubling stores each level in a single slice whose capacity
+	// doubles when it is full, which makes reads slightly faster than with
+	// chunks at the cost of copying the level when it grows.
+	GrowDoubling
+	// GrowExact stores each level in a single slice grown to the exact
+	// number of nodes needed, which wastes no memory but copies the level
+	// every time it grows. It suits trees that are built once or grown in
+	// large batches with InsertMany.
+	GrowExact
+)
+
+// String returns the name of the policy.
+func (p GrowthPolicy) String() string {
+	switch p {
+	case GrowDoubling:
+		return "doubling"
+	case GrowExact:
+		return "exact"
+	default:
+		return "chunked"
+	}
+}
+
+// WithGrowthPolicy sets how the levels of the tree grow. It has no effect on
+// trees with interning, whose levels hold handles, or in leaves-only mode for
+// the levels that are not stored.
+func WithGrowthPolicy(policy GrowthPolicy) Option {
+	return func(o *options) {
+		o.growth = policy
+	}
+}
+
+// sliceLevel is a levelStore that keeps the nodes in a single slice, used for
+// the levels carved out of an arena and by the GrowDoubling and GrowExact
+// policies.
+type sliceLevel[N comparable] struct {
+	nodes []N
+	exact bool
+}
+
+func (l *sliceLevel[N]) Len() int              { return len(l.nodes) }
+func (l *sliceLevel[N]) Get(index int) N       { return l.nodes[index] }
+func (l *sliceLevel[N]) Set(index int, node N) { l.nodes[index] = node }
+func (l *sliceLevel[N]) Truncate(length int)   { l.nodes = l.nodes[:length] }
+
+func (l *sliceLevel[N]) Append(node N) {
+	l.Grow(1)
+	l.nodes = append(l.nodes, node)
+}
+
+func (l *sliceLevel[N]) Grow(n int) {
+	length := len(l.nodes) + n
+	if length <= cap(l.nodes) {
+		return
+	}
+	if !l.exact {
+		length = max(length, 2*cap(l.nodes))
+	}
+	nodes := make([]N, len(l.nodes), length)
+	copy(nodes, l.nodes)
+	l.nodes = nodes
+}
+
+// newLevel returns an empty level with room for the given number of nodes,
+// using the representation selected by the options of the tree.
+func (t *IMT[N]) newLevel(capacity int) levelStore[N] {
+	if t.interner != nil {
+		return &internedLevel[N]{interner: t.interner, handles: make([]uint32, 0, capacity)}
+	}
+	if t.arena != nil && capacity <= cap(t.arena)-len(t.arena) {
+		start := len(t.arena)
+		t.arena = t.arena[:start+capacity]
+		return &sliceLevel[N]{nodes: t.arena[start : start : start+capacity]}
+	}
+	switch t.options.growth {
+	case GrowDoubling, GrowExact:
+		return &sliceLevel[N]{nodes: make([]N, 0, capacity), exact: t.options.growth == GrowExact}
+	default:
+		return newChunkedLevel[N](capacity)
+	}
+}
+
+// newFilledLevel returns a level of the given length, whose nodes are written
+// with Set, using the representation selected by the growth policy of the
+// tree.
+func (t *IMT[N]) newFilledLevel(length int) levelStore[N] {
+	switch t.options.growth {
+	case GrowDoubling, GrowExact:
+		return &sliceLevel[N]{nodes: make([]N, length), exact: t.options.growth == GrowExact}
+	default:
+		return newFilledChunkedLevel[N](length)
+	}
+}
+
+// levelChunkSize is the number of nodes of each chunk of a chunkedLevel.
+const levelChunkSize = 1 << 14
+
+// chunkedLevel is the default levelStore, which keeps the nodes in chunks of
+// levelChunkSize nodes. Growing the level allocates a new chunk instead of
+// copying the whole level to a larger slice, which would take a long time and
+// temporarily double the memory of the level when it is large. Only the
+// first chunk grows like a slice, so that small levels stay small.
+type chunkedLevel[N comparable] struct {
+	chunks [][]N
+	length int
+}
+
+func newChunkedLevel[N comparable](capacity int) *chunkedLevel[N] {
+	l := &chunkedLevel[N]{}
+	if capacity > 0 {
+		l.chunks = [][]N{make([]N, 0, min(capacity, levelChunkSize))}
+	}
+	return l
+}
+
+// newFilledChunkedLevel returns a chunkedLevel of the given length, whose
+// nodes are written with Set.
+func newFilledChunkedLevel[N comparable](length int) *chunkedLevel[N] {
+	l := &chunkedLevel[N]{length: length}
+	if length <= levelChunkSize {
+		l.chunks = [][]N{make([]N, length)}
+		return l
+	}
+	for start := 0; start < length; start += levelChunkSize {
+		l.chunks = append(l.chunks, make([]N, min(levelChunkSize, length-start), levelChunkSize))
+	}
+	return l
+}
+
+func (l *chunkedLevel[N]) Len() int { return l.length }
+
+func (l *chunkedLevel[N]) Get(index int) N {
+	return l.chunks[index/levelChunkSize][index%levelChunkSize]
+}
+
+func (l *chunkedLevel[N]) Set(index int, node N) {
+	l.chunks[index/levelChunkSize][index%levelChunkSize] = node
+}
+
+func (l *chunkedLevel[N]) Append(node N) {
+	switch {
+	case len(l.chunks) == 0:
+		l.chunks = append(l.chunks, make([]N, 0, 1))
+	case len(l.chunks[len(l.chunks)-1]) == levelChunkSize:
+		l.chunks = append(l.chunks, make([]N, 0, levelChunkSize))
+	}
+	last := len(l.chunks) - 1
+	l.chunks[last] = append(l.chunks[last], node)
+	l.length++
+}
+
+// Grow only grows the first chunk, since the other ones are allocated full.
+func (l *chunkedLevel[N]) Grow(n int) {
+	if len(l.chunks) <= 1 && l.length+n <= levelChunkSize {
+		if len(l.chunks) == 0 {
+			l.chunks = [][]N{nil}
+		}
+		l.chunks[0] = slices.Grow(l.chunks[0], n)
+	}
+}
+
+func (l *chunkedLevel[N]) Truncate(length int) {
+	if length == 0 {
+		l.chunks, l.length = l.chunks[:min(len(l.chunks), 1)], 0
+		if len(l.chunks) == 1 {
+			l.chunks[0] = l.chunks[0][:0]
+		}
+		return
+	}
+	last := (length - 1) / levelChunkSize
+	l.chunks = l.chunks[:last+1]
+	l.chunks[last] = l.chunks[last][:length-last*levelChunkSize]
+	l.length = length
+}
+
+// levelNodes returns a copy of the nodes of a level between start and end.
+func levelNodes[N comparable](l levelStore[N], start, end int) []N {
+	nodes := make([]N, end-start)
+	for i := range nodes {
+		nodes[i] = l.Get(start + i)
+	}
+	return nodes
+}
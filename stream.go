@@ -0,0 +1,142 @@
+package imt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"iter"
+)
+
+// AllProofs returns an iterator over the proofs of every leaf of the tree, in
+// order. The tree is traversed once: the children of each internal node are
+// read when the iterator enters the node and reused for all the leaves below
+// it, instead of being read again for every leaf as with CreateProof. The tree
+// must not be modified during the iteration.
+func (t *IMT[N]) AllProofs() iter.Seq2[int, *MerkleProof[N]] {
+	return func(yield func(int, *MerkleProof[N]) bool) {
+		root := t.Root()
+		groups := make([][]N, t.depth)
+		starts := make([]int, t.depth)
+		for level := range starts {
+			starts[level] = -1
+		}
+
+		for leafIndex := range t.nodes[0].Len() {
+			proof := &MerkleProof[N]{
+				Root:        root,
+				LeafIndex:   leafIndex,
+				Siblings:    make([][]N, t.depth),
+				PathIndices: make([]int, t.depth),
+				Depth:       t.depth,
+				Arity:       t.arity,
+				HashID:      t.options.hashID,
+			}
+
+			index := leafIndex
+			for level := 0; level < t.depth; level++ {
+				position := index % t.arity
+				if start := index - position; start != starts[level] {
+					groups[level] = t.children(level, index)
+					starts[level] = start
+				}
+				if level == 0 {
+					proof.Leaf = groups[0][position]
+				}
+				siblings := make([]N, 0, t.arity-1)
+				siblings = append(siblings, groups[level][:position]...)
+				proof.Siblings[level] = append(siblings, groups[level][position+1:]...)
+				proof.PathIndices[level] = position
+				index = index / t.arity
+			}
+
+			if !yield(leafIndex, proof) {
+				return
+			}
+		}
+	}
+}
+
+// StreamAllProofs writes the proofs of every leaf of the tree to w, in order,
+// each encoded with the codec and prefixed with its length as an unsigned
+// varint. The proofs can be read back with ReadProofs.
+func (t *IMT[N]) StreamAllProofs(w io.Writer, codec ProofValueCodec[N]) (err error) {
+	span := t.startSpan(OpStreamProofs, t.nodes[0].Len())
+	defer func() {
+		span.End(err)
+	}()
+	profile(OpStreamProofs, func() {
+		bw := bufio.NewWriter(w)
+		for _, proof := range t.AllProofs() {
+			var b []byte
+			if b, err = codec.Encode(*proof); err != nil {
+				return
+			}
+			if err = writeRecord(bw, b); err != nil {
+				return
+			}
+		}
+		err = bw.Flush()
+	})
+	return err
+}
+
+// maxStreamedProofSize bounds the size of a proof read by ReadProofs, so that
+// a corrupted length prefix cannot trigger a huge allocation.
+const maxStreamedProofSize = 1 << 20
+
+// ReadProofs returns an iterator over the proofs written by StreamAllProofs.
+// The iteration stops at the end of the stream or after yielding an error.
+func ReadProofs[N comparable](r io.Reader, codec ProofValueCodec[N]) iter.Seq2[*MerkleProof[N], error] {
+	return func(yield func(*MerkleProof[N], error) bool) {
+		br := bufio.NewReader(r)
+		for {
+			b, err := readRecord(br, maxStreamedProofSize)
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			proof, err := codec.Decode(b)
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(&proof, nil) {
+				return
+			}
+		}
+	}
+}
+
+// writeRecord writes a record prefixed with its length as an unsigned varint.
+func writeRecord(w *bufio.Writer, b []byte) error {
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(b)))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// readRecord reads a record written by writeRecord, which cannot be larger
+// than maxSize. It returns io.EOF only if the stream ends before the record.
+func readRecord(r *bufio.Reader, maxSize uint64) ([]byte, error) {
+	length, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxSize {
+		return nil, errors.New("record is too large")
+	}
+	b := make([]byte, length)
+	if _, err := io.ReadFull(r, b); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return b, nil
+}
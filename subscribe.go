@@ -0,0 +1,74 @@
+package imt
+
+import "sync"
+
+// RootUpdate describes a change of the root of a tree.
+type RootUpdate[N comparable] struct {
+	Version uint64 // The version of the tree after the change.
+	OldRoot N      // The root before the change.
+	NewRoot N      // The root after the change.
+	OpCount int    // The number of leaf writes of the change.
+}
+
+// rootSubscribers holds the channels of the subscribers of a tree. It is
+// guarded by a mutex because subscriptions can be cancelled from any
+// goroutine.
+type rootSubscribers[N comparable] struct {
+	mu       sync.Mutex
+	next     int
+	channels map[int]chan RootUpdate[N]
+}
+
+// SubscribeRoots returns a channel receiving a RootUpdate after every
+// operation that changes the leaves of the tree, and a function cancelling
+// the subscription and closing the channel. Updates are sent without
+// blocking the tree: when the buffer of the channel is full, the update is
+// dropped, which subscribers can detect as a gap between the version of an
+// update and the version plus the operation count of the previous one.
+func (t *IMT[N]) SubscribeRoots(buffer int) (<-chan RootUpdate[N], func()) {
+	s := &t.subscribers
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.channels == nil {
+		s.channels = make(map[int]chan RootUpdate[N])
+	}
+	id := s.next
+	s.next++
+	ch := make(chan RootUpdate[N], max(buffer, 0))
+	s.channels[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			delete(s.channels, id)
+			close(ch)
+		})
+	}
+}
+
+// notifyRoots sends a RootUpdate to the subscribers after an operation that
+// wrote count leaves.
+func (t *IMT[N]) notifyRoots(oldRoot N, count int) {
+	s := &t.subscribers
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.channels) == 0 {
+		return
+	}
+	update := RootUpdate[N]{
+		Version: t.version,
+		OldRoot: oldRoot,
+		NewRoot: t.Root(),
+		OpCount: count,
+	}
+	for _, ch := range s.channels {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package imt
+
+// CreateSubtreeProof creates a MerkleProof showing that an internal node, the
+// root of a subtree, is committed under the root of the tree. The leaf of the
+// proof is the node itself, its leaf index is the index of the node within its
+// level, and its siblings go from the level of the node up to the root. Level
+// 0 is the level of the leaves, so CreateSubtreeProof(0, i) is equivalent to
+// CreateProof(i).
+func (t *IMT[N]) CreateSubtreeProof(level, index int) (*MerkleProof[N], error) {
+	if level < 0 || level >= t.depth {
+		return nil, t.opError(OpCreateSubtreeProof, level, -1, ErrLevelNotFound)
+	}
+	if index < 0 || index >= t.nodes[level].Len() {
+		return nil, t.opError(OpCreateSubtreeProof, level, index, ErrNodeNotFound)
+	}
+
+	return t.createProof(level, index), nil
+}
+
+// VerifySubtreeProof verifies a proof created by CreateSubtreeProof for a node
+// at the given level of a tree of the given depth. Checking the number of
+// levels of the proof ensures that the node is not presented at another level.
+func VerifySubtreeProof[N comparable](proof *MerkleProof[N], depth, level int, hash HashFunction[N]) bool {
+	if proof == nil || level < 0 || len(proof.Siblings) != depth-level {
+		return false
+	}
+	return VerifyProof(proof, hash)
+}
@@ -0,0 +1,330 @@
+package imt
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+// SyncManifest advertises a tree to the nodes syncing it from a peer: its
+// parameters, its root, and the roots of its chunks, the subtrees whose roots
+// are at ChunkLevel, from left to right. A chunk holds arity^ChunkLevel
+// leaves, except for the last one.
+type SyncManifest[N comparable] struct {
+	Metadata
+	Root       N   `json:"root"`
+	ChunkLevel int `json:"chunkLevel"`
+	ChunkRoots []N `json:"chunkRoots"`
+}
+
+// CreateSyncManifest returns the manifest of the tree with chunks whose roots
+// are at the given level, which must be at most the depth of the tree.
+func (t *IMT[N]) CreateSyncManifest(chunkLevel int) (*SyncManifest[N], error) {
+	if chunkLevel < 0 || chunkLevel > t.depth {
+		return nil, t.opError(OpCreateSyncManifest, chunkLevel, -1, ErrLevelNotFound)
+	}
+	return &SyncManifest[N]{
+		Metadata:   t.Metadata(),
+		Root:       t.Root(),
+		ChunkLevel: chunkLevel,
+		ChunkRoots: levelNodes(t.nodes[chunkLevel], 0, syncChunks(t.nodes[0].Len(), t.arity, chunkLevel)),
+	}, nil
+}
+
+// SyncChunk returns the leaves of a chunk of the manifest of the tree with
+// chunks at the given level.
+func (t *IMT[N]) SyncChunk(chunkLevel, index int) ([]N, error) {
+	if chunkLevel < 0 || chunkLevel > t.depth {
+		return nil, t.opError(OpSyncChunk, chunkLevel, -1, ErrLevelNotFound)
+	}
+	if index < 0 || index >= syncChunks(t.nodes[0].Len(), t.arity, chunkLevel) {
+		return nil, t.opError(OpSyncChunk, chunkLevel, index, ErrNodeNotFound)
+	}
+	span := syncSpan(t.arity, chunkLevel)
+	start := index * span
+	return levelNodes(t.nodes[0], start, min(start+span, t.nodes[0].Len())), nil
+}
+
+// SyncPeer serves the chunks of a tree, e.g. over the network from a node
+// calling SyncChunk.
+type SyncPeer[N comparable] interface {
+	FetchChunk(ctx context.Context, chunkLevel, index int) ([]N, error)
+}
+
+// SyncPeerFunc adapts a function to the SyncPeer interface.
+type SyncPeerFunc[N comparable] func(ctx context.Context, chunkLevel, index int) ([]N, error)
+
+// FetchChunk calls f.
+func (f SyncPeerFunc[N]) FetchChunk(ctx context.Context, chunkLevel, index int) ([]N, error) {
+	return f(ctx, chunkLevel, index)
+}
+
+// Syncer downloads a tree from its peers in verified chunks, so a new proof
+// server doesn't need to replay the history of the tree. The roots of the
+// chunks of the manifest are checked against its root first, and the leaves
+// of each chunk are checked against the root of the chunk before they are
+// accepted, so chunks can be fetched in any order, from any peer, and a chunk
+// from a faulty peer is rejected on its own. Progress is kept across calls to
+// Run, and can be saved with Save and resumed with ResumeSyncer after a
+// restart.
+//
+// The manifest is checked against its own root, which the caller must trust,
+// e.g. because it is the root of a signed checkpoint. Deleted leaves are
+// synced as zero values, and the version of the tree, its leaf data and its
+// insertion records are not synced.
+type Syncer[N comparable] struct {
+	hash      HashFunction[N]
+	zeroValue N
+	opts      []Option
+	manifest  SyncManifest[N]
+
+	// The zero values of the levels up to the root, the number of leaves of
+	// a chunk, and the leaves of the verified chunks.
+	zeroes []N
+	span   int
+	chunks map[int][]N
+}
+
+// NewSyncer returns a Syncer downloading the tree of a manifest, which must
+// be consistent with its root, and building it with the given hash function,
+// zero value and options. The depth and the arity of the manifest must be at
+// most 64 and 65536, and the capacity of the tree must fit in an int, see
+// ValidateConfig.
+func NewSyncer[N comparable](hash HashFunction[N], zeroValue N, manifest *SyncManifest[N], opts ...Option) (*Syncer[N], error) {
+	if manifest == nil {
+		return nil, errors.New("manifest is nil")
+	}
+	// The manifest comes from a peer, so its parameters are checked before
+	// anything is allocated for them.
+	if err := validatePeerMetadata(manifest.Metadata, hash); err != nil {
+		return nil, err
+	}
+	if manifest.ChunkLevel < 0 || manifest.ChunkLevel > manifest.Depth {
+		return nil, errors.New("the chunk level must be between 0 and the depth")
+	}
+	empty, err := New(hash, manifest.Depth, zeroValue, manifest.Arity, nil, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Syncer[N]{
+		hash:      hash,
+		zeroValue: zeroValue,
+		opts:      opts,
+		manifest:  *manifest,
+		zeroes:    append(empty.Zeroes(), empty.Root()),
+		span:      syncSpan(manifest.Arity, manifest.ChunkLevel),
+		chunks:    make(map[int][]N),
+	}
+	s.manifest.ChunkRoots = slices.Clone(manifest.ChunkRoots)
+	if chunks := syncChunks(manifest.Size, manifest.Arity, manifest.ChunkLevel); len(manifest.ChunkRoots) != chunks {
+		return nil, fmt.Errorf("expected %d chunk roots, got %d", chunks, len(manifest.ChunkRoots))
+	}
+	if root, ok := s.hashUp(manifest.ChunkRoots, manifest.ChunkLevel, manifest.Depth); !ok || root != manifest.Root {
+		return nil, errors.New("the chunk roots do not match the root of the manifest")
+	}
+	return s, nil
+}
+
+// Manifest returns the manifest of the synced tree.
+func (s *Syncer[N]) Manifest() SyncManifest[N] {
+	manifest := s.manifest
+	manifest.ChunkRoots = slices.Clone(s.manifest.ChunkRoots)
+	return manifest
+}
+
+// Missing returns the indices of the chunks that have not been verified yet,
+// in increasing order.
+func (s *Syncer[N]) Missing() []int {
+	var missing []int
+	for index := range s.manifest.ChunkRoots {
+		if _, ok := s.chunks[index]; !ok {
+			missing = append(missing, index)
+		}
+	}
+	return missing
+}
+
+// Done reports whether all the chunks have been verified.
+func (s *Syncer[N]) Done() bool {
+	return len(s.chunks) == len(s.manifest.ChunkRoots)
+}
+
+// Add verifies the leaves of a chunk against its root and keeps them.
+func (s *Syncer[N]) Add(index int, leaves []N) error {
+	if index < 0 || index >= len(s.manifest.ChunkRoots) {
+		return fmt.Errorf("chunk %d does not exist", index)
+	}
+	start := index * s.span
+	if len(leaves) != min(s.span, s.manifest.Size-start) {
+		return fmt.Errorf("chunk %d has an unexpected number of leaves", index)
+	}
+	if root, ok := s.hashUp(leaves, 0, s.manifest.ChunkLevel); !ok || root != s.manifest.ChunkRoots[index] {
+		return fmt.Errorf("the leaves of chunk %d do not match its root", index)
+	}
+	s.chunks[index] = slices.Clone(leaves)
+	return nil
+}
+
+// Run fetches the missing chunks from a peer and adds them, until all of them
+// are verified or an error occurs. The chunks verified before an error are
+// kept, so Run can be called again, e.g. with another peer, to resume.
+func (s *Syncer[N]) Run(ctx context.Context, peer SyncPeer[N]) error {
+	for _, index := range s.Missing() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		leaves, err := peer.FetchChunk(ctx, s.manifest.ChunkLevel, index)
+		if err != nil {
+			return fmt.Errorf("failed to fetch chunk %d: %w", index, err)
+		}
+		if err := s.Add(index, leaves); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Finish builds the tree from the verified chunks and checks its root.
+func (s *Syncer[N]) Finish() (*IMT[N], error) {
+	if !s.Done() {
+		return nil, fmt.Errorf("%d chunks are missing", len(s.manifest.ChunkRoots)-len(s.chunks))
+	}
+	leaves := make([]N, 0, s.manifest.Size)
+	for index := range s.manifest.ChunkRoots {
+		leaves = append(leaves, s.chunks[index]...)
+	}
+	t, err := New(s.hash, s.manifest.Depth, s.zeroValue, s.manifest.Arity, leaves, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !t.equal(t.Root(), s.manifest.Root) {
+		return nil, errors.New("the synced root does not match the manifest")
+	}
+	return t, nil
+}
+
+// Save writes the manifest and the verified chunks, so the sync can be
+// resumed with ResumeSyncer.
+func (s *Syncer[N]) Save(w io.Writer, codec NodeCodec[N]) error {
+	header := binary.AppendUvarint(nil, uint64(s.manifest.Depth))
+	header = binary.AppendUvarint(header, uint64(s.manifest.Arity))
+	header = binary.AppendUvarint(header, uint64(s.manifest.Size))
+	header = binary.AppendUvarint(header, uint64(s.manifest.ChunkLevel))
+	header, err := appendNode(header, codec, s.manifest.Root)
+	if err != nil {
+		return err
+	}
+	if header, err = appendNodes(header, codec, s.manifest.ChunkRoots); err != nil {
+		return err
+	}
+	header = binary.AppendUvarint(header, uint64(len(s.chunks)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	for index := range s.manifest.ChunkRoots {
+		leaves, ok := s.chunks[index]
+		if !ok {
+			continue
+		}
+		chunk, err := appendNodes(binary.AppendUvarint(nil, uint64(index)), codec, leaves)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ResumeSyncer returns a Syncer resuming a sync saved by Save. The manifest
+// and the chunks are verified again.
+func ResumeSyncer[N comparable](r io.Reader, hash HashFunction[N], zeroValue N, codec NodeCodec[N], opts ...Option) (*Syncer[N], error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	reader := &byteReader{b: b}
+	manifest := &SyncManifest[N]{
+		Metadata: Metadata{
+			Depth: reader.int(),
+			Arity: reader.int(),
+			Size:  reader.int(),
+		},
+		ChunkLevel: reader.int(),
+	}
+	manifest.Root = readNode(reader, codec)
+	manifest.ChunkRoots = readNodes(reader, codec)
+	count := reader.length()
+	if reader.err != nil {
+		return nil, fmt.Errorf("invalid sync state: %w", reader.err)
+	}
+
+	s, err := NewSyncer(hash, zeroValue, manifest, opts...)
+	if err != nil {
+		return nil, err
+	}
+	for range count {
+		index := reader.int()
+		leaves := readNodes(reader, codec)
+		if reader.err != nil {
+			return nil, fmt.Errorf("invalid sync state: %w", reader.err)
+		}
+		if err := s.Add(index, leaves); err != nil {
+			return nil, err
+		}
+	}
+	if err := reader.done(); err != nil {
+		return nil, fmt.Errorf("invalid sync state: %w", err)
+	}
+	return s, nil
+}
+
+// syncSpan returns the number of leaves of a chunk whose root is at the given
+// level, saturating at the largest int.
+func syncSpan(arity, chunkLevel int) int {
+	return int(min(capacity(arity, chunkLevel), uint64(maxInt)))
+}
+
+// syncChunks returns the number of chunks of a tree of the given size.
+func syncChunks(size, arity, chunkLevel int) int {
+	if size == 0 {
+		return 0
+	}
+	return (size-1)/syncSpan(arity, chunkLevel) + 1
+}
+
+// hashUp hashes the nodes of a level up to the given level, padding each
+// level with its zero value, and returns the single node of that level, if
+// the nodes fit under it.
+func (s *Syncer[N]) hashUp(nodes []N, from, to int) (N, bool) {
+	if len(nodes) == 0 {
+		return s.zeroes[to], true
+	}
+	arity := s.manifest.Arity
+	for level := from; level < to; level++ {
+		parents := make([]N, 0, (len(nodes)+arity-1)/arity)
+		for start := 0; start < len(nodes); start += arity {
+			children := make([]N, arity)
+			for i := range children {
+				if start+i < len(nodes) {
+					children[i] = nodes[start+i]
+				} else {
+					children[i] = s.zeroes[level]
+				}
+			}
+			parents = append(parents, s.hash(children))
+		}
+		nodes = parents
+	}
+	if len(nodes) != 1 {
+		var zero N
+		return zero, false
+	}
+	return nodes[0], true
+}
@@ -0,0 +1,172 @@
+package imt_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+// treePeer serves the chunks of a tree, altering the leaves of the chunks
+// whose index is in faulty.
+func treePeer(tree *imt.IMT[uint64], faulty map[int]bool) imt.SyncPeer[uint64] {
+	return imt.SyncPeerFunc[uint64](func(_ context.Context, chunkLevel, index int) ([]uint64, error) {
+		leaves, err := tree.SyncChunk(chunkLevel, index)
+		if err == nil && faulty[index] {
+			leaves[0]++
+		}
+		return leaves, err
+	})
+}
+
+func TestSyncer(t *testing.T) {
+	tests := []struct {
+		arity      int
+		size       int
+		chunkLevel int
+	}{
+		{2, 0, 2},
+		{2, 1, 0},
+		{2, 13, 2},
+		{2, 16, 4},
+		{3, 20, 1},
+		{3, 27, 3},
+		{4, 5, 2},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("arity %d size %d level %d", test.arity, test.size, test.chunkLevel), func(t *testing.T) {
+			leaves := imttest.RandomLeaves(imttest.Rand(uint64(test.size)), test.size, imttest.Uint64Leaf)
+			tree, err := imt.New(imttest.Uint64Hash, 4, 0, test.arity, leaves)
+			if err != nil {
+				t.Fatal(err)
+			}
+			manifest, err := tree.CreateSyncManifest(test.chunkLevel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			syncer, err := imt.NewSyncer(imttest.Uint64Hash, 0, manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The first faulty chunk stops the sync, and another peer
+			// resumes it.
+			faulty := map[int]bool{len(manifest.ChunkRoots) / 2: true}
+			err = syncer.Run(context.Background(), treePeer(tree, faulty))
+			if len(manifest.ChunkRoots) > 0 && err == nil {
+				t.Fatal("the chunk of a faulty peer was accepted")
+			}
+			if missing := syncer.Missing(); len(manifest.ChunkRoots) > 0 && (len(missing) == 0 || missing[0] != len(manifest.ChunkRoots)/2) {
+				t.Fatalf("Missing = %v after a faulty chunk", missing)
+			}
+
+			// The progress survives a restart.
+			var saved bytes.Buffer
+			if err := syncer.Save(&saved, uint64Codec{}); err != nil {
+				t.Fatal(err)
+			}
+			resumed, err := imt.ResumeSyncer(&saved, imttest.Uint64Hash, 0, uint64Codec{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(resumed.Missing()) != len(syncer.Missing()) {
+				t.Fatal("the resumed sync lost chunks")
+			}
+			if err := resumed.Run(context.Background(), treePeer(tree, nil)); err != nil {
+				t.Fatal(err)
+			}
+			synced, err := resumed.Finish()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if synced.Root() != tree.Root() || synced.Size() != tree.Size() {
+				t.Fatal("the synced tree differs")
+			}
+		})
+	}
+}
+
+func TestSyncerRejectsInvalidChunks(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3, 4, 5, 6, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := tree.CreateSyncManifest(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syncer, err := imt.NewSyncer(imttest.Uint64Hash, 0, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		index  int
+		leaves []uint64
+	}{
+		{"missing chunk", 2, []uint64{1}},
+		{"negative index", -1, []uint64{1, 2, 3, 4}},
+		{"swapped leaves", 0, []uint64{2, 1, 3, 4}},
+		{"missing leaf", 1, []uint64{5, 6}},
+		{"extra leaf", 1, []uint64{5, 6, 7, 0}},
+		{"chunk of another index", 1, []uint64{1, 2, 3, 4}},
+	}
+	for _, test := range tests {
+		if err := syncer.Add(test.index, test.leaves); err == nil {
+			t.Errorf("%s: the chunk was accepted", test.name)
+		}
+	}
+	if _, err := syncer.Finish(); err == nil {
+		t.Fatal("finished a sync with missing chunks")
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := syncer.Run(cancelled, treePeer(tree, nil)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run with a cancelled context: got error %v", err)
+	}
+}
+
+func TestSyncerRejectsInvalidManifests(t *testing.T) {
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{1, 2, 3, 4, 5, 6, 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		tamper func(m *imt.SyncManifest[uint64])
+	}{
+		{"altered root", func(m *imt.SyncManifest[uint64]) { m.Root++ }},
+		{"altered chunk root", func(m *imt.SyncManifest[uint64]) { m.ChunkRoots[1]++ }},
+		{"missing chunk root", func(m *imt.SyncManifest[uint64]) { m.ChunkRoots = m.ChunkRoots[:1] }},
+		{"extra chunk root", func(m *imt.SyncManifest[uint64]) { m.ChunkRoots = append(m.ChunkRoots, 0) }},
+		{"larger size", func(m *imt.SyncManifest[uint64]) { m.Size = 9 }},
+		{"negative size", func(m *imt.SyncManifest[uint64]) { m.Size = -1 }},
+		{"size beyond capacity", func(m *imt.SyncManifest[uint64]) { m.Size = 17 }},
+		{"chunk level above the root", func(m *imt.SyncManifest[uint64]) { m.ChunkLevel = 5 }},
+		{"huge depth", func(m *imt.SyncManifest[uint64]) { m.Depth = 1 << 30 }},
+		{"deep unary tree", func(m *imt.SyncManifest[uint64]) { m.Depth, m.Arity, m.Size = 1<<30, 1, 1 }},
+		{"huge arity", func(m *imt.SyncManifest[uint64]) { m.Arity = 1 << 30 }},
+		{"capacity overflowing int", func(m *imt.SyncManifest[uint64]) { m.Depth = 64 }},
+		{"zero arity", func(m *imt.SyncManifest[uint64]) { m.Arity = 0 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manifest, err := tree.CreateSyncManifest(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			test.tamper(manifest)
+			if _, err := imt.NewSyncer(imttest.Uint64Hash, 0, manifest); err == nil {
+				t.Fatal("the manifest was accepted")
+			}
+		})
+	}
+	if _, err := imt.NewSyncer[uint64](imttest.Uint64Hash, 0, nil); err == nil {
+		t.Fatal("a nil manifest was accepted")
+	}
+}
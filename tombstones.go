@@ -0,0 +1,107 @@
+package imt
+
+import "math/bits"
+
+// LeafState tells whether a position of a tree holds a leaf.
+type LeafState int
+
+const (
+	// LeafAbsent is the state of a position where no leaf was inserted.
+	LeafAbsent LeafState = iota
+	// LeafLive is the state of a leaf that was inserted and not deleted,
+	// even if its value is the zero value.
+	LeafLive
+	// LeafDeleted is the state of a leaf that was deleted and not updated
+	// since.
+	LeafDeleted
+)
+
+// String returns the name of the state.
+func (s LeafState) String() string {
+	switch s {
+	case LeafLive:
+		return "live"
+	case LeafDeleted:
+		return "deleted"
+	default:
+		return "absent"
+	}
+}
+
+// LeafState returns the state of a position, which distinguishes a deleted
+// leaf from a live leaf equal to the zero value.
+func (t *IMT[N]) LeafState(index int) LeafState {
+	switch {
+	case index < 0 || index >= t.nodes[0].Len():
+		return LeafAbsent
+	case t.tombstones.has(index):
+		return LeafDeleted
+	default:
+		return LeafLive
+	}
+}
+
+// DeletedIndices returns the indices of the deleted leaves, in increasing
+// order.
+func (t *IMT[N]) DeletedIndices() []int {
+	return t.tombstones.indices()
+}
+
+// LiveSize returns the number of leaves that are not deleted.
+func (t *IMT[N]) LiveSize() int {
+	return t.nodes[0].Len() - t.tombstones.count
+}
+
+// tombstones is a bitset of the deleted leaves.
+type tombstones struct {
+	words []uint64
+	count int
+}
+
+func (s *tombstones) has(index int) bool {
+	return index/64 < len(s.words) && s.words[index/64]&(1<<(index%64)) != 0
+}
+
+func (s *tombstones) set(index int) {
+	if s.has(index) {
+		return
+	}
+	for len(s.words) <= index/64 {
+		s.words = append(s.words, 0)
+	}
+	s.words[index/64] |= 1 << (index % 64)
+	s.count++
+}
+
+func (s *tombstones) clear(index int) {
+	if !s.has(index) {
+		return
+	}
+	s.words[index/64] &^= 1 << (index % 64)
+	s.count--
+}
+
+// first returns the lowest index of the set, which must not be empty.
+func (s *tombstones) first() int {
+	for i, word := range s.words {
+		if word != 0 {
+			return i*64 + bits.TrailingZeros64(word)
+		}
+	}
+	return -1
+}
+
+func (s *tombstones) indices() []int {
+	indices := make([]int, 0, s.count)
+	for i, word := range s.words {
+		for word != 0 {
+			indices = append(indices, i*64+bits.TrailingZeros64(word))
+			word &= word - 1
+		}
+	}
+	return indices
+}
+
+func (s *tombstones) clone() tombstones {
+	return tombstones{words: append([]uint64(nil), s.words...), count: s.count}
+}
@@ -0,0 +1,88 @@
+package imt
+
+import "context"
+
+// Attribute is a key-value pair describing a span.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Span is an operation of a tree being traced.
+type Span interface {
+	// End ends the span, recording the error of the operation, if any.
+	End(err error)
+}
+
+// Tracer starts the spans of the bulk operations of a tree. Its method set is
+// small enough for an adapter to an OpenTelemetry tracer to be a few lines:
+// Start calls the Start method of the tracer with the attributes converted
+// to attribute.KeyValue, and End records the error and ends the span.
+type Tracer interface {
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+}
+
+// TracerProvider provides the Tracer of an instrumentation scope, like the
+// TracerProvider of OpenTelemetry.
+type TracerProvider interface {
+	Tracer(name string) Tracer
+}
+
+// TracerName is the name of the instrumentation scope of the spans of a tree.
+const TracerName = "github.com/noble-assets/imt"
+
+// WithTracerProvider sets the provider of the Tracer starting a span for each
+// bulk operation of the tree: the construction from a list of leaves,
+// InsertMany, StreamAllProofs, Snapshot and Compact. The spans have the
+// attributes "imt.depth", "imt.arity", "imt.batch_size", the number of leaves
+// processed, and "imt.backend", the representation of the levels. Without
+// it, no span is started.
+func WithTracerProvider(provider TracerProvider) Option {
+	return func(o *options) {
+		o.tracer = provider.Tracer(TracerName)
+	}
+}
+
+// SetTraceContext sets the context of the spans of subsequent operations, so
+// that they are children of the span of the request being served. Like the
+// gas meter, it is expected to be replaced before each request.
+func (t *IMT[N]) SetTraceContext(ctx context.Context) {
+	t.traceContext = ctx
+}
+
+// startSpan starts the span of a bulk operation processing batchSize leaves.
+func (t *IMT[N]) startSpan(op string, batchSize int) Span {
+	if t.options.tracer == nil {
+		return noopSpan{}
+	}
+	ctx := t.traceContext
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, span := t.options.tracer.Start(ctx, "imt."+op,
+		Attribute{Key: "imt.depth", Value: t.depth},
+		Attribute{Key: "imt.arity", Value: t.arity},
+		Attribute{Key: "imt.batch_size", Value: batchSize},
+		Attribute{Key: "imt.backend", Value: t.backend()},
+	)
+	return span
+}
+
+// backend returns the name of the representation of the levels of the tree.
+func (t *IMT[N]) backend() string {
+	switch {
+	case t.cache != nil:
+		return "leaves_only"
+	case t.interner != nil:
+		return "interned"
+	case t.options.arena:
+		return "arena"
+	default:
+		return "chunked"
+	}
+}
+
+// noopSpan is the span of trees without a tracer.
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
@@ -0,0 +1,59 @@
+package imt
+
+import "slices"
+
+// ProofTranscript records the verification of a proof level by level, to find
+// where the computation diverges from another implementation.
+type ProofTranscript[N comparable] struct {
+	// The nodes of the path of the leaf: the leaf, then the node computed at
+	// each level, up to the computed root.
+	Nodes []N
+	// The children hashed at each level, with the node of the path inserted
+	// among the siblings.
+	Children [][]N
+	// The error that made the proof malformed, if any, in which case nothing
+	// is computed.
+	Err error
+	// Whether the computed root is the root of the proof.
+	Valid bool
+}
+
+// VerifyProofTranscript verifies a MerkleProof like VerifyProof, and returns
+// the transcript of the verification along with the result.
+func VerifyProofTranscript[N comparable](proof *MerkleProof[N], hash HashFunction[N]) ProofTranscript[N] {
+	var transcript ProofTranscript[N]
+	if _, err := ProofArity(proof); err != nil {
+		transcript.Err = err
+		return transcript
+	}
+
+	node := proof.Leaf
+	transcript.Nodes = append(make([]N, 0, len(proof.Siblings)+1), node)
+	transcript.Children = make([][]N, 0, len(proof.Siblings))
+	for level, siblings := range proof.Siblings {
+		children := slices.Insert(slices.Clone(siblings), proof.PathIndices[level], node)
+		node = hash(children)
+		transcript.Children = append(transcript.Children, children)
+		transcript.Nodes = append(transcript.Nodes, node)
+	}
+	transcript.Valid = node == proof.Root
+
+	return transcript
+}
+
+// Mismatch returns the first level at which the nodes of the transcript differ
+// from the given ones, which are the nodes of the same path computed by
+// another implementation, starting with the leaf. It returns -1 if the nodes
+// are the same, and the length of the shorter list if one is a prefix of the
+// other.
+func (t ProofTranscript[N]) Mismatch(nodes []N) int {
+	for level := range min(len(t.Nodes), len(nodes)) {
+		if t.Nodes[level] != nodes[level] {
+			return level
+		}
+	}
+	if len(t.Nodes) != len(nodes) {
+		return min(len(t.Nodes), len(nodes))
+	}
+	return -1
+}
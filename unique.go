@@ -0,0 +1,78 @@
+package imt
+
+// WithUniqueLeaves makes the tree a set: inserting or updating a leaf to a
+// value already held by another leaf fails with ErrDuplicateLeaf, whose
+// *Error holds the index of the existing leaf. The tree keeps an index of its
+// leaves, which also makes IndexOf constant-time, so that nullifier and
+// membership sets don't race between IndexOf and Insert. The zero value of
+// the tree, which deleted leaves hold, may appear any number of times. The
+// leaves are compared with ==, even if the tree has an equality function.
+func WithUniqueLeaves() Option {
+	return func(o *options) {
+		o.uniqueLeaves = true
+	}
+}
+
+// indexLeaves builds the index of the leaves of a tree with unique leaves,
+// and returns ErrDuplicateLeaf if a leaf appears twice.
+func (t *IMT[N]) indexLeaves(op string) error {
+	if !t.options.uniqueLeaves {
+		return nil
+	}
+	t.positions = make(map[N]int, t.nodes[0].Len())
+	for index := range t.nodes[0].Len() {
+		if err := t.checkUnique(op, index, t.nodes[0].Get(index)); err != nil {
+			return err
+		}
+		t.indexLeaf(index, t.nodes[0].Get(index))
+	}
+	return nil
+}
+
+// checkUnique returns ErrDuplicateLeaf if the tree has unique leaves and a
+// leaf other than the one at the given index holds the given value.
+func (t *IMT[N]) checkUnique(op string, index int, leaf N) error {
+	if t.positions == nil || leaf == t.zeroes[0] {
+		return nil
+	}
+	if existing, ok := t.positions[leaf]; ok && existing != index {
+		return t.opError(op, 0, existing, ErrDuplicateLeaf)
+	}
+	return nil
+}
+
+// checkUniqueMany returns ErrDuplicateLeaf if the tree has unique leaves and
+// one of the given leaves, inserted in the tree, would be a duplicate. The
+// *Error of a leaf repeated in the list involves no leaf of the tree.
+func (t *IMT[N]) checkUniqueMany(op string, leaves []N) error {
+	if t.positions == nil {
+		return nil
+	}
+	seen := make(map[N]struct{}, len(leaves))
+	for _, leaf := range leaves {
+		if err := t.checkUnique(op, -1, leaf); err != nil {
+			return err
+		}
+		if _, ok := seen[leaf]; ok && leaf != t.zeroes[0] {
+			return t.opError(op, -1, -1, ErrDuplicateLeaf)
+		}
+		seen[leaf] = struct{}{}
+	}
+	return nil
+}
+
+// indexLeaf records that the leaf at the given index holds a value, if the
+// tree has unique leaves.
+func (t *IMT[N]) indexLeaf(index int, leaf N) {
+	if t.positions != nil && leaf != t.zeroes[0] {
+		t.positions[leaf] = index
+	}
+}
+
+// unindexLeaf removes the value of the leaf at the given index from the
+// index, if the tree has unique leaves.
+func (t *IMT[N]) unindexLeaf(index int, leaf N) {
+	if existing, ok := t.positions[leaf]; ok && existing == index {
+		delete(t.positions, leaf)
+	}
+}
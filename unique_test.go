@@ -0,0 +1,82 @@
+package imt_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/noble-assets/imt"
+	"github.com/noble-assets/imt/imttest"
+)
+
+func newUniqueTree(t *testing.T, leaves []uint64, opts ...imt.Option) *imt.IMT[uint64] {
+	t.Helper()
+	tree, err := imt.New(imttest.Uint64Hash, 4, 0, 2, leaves, append(opts, imt.WithUniqueLeaves())...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestUniqueLeavesRejectDuplicates(t *testing.T) {
+	tests := []struct {
+		name string
+		op   func(tree *imt.IMT[uint64]) error
+		want error
+	}{
+		{"insert new", func(tree *imt.IMT[uint64]) error { return tree.Insert(8) }, nil},
+		{"insert duplicate", func(tree *imt.IMT[uint64]) error { return tree.Insert(6) }, imt.ErrDuplicateLeaf},
+		{"insert zero twice", func(tree *imt.IMT[uint64]) error {
+			if err := tree.Insert(0); err != nil {
+				return err
+			}
+			return tree.Insert(0)
+		}, nil},
+		{"insert many duplicate", func(tree *imt.IMT[uint64]) error { return tree.InsertMany([]uint64{8, 5}) }, imt.ErrDuplicateLeaf},
+		{"insert many repeated", func(tree *imt.IMT[uint64]) error { return tree.InsertMany([]uint64{8, 8}) }, imt.ErrDuplicateLeaf},
+		{"update to duplicate", func(tree *imt.IMT[uint64]) error { return tree.Update(0, 7) }, imt.ErrDuplicateLeaf},
+		{"update to itself", func(tree *imt.IMT[uint64]) error { return tree.Update(1, 6) }, nil},
+		{"reinsert deleted", func(tree *imt.IMT[uint64]) error {
+			if err := tree.Delete(1); err != nil {
+				return err
+			}
+			return tree.Insert(6)
+		}, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tree := newUniqueTree(t, []uint64{5, 6, 7})
+			if err := test.op(tree); !errors.Is(err, test.want) {
+				t.Fatalf("got error %v, want %v", err, test.want)
+			}
+		})
+	}
+
+	if _, err := imt.New(imttest.Uint64Hash, 4, 0, 2, []uint64{5, 5}, imt.WithUniqueLeaves()); !errors.Is(err, imt.ErrDuplicateLeaf) {
+		t.Fatalf("building a tree with duplicate leaves: got error %v", err)
+	}
+}
+
+func TestUniqueLeavesAfterCompact(t *testing.T) {
+	tree := newUniqueTree(t, []uint64{5, 6, 7})
+	if err := tree.Delete(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tree.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	for leaf, want := range map[uint64]int{5: -1, 6: 0, 7: 1} {
+		if got := tree.IndexOf(leaf); got != want {
+			t.Fatalf("IndexOf(%d) = %d, want %d", leaf, got, want)
+		}
+	}
+
+	if err := tree.Update(0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Insert(6); err != nil {
+		t.Fatalf("inserting a leaf that was updated away: %v", err)
+	}
+	if err := tree.Insert(7); !errors.Is(err, imt.ErrDuplicateLeaf) {
+		t.Fatalf("inserting a moved leaf: got error %v", err)
+	}
+}
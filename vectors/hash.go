@@ -0,0 +1,39 @@
+package vectors
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/noble-assets/imt"
+)
+
+// registered holds the hash functions added with RegisterHash.
+var registered sync.Map
+
+// RegisterHash makes a hash function available to the vectors whose
+// configuration uses the given name, such as a Poseidon implementation for
+// vectors exported from zk-kit or Semaphore. It replaces any hash function
+// previously registered with the same name, including the built-in ones.
+func RegisterHash(name string, hash imt.HashFunction[[32]byte]) {
+	registered.Store(name, hash)
+}
+
+// Hash returns the hash function of the given name, either registered with
+// RegisterHash or built in. The built-in hash functions hash the
+// concatenation of the children, as abi.encodePacked does for bytes32 values:
+//
+//   - "keccak256": used by Hyperlane's MerkleLib and most Solidity trees.
+//   - "sha256": used by the Ethereum deposit contract.
+func Hash(name string) (imt.HashFunction[[32]byte], error) {
+	if hash, ok := registered.Load(name); ok {
+		return hash.(imt.HashFunction[[32]byte]), nil
+	}
+	switch name {
+	case "keccak256":
+		return imt.Keccak256Hash, nil
+	case "sha256":
+		return imt.SHA256Hash, nil
+	default:
+		return nil, fmt.Errorf("unknown hash function %q", name)
+	}
+}
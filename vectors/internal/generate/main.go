@@ -0,0 +1,86 @@
+// Command generate writes the vectors shipped with the vectors package. It is
+// run by go generate in the directory of the package.
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/noble-assets/imt/internal/keccak"
+	"github.com/noble-assets/imt/vectors"
+)
+
+// knownZeroes are published zero hashes, which the roots of the empty trees
+// of the vectors must match: the root of an empty tree of depth d is the zero
+// hash of level d.
+var knownZeroes = map[string]map[int]string{
+	// Z_1, Z_2 and Z_32 of Hyperlane's MerkleLib.
+	"keccak256": {
+		1:  "ad3228b676f7d3cd4284a5443f17f1962b36e491b30a40b2405849e597ba5fb5",
+		2:  "b4c11951957c6f8f642c4af61cd6b24640fec6dc7fc607ee8206a99e92410d30",
+		32: "27ae5ba08d7291c96c8cbddcc148bf48a6d68c7974b94356f53754ef6171d757",
+	},
+	// zero_hashes[1] and zero_hashes[2] of the Ethereum deposit contract.
+	"sha256": {
+		1: "f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a92759fb4b",
+		2: "db56114e00fdd4c1f85c892bf35ac9a89289aaecb1ebd0a96cde606a748b5d71",
+	},
+}
+
+func main() {
+	if err := checkKnownZeroes(); err != nil {
+		log.Fatal(err)
+	}
+
+	var file vectors.File
+	for _, spec := range []struct {
+		name, description string
+		config            vectors.Config
+		leaves            int
+	}{
+		{"hyperlane-keccak256-depth32", "Binary keccak256 tree of depth 32, as in Hyperlane's MerkleTreeHook.", vectors.Config{Hash: "keccak256", Depth: 32, Arity: 2}, 9},
+		{"keccak256-depth4-arity3", "Ternary keccak256 tree, partially filled.", vectors.Config{Hash: "keccak256", Depth: 4, Arity: 3}, 11},
+		{"keccak256-depth2-arity5", "Quinary keccak256 tree, filled to capacity.", vectors.Config{Hash: "keccak256", Depth: 2, Arity: 5}, 25},
+		{"sha256-depth5", "Binary sha256 tree, as used by the Ethereum deposit contract.", vectors.Config{Hash: "sha256", Depth: 5, Arity: 2}, 13},
+	} {
+		leaves := make([]vectors.Node, spec.leaves)
+		indices := make([]int, spec.leaves)
+		for i := range leaves {
+			leaves[i] = keccak.Sum256([]byte(fmt.Sprintf("leaf %d", i)))
+			indices[i] = i
+		}
+		vector, err := vectors.Generate(spec.name, spec.description, spec.config, leaves, indices)
+		if err != nil {
+			log.Fatal(err)
+		}
+		file.Vectors = append(file.Vectors, vector)
+	}
+
+	b, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("vectors.json", append(b, '\n'), 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// checkKnownZeroes checks the zero hashes computed by the package against the
+// published ones.
+func checkKnownZeroes() error {
+	for name, zeroes := range knownZeroes {
+		for depth, expected := range zeroes {
+			vector, err := vectors.Generate("", "", vectors.Config{Hash: name, Depth: depth, Arity: 2}, nil, nil)
+			if err != nil {
+				return err
+			}
+			if root := hex.EncodeToString(vector.Roots[0][:]); root != expected {
+				return fmt.Errorf("%s zero hash of level %d is %s, expected %s", name, depth, root, expected)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+// Package vectors defines a JSON format for test vectors of incremental
+// Merkle trees with 32-byte nodes, and loads and verifies them, so that
+// consumers can assert in their own CI that the roots and proofs computed by
+// another stack (a contract, a circuit or another library) match this
+// implementation.
+//
+// The package ships vectors computed by this implementation, whose empty-tree
+// roots are cross-checked against the zero hashes published by Hyperlane's
+// MerkleLib and the Ethereum deposit contract. Vectors generated by another
+// stack can be loaded with Load and verified the same way.
+package vectors
+
+//go:generate go run ./internal/generate
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/noble-assets/imt"
+)
+
+//go:embed vectors.json
+var embedded []byte
+
+// Node is a 32-byte node, encoded in JSON as a 0x-prefixed hex string.
+type Node [32]byte
+
+// MarshalText encodes the node as a 0x-prefixed hex string.
+func (n Node) MarshalText() ([]byte, error) {
+	return []byte("0x" + hex.EncodeToString(n[:])), nil
+}
+
+// UnmarshalText decodes a node from a hex string, with or without prefix.
+func (n *Node) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(strings.TrimPrefix(string(text), "0x"))
+	if err != nil {
+		return err
+	}
+	if len(b) != len(n) {
+		return fmt.Errorf("a node must have %d bytes, got %d", len(n), len(b))
+	}
+	copy(n[:], b)
+	return nil
+}
+
+// Config contains the parameters of the tree of a vector.
+type Config struct {
+	Hash      string `json:"hash"`      // The name of the hash function, see Hash.
+	Depth     int    `json:"depth"`     // The depth of the tree.
+	Arity     int    `json:"arity"`     // The number of children per node.
+	ZeroValue Node   `json:"zeroValue"` // The zero value of the leaves.
+}
+
+// Proof is an expected proof of a leaf against the final root of a vector.
+type Proof struct {
+	LeafIndex   int      `json:"leafIndex"`
+	Siblings    [][]Node `json:"siblings"`
+	PathIndices []int    `json:"pathIndices"`
+}
+
+// Vector is a test vector: the leaves inserted in order into an empty tree,
+// the expected root before the first insertion and after each insertion, and
+// expected proofs against the final root.
+type Vector struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Config      Config  `json:"config"`
+	Leaves      []Node  `json:"leaves"`
+	Roots       []Node  `json:"roots"`
+	Proofs      []Proof `json:"proofs"`
+}
+
+// File is the top-level object of a file of vectors.
+type File struct {
+	Vectors []Vector `json:"vectors"`
+}
+
+// Load reads a file of vectors.
+func Load(r io.Reader) ([]Vector, error) {
+	var file File
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return nil, err
+	}
+	return file.Vectors, nil
+}
+
+// Embedded returns the vectors shipped with the package.
+func Embedded() ([]Vector, error) {
+	return Load(bytes.NewReader(embedded))
+}
+
+// Generate computes a vector from a configuration and leaves with this
+// implementation, including the proofs of the leaves at the given indices.
+func Generate(name, description string, config Config, leaves []Node, proofIndices []int) (Vector, error) {
+	hash, err := Hash(config.Hash)
+	if err != nil {
+		return Vector{}, err
+	}
+	t, err := imt.New(hash, config.Depth, [32]byte(config.ZeroValue), config.Arity, nil)
+	if err != nil {
+		return Vector{}, err
+	}
+
+	vector := Vector{
+		Name:        name,
+		Description: description,
+		Config:      config,
+		Leaves:      leaves,
+		Roots:       []Node{t.Root()},
+	}
+	for _, leaf := range leaves {
+		if err := t.Insert(leaf); err != nil {
+			return Vector{}, err
+		}
+		vector.Roots = append(vector.Roots, t.Root())
+	}
+	for _, index := range proofIndices {
+		proof, err := t.CreateProof(index)
+		if err != nil {
+			return Vector{}, err
+		}
+		siblings := make([][]Node, len(proof.Siblings))
+		for level, nodes := range proof.Siblings {
+			for _, node := range nodes {
+				siblings[level] = append(siblings[level], node)
+			}
+		}
+		vector.Proofs = append(vector.Proofs, Proof{
+			LeafIndex:   index,
+			Siblings:    siblings,
+			PathIndices: proof.PathIndices,
+		})
+	}
+
+	return vector, nil
+}
+
+// Verify checks that this implementation reproduces the vector: every root,
+// and every proof, which must also verify against the final root.
+func (v Vector) Verify() error {
+	hash, err := Hash(v.Config.Hash)
+	if err != nil {
+		return err
+	}
+	t, err := imt.New(hash, v.Config.Depth, [32]byte(v.Config.ZeroValue), v.Config.Arity, nil)
+	if err != nil {
+		return err
+	}
+	if len(v.Roots) != len(v.Leaves)+1 {
+		return fmt.Errorf("%s: expected %d roots, got %d", v.Name, len(v.Leaves)+1, len(v.Roots))
+	}
+
+	if Node(t.Root()) != v.Roots[0] {
+		return fmt.Errorf("%s: the root of the empty tree is %x, expected %x", v.Name, t.Root(), v.Roots[0])
+	}
+	for i, leaf := range v.Leaves {
+		if err := t.Insert(leaf); err != nil {
+			return fmt.Errorf("%s: leaf %d: %w", v.Name, i, err)
+		}
+		if Node(t.Root()) != v.Roots[i+1] {
+			return fmt.Errorf("%s: the root after leaf %d is %x, expected %x", v.Name, i, t.Root(), v.Roots[i+1])
+		}
+	}
+
+	for _, expected := range v.Proofs {
+		proof, err := t.CreateProof(expected.LeafIndex)
+		if err != nil {
+			return fmt.Errorf("%s: proof of leaf %d: %w", v.Name, expected.LeafIndex, err)
+		}
+		vectorProof := &imt.MerkleProof[[32]byte]{
+			Root:        t.Root(),
+			Leaf:        [32]byte(v.Leaves[expected.LeafIndex]),
+			LeafIndex:   expected.LeafIndex,
+			Siblings:    make([][][32]byte, len(expected.Siblings)),
+			PathIndices: expected.PathIndices,
+		}
+		for level, nodes := range expected.Siblings {
+			for _, node := range nodes {
+				vectorProof.Siblings[level] = append(vectorProof.Siblings[level], node)
+			}
+		}
+		vectorProof.Depth, vectorProof.Arity = proof.Depth, proof.Arity
+		if !vectorProof.Equal(proof) {
+			return fmt.Errorf("%s: the proof of leaf %d does not match", v.Name, expected.LeafIndex)
+		}
+		if !imt.VerifyProof(vectorProof, hash) {
+			return fmt.Errorf("%s: the proof of leaf %d does not verify", v.Name, expected.LeafIndex)
+		}
+	}
+
+	return nil
+}
+
+// VerifyAll verifies vectors and returns the errors of all the vectors that
+// failed, joined.
+func VerifyAll(vectors []Vector) error {
+	var errs []error
+	for _, vector := range vectors {
+		if err := vector.Verify(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
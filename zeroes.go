@@ -0,0 +1,66 @@
+package imt
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+)
+
+// WithZeroes sets the zero values of the levels of the tree, as returned by
+// Zeroes, optionally followed by the root of the empty tree, so that they
+// are not computed when the tree is created. Deep trees with expensive hash
+// functions, such as Poseidon, then start without depth sequential hashes.
+// The first zero value must be the zero value of the tree, and check levels
+// drawn at random are checked to be the hash of the level below, or all of
+// them if check is negative. The type of the nodes must match the tree, which
+// returns an error otherwise.
+func WithZeroes[N comparable](zeroes []N, check int) Option {
+	return func(o *options) {
+		o.zeroes = zeroes
+		o.zeroChecks = check
+	}
+}
+
+// presetZeroes sets the zero values of the tree from the ones of the options
+// and returns the root of the empty tree.
+func (t *IMT[N]) presetZeroes(zeroValue N, opts options) (N, error) {
+	var root N
+	zeroes, ok := opts.zeroes.([]N)
+	if !ok {
+		return root, errors.New("the zero values do not have the type of the nodes of the tree")
+	}
+	if len(zeroes) != t.depth && len(zeroes) != t.depth+1 {
+		return root, fmt.Errorf("expected %d or %d zero values, got %d", t.depth, t.depth+1, len(zeroes))
+	}
+	if !t.equal(zeroes[0], zeroValue) {
+		return root, errors.New("the first zero value must be the zero value of the tree")
+	}
+	copy(t.zeroes, zeroes)
+
+	// Each check hashes the zero values of a level and compares the result
+	// with the zero value of the level above it, or the root.
+	levels := len(zeroes) - 1
+	checks := rand.Perm(levels)
+	if opts.zeroChecks >= 0 && opts.zeroChecks < levels {
+		checks = checks[:opts.zeroChecks]
+	}
+	for _, level := range checks {
+		if expected := t.hashZeroes(level); !t.equal(zeroes[level+1], expected) {
+			return root, fmt.Errorf("zero value %d is not the hash of the zero values of level %d", level+1, level)
+		}
+	}
+
+	if len(zeroes) == t.depth+1 {
+		return zeroes[t.depth], nil
+	}
+	return t.hashZeroes(t.depth - 1), nil
+}
+
+// hashZeroes returns the hash of arity zero values of the given level.
+func (t *IMT[N]) hashZeroes(level int) N {
+	children := make([]N, t.arity)
+	for i := range children {
+		children[i] = t.zeroes[level]
+	}
+	return t.hash(children)
+}